@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+func runConvert(e *env, args []string) int {
+	fs := newFlagSet(e, "convert", "file")
+	to := fs.String("to", "", "target format: json or yaml (default: from -o extension, else the other format)")
+	output := fs.String("o", "", "output file (default: standard output)")
+	if code, ok := parseFlags(fs, args, 1, 1); !ok {
+		return code
+	}
+
+	src, err := readSource(e, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(e.stderr, "dago-graph: %v\n", err)
+		return exitError
+	}
+
+	target := strings.ToLower(*to)
+	if target == "" {
+		switch strings.ToLower(filepath.Ext(*output)) {
+		case ".json":
+			target = formatJSON
+		case ".yaml", ".yml":
+			target = formatYAML
+		}
+	}
+	switch target {
+	case "":
+		target = formatYAML
+		if src.format == formatYAML {
+			target = formatJSON
+		}
+	case "yml":
+		target = formatYAML
+	}
+
+	g, err := src.graph()
+	if err != nil {
+		fmt.Fprintf(e.stderr, "dago-graph: %s: %v\n", src.path, err)
+		return exitError
+	}
+	out, err := encodeGraph(g, target)
+	if err != nil {
+		fmt.Fprintf(e.stderr, "dago-graph: %v\n", err)
+		return exitError
+	}
+	if err := writeOutput(e, *output, out); err != nil {
+		fmt.Fprintf(e.stderr, "dago-graph: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
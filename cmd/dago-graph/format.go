@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+	"gopkg.in/yaml.v3"
+)
+
+func runFmt(e *env, args []string) int {
+	fs := newFlagSet(e, "fmt", "file...")
+	list := fs.Bool("l", false, "list files whose formatting differs from canonical form")
+	write := fs.Bool("w", false, "write the result to the source file instead of standard output")
+	if code, ok := parseFlags(fs, args, 1, -1); !ok {
+		return code
+	}
+
+	code := exitOK
+	for _, path := range fs.Args() {
+		src, err := readSource(e, path)
+		if err != nil {
+			fmt.Fprintf(e.stderr, "dago-graph: %v\n", err)
+			return exitError
+		}
+		out, err := format(src)
+		if err != nil {
+			fmt.Fprintf(e.stderr, "dago-graph: %s: %v\n", path, err)
+			return exitError
+		}
+
+		changed := !bytes.Equal(out, src.data)
+		if *list && changed {
+			fmt.Fprintln(e.stdout, path)
+			code = exitProblems
+		}
+		switch {
+		case *write && path != "-":
+			if changed {
+				if err := writeOutput(e, path, out); err != nil {
+					fmt.Fprintf(e.stderr, "dago-graph: %v\n", err)
+					return exitError
+				}
+			}
+		case !*list:
+			if err := writeOutput(e, "", out); err != nil {
+				fmt.Fprintf(e.stderr, "dago-graph: %v\n", err)
+				return exitError
+			}
+		}
+	}
+	return code
+}
+
+// format returns src in canonical form. JSON sources are decoded and encoded
+// again; YAML sources are formatted at the yaml.Node level instead, so that
+// comments, anchors, merge keys, $include and extra keys such as x-defaults
+// survive, which decoding into a graph.Graph would resolve or drop.
+func format(src *source) ([]byte, error) {
+	g, err := src.graph()
+	if err != nil {
+		return nil, err
+	}
+	if src.format != formatYAML {
+		canonicalize(g)
+		return encodeGraph(g, src.format)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(src.data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if root.Kind == 0 {
+		return src.data, nil
+	}
+	canonicalizeYAML(&root)
+	untagMergeKeys(&root)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&root); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// canonicalize puts g in canonical order. Object keys are already ordered by
+// the encoders (struct fields in declaration order, maps sorted); edges are
+// grouped by source node in node ID order, keeping the relative order of the
+// edges that leave the same node, since it may define their precedence.
+func canonicalize(g *graph.Graph) {
+	sort.SliceStable(g.Edges, func(i, j int) bool { return g.Edges[i].From < g.Edges[j].From })
+}
+
+// canonicalizeYAML orders the edges of a YAML document the way canonicalize
+// does. Edges that are not plain mappings (aliases, includes) sort as if they
+// had no source node.
+func canonicalizeYAML(root *yaml.Node) {
+	doc := root
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		doc = doc.Content[0]
+	}
+	if doc.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		if doc.Content[i].Value != "edges" || doc.Content[i+1].Kind != yaml.SequenceNode {
+			continue
+		}
+		edges := doc.Content[i+1].Content
+		sort.SliceStable(edges, func(a, b int) bool { return yamlEdgeFrom(edges[a]) < yamlEdgeFrom(edges[b]) })
+	}
+}
+
+// yamlEdgeFrom returns the from field of an edge mapping.
+func yamlEdgeFrom(n *yaml.Node) string {
+	if n.Kind != yaml.MappingNode {
+		return ""
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == "from" {
+			return n.Content[i+1].Value
+		}
+	}
+	return ""
+}
+
+// untagMergeKeys clears the tag yaml.v3 resolves merge keys to, which it would
+// otherwise write out as "!!merge <<".
+func untagMergeKeys(n *yaml.Node) {
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!merge" {
+		n.Tag = ""
+	}
+	for _, c := range n.Content {
+		untagMergeKeys(c)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/aescanero/dago-libs/pkg/lint"
+)
+
+func runLint(e *env, args []string) int {
+	fs := newFlagSet(e, "lint", "file...")
+	asJSON := fs.Bool("json", false, "print results as JSON")
+	strict := fs.Bool("strict", false, "fail on warnings as well as errors")
+	if code, ok := parseFlags(fs, args, 1, -1); !ok {
+		return code
+	}
+
+	results := make([]fileResult, 0, fs.NArg())
+	for _, path := range fs.Args() {
+		src, err := readSource(e, path)
+		if err != nil {
+			fmt.Fprintf(e.stderr, "dago-graph: %v\n", err)
+			return exitError
+		}
+		g, err := src.graph()
+		if err != nil {
+			results = append(results, fileResult{File: path, Problems: []problem{{Severity: "error", Message: err.Error()}}})
+			continue
+		}
+
+		findings := lint.Check(g)
+		r := fileResult{File: path, Valid: !lint.HasErrors(findings), Problems: make([]problem, 0, len(findings))}
+		for _, f := range findings {
+			r.Problems = append(r.Problems, problem{
+				Severity: string(f.Severity),
+				Rule:     f.Rule,
+				Pointer:  f.Pointer,
+				Message:  f.Message,
+			})
+		}
+		results = append(results, r)
+	}
+
+	return report(e, results, *asJSON, func(r fileResult) bool {
+		return !r.Valid || (*strict && len(r.Problems) > 0)
+	})
+}
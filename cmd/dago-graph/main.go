@@ -0,0 +1,85 @@
+// Command dago-graph validates, lints, formats, renders and converts graph
+// definitions.
+//
+// Usage:
+//
+//	dago-graph validate [-json] file...
+//	dago-graph lint [-json] [-strict] file...
+//	dago-graph fmt [-l] [-w] file...
+//	dago-graph render [-f dot|mermaid|html] [-dir TB|LR] [-state state.json] [-o out] file
+//	dago-graph convert -to json|yaml [-o out] file
+//
+// Files are JSON or YAML, detected from the extension (.json, .yaml, .yml) or
+// content; "-" reads standard input. YAML files may use $include; fmt keeps
+// includes, anchors and comments rather than resolving them.
+//
+// Exit status is 0 on success, 1 when validation or lint problems are found
+// (or, for fmt -l, when files are not formatted), and 2 on usage or I/O errors.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Exit codes.
+const (
+	exitOK       = 0
+	exitProblems = 1
+	exitError    = 2
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(env *env, args []string) int
+}
+
+var commands = []command{
+	{name: "validate", summary: "check graphs against the schema and structural rules", run: runValidate},
+	{name: "lint", summary: "report unused nodes, unreachable routes and missing default routes", run: runLint},
+	{name: "fmt", summary: "rewrite graphs in canonical form", run: runFmt},
+	{name: "render", summary: "export graphs to DOT, Mermaid or HTML", run: runRender},
+	{name: "convert", summary: "convert graphs between JSON and YAML", run: runConvert},
+}
+
+// env carries the process streams, so commands can be tested.
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], &env{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}))
+}
+
+func run(args []string, e *env) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		usage(e.stderr)
+		if len(args) == 0 {
+			return exitError
+		}
+		return exitOK
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(e, args[1:])
+		}
+	}
+	fmt.Fprintf(e.stderr, "dago-graph: unknown command %q\n\n", args[0])
+	usage(e.stderr)
+	return exitError
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: dago-graph <command> [flags] file...")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-9s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'dago-graph <command> -h' for the flags of a command.")
+}
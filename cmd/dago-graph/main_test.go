@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const validGraph = `{
+  "id": "review",
+  "name": "Review",
+  "entry_node": "draft",
+  "nodes": {
+    "draft": {"id": "draft", "type": "executor", "executor_type": "llm", "config": {"model": "gpt-4"}},
+    "publish": {"id": "publish", "type": "executor", "executor_type": "tool", "config": {"tool_name": "http_post"}},
+    "check": {"id": "check", "type": "router", "routes": [{"condition": "state.ok", "target": "publish"}], "default_route": "draft"}
+  },
+  "edges": [
+    {"from": "check", "to": "publish"},
+    {"from": "draft", "to": "check"}
+  ]
+}`
+
+const invalidGraphYAML = `id: broken
+entry_node: missing
+nodes:
+  draft:
+    id: draft
+    type: executor
+    executor_type: teleport
+edges: []
+`
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func runCLI(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, &env{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr})
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun_Usage(t *testing.T) {
+	if code, _, stderr := runCLI(t, ""); code != exitError || !strings.Contains(stderr, "Commands:") {
+		t.Errorf("expected usage with exit %d, got %d: %s", exitError, code, stderr)
+	}
+	if code, _, _ := runCLI(t, "", "help"); code != exitOK {
+		t.Errorf("expected help to succeed, got %d", code)
+	}
+	if code, _, stderr := runCLI(t, "", "explode"); code != exitError || !strings.Contains(stderr, "unknown command") {
+		t.Errorf("expected unknown command error, got %d: %s", code, stderr)
+	}
+	if code, _, _ := runCLI(t, "", "validate"); code != exitError {
+		t.Errorf("expected missing file to be a usage error, got %d", code)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := writeFile(t, "valid.json", validGraph)
+	invalid := writeFile(t, "invalid.yaml", invalidGraphYAML)
+
+	code, stdout, _ := runCLI(t, "", "validate", valid)
+	if code != exitOK || !strings.Contains(stdout, "valid.json: ok") {
+		t.Errorf("expected valid graph to pass, got %d: %s", code, stdout)
+	}
+
+	code, stdout, _ = runCLI(t, "", "validate", "-json", valid, invalid)
+	if code != exitProblems {
+		t.Errorf("expected exit %d, got %d", exitProblems, code)
+	}
+	var results []fileResult
+	if err := json.Unmarshal([]byte(stdout), &results); err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, stdout)
+	}
+	if len(results) != 2 || !results[0].Valid || results[1].Valid {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+
+	var schemaProblem, structureProblem bool
+	for _, p := range results[1].Problems {
+		if p.Rule == "schema" && p.Pointer == "/nodes/draft/executor_type" && strings.HasSuffix(p.Position, ":7:5") {
+			schemaProblem = true
+		}
+		if p.Rule == "structure" && strings.Contains(p.Message, "entry node 'missing' does not exist") {
+			structureProblem = true
+		}
+	}
+	if !schemaProblem || !structureProblem {
+		t.Errorf("expected located schema and structural problems, got %+v", results[1].Problems)
+	}
+}
+
+func TestValidate_Stdin(t *testing.T) {
+	code, stdout, _ := runCLI(t, validGraph, "validate", "-")
+	if code != exitOK || !strings.Contains(stdout, "-: ok") {
+		t.Errorf("expected stdin graph to pass, got %d: %s", code, stdout)
+	}
+}
+
+func TestLint(t *testing.T) {
+	graphWithWarning := strings.Replace(validGraph, `, "default_route": "draft"`, "", 1)
+	path := writeFile(t, "graph.json", graphWithWarning)
+
+	code, stdout, _ := runCLI(t, "", "lint", path)
+	if code != exitOK || !strings.Contains(stdout, "warning: /nodes/check:") || !strings.Contains(stdout, "(missing-default-route)") {
+		t.Errorf("expected warning without failure, got %d: %s", code, stdout)
+	}
+
+	code, _, _ = runCLI(t, "", "lint", "-strict", path)
+	if code != exitProblems {
+		t.Errorf("expected -strict to fail on warnings, got %d", code)
+	}
+
+	clean := writeFile(t, "clean.json", validGraph)
+	if code, stdout, _ := runCLI(t, "", "lint", clean); code != exitOK || !strings.Contains(stdout, "clean.json: ok") {
+		t.Errorf("expected clean graph to pass, got %d: %s", code, stdout)
+	}
+}
+
+func TestFmt(t *testing.T) {
+	path := writeFile(t, "graph.json", validGraph)
+
+	code, stdout, _ := runCLI(t, "", "fmt", "-l", path)
+	if code != exitProblems || strings.TrimSpace(stdout) != path {
+		t.Errorf("expected file to be listed, got %d: %s", code, stdout)
+	}
+
+	if code, _, stderr := runCLI(t, "", "fmt", "-w", path); code != exitOK {
+		t.Fatalf("fmt -w failed: %s", stderr)
+	}
+	formatted, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read formatted file: %v", err)
+	}
+	if bytes.Index(formatted, []byte(`"from": "check"`)) > bytes.Index(formatted, []byte(`"from": "draft"`)) {
+		t.Errorf("expected edges ordered by source node:\n%s", formatted)
+	}
+
+	if code, stdout, _ := runCLI(t, "", "fmt", "-l", path); code != exitOK || stdout != "" {
+		t.Errorf("expected formatted file to be stable, got %d: %s", code, stdout)
+	}
+}
+
+func TestFmt_YAMLKeepsIncludesAndAnchors(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"pipeline.yaml", "shared-nodes.yaml"} {
+		data, err := os.ReadFile(filepath.Join("..", "..", "pkg", "domain", "graph", "testdata", name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	path := filepath.Join(dir, "pipeline.yaml")
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read pipeline: %v", err)
+	}
+
+	if code, _, stderr := runCLI(t, "", "fmt", "-w", path); code != exitOK {
+		t.Fatalf("fmt -w failed: %s", stderr)
+	}
+	formatted, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read formatted file: %v", err)
+	}
+	if !bytes.Equal(formatted, original) {
+		t.Errorf("expected canonical pipeline to round-trip unchanged, got:\n%s", formatted)
+	}
+
+	unordered := strings.Replace(string(original), "  - from: draft\n    to: summarize\n  - from: summarize\n    to: done\n",
+		"  - from: summarize\n    to: done\n  - from: draft\n    to: summarize\n", 1)
+	if err := os.WriteFile(path, []byte(unordered), 0o644); err != nil {
+		t.Fatalf("failed to write pipeline: %v", err)
+	}
+	code, stdout, _ := runCLI(t, "", "fmt", path)
+	if code != exitOK || stdout != string(original) {
+		t.Errorf("expected edges reordered with includes and anchors kept (%d):\n%s", code, stdout)
+	}
+}
+
+func TestRender(t *testing.T) {
+	path := writeFile(t, "graph.json", validGraph)
+	state := writeFile(t, "state.json", `{"graph_id": "review", "status": "running", "node_states": {"draft": {"node_id": "draft", "status": "completed"}}}`)
+
+	code, stdout, _ := runCLI(t, "", "render", "-f", "mermaid", "-dir", "LR", "-state", state, path)
+	if code != exitOK || !strings.Contains(stdout, "flowchart LR") || !strings.Contains(stdout, "class n_draft status_completed") {
+		t.Errorf("unexpected mermaid output (%d):\n%s", code, stdout)
+	}
+
+	out := filepath.Join(t.TempDir(), "graph.html")
+	if code, _, stderr := runCLI(t, "", "render", "-o", out, path); code != exitOK {
+		t.Fatalf("render to file failed: %s", stderr)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil || !strings.HasPrefix(string(data), "<!DOCTYPE html>") {
+		t.Errorf("expected HTML inferred from the output extension, got %v", err)
+	}
+
+	if code, _, _ := runCLI(t, "", "render", "-f", "png", path); code != exitError {
+		t.Errorf("expected unsupported format error, got %d", code)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	path := writeFile(t, "graph.json", validGraph)
+
+	code, yamlOut, stderr := runCLI(t, "", "convert", path)
+	if code != exitOK {
+		t.Fatalf("convert to YAML failed: %s", stderr)
+	}
+	if !strings.Contains(yamlOut, "entry_node: draft") {
+		t.Errorf("expected YAML output, got:\n%s", yamlOut)
+	}
+
+	yamlPath := writeFile(t, "graph.yaml", yamlOut)
+	code, jsonOut, stderr := runCLI(t, "", "convert", "-to", "json", yamlPath)
+	if code != exitOK {
+		t.Fatalf("convert to JSON failed: %s", stderr)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonOut), &doc); err != nil || doc["entry_node"] != "draft" {
+		t.Errorf("expected JSON round trip, got %v: %s", err, jsonOut)
+	}
+
+	if code, _, _ := runCLI(t, "", "convert", "-to", "xml", path); code != exitError {
+		t.Errorf("expected unknown format error, got %d", code)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/render"
+)
+
+func runRender(e *env, args []string) int {
+	fs := newFlagSet(e, "render", "file")
+	format := fs.String("f", "", "output format: dot, mermaid or html (default: from -o extension, else dot)")
+	direction := fs.String("dir", string(render.TopToBottom), "layout direction: TB or LR")
+	title := fs.String("title", "", "title of the drawing (default: graph name)")
+	statePath := fs.String("state", "", "JSON file with a GraphState to overlay node statuses")
+	output := fs.String("o", "", "output file (default: standard output)")
+	if code, ok := parseFlags(fs, args, 1, 1); !ok {
+		return code
+	}
+
+	f := *format
+	if f == "" {
+		f = string(render.FormatDOT)
+		if ext := filepath.Ext(*output); ext != "" {
+			f = ext
+		}
+	}
+	renderFormat, err := render.ParseFormat(f)
+	if err != nil {
+		fmt.Fprintf(e.stderr, "dago-graph: %v\n", err)
+		return exitError
+	}
+	if *direction != string(render.TopToBottom) && *direction != string(render.LeftToRight) {
+		fmt.Fprintf(e.stderr, "dago-graph: invalid direction %q (want TB or LR)\n", *direction)
+		return exitError
+	}
+	opts := render.Options{Title: *title, Direction: render.Direction(*direction)}
+
+	if *statePath != "" {
+		data, err := os.ReadFile(*statePath)
+		if err != nil {
+			fmt.Fprintf(e.stderr, "dago-graph: failed to read state: %v\n", err)
+			return exitError
+		}
+		var gs domain.GraphState
+		if err := json.Unmarshal(data, &gs); err != nil {
+			fmt.Fprintf(e.stderr, "dago-graph: invalid state %s: %v\n", *statePath, err)
+			return exitError
+		}
+		opts.State = &gs
+	}
+
+	src, err := readSource(e, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(e.stderr, "dago-graph: %v\n", err)
+		return exitError
+	}
+	g, err := src.graph()
+	if err != nil {
+		fmt.Fprintf(e.stderr, "dago-graph: %s: %v\n", src.path, err)
+		return exitError
+	}
+
+	out, err := render.Render(g, renderFormat, opts)
+	if err != nil {
+		fmt.Fprintf(e.stderr, "dago-graph: %v\n", err)
+		return exitError
+	}
+	if err := writeOutput(e, *output, []byte(out)); err != nil {
+		fmt.Fprintf(e.stderr, "dago-graph: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
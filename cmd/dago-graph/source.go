@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+)
+
+// Source formats.
+const (
+	formatJSON = "json"
+	formatYAML = "yaml"
+)
+
+// source is a graph definition read from a file or standard input.
+type source struct {
+	path   string
+	format string
+	data   []byte
+}
+
+// readSource reads a graph definition, detecting its format.
+func readSource(e *env, path string) (*source, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(e.stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return &source{path: path, format: detectFormat(path, data), data: data}, nil
+}
+
+// detectFormat uses the file extension, falling back to the content:
+// JSON documents start with an object.
+func detectFormat(path string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return formatJSON
+	case ".yaml", ".yml":
+		return formatYAML
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return formatJSON
+	}
+	return formatYAML
+}
+
+// yamlDocument parses a YAML source, resolving $include relative to the file.
+func (s *source) yamlDocument() (*graph.YAMLDocument, error) {
+	if s.path == "-" {
+		return graph.ParseYAML(s.data, "", nil)
+	}
+	dir, name := filepath.Split(s.path)
+	if dir == "" {
+		dir = "."
+	}
+	return graph.ParseYAML(s.data, name, os.DirFS(dir))
+}
+
+// json returns the source as JSON.
+func (s *source) json() ([]byte, error) {
+	if s.format == formatJSON {
+		return s.data, nil
+	}
+	doc, err := s.yamlDocument()
+	if err != nil {
+		return nil, err
+	}
+	return doc.JSON()
+}
+
+// graph decodes the source.
+func (s *source) graph() (*graph.Graph, error) {
+	data, err := s.json()
+	if err != nil {
+		return nil, err
+	}
+	return graph.FromJSON(string(data))
+}
+
+// encodeGraph serializes g in the given format.
+func encodeGraph(g *graph.Graph, format string) ([]byte, error) {
+	switch format {
+	case formatJSON:
+		out, err := g.ToJSON()
+		if err != nil {
+			return nil, err
+		}
+		return []byte(out + "\n"), nil
+	case formatYAML:
+		out, err := g.ToYAML()
+		return []byte(out), err
+	default:
+		return nil, fmt.Errorf("unknown format %q (want json or yaml)", format)
+	}
+}
+
+// writeOutput writes data to path, or to standard output if path is empty or "-".
+func writeOutput(e *env, path string, data []byte) error {
+	if path == "" || path == "-" {
+		_, err := e.stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// writeJSON writes v as indented JSON to standard output.
+func writeJSON(e *env, v interface{}) error {
+	enc := json.NewEncoder(e.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// newFlagSet creates a flag set that reports errors to stderr.
+func newFlagSet(e *env, name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(e.stderr, "Usage: dago-graph %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses args and returns the exit code to use if parsing stopped
+// the command.
+func parseFlags(fs *flag.FlagSet, args []string, minArgs, maxArgs int) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK, false
+		}
+		return exitError, false
+	}
+	if fs.NArg() < minArgs || (maxArgs >= 0 && fs.NArg() > maxArgs) {
+		fs.Usage()
+		return exitError, false
+	}
+	return exitOK, true
+}
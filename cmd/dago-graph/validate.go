@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+	"github.com/aescanero/dago-libs/pkg/schema"
+)
+
+// problem is a single validation or lint problem, as reported with -json.
+type problem struct {
+	Severity string `json:"severity"`
+	Rule     string `json:"rule,omitempty"`
+	Pointer  string `json:"pointer,omitempty"`
+	Position string `json:"position,omitempty"`
+	Message  string `json:"message"`
+}
+
+// String formats the problem as "severity: location: message (rule)".
+func (p problem) String() string {
+	loc := p.Position
+	if loc == "" {
+		loc = p.Pointer
+	}
+	s := p.Severity + ": "
+	if loc != "" {
+		s += loc + ": "
+	}
+	s += p.Message
+	if p.Rule != "" {
+		s += " (" + p.Rule + ")"
+	}
+	return s
+}
+
+// fileResult groups the problems found in one file.
+type fileResult struct {
+	File     string    `json:"file"`
+	Valid    bool      `json:"valid"`
+	Problems []problem `json:"problems"`
+}
+
+func runValidate(e *env, args []string) int {
+	fs := newFlagSet(e, "validate", "file...")
+	asJSON := fs.Bool("json", false, "print results as JSON")
+	if code, ok := parseFlags(fs, args, 1, -1); !ok {
+		return code
+	}
+
+	validator, err := schema.NewValidator()
+	if err != nil {
+		fmt.Fprintf(e.stderr, "dago-graph: %v\n", err)
+		return exitError
+	}
+
+	results := make([]fileResult, 0, fs.NArg())
+	for _, path := range fs.Args() {
+		src, err := readSource(e, path)
+		if err != nil {
+			fmt.Fprintf(e.stderr, "dago-graph: %v\n", err)
+			return exitError
+		}
+		problems := validateSource(validator, src)
+		results = append(results, fileResult{File: path, Valid: len(problems) == 0, Problems: problems})
+	}
+	return report(e, results, *asJSON, func(r fileResult) bool { return !r.Valid })
+}
+
+// validateSource runs schema and structural validation on a source.
+func validateSource(validator *schema.Validator, src *source) []problem {
+	var report *schema.ValidationReport
+	var err error
+	if src.format == formatYAML {
+		var doc *graph.YAMLDocument
+		if doc, err = src.yamlDocument(); err == nil {
+			report, err = validator.ReportGraphYAMLDocument(doc)
+		}
+	} else {
+		report, err = validator.ReportGraph(src.data)
+	}
+	if err != nil {
+		return []problem{{Severity: string(schema.SeverityError), Message: err.Error()}}
+	}
+
+	problems := make([]problem, 0, len(report.Violations))
+	for _, v := range report.Violations {
+		p := problem{Severity: string(v.Severity), Rule: v.Source, Pointer: v.Pointer, Message: v.Message}
+		if v.Position != nil {
+			p.Position = v.Position.String()
+		}
+		problems = append(problems, p)
+	}
+	return problems
+}
+
+// report prints results and returns exitProblems if any result failed.
+func report(e *env, results []fileResult, asJSON bool, failed func(fileResult) bool) int {
+	code := exitOK
+	for _, r := range results {
+		if failed(r) {
+			code = exitProblems
+		}
+	}
+
+	if asJSON {
+		if err := writeJSON(e, results); err != nil {
+			fmt.Fprintf(e.stderr, "dago-graph: %v\n", err)
+			return exitError
+		}
+		return code
+	}
+
+	for _, r := range results {
+		if len(r.Problems) == 0 {
+			fmt.Fprintf(e.stdout, "%s: ok\n", r.File)
+			continue
+		}
+		for _, p := range r.Problems {
+			fmt.Fprintf(e.stdout, "%s: %s\n", r.File, p)
+		}
+	}
+	return code
+}
@@ -3,6 +3,15 @@ module github.com/aescanero/dago-libs
 go 1.25.5
 
 require (
+	github.com/fxamacker/cbor/v2 v2.9.4
 	github.com/google/uuid v1.5.0
+	github.com/klauspost/compress v1.20.1
 	github.com/santhosh-tekuri/jsonschema/v5 v5.3.1
+	github.com/vmihailenco/msgpack/v5 v5.4.1
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
 )
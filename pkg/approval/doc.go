@@ -0,0 +1,15 @@
+// Package approval implements the pause and resume protocol of human-in-the-loop
+// executions.
+//
+// When a graph.ApprovalNode runs without a response it returns a
+// *graph.InputRequiredError. The engine then creates the node's
+// graph.InputRequest and calls Service.Pause, which marks the execution
+// waiting_for_input and persists the request in ports.ExecutionStorage.
+//
+// A UI or API lists the pending requests with Service.ListPending and answers
+// one with Service.Resume. Resume checks the response against the request
+// (request ID, expiry, assignees and input schema), delivers it into the
+// execution state under graph.InputResponseKey, marks the execution running
+// again and hands it to the Continuer, typically the engine, which runs the
+// approval node again so that it consumes the response.
+package approval
@@ -0,0 +1,246 @@
+package approval
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+var (
+	// ErrNotWaiting is returned when the execution is not waiting for input.
+	ErrNotWaiting = errors.New("execution is not waiting for input")
+
+	// ErrRequestMismatch is returned when a response answers another request
+	// than the pending one, e.g. from a stale UI.
+	ErrRequestMismatch = errors.New("response does not match the pending request")
+
+	// ErrExpired is returned when the pending request has expired.
+	ErrExpired = errors.New("input request has expired")
+
+	// ErrNotAssignee is returned when the responder is not an assignee of the request.
+	ErrNotAssignee = errors.New("responder is not an assignee of the request")
+
+	// ErrInvalidInput is returned when the response data does not match the
+	// request's input schema.
+	ErrInvalidInput = errors.New("invalid input")
+)
+
+// Continuer continues an execution from a node. Engines implement it so that
+// Resume can hand resumed executions back to them.
+type Continuer interface {
+	Continue(ctx context.Context, executionID, nodeID string) error
+}
+
+// Service pauses executions waiting for human input and resumes them with the
+// responses.
+type Service struct {
+	executions ports.ExecutionStorage
+	states     state.Manager
+	continuer  Continuer
+	now        func() time.Time
+}
+
+// NewService creates a Service persisting requests in executions and
+// delivering responses through states.
+func NewService(executions ports.ExecutionStorage, states state.Manager) *Service {
+	return &Service{
+		executions: executions,
+		states:     states,
+		now:        time.Now,
+	}
+}
+
+// WithContinuer sets the Continuer called by Resume. Without one, resumed
+// executions are left in the running status for a worker to pick up.
+func (s *Service) WithContinuer(c Continuer) *Service {
+	s.continuer = c
+	return s
+}
+
+// WithClock sets the clock used for expiry and response timestamps.
+func (s *Service) WithClock(now func() time.Time) *Service {
+	s.now = now
+	return s
+}
+
+// Pause marks the execution of req as waiting for input and persists req.
+func (s *Service) Pause(ctx context.Context, req *graph.InputRequest) error {
+	metadata, err := s.executions.Load(ctx, req.ExecutionID)
+	if err != nil {
+		return fmt.Errorf("failed to load execution '%s': %w", req.ExecutionID, err)
+	}
+	metadata.Status = ports.ExecutionStatusWaitingForInput
+	metadata.CurrentNodeID = req.NodeID
+	metadata.PendingInput = req
+	if err := s.executions.Save(ctx, *metadata); err != nil {
+		return fmt.Errorf("failed to save execution '%s': %w", req.ExecutionID, err)
+	}
+	return nil
+}
+
+// Pending returns the request an execution is waiting on.
+func (s *Service) Pending(ctx context.Context, executionID string) (*graph.InputRequest, error) {
+	metadata, err := s.waiting(ctx, executionID)
+	if err != nil {
+		return nil, err
+	}
+	return metadata.PendingInput, nil
+}
+
+// ListPending returns the requests of all executions waiting for input that
+// responder may answer, oldest first. An empty responder lists every request.
+func (s *Service) ListPending(ctx context.Context, responder string) ([]*graph.InputRequest, error) {
+	status := ports.ExecutionStatusWaitingForInput
+	executions, err := s.executions.List(ctx, &status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list executions: %w", err)
+	}
+	requests := make([]*graph.InputRequest, 0, len(executions))
+	for _, metadata := range executions {
+		req := metadata.PendingInput
+		if req == nil || (responder != "" && !req.AcceptsResponder(responder)) {
+			continue
+		}
+		requests = append(requests, req)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		if !requests[i].RequestedAt.Equal(requests[j].RequestedAt) {
+			return requests[i].RequestedAt.Before(requests[j].RequestedAt)
+		}
+		return requests[i].ExecutionID < requests[j].ExecutionID
+	})
+	return requests, nil
+}
+
+// Resume answers the pending request of an execution. The response is
+// checked against the request, delivered into the execution state, and the
+// execution is marked running and handed to the Continuer, if any.
+//
+// When the execution storage implements ports.ExecutionStatusSwapper, Resume
+// first claims the execution by swapping its status from waiting_for_input to
+// running, so that of concurrent responses only one resumes it and the others
+// fail with ErrNotWaiting. Other storages cannot tell concurrent responses
+// apart.
+func (s *Service) Resume(ctx context.Context, executionID string, response graph.InputResponse) error {
+	metadata, err := s.waiting(ctx, executionID)
+	if err != nil {
+		return err
+	}
+	req := metadata.PendingInput
+	now := s.now()
+	switch {
+	case response.RequestID != req.ID:
+		return fmt.Errorf("execution '%s': %w", executionID, ErrRequestMismatch)
+	case req.Expired(now):
+		return fmt.Errorf("execution '%s': %w", executionID, ErrExpired)
+	case !req.AcceptsResponder(response.Responder):
+		return fmt.Errorf("execution '%s': %w: '%s'", executionID, ErrNotAssignee, response.Responder)
+	}
+	if err := validateInput(req.InputSchema, response.Data); err != nil {
+		return fmt.Errorf("execution '%s': %w", executionID, err)
+	}
+	if response.RespondedAt.IsZero() {
+		response.RespondedAt = now
+	}
+
+	swapper, claim := s.executions.(ports.ExecutionStatusSwapper)
+	if claim {
+		swapped, err := swapper.CompareAndSwapStatus(ctx, executionID, ports.ExecutionStatusWaitingForInput, ports.ExecutionStatusRunning)
+		if err != nil {
+			return fmt.Errorf("failed to claim execution '%s': %w", executionID, err)
+		}
+		if !swapped {
+			return fmt.Errorf("execution '%s': %w", executionID, ErrNotWaiting)
+		}
+	}
+
+	err = s.states.UpdateState(ctx, executionID, func(st state.State) (state.State, error) {
+		if st == nil {
+			st = state.NewState()
+		}
+		st.Set(graph.InputResponseKey(req.NodeID), response.ToMap())
+		return st, nil
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to deliver response to execution '%s': %w", executionID, err)
+		if claim {
+			// Release the claim so that the request can be answered again.
+			if _, releaseErr := swapper.CompareAndSwapStatus(ctx, executionID, ports.ExecutionStatusRunning, ports.ExecutionStatusWaitingForInput); releaseErr != nil {
+				err = errors.Join(err, fmt.Errorf("failed to release execution '%s': %w", executionID, releaseErr))
+			}
+		}
+		return err
+	}
+
+	metadata.Status = ports.ExecutionStatusRunning
+	metadata.PendingInput = nil
+	if err := s.executions.Save(ctx, *metadata); err != nil {
+		return fmt.Errorf("failed to save execution '%s': %w", executionID, err)
+	}
+
+	if s.continuer != nil {
+		if err := s.continuer.Continue(ctx, executionID, req.NodeID); err != nil {
+			return fmt.Errorf("failed to continue execution '%s': %w", executionID, err)
+		}
+	}
+	return nil
+}
+
+// waiting loads an execution and checks that it is waiting for input.
+func (s *Service) waiting(ctx context.Context, executionID string) (*ports.ExecutionMetadata, error) {
+	metadata, err := s.executions.Load(ctx, executionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load execution '%s': %w", executionID, err)
+	}
+	if metadata.Status != ports.ExecutionStatusWaitingForInput || metadata.PendingInput == nil {
+		return nil, fmt.Errorf("execution '%s': %w", executionID, ErrNotWaiting)
+	}
+	return metadata, nil
+}
+
+// validateInput validates response data against the input schema of a request.
+func validateInput(schema map[string]interface{}, data map[string]interface{}) error {
+	if len(schema) == 0 {
+		return nil
+	}
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return fmt.Errorf("failed to encode input schema: %w", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft7
+	if err := compiler.AddResource("input.schema.json", bytes.NewReader(schemaJSON)); err != nil {
+		return fmt.Errorf("invalid input schema: %w", err)
+	}
+	compiled, err := compiler.Compile("input.schema.json")
+	if err != nil {
+		return fmt.Errorf("invalid input schema: %w", err)
+	}
+
+	// Validate the JSON form of the data, as it will be stored.
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	var instance interface{}
+	if err := json.Unmarshal(dataJSON, &instance); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	if err := compiled.Validate(instance); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-libs/pkg/internal/fakes"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+type continuation struct {
+	executionID, nodeID string
+}
+
+type recordingContinuer struct {
+	calls []continuation
+}
+
+func (c *recordingContinuer) Continue(ctx context.Context, executionID, nodeID string) error {
+	c.calls = append(c.calls, continuation{executionID, nodeID})
+	return nil
+}
+
+var now = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func newPausedService(t *testing.T) (*Service, fakes.Executions, fakes.States, *recordingContinuer, *graph.InputRequest) {
+	t.Helper()
+	executions := fakes.Executions{"exec-1": {ExecutionID: "exec-1", GraphID: "publish", Status: ports.ExecutionStatusRunning}}
+	states := fakes.States{"exec-1": state.State{"draft": "Hello"}}
+	continuer := &recordingContinuer{}
+	service := NewService(executions, states).WithContinuer(continuer).WithClock(func() time.Time { return now })
+
+	node := &graph.ApprovalNode{
+		BaseNode:     graph.BaseNode{ID: "review", Type: graph.NodeTypeApproval},
+		Prompt:       "Publish?",
+		Assignees:    []string{"ann", "bob"},
+		ExpiresAfter: graph.Duration(time.Hour),
+		InputSchema: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"title": map[string]interface{}{"type": "string"}},
+		},
+	}
+	req := node.NewRequest("exec-1", states["exec-1"], now)
+	if err := service.Pause(context.Background(), req); err != nil {
+		t.Fatalf("Pause failed: %v", err)
+	}
+	return service, executions, states, continuer, req
+}
+
+func TestService_Pause(t *testing.T) {
+	service, executions, _, _, req := newPausedService(t)
+
+	metadata := executions["exec-1"]
+	if metadata.Status != ports.ExecutionStatusWaitingForInput || metadata.CurrentNodeID != "review" || metadata.PendingInput != req {
+		t.Errorf("unexpected execution metadata: %+v", metadata)
+	}
+
+	pending, err := service.Pending(context.Background(), "exec-1")
+	if err != nil || pending.ID != req.ID {
+		t.Errorf("expected pending request %s, got %v, %v", req.ID, pending, err)
+	}
+
+	executions["exec-2"] = ports.ExecutionMetadata{ExecutionID: "exec-2", Status: ports.ExecutionStatusWaitingForInput,
+		PendingInput: &graph.InputRequest{ID: "r2", ExecutionID: "exec-2", Assignees: []string{"carol"}, RequestedAt: now.Add(-time.Minute)}}
+	all, err := service.ListPending(context.Background(), "")
+	if err != nil || len(all) != 2 || all[0].ExecutionID != "exec-2" {
+		t.Errorf("expected both requests, oldest first, got %v, %v", all, err)
+	}
+	mine, err := service.ListPending(context.Background(), "ann")
+	if err != nil || len(mine) != 1 || mine[0].ID != req.ID {
+		t.Errorf("expected only the request assigned to ann, got %v, %v", mine, err)
+	}
+}
+
+func TestService_Resume(t *testing.T) {
+	service, executions, states, continuer, req := newPausedService(t)
+
+	err := service.Resume(context.Background(), "exec-1", graph.InputResponse{
+		RequestID: req.ID,
+		Approved:  true,
+		Responder: "ann",
+		Data:      map[string]interface{}{"title": "Hello"},
+	})
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+
+	metadata := executions["exec-1"]
+	if metadata.Status != ports.ExecutionStatusRunning || metadata.PendingInput != nil {
+		t.Errorf("expected a running execution without pending input, got %+v", metadata)
+	}
+	response, ok := graph.ResponseFrom(states["exec-1"], graph.InputResponseKey("review"))
+	if !ok || !response.Approved || response.Responder != "ann" || !response.RespondedAt.Equal(now) {
+		t.Errorf("expected the response in state, got %v", states["exec-1"])
+	}
+	if len(continuer.calls) != 1 || continuer.calls[0] != (continuation{"exec-1", "review"}) {
+		t.Errorf("expected the execution to continue at review, got %v", continuer.calls)
+	}
+
+	// The approval node consumes the response when it runs again.
+	node := &graph.ApprovalNode{BaseNode: graph.BaseNode{ID: "review", Type: graph.NodeTypeApproval}, Prompt: "Publish?"}
+	if _, err := node.Execute(context.Background(), states["exec-1"]); err != nil {
+		t.Errorf("expected the approval node to complete, got %v", err)
+	}
+
+	if err := service.Resume(context.Background(), "exec-1", graph.InputResponse{RequestID: req.ID, Responder: "ann"}); !errors.Is(err, ErrNotWaiting) {
+		t.Errorf("expected a second response to be rejected, got %v", err)
+	}
+}
+
+func TestService_ResumeRejectsInvalidResponses(t *testing.T) {
+	tests := []struct {
+		name     string
+		response func(req *graph.InputRequest) graph.InputResponse
+		clock    time.Time
+		want     error
+	}{
+		{
+			name: "stale request",
+			response: func(req *graph.InputRequest) graph.InputResponse {
+				return graph.InputResponse{RequestID: "old", Responder: "ann"}
+			},
+			want: ErrRequestMismatch,
+		},
+		{
+			name: "expired",
+			response: func(req *graph.InputRequest) graph.InputResponse {
+				return graph.InputResponse{RequestID: req.ID, Responder: "ann"}
+			},
+			clock: now.Add(2 * time.Hour),
+			want:  ErrExpired,
+		},
+		{
+			name: "not an assignee",
+			response: func(req *graph.InputRequest) graph.InputResponse {
+				return graph.InputResponse{RequestID: req.ID, Responder: "eve"}
+			},
+			want: ErrNotAssignee,
+		},
+		{
+			name: "invalid input",
+			response: func(req *graph.InputRequest) graph.InputResponse {
+				return graph.InputResponse{RequestID: req.ID, Responder: "ann", Data: map[string]interface{}{"title": 5}}
+			},
+			want: ErrInvalidInput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, executions, states, continuer, req := newPausedService(t)
+			if !tt.clock.IsZero() {
+				service.WithClock(func() time.Time { return tt.clock })
+			}
+
+			err := service.Resume(context.Background(), "exec-1", tt.response(req))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+			if executions["exec-1"].Status != ports.ExecutionStatusWaitingForInput {
+				t.Error("expected the execution to keep waiting")
+			}
+			if _, delivered := states["exec-1"][graph.InputResponseKey("review")]; delivered || len(continuer.calls) != 0 {
+				t.Error("expected the response not to be delivered")
+			}
+		})
+	}
+}
+
+// lockedExecutions is a fakes.Executions safe for concurrent use.
+type lockedExecutions struct {
+	mu sync.Mutex
+	fakes.Executions
+}
+
+func (s *lockedExecutions) Save(ctx context.Context, metadata ports.ExecutionMetadata) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Executions.Save(ctx, metadata)
+}
+
+func (s *lockedExecutions) Load(ctx context.Context, executionID string) (*ports.ExecutionMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Executions.Load(ctx, executionID)
+}
+
+func (s *lockedExecutions) CompareAndSwapStatus(ctx context.Context, executionID string, from, to ports.ExecutionStatus) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Executions.CompareAndSwapStatus(ctx, executionID, from, to)
+}
+
+// lockedStates is a fakes.States safe for concurrent use.
+type lockedStates struct {
+	mu sync.Mutex
+	fakes.States
+}
+
+func (s *lockedStates) UpdateState(ctx context.Context, executionID string, updateFn func(state.State) (state.State, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.States.UpdateState(ctx, executionID, updateFn)
+}
+
+func TestService_ResumeOnce(t *testing.T) {
+	executions := &lockedExecutions{Executions: fakes.Executions{"exec-1": {ExecutionID: "exec-1", Status: ports.ExecutionStatusRunning}}}
+	states := &lockedStates{States: fakes.States{"exec-1": state.State{}}}
+	continuer := &recordingContinuer{}
+	service := NewService(executions, states).WithContinuer(continuer)
+
+	node := &graph.ApprovalNode{BaseNode: graph.BaseNode{ID: "review", Type: graph.NodeTypeApproval}, Prompt: "Publish?"}
+	req := node.NewRequest("exec-1", state.State{}, time.Now())
+	if err := service.Pause(context.Background(), req); err != nil {
+		t.Fatalf("Pause failed: %v", err)
+	}
+
+	const responders = 8
+	errs := make(chan error, responders)
+	var wg sync.WaitGroup
+	for i := 0; i < responders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- service.Resume(context.Background(), "exec-1", graph.InputResponse{RequestID: req.ID, Approved: i%2 == 0, Responder: fmt.Sprintf("user-%d", i)})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	resumed := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			resumed++
+		case !errors.Is(err, ErrNotWaiting):
+			t.Errorf("expected the other responses to be rejected, got %v", err)
+		}
+	}
+	if resumed != 1 || len(continuer.calls) != 1 {
+		t.Errorf("expected one response to resume the execution, got %d resumed and %d continuations", resumed, len(continuer.calls))
+	}
+}
+
+func TestService_NotWaiting(t *testing.T) {
+	executions := fakes.Executions{"exec-1": {ExecutionID: "exec-1", Status: ports.ExecutionStatusRunning}}
+	service := NewService(executions, fakes.States{})
+
+	if _, err := service.Pending(context.Background(), "exec-1"); !errors.Is(err, ErrNotWaiting) {
+		t.Errorf("expected ErrNotWaiting, got %v", err)
+	}
+	if err := service.Resume(context.Background(), "missing", graph.InputResponse{}); err == nil {
+		t.Error("expected an error for an unknown execution")
+	}
+}
@@ -0,0 +1,16 @@
+// Package blob provides offloading of large state values to a content-addressed blob store.
+//
+// Storage wraps a ports.StateStorage so that state values whose JSON encoding
+// exceeds a size threshold are written to a ports.BlobStore on Save and
+// replaced with a small reference:
+//
+//	{"$blob": {"digest": "sha256:...", "size": 1048576}}
+//
+// On Load, references are turned into lazy values that fetch the content from
+// the blob store the first time the key is read through state.State accessors.
+// When an execution is deleted, its blob references are released and blobs no
+// longer referenced by any execution are garbage collected.
+//
+// FileStore is a filesystem implementation of ports.BlobStore suitable for
+// single-host deployments and tests.
+package blob
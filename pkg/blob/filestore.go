@@ -0,0 +1,216 @@
+package blob
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// digestPrefix is the algorithm prefix of digests produced by FileStore.
+const digestPrefix = "sha256:"
+
+var digestPattern = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// FileStore is a filesystem implementation of ports.BlobStore.
+//
+// Layout under the root directory:
+//
+//	blobs/<hex[0:2]>/<hex>   blob content
+//	refs/<hex>/<owner>       one marker file per owner of a blob
+//	owners/<owner>/<hex>     one marker file per blob held by an owner
+//
+// Owner names are base64url-encoded so that any execution ID is a valid file name.
+// FileStore is safe for concurrent use within a single process.
+type FileStore struct {
+	root string
+	mu   sync.Mutex
+}
+
+// NewFileStore creates a FileStore rooted at dir, creating the directory if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	for _, sub := range []string{"blobs", "refs", "owners"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o750); err != nil {
+			return nil, fmt.Errorf("failed to create blob store directory: %w", err)
+		}
+	}
+	return &FileStore{root: dir}, nil
+}
+
+// Put stores data on behalf of owner and returns its content digest.
+func (fs *FileStore) Put(ctx context.Context, owner string, data []byte) (string, error) {
+	if owner == "" {
+		return "", fmt.Errorf("blob owner cannot be empty")
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	hexSum := hex.EncodeToString(sum[:])
+	ownerName := encodeOwner(owner)
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	blobPath := fs.blobPath(hexSum)
+	if _, err := os.Stat(blobPath); errors.Is(err, os.ErrNotExist) {
+		if err := writeFileAtomic(blobPath, data); err != nil {
+			return "", fmt.Errorf("failed to write blob: %w", err)
+		}
+	} else if err != nil {
+		return "", fmt.Errorf("failed to stat blob: %w", err)
+	}
+
+	if err := touch(filepath.Join(fs.root, "refs", hexSum, ownerName)); err != nil {
+		return "", fmt.Errorf("failed to record blob reference: %w", err)
+	}
+	if err := touch(filepath.Join(fs.root, "owners", ownerName, hexSum)); err != nil {
+		return "", fmt.Errorf("failed to record blob owner: %w", err)
+	}
+
+	return digestPrefix + hexSum, nil
+}
+
+// Get retrieves the content for a digest.
+func (fs *FileStore) Get(ctx context.Context, digest string) ([]byte, error) {
+	hexSum, err := parseDigest(digest)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(fs.blobPath(hexSum))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ports.ErrBlobNotFound, digest)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %w", err)
+	}
+	return data, nil
+}
+
+// Exists checks if a blob with the given digest is stored.
+func (fs *FileStore) Exists(ctx context.Context, digest string) (bool, error) {
+	hexSum, err := parseDigest(digest)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(fs.blobPath(hexSum))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to stat blob: %w", err)
+	}
+	return true, nil
+}
+
+// Release drops every reference held by owner and deletes unreferenced blobs.
+func (fs *FileStore) Release(ctx context.Context, owner string) (int, error) {
+	ownerName := encodeOwner(owner)
+	ownerDir := filepath.Join(fs.root, "owners", ownerName)
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	entries, err := os.ReadDir(ownerDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to list blobs for owner: %w", err)
+	}
+
+	deleted := 0
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return deleted, err
+		}
+		hexSum := entry.Name()
+		refDir := filepath.Join(fs.root, "refs", hexSum)
+		if err := os.Remove(filepath.Join(refDir, ownerName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return deleted, fmt.Errorf("failed to remove blob reference: %w", err)
+		}
+
+		remaining, err := os.ReadDir(refDir)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return deleted, fmt.Errorf("failed to list blob references: %w", err)
+		}
+		if len(remaining) == 0 {
+			if err := os.Remove(fs.blobPath(hexSum)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return deleted, fmt.Errorf("failed to delete blob: %w", err)
+			}
+			_ = os.Remove(refDir)
+			deleted++
+		}
+		if err := os.Remove(filepath.Join(ownerDir, hexSum)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return deleted, fmt.Errorf("failed to remove blob owner record: %w", err)
+		}
+	}
+
+	if err := os.Remove(ownerDir); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return deleted, fmt.Errorf("failed to remove owner directory: %w", err)
+	}
+	return deleted, nil
+}
+
+func (fs *FileStore) blobPath(hexSum string) string {
+	return filepath.Join(fs.root, "blobs", hexSum[:2], hexSum)
+}
+
+// parseDigest validates a digest and returns its hex part.
+func parseDigest(digest string) (string, error) {
+	if !digestPattern.MatchString(digest) {
+		return "", fmt.Errorf("invalid blob digest %q", digest)
+	}
+	return strings.TrimPrefix(digest, digestPrefix), nil
+}
+
+func encodeOwner(owner string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(owner))
+}
+
+// touch creates an empty file, creating parent directories as needed.
+func touch(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
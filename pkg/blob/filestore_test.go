@@ -0,0 +1,100 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+func TestFileStore_PutGet(t *testing.T) {
+	ctx := context.Background()
+	fs, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+
+	digest, err := fs.Put(ctx, "exec-1", []byte("hello"))
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if !digestPattern.MatchString(digest) {
+		t.Errorf("unexpected digest format %q", digest)
+	}
+
+	again, err := fs.Put(ctx, "exec-2", []byte("hello"))
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if again != digest {
+		t.Errorf("expected identical content to share digest, got %q and %q", digest, again)
+	}
+
+	data, err := fs.Get(ctx, digest)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("expected 'hello', got %q", data)
+	}
+}
+
+func TestFileStore_GetMissing(t *testing.T) {
+	fs, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+
+	_, err = fs.Get(context.Background(), "sha256:0000000000000000000000000000000000000000000000000000000000000000")
+	if !errors.Is(err, ports.ErrBlobNotFound) {
+		t.Errorf("expected ErrBlobNotFound, got %v", err)
+	}
+
+	if _, err := fs.Get(context.Background(), "../../etc/passwd"); err == nil {
+		t.Error("expected error for invalid digest")
+	}
+}
+
+func TestFileStore_Release(t *testing.T) {
+	ctx := context.Background()
+	fs, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+
+	shared, _ := fs.Put(ctx, "exec-1", []byte("shared"))
+	_, _ = fs.Put(ctx, "exec-2", []byte("shared"))
+	own, _ := fs.Put(ctx, "exec-1", []byte("own"))
+
+	deleted, err := fs.Release(ctx, "exec-1")
+	if err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 blob deleted, got %d", deleted)
+	}
+
+	if ok, _ := fs.Exists(ctx, own); ok {
+		t.Error("expected unreferenced blob to be deleted")
+	}
+	if ok, _ := fs.Exists(ctx, shared); !ok {
+		t.Error("expected blob still referenced by exec-2 to be kept")
+	}
+
+	deleted, err = fs.Release(ctx, "exec-2")
+	if err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("expected 1 blob deleted, got %d", deleted)
+	}
+	if ok, _ := fs.Exists(ctx, shared); ok {
+		t.Error("expected shared blob to be deleted after last release")
+	}
+
+	// Releasing an unknown owner is a no-op
+	if deleted, err := fs.Release(ctx, "unknown"); err != nil || deleted != 0 {
+		t.Errorf("expected no-op release, got deleted=%d err=%v", deleted, err)
+	}
+}
@@ -0,0 +1,117 @@
+package blob
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/aescanero/dago-libs/pkg/codec"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// RefKey is the key that marks a JSON object as a blob reference.
+const RefKey = "$blob"
+
+// Ref identifies a value stored in a blob store.
+type Ref struct {
+	// Digest is the content digest of the stored value (e.g. "sha256:<hex>").
+	Digest string `json:"digest"`
+
+	// Size is the size in bytes of the stored value.
+	Size int64 `json:"size"`
+}
+
+// MarshalJSON encodes the reference in its wrapped form ({"$blob": {...}}).
+func (r Ref) MarshalJSON() ([]byte, error) {
+	type plain Ref
+	return json.Marshal(map[string]plain{RefKey: plain(r)})
+}
+
+// ParseRef reports whether v is the decoded form of a blob reference and returns it.
+func ParseRef(v interface{}) (Ref, bool) {
+	m, ok := v.(map[string]interface{})
+	if !ok || len(m) != 1 {
+		return Ref{}, false
+	}
+	inner, ok := m[RefKey].(map[string]interface{})
+	if !ok {
+		return Ref{}, false
+	}
+	digest, ok := inner["digest"].(string)
+	if !ok || digest == "" {
+		return Ref{}, false
+	}
+	ref := Ref{Digest: digest}
+	switch size := inner["size"].(type) {
+	case float64:
+		ref.Size = int64(size)
+	case int64:
+		ref.Size = size
+	case int:
+		ref.Size = int64(size)
+	}
+	return ref, true
+}
+
+// Value is a lazily resolved state value backed by a blob store.
+// It implements state.LazyValue and encodes to JSON as its reference,
+// so a state that was never read can be saved again without fetching the blob.
+// The decoded value is cached after the first successful Resolve; it is
+// shared by every reader and must be treated as read-only.
+type Value struct {
+	ref   Ref
+	owner string
+	store ports.BlobStore
+	ctx   context.Context
+
+	mu       sync.Mutex
+	resolved bool
+	value    interface{}
+}
+
+// NewValue creates a lazy value for ref, owned by owner, that resolves from store.
+// The context is used for the deferred blob read; its cancellation is ignored.
+func NewValue(ctx context.Context, store ports.BlobStore, owner string, ref Ref) *Value {
+	return &Value{
+		ref:   ref,
+		owner: owner,
+		store: store,
+		ctx:   context.WithoutCancel(ctx),
+	}
+}
+
+// Ref returns the blob reference backing this value.
+func (v *Value) Ref() Ref {
+	return v.ref
+}
+
+// Owner returns the owner under which the blob was loaded.
+func (v *Value) Owner() string {
+	return v.owner
+}
+
+// Resolve fetches the blob and decodes it. It is safe for concurrent use;
+// the blob is fetched again only if an earlier attempt failed.
+func (v *Value) Resolve() (interface{}, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.resolved {
+		return v.value, nil
+	}
+	data, err := v.store.Get(v.ctx, v.ref.Digest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load blob %s: %w", v.ref.Digest, err)
+	}
+	var out interface{}
+	if err := codec.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("failed to decode blob %s: %w", v.ref.Digest, err)
+	}
+	v.value, v.resolved = out, true
+	return out, nil
+}
+
+// MarshalJSON encodes the value as its blob reference.
+func (v *Value) MarshalJSON() ([]byte, error) {
+	return v.ref.MarshalJSON()
+}
@@ -0,0 +1,134 @@
+package blob
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aescanero/dago-libs/pkg/codec"
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// DefaultThreshold is the default size in bytes above which values are offloaded.
+const DefaultThreshold = 64 * 1024
+
+// Storage is a ports.StateStorage decorator that offloads large values to a BlobStore.
+//
+// Only top-level state values are considered for offloading. Values are
+// serialized with the storage's codec (JSON by default, see WithCodec) and the
+// threshold applies to the encoded size.
+//
+// Methods that are not overridden (Exists, SetTTL, List and the compatibility
+// methods) are passed through to the wrapped storage unchanged. Blobs are not
+// affected by SetTTL; they are released when the execution is deleted.
+type Storage struct {
+	ports.StateStorage
+
+	blobs     ports.BlobStore
+	threshold int
+	codec     codec.Codec
+}
+
+// NewStorage wraps inner so that values larger than threshold bytes are stored in blobs.
+// A threshold of zero or less uses DefaultThreshold.
+func NewStorage(inner ports.StateStorage, blobs ports.BlobStore, threshold int) *Storage {
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	return &Storage{
+		StateStorage: inner,
+		blobs:        blobs,
+		threshold:    threshold,
+		codec:        codec.Default,
+	}
+}
+
+// WithCodec sets the codec used to serialize offloaded values.
+// Blobs written with any codec remain readable, as the format is detected on read.
+func (s *Storage) WithCodec(c codec.Codec) *Storage {
+	s.codec = c
+	return s
+}
+
+// Threshold returns the size in bytes above which values are offloaded.
+func (s *Storage) Threshold() int {
+	return s.threshold
+}
+
+// Save offloads large values to the blob store and persists the remaining state.
+// The given state is not modified.
+func (s *Storage) Save(ctx context.Context, executionID string, st state.State) error {
+	out := make(state.State, len(st))
+	for key, val := range st {
+		offloaded, err := s.offload(ctx, executionID, val)
+		if err != nil {
+			return fmt.Errorf("failed to offload state key '%s': %w", key, err)
+		}
+		out[key] = offloaded
+	}
+	return s.StateStorage.Save(ctx, executionID, out)
+}
+
+// offload returns the value to persist in place of val.
+func (s *Storage) offload(ctx context.Context, executionID string, val interface{}) (interface{}, error) {
+	if lazy, ok := val.(*Value); ok {
+		// Values owned by this execution are already stored: reads do
+		// not replace them, and changes are made with State.Set.
+		if lazy.owner == executionID {
+			return lazy.ref, nil
+		}
+		resolved, err := lazy.Resolve()
+		if err != nil {
+			return nil, err
+		}
+		val = resolved
+	}
+
+	data, err := s.codec.Marshal(val)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode value: %w", err)
+	}
+	if len(data) <= s.threshold {
+		return val, nil
+	}
+
+	digest, err := s.blobs.Put(ctx, executionID, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store blob: %w", err)
+	}
+	return Ref{Digest: digest, Size: int64(len(data))}, nil
+}
+
+// Load retrieves the state and replaces blob references with lazy values.
+func (s *Storage) Load(ctx context.Context, executionID string) (state.State, error) {
+	st, err := s.StateStorage.Load(ctx, executionID)
+	if err != nil {
+		return nil, err
+	}
+	for key, val := range st {
+		if ref, ok := asRef(val); ok {
+			st[key] = NewValue(ctx, s.blobs, executionID, ref)
+		}
+	}
+	return st, nil
+}
+
+// Delete removes the state and releases the blobs referenced by the execution.
+func (s *Storage) Delete(ctx context.Context, executionID string) error {
+	if err := s.StateStorage.Delete(ctx, executionID); err != nil {
+		return err
+	}
+	if _, err := s.blobs.Release(ctx, executionID); err != nil {
+		return fmt.Errorf("failed to release blobs for execution '%s': %w", executionID, err)
+	}
+	return nil
+}
+
+// asRef recognizes references returned by storages that keep Go values (Ref)
+// as well as those that decode JSON (map form).
+func asRef(v interface{}) (Ref, bool) {
+	if ref, ok := v.(Ref); ok {
+		return ref, true
+	}
+	return ParseRef(v)
+}
@@ -0,0 +1,267 @@
+package blob
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/codec"
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+)
+
+// memoryStateStorage is a minimal ports.StateStorage that stores JSON, like a real backend.
+type memoryStateStorage struct {
+	data map[string][]byte
+}
+
+func newMemoryStateStorage() *memoryStateStorage {
+	return &memoryStateStorage{data: make(map[string][]byte)}
+}
+
+func (m *memoryStateStorage) Save(ctx context.Context, executionID string, s state.State) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	m.data[executionID] = data
+	return nil
+}
+
+func (m *memoryStateStorage) Load(ctx context.Context, executionID string) (state.State, error) {
+	data, ok := m.data[executionID]
+	if !ok {
+		return nil, fmt.Errorf("state not found")
+	}
+	s := state.NewState()
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (m *memoryStateStorage) Delete(ctx context.Context, executionID string) error {
+	delete(m.data, executionID)
+	return nil
+}
+
+func (m *memoryStateStorage) Exists(ctx context.Context, executionID string) (bool, error) {
+	_, ok := m.data[executionID]
+	return ok, nil
+}
+
+func (m *memoryStateStorage) SetTTL(ctx context.Context, executionID string, ttl time.Duration) error {
+	return nil
+}
+
+func (m *memoryStateStorage) List(ctx context.Context) ([]string, error) {
+	ids := make([]string, 0, len(m.data))
+	for id := range m.data {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (m *memoryStateStorage) SaveState(ctx context.Context, s interface{}) error {
+	return nil
+}
+
+func (m *memoryStateStorage) GetState(ctx context.Context, graphID string) (interface{}, error) {
+	return nil, nil
+}
+
+func newTestStorage(t *testing.T) (*Storage, *memoryStateStorage, *FileStore) {
+	t.Helper()
+	fs, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	inner := newMemoryStateStorage()
+	return NewStorage(inner, fs, 32), inner, fs
+}
+
+func TestStorage_OffloadsLargeValues(t *testing.T) {
+	ctx := context.Background()
+	storage, inner, _ := newTestStorage(t)
+
+	large := strings.Repeat("x", 100)
+	s := state.NewState()
+	s.Set("small", "tiny")
+	s.Set("large", large)
+
+	if err := storage.Save(ctx, "exec-1", s); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	if strings.Contains(string(inner.data["exec-1"]), large) {
+		t.Error("expected large value to be offloaded from the stored state")
+	}
+	if !strings.Contains(string(inner.data["exec-1"]), RefKey) {
+		t.Error("expected stored state to contain a blob reference")
+	}
+	if s.Get("large") != large {
+		t.Error("Save must not modify the caller's state")
+	}
+
+	loaded, err := storage.Load(ctx, "exec-1")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if _, ok := loaded["large"].(*Value); !ok {
+		t.Fatalf("expected large value to be lazy, got %T", loaded["large"])
+	}
+	if val, ok := loaded.GetString("large"); !ok || val != large {
+		t.Errorf("expected lazy value to resolve to original, got %q (ok=%v)", val, ok)
+	}
+	if val, ok := loaded.GetString("small"); !ok || val != "tiny" {
+		t.Errorf("expected small value inline, got %q (ok=%v)", val, ok)
+	}
+}
+
+func TestStorage_ResaveUnreadValue(t *testing.T) {
+	ctx := context.Background()
+	storage, _, _ := newTestStorage(t)
+
+	s := state.NewState()
+	s.Set("doc", map[string]interface{}{"body": strings.Repeat("y", 100)})
+	if err := storage.Save(ctx, "exec-1", s); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := storage.Load(ctx, "exec-1")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	loaded.Set("step", 2)
+	if err := storage.Save(ctx, "exec-1", loaded); err != nil {
+		t.Fatalf("Save of loaded state failed: %v", err)
+	}
+
+	again, err := storage.Load(ctx, "exec-1")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	doc, ok := again.Get("doc").(map[string]interface{})
+	if !ok || doc["body"] != strings.Repeat("y", 100) {
+		t.Errorf("expected offloaded map to survive a round trip, got %v", again.Get("doc"))
+	}
+}
+
+// countingStore counts the reads of a ports.BlobStore.
+type countingStore struct {
+	*FileStore
+	mu   sync.Mutex
+	gets int
+}
+
+func (c *countingStore) Get(ctx context.Context, digest string) ([]byte, error) {
+	c.mu.Lock()
+	c.gets++
+	c.mu.Unlock()
+	return c.FileStore.Get(ctx, digest)
+}
+
+func TestStorage_ConcurrentReads(t *testing.T) {
+	ctx := context.Background()
+	_, inner, fs := newTestStorage(t)
+	blobs := &countingStore{FileStore: fs}
+	storage := NewStorage(inner, blobs, 32)
+
+	large := strings.Repeat("z", 100)
+	if err := storage.Save(ctx, "exec-1", state.State{"large": large}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := storage.Load(ctx, "exec-1")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if val, err := loaded.GetE("large"); err != nil || val != large {
+				t.Errorf("unexpected value %v, %v", val, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, ok := loaded["large"].(*Value); !ok {
+		t.Errorf("expected reads to leave the lazy value in place, got %T", loaded["large"])
+	}
+	if blobs.gets != 1 {
+		t.Errorf("expected the blob to be fetched once, got %d reads", blobs.gets)
+	}
+}
+
+func TestStorage_DeleteReleasesBlobs(t *testing.T) {
+	ctx := context.Background()
+	storage, _, fs := newTestStorage(t)
+
+	s := state.NewState()
+	s.Set("large", strings.Repeat("z", 100))
+	if err := storage.Save(ctx, "exec-1", s); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, _ := storage.Load(ctx, "exec-1")
+	digest := loaded["large"].(*Value).Ref().Digest
+
+	if err := storage.Delete(ctx, "exec-1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if ok, _ := fs.Exists(ctx, digest); ok {
+		t.Error("expected blob to be garbage collected after delete")
+	}
+}
+
+func TestParseRef(t *testing.T) {
+	data, err := json.Marshal(Ref{Digest: "sha256:abc", Size: 10})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded interface{}
+	_ = json.Unmarshal(data, &decoded)
+
+	ref, ok := ParseRef(decoded)
+	if !ok {
+		t.Fatalf("expected %s to parse as a reference", data)
+	}
+	if ref.Digest != "sha256:abc" || ref.Size != 10 {
+		t.Errorf("unexpected reference %+v", ref)
+	}
+
+	if _, ok := ParseRef(map[string]interface{}{"other": 1}); ok {
+		t.Error("expected plain map not to parse as a reference")
+	}
+}
+
+func TestStorage_WithCodec(t *testing.T) {
+	ctx := context.Background()
+	storage, _, _ := newTestStorage(t)
+	c, err := codec.Parse("msgpack+zstd")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	storage.WithCodec(c)
+
+	s := state.NewState()
+	s.Set("items", []interface{}{strings.Repeat("a", 200), strings.Repeat("b", 200)})
+	if err := storage.Save(ctx, "exec-1", s); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := storage.Load(ctx, "exec-1")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	items, ok := loaded.Get("items").([]interface{})
+	if !ok || len(items) != 2 || items[1] != strings.Repeat("b", 200) {
+		t.Errorf("expected items to round trip through msgpack+zstd, got %v", loaded.Get("items"))
+	}
+}
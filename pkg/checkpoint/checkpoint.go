@@ -0,0 +1,420 @@
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// StateKey is the state key the checkpoint of an execution is stored under.
+const StateKey = "_checkpoint"
+
+// Checkpoint records the progress of an execution.
+type Checkpoint struct {
+	// Sequence increases with every recorded completion.
+	Sequence int64 `json:"sequence"`
+
+	// Completed holds the last completion of each completed node.
+	Completed map[string]NodeCompletion `json:"completed,omitempty"`
+
+	// Pending lists the nodes left to run, in scheduling order.
+	Pending []string `json:"pending,omitempty"`
+
+	// Started lists the pending nodes that started running and have not
+	// completed since. After a crash they may have had side effects.
+	Started []string `json:"started,omitempty"`
+
+	// Waiting holds the nodes reached by some of their predecessors, with
+	// the predecessors that completed, until none of their other
+	// predecessors can still run.
+	Waiting map[string][]string `json:"waiting,omitempty"`
+
+	// UpdatedAt is when the checkpoint was last recorded.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NodeCompletion records the completion of a node.
+type NodeCompletion struct {
+	// Sequence is the checkpoint sequence the completion was recorded at.
+	Sequence int64 `json:"sequence"`
+
+	// Attempts is the number of attempts the node took.
+	Attempts int `json:"attempts,omitempty"`
+
+	// Keys lists the state keys the node wrote or deleted.
+	Keys []string `json:"keys,omitempty"`
+
+	// CompletedAt is when the node completed.
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// Completion describes a completed node to record.
+type Completion struct {
+	// NodeID is the completed node.
+	NodeID string
+
+	// Output holds the state keys the node set.
+	Output state.State
+
+	// Deleted lists the state keys the node deleted.
+	Deleted []string
+
+	// Next lists the nodes to run after this one.
+	Next []string
+
+	// Attempts is the number of attempts the node took.
+	Attempts int
+
+	// Graph, if set, joins branches: a next node with other predecessors
+	// waits until they complete or can no longer run. Without it, next nodes
+	// are scheduled at once.
+	Graph *graph.Graph
+}
+
+// IsCompleted reports whether nodeID has completed at least once.
+func (c *Checkpoint) IsCompleted(nodeID string) bool {
+	_, ok := c.Completed[nodeID]
+	return ok
+}
+
+// IsPending reports whether nodeID is left to run.
+func (c *Checkpoint) IsPending(nodeID string) bool {
+	for _, id := range c.Pending {
+		if id == nodeID {
+			return true
+		}
+	}
+	return false
+}
+
+// Start records that nodes started running.
+func (c *Checkpoint) Start(nodeIDs []string, now time.Time) {
+	for _, id := range nodeIDs {
+		if !contains(c.Started, id) {
+			c.Started = append(c.Started, id)
+		}
+	}
+	c.UpdatedAt = now
+}
+
+// Unsafe returns the started nodes of g that are not safe to run again: nodes
+// whose effective policy is not idempotent, other than the start, end, router
+// and approval nodes, which have no side effects.
+func (c *Checkpoint) Unsafe(g *graph.Graph) []string {
+	var unsafe []string
+	for _, id := range c.Started {
+		switch g.GetNode(id).(type) {
+		case nil, *graph.StartNode, *graph.EndNode, *graph.RouterNode, *graph.ApprovalNode:
+			continue
+		}
+		if !g.EffectivePolicy(id).IsIdempotent() {
+			unsafe = append(unsafe, id)
+		}
+	}
+	return unsafe
+}
+
+// Record records a completion in the checkpoint: the node leaves the pending
+// list once and the next nodes not already pending join it. With
+// Completion.Graph, next nodes join it once every predecessor that can still
+// run has completed (see Ready). Engines running without a Recorder use it
+// to track progress the same way.
+func (c *Checkpoint) Record(completion Completion, now time.Time) {
+	c.Sequence++
+	if c.Completed == nil {
+		c.Completed = make(map[string]NodeCompletion)
+	}
+	keys := make([]string, 0, len(completion.Output)+len(completion.Deleted))
+	keys = append(keys, completion.Output.Keys()...)
+	keys = append(keys, completion.Deleted...)
+	c.Completed[completion.NodeID] = NodeCompletion{
+		Sequence:    c.Sequence,
+		Attempts:    completion.Attempts,
+		Keys:        keys,
+		CompletedAt: now,
+	}
+	c.Pending = remove(c.Pending, completion.NodeID)
+	c.Started = remove(c.Started, completion.NodeID)
+	if len(c.Started) == 0 {
+		c.Started = nil
+	}
+	if completion.Graph == nil {
+		for _, id := range completion.Next {
+			if !c.IsPending(id) {
+				c.Pending = append(c.Pending, id)
+			}
+		}
+		c.UpdatedAt = now
+		return
+	}
+
+	for _, id := range completion.Next {
+		if c.IsPending(id) {
+			continue
+		}
+		if c.Waiting == nil {
+			c.Waiting = make(map[string][]string)
+		}
+		if !contains(c.Waiting[id], completion.NodeID) {
+			c.Waiting[id] = append(c.Waiting[id], completion.NodeID)
+		}
+	}
+	// Next nodes are scheduled in their order, then the other waiting nodes
+	// the completion may have released.
+	order := append([]string(nil), completion.Next...)
+	var others []string
+	for id := range c.Waiting {
+		if !contains(order, id) {
+			others = append(others, id)
+		}
+	}
+	sort.Strings(others)
+	var ready []string
+	for _, id := range append(order, others...) {
+		if _, ok := c.Waiting[id]; ok && !contains(ready, id) && c.Ready(completion.Graph, id) {
+			ready = append(ready, id)
+		}
+	}
+	// Branches waiting on each other would never run: release them all.
+	if len(ready) == 0 && len(c.Pending) == 0 {
+		ready = append(ready, others...)
+		for _, id := range order {
+			if _, ok := c.Waiting[id]; ok && !contains(ready, id) {
+				ready = append(ready, id)
+			}
+		}
+	}
+	for _, id := range ready {
+		delete(c.Waiting, id)
+		c.Pending = append(c.Pending, id)
+	}
+	if len(c.Waiting) == 0 {
+		c.Waiting = nil
+	}
+	c.UpdatedAt = now
+}
+
+// Ready reports whether a waiting node can run: each of its predecessors
+// in g has completed since the node was reached, or can no longer run
+// because no pending or other waiting node leads to it without passing
+// through the node.
+func (c *Checkpoint) Ready(g *graph.Graph, nodeID string) bool {
+	arrived := c.Waiting[nodeID]
+	var missing []string
+	for id := range g.Nodes {
+		if id != nodeID && !contains(arrived, id) && contains(g.Successors(id), nodeID) {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return true
+	}
+
+	var stack []string
+	for _, id := range c.Pending {
+		if id != nodeID {
+			stack = append(stack, id)
+		}
+	}
+	for id := range c.Waiting {
+		if id != nodeID {
+			stack = append(stack, id)
+		}
+	}
+	visited := map[string]bool{nodeID: true}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if visited[id] {
+			continue
+		}
+		if contains(missing, id) {
+			return false
+		}
+		visited[id] = true
+		stack = append(stack, g.Successors(id)...)
+	}
+	return true
+}
+
+// remove returns list without the first occurrence of s, leaving list
+// itself unchanged.
+func remove(list []string, s string) []string {
+	for i, v := range list {
+		if v == s {
+			return append(list[:i:i], list[i+1:]...)
+		}
+	}
+	return list
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// toMap returns the checkpoint in the JSON-compatible form stored in state.
+func (c *Checkpoint) toMap() (map[string]interface{}, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// FromState decodes the checkpoint stored in s.
+func FromState(s state.State) (*Checkpoint, bool) {
+	value := s.Get(StateKey)
+	if value == nil {
+		return nil, false
+	}
+	if c, ok := value.(*Checkpoint); ok {
+		return c, true
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	var c Checkpoint
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, false
+	}
+	return &c, true
+}
+
+// Recorder records the checkpoints of executions.
+type Recorder struct {
+	states     state.Manager
+	executions ports.ExecutionStorage
+	now        func() time.Time
+}
+
+// NewRecorder creates a Recorder storing checkpoints in states and execution
+// metadata in executions.
+func NewRecorder(states state.Manager, executions ports.ExecutionStorage) *Recorder {
+	return &Recorder{
+		states:     states,
+		executions: executions,
+		now:        time.Now,
+	}
+}
+
+// WithClock sets the clock used for checkpoint timestamps.
+func (r *Recorder) WithClock(now func() time.Time) *Recorder {
+	r.now = now
+	return r
+}
+
+// Start initializes the state of a new execution with a checkpoint whose
+// pending nodes are entry, and saves metadata as running. Set
+// metadata.WorkerID so that Recovery can tell whether the execution is
+// orphaned.
+func (r *Recorder) Start(ctx context.Context, metadata ports.ExecutionMetadata, initial state.State, entry ...string) error {
+	st := initial.DeepCopy()
+	if st == nil {
+		st = state.NewState()
+	}
+	now := r.now()
+	cp := &Checkpoint{Pending: append([]string(nil), entry...), UpdatedAt: now}
+	m, err := cp.toMap()
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	st.Set(StateKey, m)
+	if err := r.states.Initialize(ctx, metadata.ExecutionID, st); err != nil {
+		return fmt.Errorf("failed to initialize state of execution '%s': %w", metadata.ExecutionID, err)
+	}
+
+	metadata.Status = ports.ExecutionStatusRunning
+	if metadata.StartedAt.IsZero() {
+		metadata.StartedAt = now
+	}
+	if err := r.executions.Save(ctx, metadata); err != nil {
+		return fmt.Errorf("failed to save execution '%s': %w", metadata.ExecutionID, err)
+	}
+	return nil
+}
+
+// Begin records in the checkpoint of an execution that nodes are about to
+// run, so that Recovery can tell which nodes may have had side effects before
+// a crash (see Checkpoint.Unsafe).
+func (r *Recorder) Begin(ctx context.Context, executionID string, nodeIDs ...string) error {
+	err := r.states.UpdateState(ctx, executionID, func(st state.State) (state.State, error) {
+		if st == nil {
+			st = state.NewState()
+		}
+		cp, ok := FromState(st)
+		if !ok {
+			cp = &Checkpoint{}
+		}
+		cp.Start(nodeIDs, r.now())
+		m, err := cp.toMap()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode checkpoint: %w", err)
+		}
+		st.Set(StateKey, m)
+		return st, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to checkpoint the start of nodes of execution '%s': %w", executionID, err)
+	}
+	return nil
+}
+
+// Complete applies the output of a completed node to the execution state and
+// records the completion in its checkpoint, in a single state update.
+func (r *Recorder) Complete(ctx context.Context, executionID string, completion Completion) error {
+	err := r.states.UpdateState(ctx, executionID, func(st state.State) (state.State, error) {
+		if st == nil {
+			st = state.NewState()
+		}
+		cp, ok := FromState(st)
+		if !ok {
+			cp = &Checkpoint{}
+		}
+		for key, value := range completion.Output {
+			st.Set(key, value)
+		}
+		for _, key := range completion.Deleted {
+			st.Delete(key)
+		}
+		cp.Record(completion, r.now())
+		m, err := cp.toMap()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode checkpoint: %w", err)
+		}
+		st.Set(StateKey, m)
+		return st, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to checkpoint node '%s' of execution '%s': %w", completion.NodeID, executionID, err)
+	}
+	return nil
+}
+
+// Load returns the last checkpoint of an execution, or nil if it has none.
+func (r *Recorder) Load(ctx context.Context, executionID string) (*Checkpoint, error) {
+	st, err := r.states.GetState(ctx, executionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state of execution '%s': %w", executionID, err)
+	}
+	cp, ok := FromState(st)
+	if !ok {
+		return nil, nil
+	}
+	return cp, nil
+}
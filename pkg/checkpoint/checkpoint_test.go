@@ -0,0 +1,171 @@
+package checkpoint
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-libs/pkg/internal/fakes"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+var now = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func TestRecorder_Complete(t *testing.T) {
+	executions := fakes.Executions{}
+	states := fakes.States{}
+	recorder := NewRecorder(states, executions).WithClock(func() time.Time { return now })
+
+	err := recorder.Start(context.Background(), ports.ExecutionMetadata{ExecutionID: "exec-1", GraphID: "g", WorkerID: "w1"},
+		state.State{"input": "hi"}, "fetch")
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if executions["exec-1"].Status != ports.ExecutionStatusRunning || executions["exec-1"].WorkerID != "w1" {
+		t.Errorf("expected a running execution owned by w1, got %+v", executions["exec-1"])
+	}
+
+	err = recorder.Complete(context.Background(), "exec-1", Completion{
+		NodeID:   "fetch",
+		Output:   state.State{"page": "<html>"},
+		Deleted:  []string{"input"},
+		Next:     []string{"parse", "index"},
+		Attempts: 2,
+	})
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+
+	st := states["exec-1"]
+	if st.Get("page") != "<html>" || st.Has("input") {
+		t.Errorf("expected the output applied to state, got %v", st)
+	}
+	cp, err := recorder.Load(context.Background(), "exec-1")
+	if err != nil || cp == nil {
+		t.Fatalf("Load failed: %v, %v", cp, err)
+	}
+	if cp.Sequence != 1 || !cp.IsCompleted("fetch") || cp.IsPending("fetch") {
+		t.Errorf("expected fetch completed, got %+v", cp)
+	}
+	if len(cp.Pending) != 2 || cp.Pending[0] != "parse" || cp.Pending[1] != "index" {
+		t.Errorf("expected parse and index pending, got %v", cp.Pending)
+	}
+	if c := cp.Completed["fetch"]; c.Attempts != 2 || len(c.Keys) != 2 || !c.CompletedAt.Equal(now) {
+		t.Errorf("unexpected completion %+v", c)
+	}
+
+	// Nodes already pending are not scheduled twice.
+	if err := recorder.Complete(context.Background(), "exec-1", Completion{NodeID: "parse", Next: []string{"index"}}); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	cp, _ = recorder.Load(context.Background(), "exec-1")
+	if cp.Sequence != 2 || len(cp.Pending) != 1 || cp.Pending[0] != "index" {
+		t.Errorf("expected only index pending, got %+v", cp)
+	}
+}
+
+func TestRecorder_CompleteFailureLeavesStateUnchanged(t *testing.T) {
+	states := failingStates{fakes.States{"exec-1": state.State{"a": 1}}}
+	recorder := NewRecorder(states, fakes.Executions{})
+
+	err := recorder.Complete(context.Background(), "exec-1", Completion{NodeID: "n", Output: state.State{"a": 2}})
+	if err == nil {
+		t.Fatal("expected the update to fail")
+	}
+	if states.States["exec-1"].Get("a") != 1 {
+		t.Error("expected neither the output nor the checkpoint to be recorded")
+	}
+}
+
+// failingStates fails every update after running it on a copy, like a
+// transactional store aborting the transaction.
+type failingStates struct {
+	fakes.States
+}
+
+func (f failingStates) UpdateState(ctx context.Context, executionID string, updateFn func(state.State) (state.State, error)) error {
+	if _, err := updateFn(f.States[executionID].DeepCopy()); err != nil {
+		return err
+	}
+	return fmt.Errorf("transaction aborted")
+}
+
+func TestCheckpoint_Unsafe(t *testing.T) {
+	idempotent := true
+	g := joinGraph(t)
+	g.GetNode("c").(*graph.ExecutorNode).Policy = &graph.Policy{Idempotent: &idempotent}
+	g.AddNode(&graph.RouterNode{BaseNode: graph.BaseNode{ID: "route", Type: graph.NodeTypeRouter}, DefaultRoute: "d"})
+
+	cp := &Checkpoint{Pending: []string{"b", "c", "route"}}
+	cp.Start([]string{"b", "c", "route"}, now)
+	if unsafe := cp.Unsafe(g); len(unsafe) != 1 || unsafe[0] != "b" {
+		t.Errorf("expected only b to be unsafe, got %v", unsafe)
+	}
+	cp.Record(Completion{NodeID: "b", Next: []string{"d"}}, now)
+	if unsafe := cp.Unsafe(g); len(unsafe) != 0 {
+		t.Errorf("expected completed nodes to be safe, got %v", unsafe)
+	}
+}
+
+func TestFromState(t *testing.T) {
+	if _, ok := FromState(state.State{}); ok {
+		t.Error("expected no checkpoint in an empty state")
+	}
+	cp, ok := FromState(state.State{StateKey: &Checkpoint{Sequence: 3}})
+	if !ok || cp.Sequence != 3 {
+		t.Errorf("expected the stored checkpoint, got %+v", cp)
+	}
+}
+
+// joinGraph is start→b→d, start→c→e→d, with a loop e→c.
+func joinGraph(t *testing.T) *graph.Graph {
+	t.Helper()
+	g := graph.NewGraph("join")
+	for _, id := range []string{"start", "b", "c", "d", "e"} {
+		node := &graph.ExecutorNode{BaseNode: graph.BaseNode{ID: id, Type: graph.NodeTypeExecutor, Name: id}, ExecutorType: graph.ExecutorTypeTool, Config: map[string]interface{}{"tool_name": id}}
+		if err := g.AddNode(node); err != nil {
+			t.Fatalf("AddNode failed: %v", err)
+		}
+	}
+	for _, edge := range [][2]string{{"start", "b"}, {"start", "c"}, {"b", "d"}, {"c", "e"}, {"e", "d"}, {"e", "c"}} {
+		if err := g.AddEdge(graph.NewEdge(edge[0], edge[1])); err != nil {
+			t.Fatalf("AddEdge failed: %v", err)
+		}
+	}
+	return g
+}
+
+func TestCheckpoint_RecordJoins(t *testing.T) {
+	g := joinGraph(t)
+	cp := &Checkpoint{Pending: []string{"start"}}
+	now := time.Now()
+
+	cp.Record(Completion{NodeID: "start", Next: []string{"b", "c"}, Graph: g}, now)
+	cp.Record(Completion{NodeID: "b", Next: []string{"d"}, Graph: g}, now)
+	if cp.IsPending("d") || len(cp.Waiting["d"]) != 1 {
+		t.Fatalf("expected d to wait for e, got %+v", cp)
+	}
+	cp.Record(Completion{NodeID: "c", Next: []string{"e"}, Graph: g}, now)
+	// e loops back to c: d keeps waiting for the branch to end.
+	cp.Record(Completion{NodeID: "e", Next: []string{"c"}, Graph: g}, now)
+	if cp.IsPending("d") || !cp.IsPending("c") {
+		t.Fatalf("expected c pending and d waiting, got %+v", cp)
+	}
+	cp.Record(Completion{NodeID: "c", Next: []string{"e"}, Graph: g}, now)
+	cp.Record(Completion{NodeID: "e", Next: []string{"d"}, Graph: g}, now)
+	if !cp.IsPending("d") || cp.Waiting != nil {
+		t.Errorf("expected d pending once the loop ended, got %+v", cp)
+	}
+}
+
+func TestCheckpoint_RecordWithoutGraph(t *testing.T) {
+	cp := &Checkpoint{Pending: []string{"start"}}
+	cp.Record(Completion{NodeID: "start", Next: []string{"b", "c"}}, time.Now())
+	cp.Record(Completion{NodeID: "b", Next: []string{"d"}}, time.Now())
+	if !cp.IsPending("d") || !cp.IsPending("c") {
+		t.Errorf("expected next nodes to be scheduled at once, got %+v", cp)
+	}
+}
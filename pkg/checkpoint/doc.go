@@ -0,0 +1,19 @@
+// Package checkpoint records durable execution checkpoints and recovers
+// executions orphaned by dead workers.
+//
+// A Checkpoint lives in the execution state under StateKey. Recorder.Complete
+// applies a node's output and records its completion in the same
+// state.Manager.UpdateState call, so the state and the checkpoint can never
+// disagree: after a crash, every node listed as completed has its output in
+// state, and the nodes listed as pending are the ones left to run.
+//
+// Recovery finds executions stuck in the running status whose worker is no
+// longer live in the ports.WorkerRegistry, claims them for the recovering
+// worker and hands them, with their last checkpoint, to a Resumer, typically
+// the engine. Recorder.Begin records the nodes that start running, so a
+// recovered checkpoint tells which pending nodes may have had side effects
+// before the crash. Only nodes declared idempotent (graph.Policy.Idempotent)
+// run again; executions interrupted in other nodes fail with
+// ErrNotIdempotent. Idempotent nodes with side effects should pass their
+// idempotency key to external systems (see graph.IdempotencyKeyFromContext).
+package checkpoint
@@ -0,0 +1,136 @@
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// ErrNotIdempotent is returned when recovering an execution that was running
+// nodes that are not safe to run again (see Checkpoint.Unsafe).
+var ErrNotIdempotent = errors.New("execution was running nodes that are not idempotent")
+
+// Resumer resumes an execution from its last checkpoint. Engines implement it
+// to run the pending nodes of recovered executions. Nodes in cp.Started may
+// have had side effects before the crash: resumers fail the execution with
+// ErrNotIdempotent rather than run unsafe nodes again.
+type Resumer interface {
+	ResumeFrom(ctx context.Context, metadata ports.ExecutionMetadata, cp *Checkpoint) error
+}
+
+// Recovery finds executions orphaned by dead workers and resumes them from
+// their last checkpoint.
+type Recovery struct {
+	recorder   *Recorder
+	executions ports.ExecutionStorage
+	workers    ports.WorkerRegistry
+	workerID   string
+	staleAfter time.Duration
+	now        func() time.Time
+}
+
+// NewRecovery creates a Recovery run by the worker workerID, which claims the
+// executions it recovers.
+func NewRecovery(recorder *Recorder, workers ports.WorkerRegistry, workerID string) *Recovery {
+	return &Recovery{
+		recorder:   recorder,
+		executions: recorder.executions,
+		workers:    workers,
+		workerID:   workerID,
+		now:        time.Now,
+	}
+}
+
+// WithStaleAfter treats workers without a heartbeat for longer than d as dead,
+// even if the registry still reports them healthy. Zero trusts the registry.
+func (r *Recovery) WithStaleAfter(d time.Duration) *Recovery {
+	r.staleAfter = d
+	return r
+}
+
+// WithClock sets the clock used to check heartbeats.
+func (r *Recovery) WithClock(now func() time.Time) *Recovery {
+	r.now = now
+	return r
+}
+
+// Orphaned returns the running executions whose worker is not live: unknown
+// to the registry, unhealthy, stopped or, with WithStaleAfter, silent for too
+// long. Executions without a worker are orphaned too.
+func (r *Recovery) Orphaned(ctx context.Context) ([]ports.ExecutionMetadata, error) {
+	status := ports.ExecutionStatusRunning
+	running, err := r.executions.List(ctx, &status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list executions: %w", err)
+	}
+	if len(running) == 0 {
+		return nil, nil
+	}
+	workers, err := r.workers.ListWorkers(ctx, ports.WorkerFilter{HealthyOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workers: %w", err)
+	}
+	live := make(map[string]bool, len(workers))
+	now := r.now()
+	for _, w := range workers {
+		if w.Status == ports.WorkerStatusUnhealthy || w.Status == ports.WorkerStatusStopped {
+			continue
+		}
+		if r.staleAfter > 0 && now.Sub(w.LastHeartbeat) > r.staleAfter {
+			continue
+		}
+		live[w.ID] = true
+	}
+
+	var orphaned []ports.ExecutionMetadata
+	for _, metadata := range running {
+		if !live[metadata.WorkerID] {
+			orphaned = append(orphaned, metadata)
+		}
+	}
+	return orphaned, nil
+}
+
+// Recover claims the orphaned executions that have a checkpoint and resumes
+// them with resumer. Executions without a checkpoint were not started through
+// a Recorder and are left alone. It returns the IDs of the resumed executions
+// and the errors of the others, joined; executions the resumer refused to
+// resume report ErrNotIdempotent.
+//
+// Claiming saves the execution with the recovering worker's ID before
+// resuming it, so that other recovering workers skip it once they see the
+// update. The claim changes the worker ID of a running execution, not its
+// status, so ports.ExecutionStatusSwapper cannot make it atomic: run a single
+// recovering worker at a time (e.g. the orchestrator) to avoid double claims.
+func (r *Recovery) Recover(ctx context.Context, resumer Resumer) ([]string, error) {
+	orphaned, err := r.Orphaned(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var recovered []string
+	var errs []error
+	for _, metadata := range orphaned {
+		cp, err := r.recorder.Load(ctx, metadata.ExecutionID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if cp == nil {
+			continue
+		}
+		metadata.WorkerID = r.workerID
+		if err := r.executions.Save(ctx, metadata); err != nil {
+			errs = append(errs, fmt.Errorf("failed to claim execution '%s': %w", metadata.ExecutionID, err))
+			continue
+		}
+		if err := resumer.ResumeFrom(ctx, metadata, cp); err != nil {
+			errs = append(errs, fmt.Errorf("failed to resume execution '%s': %w", metadata.ExecutionID, err))
+			continue
+		}
+		recovered = append(recovered, metadata.ExecutionID)
+	}
+	return recovered, errors.Join(errs...)
+}
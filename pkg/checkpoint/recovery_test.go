@@ -0,0 +1,138 @@
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-libs/pkg/internal/fakes"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// memoryWorkers is a minimal ports.WorkerRegistry.
+type memoryWorkers []ports.WorkerInfo
+
+func (m memoryWorkers) Register(ctx context.Context, worker ports.WorkerInfo) error { return nil }
+
+func (m memoryWorkers) Unregister(ctx context.Context, workerID string) error { return nil }
+
+func (m memoryWorkers) Heartbeat(ctx context.Context, workerID string, status ports.WorkerStatus, currentTask string) error {
+	return nil
+}
+
+func (m memoryWorkers) GetWorker(ctx context.Context, workerID string) (*ports.WorkerInfo, error) {
+	for _, w := range m {
+		if w.ID == workerID {
+			return &w, nil
+		}
+	}
+	return nil, errors.New("worker not found")
+}
+
+func (m memoryWorkers) ListWorkers(ctx context.Context, filter ports.WorkerFilter) ([]ports.WorkerInfo, error) {
+	return m, nil
+}
+
+func (m memoryWorkers) GetWorkerStats(ctx context.Context, workerType ports.WorkerType) (*ports.WorkerStats, error) {
+	return &ports.WorkerStats{Type: workerType}, nil
+}
+
+func (m memoryWorkers) CleanupStaleWorkers(ctx context.Context, timeout time.Duration) (int, error) {
+	return 0, nil
+}
+
+type recordingResumer struct {
+	resumed map[string]*Checkpoint
+	owners  map[string]string
+	err     error
+}
+
+func (r *recordingResumer) ResumeFrom(ctx context.Context, metadata ports.ExecutionMetadata, cp *Checkpoint) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.resumed[metadata.ExecutionID] = cp
+	r.owners[metadata.ExecutionID] = metadata.WorkerID
+	return nil
+}
+
+func newRecovery(t *testing.T) (*Recovery, fakes.Executions) {
+	t.Helper()
+	executions := fakes.Executions{}
+	states := fakes.States{}
+	recorder := NewRecorder(states, executions).WithClock(func() time.Time { return now })
+	for id, worker := range map[string]string{"live": "w1", "dead": "w2", "stale": "w3", "stopped": "w4", "ownerless": ""} {
+		metadata := ports.ExecutionMetadata{ExecutionID: id, WorkerID: worker}
+		if err := recorder.Start(context.Background(), metadata, state.State{}, "a"); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+	}
+	executions["done"] = ports.ExecutionMetadata{ExecutionID: "done", WorkerID: "w2", Status: ports.ExecutionStatusCompleted}
+	executions["unchecked"] = ports.ExecutionMetadata{ExecutionID: "unchecked", WorkerID: "w2", Status: ports.ExecutionStatusRunning}
+
+	workers := memoryWorkers{
+		{ID: "w1", Status: ports.WorkerStatusBusy, LastHeartbeat: now.Add(-time.Second)},
+		{ID: "w3", Status: ports.WorkerStatusBusy, LastHeartbeat: now.Add(-time.Hour)},
+		{ID: "w4", Status: ports.WorkerStatusStopped, LastHeartbeat: now},
+	}
+	recovery := NewRecovery(recorder, workers, "recoverer").
+		WithStaleAfter(time.Minute).
+		WithClock(func() time.Time { return now })
+	return recovery, executions
+}
+
+func TestRecovery_Orphaned(t *testing.T) {
+	recovery, _ := newRecovery(t)
+
+	orphaned, err := recovery.Orphaned(context.Background())
+	if err != nil {
+		t.Fatalf("Orphaned failed: %v", err)
+	}
+	var ids []string
+	for _, metadata := range orphaned {
+		ids = append(ids, metadata.ExecutionID)
+	}
+	sort.Strings(ids)
+	want := []string{"dead", "ownerless", "stale", "stopped", "unchecked"}
+	if len(ids) != len(want) {
+		t.Fatalf("expected %v, got %v", want, ids)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, ids)
+		}
+	}
+}
+
+func TestRecovery_Recover(t *testing.T) {
+	recovery, executions := newRecovery(t)
+	resumer := &recordingResumer{resumed: map[string]*Checkpoint{}, owners: map[string]string{}}
+
+	recovered, err := recovery.Recover(context.Background(), resumer)
+	if err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+	if len(recovered) != 4 || len(resumer.resumed) != 4 {
+		t.Fatalf("expected four recovered executions, got %v", recovered)
+	}
+	for _, id := range recovered {
+		if cp := resumer.resumed[id]; cp == nil || !cp.IsPending("a") {
+			t.Errorf("expected %s to resume at a, got %+v", id, cp)
+		}
+		if resumer.owners[id] != "recoverer" || executions[id].WorkerID != "recoverer" {
+			t.Errorf("expected %s to be claimed by the recovering worker", id)
+		}
+	}
+	if _, ok := resumer.resumed["unchecked"]; ok {
+		t.Error("expected executions without a checkpoint to be left alone")
+	}
+
+	// The recovering worker is not registered, so its claims are orphaned again.
+	resumer.err = errors.New("engine unavailable")
+	if _, err := recovery.Recover(context.Background(), resumer); err == nil {
+		t.Error("expected resume errors to be reported")
+	}
+}
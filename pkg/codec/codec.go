@@ -0,0 +1,188 @@
+package codec
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Format identifies a serialization format.
+type Format string
+
+const (
+	// FormatJSON encodes values as JSON.
+	FormatJSON Format = "json"
+
+	// FormatMsgPack encodes values as MessagePack.
+	FormatMsgPack Format = "msgpack"
+
+	// FormatCBOR encodes values as CBOR (RFC 8949).
+	FormatCBOR Format = "cbor"
+)
+
+// Compression identifies a compression algorithm applied after encoding.
+type Compression string
+
+const (
+	// CompressionNone leaves the encoded payload uncompressed.
+	CompressionNone Compression = "none"
+
+	// CompressionGzip compresses the payload with gzip.
+	CompressionGzip Compression = "gzip"
+
+	// CompressionZstd compresses the payload with Zstandard.
+	CompressionZstd Compression = "zstd"
+)
+
+// headerVersion is the version of the header layout written by Marshal.
+const headerVersion = 1
+
+// magic marks data that starts with a codec header. 0xDA cannot start a valid
+// JSON document, so headerless JSON is never mistaken for framed data.
+var magic = []byte{0xDA, 0x60}
+
+// headerLen is the total size of the header in bytes.
+const headerLen = 5
+
+// Wire identifiers for formats and compressions in the header.
+var (
+	formatIDs      = map[Format]byte{FormatJSON: 1, FormatMsgPack: 2, FormatCBOR: 3}
+	compressionIDs = map[Compression]byte{CompressionNone: 0, CompressionGzip: 1, CompressionZstd: 2}
+)
+
+// Codec combines a serialization format with a compression algorithm.
+// The zero value is plain JSON.
+type Codec struct {
+	Format      Format
+	Compression Compression
+}
+
+// Default is the codec used when none is configured: uncompressed JSON.
+var Default = Codec{Format: FormatJSON, Compression: CompressionNone}
+
+// Parse parses a codec name of the form "<format>[+<compression>]",
+// e.g. "json", "msgpack", "cbor+zstd" or "json+gzip".
+func Parse(name string) (Codec, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return Default, nil
+	}
+
+	formatName, compressionName, _ := strings.Cut(name, "+")
+	c := Codec{Format: Format(formatName), Compression: Compression(compressionName)}
+	if err := c.Validate(); err != nil {
+		return Codec{}, err
+	}
+	return c.normalized(), nil
+}
+
+// String returns the codec name in the form accepted by Parse.
+func (c Codec) String() string {
+	c = c.normalized()
+	if c.Compression == CompressionNone {
+		return string(c.Format)
+	}
+	return string(c.Format) + "+" + string(c.Compression)
+}
+
+// Validate checks that the format and compression are supported.
+func (c Codec) Validate() error {
+	c = c.normalized()
+	if _, ok := formatIDs[c.Format]; !ok {
+		return fmt.Errorf("unsupported codec format '%s'", c.Format)
+	}
+	if _, ok := compressionIDs[c.Compression]; !ok {
+		return fmt.Errorf("unsupported codec compression '%s'", c.Compression)
+	}
+	return nil
+}
+
+// normalized fills in defaults for empty fields.
+func (c Codec) normalized() Codec {
+	if c.Format == "" {
+		c.Format = FormatJSON
+	}
+	if c.Compression == "" {
+		c.Compression = CompressionNone
+	}
+	return c
+}
+
+// Marshal encodes v with the codec.
+// Plain JSON is written without a header; every other combination is framed.
+func (c Codec) Marshal(v interface{}) ([]byte, error) {
+	c = c.normalized()
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	payload, err := encode(c.Format, v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", c.Format, err)
+	}
+
+	if c.Format == FormatJSON && c.Compression == CompressionNone {
+		return payload, nil
+	}
+
+	payload, err = compress(c.Compression, payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress with %s: %w", c.Compression, err)
+	}
+
+	out := make([]byte, 0, headerLen+len(payload))
+	out = append(out, magic...)
+	out = append(out, headerVersion, formatIDs[c.Format], compressionIDs[c.Compression])
+	return append(out, payload...), nil
+}
+
+// Unmarshal decodes data produced by any codec into v, detecting the format
+// from the header. Data without a header is decoded as JSON.
+func Unmarshal(data []byte, v interface{}) error {
+	c, payload, err := Detect(data)
+	if err != nil {
+		return err
+	}
+
+	payload, err = decompress(c.Compression, payload)
+	if err != nil {
+		return fmt.Errorf("failed to decompress %s: %w", c.Compression, err)
+	}
+
+	if err := decode(c.Format, payload, v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", c.Format, err)
+	}
+	return nil
+}
+
+// Detect returns the codec that produced data and the payload following the header.
+func Detect(data []byte) (Codec, []byte, error) {
+	if !bytes.HasPrefix(data, magic) {
+		return Default, data, nil
+	}
+	if len(data) < headerLen {
+		return Codec{}, nil, fmt.Errorf("truncated codec header")
+	}
+	if data[2] != headerVersion {
+		return Codec{}, nil, fmt.Errorf("unsupported codec header version %d", data[2])
+	}
+
+	c := Codec{}
+	for f, id := range formatIDs {
+		if id == data[3] {
+			c.Format = f
+		}
+	}
+	for comp, id := range compressionIDs {
+		if id == data[4] {
+			c.Compression = comp
+		}
+	}
+	if c.Format == "" {
+		return Codec{}, nil, fmt.Errorf("unknown codec format id %d", data[3])
+	}
+	if c.Compression == "" {
+		return Codec{}, nil, fmt.Errorf("unknown codec compression id %d", data[4])
+	}
+	return c, data[headerLen:], nil
+}
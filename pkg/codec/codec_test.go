@@ -0,0 +1,205 @@
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+)
+
+var allCodecs = []string{
+	"json", "json+gzip", "json+zstd",
+	"msgpack", "msgpack+gzip", "msgpack+zstd",
+	"cbor", "cbor+gzip", "cbor+zstd",
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		expect      Codec
+		expectError bool
+	}{
+		{"", Default, false},
+		{"json", Codec{FormatJSON, CompressionNone}, false},
+		{"CBOR+ZSTD", Codec{FormatCBOR, CompressionZstd}, false},
+		{"msgpack+gzip", Codec{FormatMsgPack, CompressionGzip}, false},
+		{"yaml", Codec{}, true},
+		{"json+lz4", Codec{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Parse(tt.name)
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if c != tt.expect {
+				t.Errorf("expected %v, got %v", tt.expect, c)
+			}
+		})
+	}
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+
+	for _, name := range allCodecs {
+		t.Run(name, func(t *testing.T) {
+			c, err := Parse(name)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			s := state.NewState()
+			s.Set("text", strings.Repeat("hello ", 100))
+			s.Set("count", 42)
+			s.Set("nested", map[string]interface{}{"list": []interface{}{"a", 1.5, true}})
+			if c.Format != FormatJSON {
+				s.Set("when", now)
+			}
+
+			data, err := c.Marshal(s)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+
+			var out state.State
+			if err := Unmarshal(data, &out); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+
+			if val, ok := out.GetString("text"); !ok || val != strings.Repeat("hello ", 100) {
+				t.Errorf("text not preserved: %q", val)
+			}
+			if val, ok := out.GetInt("count"); !ok || val != 42 {
+				t.Errorf("count not preserved: %v", out.Get("count"))
+			}
+			nested, ok := out.Get("nested").(map[string]interface{})
+			if !ok {
+				t.Fatalf("expected nested map, got %T", out.Get("nested"))
+			}
+			if list, ok := nested["list"].([]interface{}); !ok || len(list) != 3 || list[2] != true {
+				t.Errorf("nested list not preserved: %v", nested["list"])
+			}
+			if c.Format != FormatJSON {
+				if _, ok := out.Get("count").(int64); !ok {
+					t.Errorf("expected binary format to keep integers, got %T", out.Get("count"))
+				}
+				if when, ok := out.Get("when").(time.Time); !ok || !when.Equal(now) {
+					t.Errorf("expected time to be preserved, got %T %v", out.Get("when"), out.Get("when"))
+				}
+			}
+		})
+	}
+}
+
+func TestGraphRoundTrip(t *testing.T) {
+	g := graph.NewGraph("codec")
+	_ = g.AddNode(&graph.ExecutorNode{
+		BaseNode:     graph.BaseNode{ID: "llm", Type: graph.NodeTypeExecutor},
+		ExecutorType: "llm",
+		Config:       map[string]interface{}{"model": "gpt-4", "max_tokens": 100},
+	})
+	_ = g.AddNode(&graph.RouterNode{
+		BaseNode:     graph.BaseNode{ID: "route", Type: graph.NodeTypeRouter},
+		DefaultRoute: "llm",
+	})
+	_ = g.AddEdge(graph.NewEdge("llm", "route"))
+	g.EntryNode = "llm"
+
+	for _, name := range allCodecs {
+		t.Run(name, func(t *testing.T) {
+			c, _ := Parse(name)
+			data, err := c.Marshal(g)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+
+			var out graph.Graph
+			if err := Unmarshal(data, &out); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if out.ID != g.ID || out.EdgeCount() != 1 {
+				t.Errorf("graph fields not preserved: %+v", out)
+			}
+			exec, ok := out.GetNode("llm").(*graph.ExecutorNode)
+			if !ok || exec.Config["model"] != "gpt-4" {
+				t.Errorf("executor node not preserved: %#v", out.GetNode("llm"))
+			}
+			if err := out.Validate(); err != nil {
+				t.Errorf("decoded graph is invalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestPlainJSONHasNoHeader(t *testing.T) {
+	s := state.State{"a": "b"}
+	data, err := Default.Marshal(s)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	legacy, _ := s.ToJSON()
+	if string(data) != legacy {
+		t.Errorf("expected default codec to match ToJSON, got %s", data)
+	}
+
+	framed, _ := Codec{Format: FormatJSON, Compression: CompressionGzip}.Marshal(s)
+	if !bytes.HasPrefix(framed, magic) {
+		t.Error("expected compressed JSON to carry a header")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	c, payload, err := Detect([]byte(`{"legacy": true}`))
+	if err != nil || c != Default || string(payload) != `{"legacy": true}` {
+		t.Errorf("expected headerless data to be detected as JSON, got %v %q %v", c, payload, err)
+	}
+
+	data, _ := Codec{Format: FormatCBOR, Compression: CompressionZstd}.Marshal(map[string]interface{}{"a": 1})
+	c, _, err = Detect(data)
+	if err != nil || c.Format != FormatCBOR || c.Compression != CompressionZstd {
+		t.Errorf("expected cbor+zstd, got %v (err=%v)", c, err)
+	}
+
+	if _, _, err := Detect([]byte{0xDA, 0x60, 9, 1, 0}); err == nil {
+		t.Error("expected error for unsupported header version")
+	}
+	if _, _, err := Detect([]byte{0xDA, 0x60}); err == nil {
+		t.Error("expected error for truncated header")
+	}
+}
+
+func TestUnmarshal_DecompressionLimit(t *testing.T) {
+	defer func(max int64) { maxDecompressedSize = max }(maxDecompressedSize)
+	maxDecompressedSize = 1024
+
+	for _, name := range []string{"json+gzip", "json+zstd"} {
+		t.Run(name, func(t *testing.T) {
+			c, _ := Parse(name)
+			small, _ := c.Marshal(state.State{"text": strings.Repeat("a", 512)})
+			large, _ := c.Marshal(state.State{"text": strings.Repeat("a", 4096)})
+			if len(large) >= 1024 {
+				t.Fatalf("expected the payload to compress below the limit, got %d bytes", len(large))
+			}
+
+			var s state.State
+			if err := Unmarshal(small, &s); err != nil {
+				t.Errorf("expected payloads under the limit to decode, got %v", err)
+			}
+			if err := Unmarshal(large, &s); !errors.Is(err, ErrTooLarge) {
+				t.Errorf("expected ErrTooLarge, got %v", err)
+			}
+		})
+	}
+}
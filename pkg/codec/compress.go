@@ -0,0 +1,104 @@
+package codec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// MaxDecompressedSize is the largest payload Unmarshal decompresses. Larger
+// payloads fail with ErrTooLarge, so that a small compressed document cannot
+// exhaust memory. Large state values belong in a blob store.
+const MaxDecompressedSize = 256 << 20
+
+// ErrTooLarge is returned when a compressed payload expands past
+// MaxDecompressedSize.
+var ErrTooLarge = errors.New("decompressed payload too large")
+
+// maxDecompressedSize is MaxDecompressedSize, lowered by tests.
+var maxDecompressedSize int64 = MaxDecompressedSize
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+// zstdCoders lazily creates shared zstd encoder and decoder instances.
+// Both are safe for concurrent use through EncodeAll and DecodeAll. The
+// decoder refuses to allocate more than maxDecompressedSize.
+func zstdCoders() (*zstd.Encoder, *zstd.Decoder, error) {
+	zstdOnce.Do(func() {
+		zstdEncoder, zstdErr = zstd.NewWriter(nil)
+		if zstdErr != nil {
+			return
+		}
+		zstdDecoder, zstdErr = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(uint64(maxDecompressedSize)))
+	})
+	return zstdEncoder, zstdDecoder, zstdErr
+}
+
+func compress(c Compression, data []byte) ([]byte, error) {
+	switch c {
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case CompressionZstd:
+		enc, _, err := zstdCoders()
+		if err != nil {
+			return nil, err
+		}
+		return enc.EncodeAll(data, nil), nil
+	default:
+		return data, nil
+	}
+}
+
+// decompress decompresses data, failing with ErrTooLarge if it expands past
+// maxDecompressedSize.
+func decompress(c Compression, data []byte) ([]byte, error) {
+	var out []byte
+	switch c {
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		out, err = io.ReadAll(io.LimitReader(r, maxDecompressedSize+1))
+		if err != nil {
+			return nil, err
+		}
+	case CompressionZstd:
+		_, dec, err := zstdCoders()
+		if err != nil {
+			return nil, err
+		}
+		out, err = dec.DecodeAll(data, nil)
+		if errors.Is(err, zstd.ErrDecoderSizeExceeded) || errors.Is(err, zstd.ErrWindowSizeExceeded) {
+			return nil, fmt.Errorf("%w: exceeds %d bytes", ErrTooLarge, maxDecompressedSize)
+		}
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return data, nil
+	}
+	if int64(len(out)) > maxDecompressedSize {
+		return nil, fmt.Errorf("%w: exceeds %d bytes", ErrTooLarge, maxDecompressedSize)
+	}
+	return out, nil
+}
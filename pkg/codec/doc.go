@@ -0,0 +1,17 @@
+// Package codec provides pluggable serialization formats for states and graphs.
+//
+// Supported formats are JSON, MessagePack and CBOR, each optionally compressed
+// with gzip or zstd. Encoded data carries a small self-describing header so
+// that Unmarshal detects the format automatically:
+//
+//	0xDA 0x60 <version> <format> <compression> <payload...>
+//
+// Plain uncompressed JSON is written without a header, so data produced with
+// the default codec is identical to the output of State.ToJSON and Graph.ToJSON,
+// and existing JSON blobs remain readable. Compressed payloads expanding past
+// MaxDecompressedSize fail to decode with ErrTooLarge.
+//
+// A Codec is a small value that storage adapters hold to select their wire
+// format, typically parsed from configuration with Parse ("json", "msgpack",
+// "cbor+zstd", "json+gzip", ...).
+package codec
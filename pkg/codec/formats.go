@@ -0,0 +1,214 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+)
+
+var (
+	cborEnc cbor.EncMode
+	cborDec cbor.DecMode
+)
+
+func init() {
+	var err error
+	cborEnc, err = cbor.EncOptions{
+		Sort:    cbor.SortCanonical,
+		Time:    cbor.TimeRFC3339Nano,
+		TimeTag: cbor.EncTagRequired,
+	}.EncMode()
+	if err != nil {
+		panic(fmt.Sprintf("codec: invalid CBOR encoding options: %v", err))
+	}
+	cborDec, err = cbor.DecOptions{
+		DefaultMapType: reflect.TypeOf(map[string]interface{}(nil)),
+		IntDec:         cbor.IntDecConvertSignedOrBigInt,
+	}.DecMode()
+	if err != nil {
+		panic(fmt.Sprintf("codec: invalid CBOR decoding options: %v", err))
+	}
+}
+
+// encode serializes v in the given format.
+//
+// The binary formats encode the JSON data model of v: states and generic maps
+// are encoded directly (keeping integer and time types), while other values
+// such as graphs are first converted through their JSON representation so that
+// custom JSON marshaling (e.g. node types) is honored.
+func encode(f Format, v interface{}) ([]byte, error) {
+	if f == FormatJSON {
+		return json.Marshal(v)
+	}
+
+	generic, err := toGeneric(v)
+	if err != nil {
+		return nil, err
+	}
+
+	switch f {
+	case FormatMsgPack:
+		return msgpack.Marshal(generic)
+	case FormatCBOR:
+		return cborEnc.Marshal(generic)
+	default:
+		return nil, fmt.Errorf("unsupported format '%s'", f)
+	}
+}
+
+// decode deserializes data in the given format into v.
+func decode(f Format, data []byte, v interface{}) error {
+	if f == FormatJSON {
+		return json.Unmarshal(data, v)
+	}
+
+	var generic interface{}
+	switch f {
+	case FormatMsgPack:
+		dec := msgpack.NewDecoder(bytes.NewReader(data))
+		dec.UseLooseInterfaceDecoding(true)
+		if err := dec.Decode(&generic); err != nil {
+			return err
+		}
+	case FormatCBOR:
+		if err := cborDec.Unmarshal(data, &generic); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported format '%s'", f)
+	}
+
+	return fromGeneric(generic, v)
+}
+
+// toGeneric converts v to a tree of maps, slices and scalars.
+func toGeneric(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case state.State:
+		return normalizeMap(val)
+	case map[string]interface{}:
+		return normalizeMap(val)
+	default:
+		return viaJSON(v)
+	}
+}
+
+// fromGeneric stores a decoded generic tree into v.
+// States and generic maps are assigned directly; other targets are filled
+// through their JSON representation.
+func fromGeneric(generic interface{}, v interface{}) error {
+	switch target := v.(type) {
+	case *interface{}:
+		*target = generic
+		return nil
+	case *state.State:
+		m, ok := generic.(map[string]interface{})
+		if !ok && generic != nil {
+			return fmt.Errorf("cannot decode %T into state", generic)
+		}
+		*target = state.State(m)
+		return nil
+	case *map[string]interface{}:
+		m, ok := generic.(map[string]interface{})
+		if !ok && generic != nil {
+			return fmt.Errorf("cannot decode %T into map", generic)
+		}
+		*target = m
+		return nil
+	}
+
+	data, err := json.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func normalizeMap(m map[string]interface{}) (interface{}, error) {
+	if m == nil {
+		return nil, nil
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		nv, err := normalize(v)
+		if err != nil {
+			return nil, fmt.Errorf("key '%s': %w", k, err)
+		}
+		out[k] = nv
+	}
+	return out, nil
+}
+
+// normalize keeps values that the binary formats encode natively and converts
+// anything else through JSON.
+func normalize(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case nil, bool, string, []byte, time.Time,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64,
+		float32, float64:
+		return val, nil
+	case map[string]interface{}:
+		return normalizeMap(val)
+	case state.State:
+		return normalizeMap(val)
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			nv, err := normalize(item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = nv
+		}
+		return out, nil
+	default:
+		return viaJSON(val)
+	}
+}
+
+// viaJSON converts v to its generic JSON representation, keeping integral
+// numbers as int64 rather than float64.
+func viaJSON(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return convertNumbers(generic), nil
+}
+
+func convertNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		f, _ := val.Float64()
+		return f
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = convertNumbers(item)
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = convertNumbers(item)
+		}
+		return val
+	default:
+		return val
+	}
+}
@@ -36,12 +36,13 @@ const (
 type ExecutionStatus string
 
 const (
-	ExecutionStatusPending   ExecutionStatus = "pending"
-	ExecutionStatusRunning   ExecutionStatus = "running"
-	ExecutionStatusCompleted ExecutionStatus = "completed"
-	ExecutionStatusFailed    ExecutionStatus = "failed"
-	ExecutionStatusCancelled ExecutionStatus = "cancelled"
-	ExecutionStatusSubmitted ExecutionStatus = "submitted"
+	ExecutionStatusPending         ExecutionStatus = "pending"
+	ExecutionStatusRunning         ExecutionStatus = "running"
+	ExecutionStatusCompleted       ExecutionStatus = "completed"
+	ExecutionStatusFailed          ExecutionStatus = "failed"
+	ExecutionStatusCancelled       ExecutionStatus = "cancelled"
+	ExecutionStatusSubmitted       ExecutionStatus = "submitted"
+	ExecutionStatusWaitingForInput ExecutionStatus = "waiting_for_input"
 )
 
 // GraphState represents the state of a graph execution
@@ -72,25 +73,28 @@ type NodeState struct {
 type EventType string
 
 const (
-	EventTypeGraphSubmitted  EventType = "graph.submitted"
-	EventTypeGraphStarted    EventType = "graph.started"
-	EventTypeGraphCompleted  EventType = "graph.completed"
-	EventTypeGraphFailed     EventType = "graph.failed"
-	EventTypeGraphCancelled  EventType = "graph.cancelled"
-	EventTypeNodeStarted     EventType = "node.started"
-	EventTypeNodeCompleted   EventType = "node.completed"
-	EventTypeNodeFailed      EventType = "node.failed"
-	EventTypeNodeReady       EventType = "node.ready"
+	EventTypeGraphSubmitted      EventType = "graph.submitted"
+	EventTypeGraphStarted        EventType = "graph.started"
+	EventTypeGraphCompleted      EventType = "graph.completed"
+	EventTypeGraphFailed         EventType = "graph.failed"
+	EventTypeGraphCancelled      EventType = "graph.cancelled"
+	EventTypeNodeStarted         EventType = "node.started"
+	EventTypeNodeCompleted       EventType = "node.completed"
+	EventTypeNodeFailed          EventType = "node.failed"
+	EventTypeNodeReady           EventType = "node.ready"
+	EventTypeNodeWaitingForInput EventType = "node.waiting_for_input"
+	EventTypeGraphResumed        EventType = "graph.resumed"
 )
 
 // Event represents an event in the system
 type Event struct {
-	ID        string                 `json:"id"`
-	Type      EventType              `json:"type"`
-	GraphID   string                 `json:"graph_id"`
-	NodeID    string                 `json:"node_id,omitempty"`
-	Timestamp time.Time              `json:"timestamp"`
-	Data      map[string]interface{} `json:"data,omitempty"`
+	ID            string                 `json:"id"`
+	Type          EventType              `json:"type"`
+	SchemaVersion string                 `json:"schema_version,omitempty"`
+	GraphID       string                 `json:"graph_id"`
+	NodeID        string                 `json:"node_id,omitempty"`
+	Timestamp     time.Time              `json:"timestamp"`
+	Data          map[string]interface{} `json:"data,omitempty"`
 }
 
 // LLMRequest represents a request to an LLM
@@ -0,0 +1,272 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+)
+
+// ErrWaitingForInput is returned (wrapped in an *InputRequiredError) by nodes
+// that cannot complete until a human responds.
+var ErrWaitingForInput = errors.New("waiting for input")
+
+// ErrInputRejected is returned by an approval node whose request was rejected.
+// Route rejections with an error edge for the "rejected" error kind.
+var ErrInputRejected = errors.New("input rejected")
+
+// InputRequiredError reports that a node is waiting for human input. Engines
+// pause the execution, persist the node's InputRequest and run the node again
+// once the response has been delivered.
+type InputRequiredError struct {
+	NodeID string
+}
+
+// Error implements the error interface.
+func (e *InputRequiredError) Error() string {
+	return fmt.Sprintf("node '%s' is waiting for input", e.NodeID)
+}
+
+// Unwrap returns ErrWaitingForInput.
+func (e *InputRequiredError) Unwrap() error {
+	return ErrWaitingForInput
+}
+
+// ErrorKind returns ErrorKindWaitingForInput.
+func (e *InputRequiredError) ErrorKind() string {
+	return ErrorKindWaitingForInput
+}
+
+// rejectedError reports the rejection of an approval request.
+type rejectedError struct {
+	nodeID  string
+	comment string
+}
+
+func (e *rejectedError) Error() string {
+	if e.comment != "" {
+		return fmt.Sprintf("node '%s': %v: %s", e.nodeID, ErrInputRejected, e.comment)
+	}
+	return fmt.Sprintf("node '%s': %v", e.nodeID, ErrInputRejected)
+}
+
+func (e *rejectedError) Unwrap() error {
+	return ErrInputRejected
+}
+
+func (e *rejectedError) ErrorKind() string {
+	return ErrorKindRejected
+}
+
+// ApprovalNode pauses the execution until a human approves it or provides
+// input, e.g. to review a draft or authorize a tool call.
+//
+// Executing the node without a response returns an *InputRequiredError. The
+// response, delivered under InputResponseKey, is moved to OutputKey the next
+// time the node runs: an approval completes the node, and a rejection fails it
+// with ErrInputRejected unless the node only collects input.
+type ApprovalNode struct {
+	BaseNode
+	// Prompt is the question or instruction shown to the human.
+	Prompt string `json:"prompt"`
+
+	// InputSchema is the JSON schema of the data expected with the response.
+	// If empty, only an approval or rejection is expected.
+	InputSchema map[string]interface{} `json:"input_schema,omitempty"`
+
+	// ContextKeys lists the state keys shown to the human with the request.
+	ContextKeys []string `json:"context_keys,omitempty"`
+
+	// Assignees lists the users or groups allowed to respond. Empty means anyone.
+	Assignees []string `json:"assignees,omitempty"`
+
+	// ExpiresAfter is how long the request stays open. Zero means no expiry.
+	ExpiresAfter Duration `json:"expires_after,omitempty"`
+
+	// OutputKey is the state key the response is written to. Empty means the
+	// node ID.
+	OutputKey string `json:"output_key,omitempty"`
+
+	// InputOnly makes the node collect data without an approval decision, so
+	// that responses are never treated as rejections.
+	InputOnly bool `json:"input_only,omitempty"`
+}
+
+// InputResponseKey returns the state key where the response to a node's
+// request is delivered before the node consumes it.
+func InputResponseKey(nodeID string) string {
+	return "_input." + nodeID
+}
+
+// ResponseKey returns the state key the response is written to.
+func (n *ApprovalNode) ResponseKey() string {
+	if n.OutputKey != "" {
+		return n.OutputKey
+	}
+	return n.ID
+}
+
+// Execute consumes a delivered response, or reports that the node is waiting
+// for one.
+func (n *ApprovalNode) Execute(ctx context.Context, s state.State) (state.State, error) {
+	response, ok := ResponseFrom(s, InputResponseKey(n.ID))
+	if !ok {
+		return s, &InputRequiredError{NodeID: n.ID}
+	}
+	delete(s, InputResponseKey(n.ID))
+	s.Set(n.ResponseKey(), response.ToMap())
+	if !response.Approved && !n.InputOnly {
+		return s, &rejectedError{nodeID: n.ID, comment: response.Comment}
+	}
+	return s, nil
+}
+
+// Validate checks if the approval node configuration is valid.
+func (n *ApprovalNode) Validate() error {
+	if n.ID == "" {
+		return &ValidationError{Field: "id", Message: "approval node ID cannot be empty"}
+	}
+	if n.Prompt == "" {
+		return &ValidationError{Field: "prompt", Message: "approval prompt cannot be empty"}
+	}
+	if n.ExpiresAfter < 0 {
+		return &ValidationError{Field: "expires_after", Message: "expiry cannot be negative"}
+	}
+	return nil
+}
+
+// NewRequest creates the input request of the node in an execution, with the
+// values of ContextKeys taken from s.
+func (n *ApprovalNode) NewRequest(executionID string, s state.State, now time.Time) *InputRequest {
+	req := &InputRequest{
+		ID:          uuid.New().String(),
+		ExecutionID: executionID,
+		NodeID:      n.ID,
+		Prompt:      n.Prompt,
+		InputSchema: n.InputSchema,
+		Assignees:   n.Assignees,
+		InputOnly:   n.InputOnly,
+		RequestedAt: now,
+	}
+	if len(n.ContextKeys) > 0 {
+		req.Context = make(map[string]interface{}, len(n.ContextKeys))
+		for _, key := range n.ContextKeys {
+			req.Context[key] = s.Get(key)
+		}
+	}
+	if n.ExpiresAfter > 0 {
+		expires := now.Add(time.Duration(n.ExpiresAfter))
+		req.ExpiresAt = &expires
+	}
+	return req
+}
+
+// InputRequest is a pending request for human input, persisted with the
+// execution while it waits.
+type InputRequest struct {
+	// ID identifies the request; responses must reference it.
+	ID string `json:"id"`
+
+	// ExecutionID is the execution waiting for the response.
+	ExecutionID string `json:"execution_id"`
+
+	// NodeID is the node waiting for the response.
+	NodeID string `json:"node_id"`
+
+	// Prompt is the question or instruction shown to the human.
+	Prompt string `json:"prompt"`
+
+	// InputSchema is the JSON schema of the response data, if any.
+	InputSchema map[string]interface{} `json:"input_schema,omitempty"`
+
+	// Context holds the state values shown with the request.
+	Context map[string]interface{} `json:"context,omitempty"`
+
+	// Assignees lists who may respond. Empty means anyone.
+	Assignees []string `json:"assignees,omitempty"`
+
+	// InputOnly reports that the request collects data without a decision.
+	InputOnly bool `json:"input_only,omitempty"`
+
+	// RequestedAt is when the execution paused.
+	RequestedAt time.Time `json:"requested_at"`
+
+	// ExpiresAt is when the request stops accepting responses, if ever.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Expired reports whether the request no longer accepts responses at now.
+func (r *InputRequest) Expired(now time.Time) bool {
+	return r.ExpiresAt != nil && !now.Before(*r.ExpiresAt)
+}
+
+// AcceptsResponder reports whether responder is one of the assignees.
+func (r *InputRequest) AcceptsResponder(responder string) bool {
+	return len(r.Assignees) == 0 || contains(r.Assignees, responder)
+}
+
+// InputResponse is a human's response to an InputRequest.
+type InputResponse struct {
+	// RequestID is the ID of the request answered.
+	RequestID string `json:"request_id"`
+
+	// Approved is the decision. It is ignored for input-only requests.
+	Approved bool `json:"approved"`
+
+	// Data holds the input provided, validated against the request's InputSchema.
+	Data map[string]interface{} `json:"data,omitempty"`
+
+	// Responder identifies who responded.
+	Responder string `json:"responder,omitempty"`
+
+	// Comment is an optional free-form note.
+	Comment string `json:"comment,omitempty"`
+
+	// RespondedAt is when the response was given.
+	RespondedAt time.Time `json:"responded_at"`
+}
+
+// ToMap returns the response as a JSON-compatible map, the form stored in
+// state so that route conditions can read it (e.g. "state.review.approved").
+func (r *InputResponse) ToMap() map[string]interface{} {
+	m := map[string]interface{}{
+		"request_id":   r.RequestID,
+		"approved":     r.Approved,
+		"responded_at": r.RespondedAt.UTC().Format(time.RFC3339Nano),
+	}
+	if r.Data != nil {
+		m["data"] = r.Data
+	}
+	if r.Responder != "" {
+		m["responder"] = r.Responder
+	}
+	if r.Comment != "" {
+		m["comment"] = r.Comment
+	}
+	return m
+}
+
+// ResponseFrom decodes the response stored under key in s.
+func ResponseFrom(s state.State, key string) (*InputResponse, bool) {
+	value := s.Get(key)
+	if value == nil {
+		return nil, false
+	}
+	if r, ok := value.(*InputResponse); ok {
+		return r, true
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	var r InputResponse
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, false
+	}
+	return &r, true
+}
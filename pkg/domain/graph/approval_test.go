@@ -0,0 +1,152 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+)
+
+func approvalNode() *ApprovalNode {
+	return &ApprovalNode{
+		BaseNode:     BaseNode{ID: "review", Type: NodeTypeApproval},
+		Prompt:       "Publish this draft?",
+		ContextKeys:  []string{"draft"},
+		Assignees:    []string{"editors"},
+		ExpiresAfter: Duration(time.Hour),
+	}
+}
+
+func TestApprovalNode_Validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		node  *ApprovalNode
+		field string
+	}{
+		{name: "valid", node: approvalNode()},
+		{name: "missing ID", node: &ApprovalNode{Prompt: "ok?"}, field: "id"},
+		{name: "missing prompt", node: &ApprovalNode{BaseNode: BaseNode{ID: "review"}}, field: "prompt"},
+		{name: "negative expiry", node: &ApprovalNode{BaseNode: BaseNode{ID: "review"}, Prompt: "ok?", ExpiresAfter: -1}, field: "expires_after"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.node.Validate()
+			if tt.field == "" {
+				if err != nil {
+					t.Errorf("unexpected validation error: %v", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) || ve.Field != tt.field {
+				t.Errorf("expected error on field %q, got %v", tt.field, err)
+			}
+		})
+	}
+}
+
+func TestApprovalNode_Execute(t *testing.T) {
+	node := approvalNode()
+	s := state.State{"draft": "Hello"}
+
+	_, err := node.Execute(context.Background(), s)
+	if !errors.Is(err, ErrWaitingForInput) || ErrorKind(err) != ErrorKindWaitingForInput {
+		t.Fatalf("expected to wait for input, got %v", err)
+	}
+
+	s.Set(InputResponseKey("review"), (&InputResponse{RequestID: "r1", Approved: true, Responder: "ann"}).ToMap())
+	out, err := node.Execute(context.Background(), s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, pending := out[InputResponseKey("review")]; pending {
+		t.Error("expected the delivered response to be consumed")
+	}
+	response, ok := ResponseFrom(out, "review")
+	if !ok || !response.Approved || response.Responder != "ann" {
+		t.Errorf("expected the response under the node ID, got %+v", out["review"])
+	}
+
+	// The response is consumed, so running the node again asks again.
+	if _, err := node.Execute(context.Background(), out); !errors.Is(err, ErrWaitingForInput) {
+		t.Errorf("expected to wait for input again, got %v", err)
+	}
+}
+
+func TestApprovalNode_ExecuteRejected(t *testing.T) {
+	node := approvalNode()
+	node.OutputKey = "decision"
+	s := state.State{InputResponseKey("review"): &InputResponse{RequestID: "r1", Comment: "too long"}}
+
+	out, err := node.Execute(context.Background(), s)
+	if !errors.Is(err, ErrInputRejected) || ErrorKind(err) != ErrorKindRejected {
+		t.Fatalf("expected a rejection, got %v", err)
+	}
+	if _, ok := ResponseFrom(out, "decision"); !ok {
+		t.Error("expected the rejection to be recorded in state")
+	}
+
+	// Error edges route rejections; they are not retried.
+	policy := &Policy{Retry: &RetryPolicy{MaxAttempts: 3}, OnError: []ErrorHandler{{Errors: []string{ErrorKindRejected}, Target: "revise"}}}
+	if policy.Retry.ShouldRetry(1, err) {
+		t.Error("expected rejections not to be retried")
+	}
+	if target, ok := policy.ErrorTarget(err); !ok || target != "revise" {
+		t.Errorf("expected rejection to go to revise, got %q", target)
+	}
+	if _, ok := (&Policy{OnError: []ErrorHandler{{Target: "revise"}}}).ErrorTarget(&InputRequiredError{NodeID: "review"}); ok {
+		t.Error("expected waiting for input not to be handled by error edges")
+	}
+
+	node.InputOnly = true
+	s[InputResponseKey("review")] = &InputResponse{RequestID: "r2", Data: map[string]interface{}{"title": "Hi"}}
+	if _, err := node.Execute(context.Background(), s); err != nil {
+		t.Errorf("expected input-only responses to complete the node, got %v", err)
+	}
+}
+
+func TestApprovalNode_NewRequest(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	req := approvalNode().NewRequest("exec-1", state.State{"draft": "Hello", "other": 1}, now)
+
+	if req.ID == "" || req.ExecutionID != "exec-1" || req.NodeID != "review" || req.Prompt != "Publish this draft?" {
+		t.Errorf("unexpected request: %+v", req)
+	}
+	if len(req.Context) != 1 || req.Context["draft"] != "Hello" {
+		t.Errorf("expected only the context keys, got %v", req.Context)
+	}
+	if req.ExpiresAt == nil || !req.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("unexpected expiry %v", req.ExpiresAt)
+	}
+	if req.Expired(now) || !req.Expired(now.Add(time.Hour)) {
+		t.Error("expected the request to expire after an hour")
+	}
+	if !req.AcceptsResponder("editors") || req.AcceptsResponder("ann") {
+		t.Error("expected only assignees to be accepted")
+	}
+}
+
+func TestApprovalNode_JSONRoundTrip(t *testing.T) {
+	g, err := Build("publish").ID("publish").
+		Start().
+		Approval("review", "Publish this draft?").
+		End()
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+	data, err := g.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	decoded, err := FromJSON(data)
+	if err != nil {
+		t.Fatalf("FromJSON failed: %v", err)
+	}
+	node, ok := decoded.GetNode("review").(*ApprovalNode)
+	if !ok || node.Prompt != "Publish this draft?" {
+		t.Errorf("expected an approval node, got %#v", decoded.GetNode("review"))
+	}
+}
@@ -0,0 +1,323 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Default IDs for the nodes added by Builder.Start and Builder.End.
+const (
+	StartNodeID = "start"
+	EndNodeID   = "end"
+)
+
+// Builder constructs graphs with a fluent API.
+//
+// Each node added is wired with an edge from the current node(s), the cursor,
+// and becomes the new cursor. Routers are the exception: nodes following a
+// router are reached through its routes, for which edges are created when the
+// graph is built, so a router leaves the cursor empty. Forward references are
+// allowed; edges are resolved when the graph is built.
+//
+// Errors are accumulated and reported by Graph or End, so calls can be chained
+// without checking each step:
+//
+//	g, err := graph.Build("review").
+//		Start().
+//		LLM("draft", map[string]interface{}{"model": "gpt-4"}).
+//		Router("check", graph.When("state.approved == true", "publish")).Default("draft").
+//		Tool("publish", "http_post", nil).
+//		End()
+type Builder struct {
+	g       *Graph
+	cursor  []string
+	last    Node
+	edges   []*Edge
+	routers []*RouterNode // in the order added
+	wired   int           // routers whose routes are wired
+	errs    []error
+}
+
+// Build starts building a graph with the given name.
+func Build(name string) *Builder {
+	return &Builder{g: NewGraph(name)}
+}
+
+// When creates a conditional route to target.
+func When(condition, target string) Route {
+	return Route{Condition: condition, Target: target}
+}
+
+// ID sets the graph ID instead of the generated UUID.
+func (b *Builder) ID(id string) *Builder {
+	b.g.ID = id
+	return b
+}
+
+// Description sets the graph description.
+func (b *Builder) Description(description string) *Builder {
+	b.g.Description = description
+	return b
+}
+
+// Version sets the graph schema version.
+func (b *Builder) Version(version string) *Builder {
+	b.g.Version = version
+	return b
+}
+
+// Metadata sets a graph-level metadata value.
+func (b *Builder) Metadata(key string, value interface{}) *Builder {
+	b.g.Metadata[key] = value
+	return b
+}
+
+// DefaultPolicy sets the default execution policy of the graph's nodes.
+func (b *Builder) DefaultPolicy(policy *Policy) *Builder {
+	b.g.Policy = policy
+	return b
+}
+
+// Start adds a start node and makes it the entry node.
+func (b *Builder) Start() *Builder {
+	b.Node(&StartNode{BaseNode: BaseNode{ID: StartNodeID, Type: NodeTypeStart}})
+	b.g.EntryNode = StartNodeID
+	return b
+}
+
+// LLM adds an executor node of type "llm".
+func (b *Builder) LLM(id string, config map[string]interface{}) *Builder {
+	return b.Executor(id, ExecutorTypeLLM, config)
+}
+
+// Tool adds an executor node of type "tool" that calls toolName with static parameters.
+func (b *Builder) Tool(id, toolName string, parameters map[string]interface{}) *Builder {
+	config := map[string]interface{}{"tool_name": toolName}
+	if parameters != nil {
+		config["parameters"] = parameters
+	}
+	return b.Executor(id, ExecutorTypeTool, config)
+}
+
+// Executor adds an executor node of any executor type.
+func (b *Builder) Executor(id, executorType string, config map[string]interface{}) *Builder {
+	if config == nil {
+		config = make(map[string]interface{})
+	}
+	return b.Node(&ExecutorNode{
+		BaseNode:     BaseNode{ID: id, Type: NodeTypeExecutor},
+		ExecutorType: executorType,
+		Config:       config,
+	})
+}
+
+// Approval adds an approval node that waits for a human to respond to prompt.
+func (b *Builder) Approval(id, prompt string) *Builder {
+	return b.Node(&ApprovalNode{BaseNode: BaseNode{ID: id, Type: NodeTypeApproval}, Prompt: prompt})
+}
+
+// Router adds a router node with the given routes.
+func (b *Builder) Router(id string, routes ...Route) *Builder {
+	b.Node(&RouterNode{
+		BaseNode: BaseNode{ID: id, Type: NodeTypeRouter},
+		Routes:   routes,
+	})
+	b.cursor = nil
+	return b
+}
+
+// Default sets the default route of the last added router node.
+func (b *Builder) Default(target string) *Builder {
+	router, ok := b.last.(*RouterNode)
+	if !ok {
+		b.errs = append(b.errs, fmt.Errorf("Default requires the last added node to be a router"))
+		return b
+	}
+	router.DefaultRoute = target
+	return b
+}
+
+// Node adds any node, wiring it from the cursor.
+// A node the cursor already points at (see Then and At) is not wired again.
+func (b *Builder) Node(node Node) *Builder {
+	if err := b.g.AddNode(node); err != nil {
+		b.errs = append(b.errs, err)
+		return b
+	}
+	if b.g.EntryNode == "" {
+		b.g.EntryNode = node.GetID()
+	}
+	if !b.atCursor(node.GetID()) {
+		b.wire(node.GetID())
+	}
+	if router, ok := node.(*RouterNode); ok {
+		b.routers = append(b.routers, router)
+	}
+	b.cursor = []string{node.GetID()}
+	b.last = node
+	return b
+}
+
+// Then adds edges from the cursor to the given nodes, which may be defined
+// later, and moves the cursor to them. With an empty cursor (e.g. after a
+// router) it only moves the cursor. Use it to fan out and to join branches:
+//
+//	b.Start().Then("a", "b").
+//		LLM("a", cfgA).Then("merge").
+//		At("b").LLM("b", cfgB).Then("merge").
+//		LLM("merge", cfgMerge)
+func (b *Builder) Then(ids ...string) *Builder {
+	for _, id := range ids {
+		b.wire(id)
+	}
+	b.cursor = append([]string(nil), ids...)
+	return b
+}
+
+// At moves the cursor to the given nodes without adding edges.
+func (b *Builder) At(ids ...string) *Builder {
+	b.cursor = append([]string(nil), ids...)
+	return b
+}
+
+// Edge adds an edge between two nodes, which may be defined later.
+func (b *Builder) Edge(edge *Edge) *Builder {
+	b.edges = append(b.edges, edge)
+	return b
+}
+
+// Inputs sets the input mapping of the last added executor node.
+func (b *Builder) Inputs(mapping map[string]string) *Builder {
+	if exec, ok := b.lastExecutor("Inputs"); ok {
+		exec.InputMapping = mapping
+	}
+	return b
+}
+
+// Outputs sets the output mapping of the last added executor node.
+func (b *Builder) Outputs(mapping map[string]string) *Builder {
+	if exec, ok := b.lastExecutor("Outputs"); ok {
+		exec.OutputMapping = mapping
+	}
+	return b
+}
+
+// Describe sets the name and description of the last added node.
+func (b *Builder) Describe(name, description string) *Builder {
+	if b.last == nil {
+		b.errs = append(b.errs, fmt.Errorf("Describe called before any node was added"))
+		return b
+	}
+	if n, ok := b.last.(interface{ Base() *BaseNode }); ok {
+		n.Base().Name = name
+		n.Base().Description = description
+	}
+	return b
+}
+
+// Policy sets the execution policy of the last added node.
+func (b *Builder) Policy(policy *Policy) *Builder {
+	if base, ok := b.lastBase("Policy"); ok {
+		base.Policy = policy
+	}
+	return b
+}
+
+// OnError adds an error edge from the last added node to target, for errors
+// of the given kinds or, without kinds, for every error. The target may be
+// defined later.
+func (b *Builder) OnError(target string, kinds ...string) *Builder {
+	base, ok := b.lastBase("OnError")
+	if !ok {
+		return b
+	}
+	if base.Policy == nil {
+		base.Policy = &Policy{}
+	}
+	base.Policy.OnError = append(base.Policy.OnError, ErrorHandler{Errors: kinds, Target: target})
+	return b
+}
+
+// End adds an end node wired from the cursor and builds the graph.
+func (b *Builder) End() (*Graph, error) {
+	b.Node(&EndNode{BaseNode: BaseNode{ID: EndNodeID, Type: NodeTypeEnd}})
+	return b.Graph()
+}
+
+// Graph resolves pending edges, wires router routes and validates the graph.
+// It returns every error accumulated while building. Graph may be called
+// again after adding nodes; edges already added are not added twice.
+func (b *Builder) Graph() (*Graph, error) {
+	errs := append([]error(nil), b.errs...)
+
+	for _, edge := range b.edges {
+		if err := b.g.AddEdge(edge); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Routers are wired in the order they were added, once, so that edges
+	// are deterministic and calling Graph again does not duplicate them.
+	for _, router := range b.routers[b.wired:] {
+		for _, r := range router.Routes {
+			edge := NewEdge(router.ID, r.Target).WithCondition(r.Condition).WithLabel(r.Description)
+			if err := b.g.AddEdge(edge); err != nil {
+				errs = append(errs, fmt.Errorf("router '%s': %w", router.ID, err))
+			}
+		}
+		if router.DefaultRoute != "" {
+			if err := b.g.AddEdge(NewEdge(router.ID, router.DefaultRoute).WithLabel("default")); err != nil {
+				errs = append(errs, fmt.Errorf("router '%s': %w", router.ID, err))
+			}
+		}
+	}
+	b.edges = nil
+	b.wired = len(b.routers)
+
+	if len(errs) == 0 {
+		if err := b.g.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return b.g, nil
+}
+
+func (b *Builder) atCursor(id string) bool {
+	for _, c := range b.cursor {
+		if c == id {
+			return true
+		}
+	}
+	return false
+}
+
+// wire records edges from every cursor node to id.
+// Routers are skipped, since they transition through their routes.
+func (b *Builder) wire(id string) {
+	for _, from := range b.cursor {
+		if _, isRouter := b.g.GetNode(from).(*RouterNode); isRouter {
+			continue
+		}
+		b.edges = append(b.edges, NewEdge(from, id))
+	}
+}
+
+func (b *Builder) lastExecutor(method string) (*ExecutorNode, bool) {
+	exec, ok := b.last.(*ExecutorNode)
+	if !ok {
+		b.errs = append(b.errs, fmt.Errorf("%s requires the last added node to be an executor", method))
+	}
+	return exec, ok
+}
+
+func (b *Builder) lastBase(method string) (*BaseNode, bool) {
+	n, ok := b.last.(interface{ Base() *BaseNode })
+	if !ok {
+		b.errs = append(b.errs, fmt.Errorf("%s requires a node to be added first", method))
+		return nil, false
+	}
+	return n.Base(), true
+}
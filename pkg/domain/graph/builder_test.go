@@ -0,0 +1,145 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuilder_LinearChain(t *testing.T) {
+	g, err := Build("summarize").
+		ID("graph-1").
+		Start().
+		LLM("summarize", map[string]interface{}{"model": "gpt-4"}).
+		Inputs(map[string]string{"text": "document"}).
+		Outputs(map[string]string{"summary": "summary"}).
+		Tool("store", "save_document", nil).
+		End()
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+
+	if g.ID != "graph-1" || g.EntryNode != StartNodeID {
+		t.Errorf("unexpected graph header: id=%q entry=%q", g.ID, g.EntryNode)
+	}
+	if g.NodeCount() != 4 {
+		t.Errorf("expected 4 nodes, got %d", g.NodeCount())
+	}
+
+	expected := [][2]string{{"start", "summarize"}, {"summarize", "store"}, {"store", "end"}}
+	if g.EdgeCount() != len(expected) {
+		t.Fatalf("expected %d edges, got %d", len(expected), g.EdgeCount())
+	}
+	for i, e := range expected {
+		if g.Edges[i].From != e[0] || g.Edges[i].To != e[1] {
+			t.Errorf("edge %d: expected %s->%s, got %s->%s", i, e[0], e[1], g.Edges[i].From, g.Edges[i].To)
+		}
+	}
+
+	exec := g.GetNode("summarize").(*ExecutorNode)
+	if exec.InputMapping["text"] != "document" || exec.OutputMapping["summary"] != "summary" {
+		t.Errorf("mappings not applied: %+v", exec)
+	}
+}
+
+func TestBuilder_RouterWiresRoutes(t *testing.T) {
+	g, err := Build("review").
+		Start().
+		LLM("draft", map[string]interface{}{"model": "gpt-4"}).
+		Router("check", When("state.approved == true", "publish")).
+		Default("draft").
+		Tool("publish", "http_post", map[string]interface{}{"url": "https://example.com"}).
+		End()
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+
+	router := g.GetNode("check").(*RouterNode)
+	if router.DefaultRoute != "draft" || len(router.Routes) != 1 {
+		t.Errorf("unexpected router: %+v", router)
+	}
+
+	out := g.GetOutgoingEdges("check")
+	if len(out) != 2 {
+		t.Fatalf("expected 2 edges out of router, got %d", len(out))
+	}
+	if len(g.GetIncomingEdges("publish")) != 1 {
+		t.Error("expected publish to be reached only through the router route")
+	}
+}
+
+func TestBuilder_RouterEdgesAreStable(t *testing.T) {
+	cfg := map[string]interface{}{"model": "gpt-4"}
+	b := Build("review").
+		Start().
+		Router("first", When("state.a", "x")).Default("second").
+		At().Router("second", When("state.b", "y")).Default("z").
+		At().LLM("x", cfg).
+		At().LLM("y", cfg).
+		At().LLM("z", cfg)
+	g, err := b.Graph()
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+	var got []string
+	for _, e := range g.Edges {
+		got = append(got, e.From+"->"+e.To)
+	}
+	want := "start->first,first->x,first->second,second->y,second->z"
+	if strings.Join(got, ",") != want {
+		t.Errorf("expected edges %s, got %s", want, strings.Join(got, ","))
+	}
+
+	again, err := b.Graph()
+	if err != nil {
+		t.Fatalf("second build failed: %v", err)
+	}
+	if again.EdgeCount() != len(got) {
+		t.Errorf("expected Graph not to wire routes twice, got %d edges", again.EdgeCount())
+	}
+}
+
+func TestBuilder_ThenJoinsBranches(t *testing.T) {
+	g, err := Build("fan-in").
+		Start().
+		Then("a", "b").
+		Executor("a", "python", map[string]interface{}{"code": "print(1)"}).
+		Then("merge").
+		At("b").
+		Executor("b", "bash", map[string]interface{}{"command": "echo 2"}).
+		Then("merge").
+		Executor("merge", "python", map[string]interface{}{"code": "pass"}).
+		Graph()
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+
+	if got := len(g.GetIncomingEdges("merge")); got != 2 {
+		t.Errorf("expected 2 edges into merge, got %d", got)
+	}
+	if got := len(g.GetOutgoingEdges("start")); got != 2 {
+		t.Errorf("expected 2 edges out of start, got %d", got)
+	}
+	if g.EdgeCount() != 4 {
+		t.Errorf("expected 4 edges, got %d", g.EdgeCount())
+	}
+}
+
+func TestBuilder_AccumulatesErrors(t *testing.T) {
+	_, err := Build("broken").
+		Start().
+		LLM("dup", map[string]interface{}{"model": "gpt-4"}).
+		LLM("dup", map[string]interface{}{"model": "gpt-4"}).
+		Then("missing").
+		Default("x").
+		Graph()
+	if err == nil {
+		t.Fatal("expected build errors")
+	}
+
+	msg := err.Error()
+	for _, want := range []string{"already exists", "missing", "Default requires"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected error to mention %q, got: %v", want, msg)
+		}
+	}
+}
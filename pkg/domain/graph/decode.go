@@ -0,0 +1,93 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// NodeFactory creates an empty node of a concrete type, ready to be decoded into.
+type NodeFactory func() Node
+
+var (
+	nodeFactoriesMu sync.RWMutex
+	nodeFactories   = map[NodeType]NodeFactory{
+		NodeTypeExecutor: func() Node { return &ExecutorNode{} },
+		NodeTypeRouter:   func() Node { return &RouterNode{} },
+		NodeTypeStart:    func() Node { return &StartNode{} },
+		NodeTypeEnd:      func() Node { return &EndNode{} },
+		NodeTypeSubgraph: func() Node { return &SubgraphNode{} },
+		NodeTypeApproval: func() Node { return &ApprovalNode{} },
+	}
+)
+
+// RegisterNodeType registers a factory used to decode nodes of the given type.
+// Registering a type that already exists replaces its factory, which allows
+// downstream repositories to provide richer implementations of built-in types.
+func RegisterNodeType(nodeType NodeType, factory NodeFactory) {
+	nodeFactoriesMu.Lock()
+	defer nodeFactoriesMu.Unlock()
+	nodeFactories[nodeType] = factory
+}
+
+// RegisteredNodeTypes returns the node types that can be decoded.
+func RegisteredNodeTypes() []NodeType {
+	nodeFactoriesMu.RLock()
+	defer nodeFactoriesMu.RUnlock()
+	types := make([]NodeType, 0, len(nodeFactories))
+	for t := range nodeFactories {
+		types = append(types, t)
+	}
+	return types
+}
+
+// DecodeNode decodes a single node from JSON, choosing the concrete type from
+// its "type" field.
+func DecodeNode(data []byte) (Node, error) {
+	var header struct {
+		Type NodeType `json:"type"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("failed to read node type: %w", err)
+	}
+	if header.Type == "" {
+		return nil, &ValidationError{Field: "type", Message: "node type cannot be empty"}
+	}
+
+	nodeFactoriesMu.RLock()
+	factory, ok := nodeFactories[header.Type]
+	nodeFactoriesMu.RUnlock()
+	if !ok {
+		return nil, &ValidationError{Field: "type", Message: fmt.Sprintf("unknown node type '%s'", header.Type)}
+	}
+
+	node := factory()
+	if err := json.Unmarshal(data, node); err != nil {
+		return nil, fmt.Errorf("failed to decode %s node: %w", header.Type, err)
+	}
+	return node, nil
+}
+
+// UnmarshalJSON decodes a graph, creating concrete node types from the
+// "type" field of each node.
+func (g *Graph) UnmarshalJSON(data []byte) error {
+	type plain Graph
+	aux := struct {
+		*plain
+		Nodes map[string]json.RawMessage `json:"nodes"`
+	}{plain: (*plain)(g)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	g.Nodes = make(map[string]Node, len(aux.Nodes))
+	for id, raw := range aux.Nodes {
+		node, err := DecodeNode(raw)
+		if err != nil {
+			return fmt.Errorf("node '%s': %w", id, err)
+		}
+		g.Nodes[id] = node
+	}
+	return nil
+}
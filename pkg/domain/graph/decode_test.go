@@ -0,0 +1,103 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+)
+
+func TestFromJSON_DecodesNodeTypes(t *testing.T) {
+	jsonStr := `{
+		"id": "graph-1",
+		"nodes": {
+			"start": {"id": "start", "type": "start"},
+			"llm": {"id": "llm", "type": "executor", "executor_type": "llm", "config": {"model": "gpt-4"}},
+			"check": {"id": "check", "type": "router", "default_route": "done"},
+			"done": {"id": "done", "type": "end"}
+		},
+		"edges": [{"from": "start", "to": "llm"}, {"from": "llm", "to": "check"}],
+		"entry_node": "start"
+	}`
+
+	g, err := FromJSON(jsonStr)
+	if err != nil {
+		t.Fatalf("FromJSON failed: %v", err)
+	}
+
+	if _, ok := g.GetNode("start").(*StartNode); !ok {
+		t.Errorf("expected *StartNode, got %T", g.GetNode("start"))
+	}
+	exec, ok := g.GetNode("llm").(*ExecutorNode)
+	if !ok {
+		t.Fatalf("expected *ExecutorNode, got %T", g.GetNode("llm"))
+	}
+	if exec.ExecutorType != "llm" || exec.Config["model"] != "gpt-4" {
+		t.Errorf("executor node not decoded correctly: %+v", exec)
+	}
+	if r, ok := g.GetNode("check").(*RouterNode); !ok || r.DefaultRoute != "done" {
+		t.Errorf("router node not decoded correctly: %#v", g.GetNode("check"))
+	}
+	if _, ok := g.GetNode("done").(*EndNode); !ok {
+		t.Errorf("expected *EndNode, got %T", g.GetNode("done"))
+	}
+	if err := g.Validate(); err != nil {
+		t.Errorf("decoded graph failed validation: %v", err)
+	}
+}
+
+func TestFromJSON_UnknownNodeType(t *testing.T) {
+	_, err := FromJSON(`{"id": "g", "nodes": {"x": {"id": "x", "type": "teleport"}}, "entry_node": "x"}`)
+	if err == nil {
+		t.Error("expected error for unknown node type")
+	}
+}
+
+type customNode struct {
+	BaseNode
+	Payload string `json:"payload"`
+}
+
+func (n *customNode) Execute(ctx context.Context, s state.State) (state.State, error) {
+	return s, nil
+}
+
+func (n *customNode) Validate() error {
+	return nil
+}
+
+func TestRegisterNodeType(t *testing.T) {
+	RegisterNodeType("custom-test", func() Node { return &customNode{} })
+
+	node, err := DecodeNode([]byte(`{"id": "c", "type": "custom-test", "payload": "hi"}`))
+	if err != nil {
+		t.Fatalf("DecodeNode failed: %v", err)
+	}
+	c, ok := node.(*customNode)
+	if !ok || c.Payload != "hi" {
+		t.Errorf("expected custom node with payload, got %#v", node)
+	}
+}
+
+func TestGraphClone_PreservesNodes(t *testing.T) {
+	g := NewGraph("test")
+	_ = g.AddNode(&ExecutorNode{
+		BaseNode:     BaseNode{ID: "exec", Type: NodeTypeExecutor},
+		ExecutorType: "tool",
+		Config:       map[string]interface{}{"tool_name": "search"},
+	})
+	g.EntryNode = "exec"
+
+	clone, err := g.Clone()
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	exec, ok := clone.GetNode("exec").(*ExecutorNode)
+	if !ok {
+		t.Fatalf("expected *ExecutorNode in clone, got %T", clone.GetNode("exec"))
+	}
+	exec.Config["tool_name"] = "changed"
+	if g.GetNode("exec").(*ExecutorNode).Config["tool_name"] != "search" {
+		t.Error("clone shares config with original")
+	}
+}
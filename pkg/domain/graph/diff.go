@@ -0,0 +1,320 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ChangeType describes how an element differs between two graphs.
+type ChangeType string
+
+const (
+	// ChangeAdded means the element only exists in the new graph.
+	ChangeAdded ChangeType = "added"
+
+	// ChangeRemoved means the element only exists in the old graph.
+	ChangeRemoved ChangeType = "removed"
+
+	// ChangeModified means the element exists in both graphs with different content.
+	ChangeModified ChangeType = "modified"
+)
+
+// FieldChange is a change to a single field.
+// Old is nil for added fields and New is nil for removed ones.
+type FieldChange struct {
+	// Path is the dotted path of the field relative to its element,
+	// with list indexes in brackets (e.g. "config.model", "routes[0].target").
+	Path string `json:"path"`
+
+	Type ChangeType  `json:"type"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// NodeChange is a change to a node.
+type NodeChange struct {
+	ID   string     `json:"id"`
+	Type ChangeType `json:"type"`
+
+	// Fields lists the changed fields of a modified node.
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+// EdgeChange is a change to an edge.
+type EdgeChange struct {
+	// Key identifies the edge (see EdgeKey).
+	Key  string     `json:"key"`
+	Type ChangeType `json:"type"`
+	Old  *Edge      `json:"old,omitempty"`
+	New  *Edge      `json:"new,omitempty"`
+
+	// Fields lists the changed fields of a modified edge.
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+// GraphDiff describes the differences between two graphs.
+// Changes are sorted by node ID, edge key and field path.
+type GraphDiff struct {
+	// Fields lists changes to graph-level fields (name, entry_node, metadata, ...).
+	Fields []FieldChange `json:"fields,omitempty"`
+	Nodes  []NodeChange  `json:"nodes,omitempty"`
+	Edges  []EdgeChange  `json:"edges,omitempty"`
+}
+
+// IsEmpty reports whether the graphs are equivalent.
+func (d *GraphDiff) IsEmpty() bool {
+	return len(d.Fields) == 0 && len(d.Nodes) == 0 && len(d.Edges) == 0
+}
+
+// String formats the diff as one change per line, e.g.
+//
+//	~ entry_node: "start" -> "init"
+//	+ node review
+//	~ node draft config.model: "gpt-4" -> "gpt-4o"
+//	- edge draft->publish
+func (d *GraphDiff) String() string {
+	var b strings.Builder
+	for _, f := range d.Fields {
+		writeField(&b, "", f)
+	}
+	for _, n := range d.Nodes {
+		if n.Type != ChangeModified {
+			fmt.Fprintf(&b, "%s node %s\n", changeSymbol(n.Type), n.ID)
+			continue
+		}
+		for _, f := range n.Fields {
+			writeField(&b, "node "+n.ID+" ", f)
+		}
+	}
+	for _, e := range d.Edges {
+		if e.Type != ChangeModified {
+			fmt.Fprintf(&b, "%s edge %s\n", changeSymbol(e.Type), e.Key)
+			continue
+		}
+		for _, f := range e.Fields {
+			writeField(&b, "edge "+e.Key+" ", f)
+		}
+	}
+	return b.String()
+}
+
+func writeField(b *strings.Builder, prefix string, f FieldChange) {
+	switch f.Type {
+	case ChangeAdded:
+		fmt.Fprintf(b, "+ %s%s: %s\n", prefix, f.Path, formatValue(f.New))
+	case ChangeRemoved:
+		fmt.Fprintf(b, "- %s%s: %s\n", prefix, f.Path, formatValue(f.Old))
+	default:
+		fmt.Fprintf(b, "~ %s%s: %s -> %s\n", prefix, f.Path, formatValue(f.Old), formatValue(f.New))
+	}
+}
+
+func changeSymbol(t ChangeType) string {
+	switch t {
+	case ChangeAdded:
+		return "+"
+	case ChangeRemoved:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+func formatValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+// EdgeKey returns the identity of an edge used to match edges across graph
+// versions: its ID if set, otherwise "from->to".
+func EdgeKey(e *Edge) string {
+	if e.ID != "" {
+		return e.ID
+	}
+	return e.From + "->" + e.To
+}
+
+// Diff compares graph a (old) with graph b (new).
+//
+// Nodes are matched by ID and edges by EdgeKey; when several edges share a
+// key, they are matched in order and suffixed with "#2", "#3", ... Fields are
+// compared on their JSON representation, so any node type can be diffed.
+func Diff(a, b *Graph) (*GraphDiff, error) {
+	docA, err := graphDocument(a)
+	if err != nil {
+		return nil, err
+	}
+	docB, err := graphDocument(b)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &GraphDiff{}
+
+	nodesA, _ := docA["nodes"].(map[string]interface{})
+	nodesB, _ := docB["nodes"].(map[string]interface{})
+	delete(docA, "nodes")
+	delete(docB, "nodes")
+	edgesA := keyedEdges(a.Edges)
+	edgesB := keyedEdges(b.Edges)
+	delete(docA, "edges")
+	delete(docB, "edges")
+
+	d.Fields = diffValues("", docA, docB, nil)
+
+	for _, id := range unionKeys(nodesA, nodesB) {
+		oldNode, inA := nodesA[id]
+		newNode, inB := nodesB[id]
+		switch {
+		case !inA:
+			d.Nodes = append(d.Nodes, NodeChange{ID: id, Type: ChangeAdded})
+		case !inB:
+			d.Nodes = append(d.Nodes, NodeChange{ID: id, Type: ChangeRemoved})
+		default:
+			if fields := diffValues("", oldNode, newNode, nil); len(fields) > 0 {
+				d.Nodes = append(d.Nodes, NodeChange{ID: id, Type: ChangeModified, Fields: fields})
+			}
+		}
+	}
+
+	for _, key := range unionKeys(edgesA, edgesB) {
+		oldEdge, inA := edgesA[key]
+		newEdge, inB := edgesB[key]
+		switch {
+		case !inA:
+			d.Edges = append(d.Edges, EdgeChange{Key: key, Type: ChangeAdded, New: newEdge})
+		case !inB:
+			d.Edges = append(d.Edges, EdgeChange{Key: key, Type: ChangeRemoved, Old: oldEdge})
+		default:
+			oldDoc, err := toDocument(oldEdge)
+			if err != nil {
+				return nil, err
+			}
+			newDoc, err := toDocument(newEdge)
+			if err != nil {
+				return nil, err
+			}
+			if fields := diffValues("", oldDoc, newDoc, nil); len(fields) > 0 {
+				d.Edges = append(d.Edges, EdgeChange{Key: key, Type: ChangeModified, Old: oldEdge, New: newEdge, Fields: fields})
+			}
+		}
+	}
+
+	return d, nil
+}
+
+// diffValues appends the differences between two JSON values to changes.
+// Objects are compared key by key and lists of equal length item by item;
+// anything else is compared as a whole.
+func diffValues(path string, a, b interface{}, changes []FieldChange) []FieldChange {
+	if reflect.DeepEqual(a, b) {
+		return changes
+	}
+
+	mapA, okA := a.(map[string]interface{})
+	mapB, okB := b.(map[string]interface{})
+	if okA && okB {
+		for _, key := range unionKeys(mapA, mapB) {
+			va, inA := mapA[key]
+			vb, inB := mapB[key]
+			child := joinPath(path, key)
+			switch {
+			case !inA:
+				changes = append(changes, FieldChange{Path: child, Type: ChangeAdded, New: vb})
+			case !inB:
+				changes = append(changes, FieldChange{Path: child, Type: ChangeRemoved, Old: va})
+			default:
+				changes = diffValues(child, va, vb, changes)
+			}
+		}
+		return changes
+	}
+
+	listA, okA := a.([]interface{})
+	listB, okB := b.([]interface{})
+	if okA && okB && len(listA) == len(listB) {
+		for i := range listA {
+			changes = diffValues(fmt.Sprintf("%s[%d]", path, i), listA[i], listB[i], changes)
+		}
+		return changes
+	}
+
+	return append(changes, FieldChange{Path: path, Type: ChangeModified, Old: a, New: b})
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// graphDocument converts a graph to the JSON data model.
+func graphDocument(g *Graph) (map[string]interface{}, error) {
+	doc, err := toDocument(g)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert graph '%s': %w", g.ID, err)
+	}
+	m, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("graph '%s' is not a JSON object", g.ID)
+	}
+	return m, nil
+}
+
+func toDocument(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// keyedEdges indexes edges by EdgeKey, suffixing duplicate keys.
+func keyedEdges(edges []*Edge) map[string]*Edge {
+	out := make(map[string]*Edge, len(edges))
+	for i, key := range orderedEdgeKeys(edges) {
+		out[key] = edges[i]
+	}
+	return out
+}
+
+// orderedEdgeKeys returns the key of each edge, in edge order.
+func orderedEdgeKeys(edges []*Edge) []string {
+	keys := make([]string, 0, len(edges))
+	seen := make(map[string]int, len(edges))
+	for _, e := range edges {
+		key := EdgeKey(e)
+		seen[key]++
+		if n := seen[key]; n > 1 {
+			key = fmt.Sprintf("%s#%d", key, n)
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// unionKeys returns the keys of both maps, sorted.
+func unionKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
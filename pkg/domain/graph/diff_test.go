@@ -0,0 +1,126 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+)
+
+func reviewGraph(t *testing.T) *Graph {
+	t.Helper()
+	g, err := Build("review").
+		ID("review").
+		Start().
+		LLM("draft", map[string]interface{}{"model": "gpt-4", "temperature": 0.2}).
+		Router("check", When("state.approved == true", "publish")).
+		Default("draft").
+		Tool("publish", "http_post", nil).
+		End()
+	if err != nil {
+		t.Fatalf("failed to build review graph: %v", err)
+	}
+	return g
+}
+
+func TestDiff_Identical(t *testing.T) {
+	a := reviewGraph(t)
+	b, err := a.Clone()
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+
+	d, err := Diff(a, b)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if !d.IsEmpty() {
+		t.Errorf("expected empty diff, got:\n%s", d)
+	}
+}
+
+func TestDiff_Changes(t *testing.T) {
+	a := reviewGraph(t)
+	b, err := a.Clone()
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+
+	b.Description = "Reviewed publishing"
+	b.GetNode("draft").(*ExecutorNode).Config["model"] = "gpt-4o"
+	b.GetNode("check").(*RouterNode).Routes[0].Condition = "state.score > 0.8"
+	b.RemoveNode("publish")
+	if err := b.AddNode(&ExecutorNode{BaseNode: BaseNode{ID: "notify", Type: NodeTypeExecutor}, ExecutorType: "tool", Config: map[string]interface{}{"tool_name": "notify"}}); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	b.Edges = append(b.Edges, NewEdge("notify", EndNodeID).WithLabel("done"))
+
+	d, err := Diff(a, b)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+
+	if len(d.Fields) != 1 || d.Fields[0].Path != "description" || d.Fields[0].Type != ChangeAdded {
+		t.Errorf("unexpected graph field changes: %+v", d.Fields)
+	}
+
+	nodes := make(map[string]NodeChange)
+	for _, n := range d.Nodes {
+		nodes[n.ID] = n
+	}
+	if nodes["notify"].Type != ChangeAdded {
+		t.Errorf("expected notify to be added, got %+v", nodes["notify"])
+	}
+	if nodes["publish"].Type != ChangeRemoved {
+		t.Errorf("expected publish to be removed, got %+v", nodes["publish"])
+	}
+	draft := nodes["draft"]
+	if draft.Type != ChangeModified || len(draft.Fields) != 1 {
+		t.Fatalf("expected a single field change on draft, got %+v", draft)
+	}
+	if f := draft.Fields[0]; f.Path != "config.model" || f.Old != "gpt-4" || f.New != "gpt-4o" {
+		t.Errorf("unexpected draft change: %+v", f)
+	}
+	if check := nodes["check"]; len(check.Fields) != 1 || check.Fields[0].Path != "routes[0].condition" {
+		t.Errorf("unexpected check change: %+v", check)
+	}
+
+	edges := make(map[string]EdgeChange)
+	for _, e := range d.Edges {
+		edges[e.Key] = e
+	}
+	if edges["notify->end"].Type != ChangeAdded {
+		t.Errorf("expected edge notify->end to be added, got %+v", edges["notify->end"])
+	}
+	if edges["check->publish"].Type != ChangeRemoved {
+		t.Errorf("expected edge check->publish to be removed with its node, got %+v", edges["check->publish"])
+	}
+
+	out := d.String()
+	for _, line := range []string{
+		`+ description: "Reviewed publishing"`,
+		`~ node draft config.model: "gpt-4" -> "gpt-4o"`,
+		`+ node notify`,
+		`- node publish`,
+		`+ edge notify->end`,
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("expected %q in diff output:\n%s", line, out)
+		}
+	}
+}
+
+func TestDiff_DuplicateEdgeKeys(t *testing.T) {
+	a := reviewGraph(t)
+	b, err := a.Clone()
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	b.Edges = append(b.Edges, NewEdge("draft", "check").WithCondition("state.retry == true"))
+
+	d, err := Diff(a, b)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if len(d.Edges) != 1 || d.Edges[0].Key != "draft->check#2" || d.Edges[0].Type != ChangeAdded {
+		t.Errorf("expected duplicate edge to be added with suffix, got %+v", d.Edges)
+	}
+}
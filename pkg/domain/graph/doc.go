@@ -6,8 +6,24 @@
 // Node Types:
 //   - ExecutorNode: Executes tasks like LLM calls, tool invocations, or code execution
 //   - RouterNode: Makes routing decisions based on state conditions
+//   - SubgraphNode: Runs another graph, referenced from storage or inline, as a single step
+//   - ApprovalNode: Pauses the execution until a human approves it or provides input
 //   - Start/End: Special nodes for graph entry and exit points
 //
-// This package defines only the domain models and interfaces. Actual implementations
-// of node execution logic should be in the main dago repository.
+// The Config of an ExecutorNode is decoded into a typed ExecutorConfig (such as
+// LLMConfig or HTTPConfig) according to its executor type, and validated with
+// the node. Custom executor types register their decoder with
+// RegisterExecutorConfig.
+//
+// A Policy declares the timeout, retries, idempotency and error edges of a
+// node. Graph.Policy holds the defaults and BaseNode.Policy the per-node
+// overrides; Graph.EffectivePolicy merges them and Policy.Execute applies the
+// result.
+//
+// Graph.Validate checks the structure of a graph and returns the first problem;
+// Graph.ValidateAll returns every problem, each located by a JSON Pointer.
+//
+// This package defines only the domain models and interfaces. Executor, router
+// and subgraph nodes return ErrEngineRequired when executed directly; package
+// engine runs graphs, dispatching executor nodes to registered executors.
 package graph
@@ -0,0 +1,225 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+// ExecutorConfig is the typed configuration of an executor node, decoded from
+// ExecutorNode.Config according to the node's executor type.
+type ExecutorConfig interface {
+	// Validate checks the configuration. Errors are *ValidationError values
+	// whose Field is relative to the config (e.g. "model").
+	Validate() error
+}
+
+// ExecutorConfigDecoder decodes the raw configuration of an executor node.
+type ExecutorConfigDecoder func(config map[string]interface{}) (ExecutorConfig, error)
+
+var (
+	executorConfigsMu sync.RWMutex
+	executorConfigs   = map[string]ExecutorConfigDecoder{
+		ExecutorTypeLLM:    func(c map[string]interface{}) (ExecutorConfig, error) { return decodeInto(c, &LLMConfig{}) },
+		ExecutorTypeTool:   func(c map[string]interface{}) (ExecutorConfig, error) { return decodeInto(c, &ToolConfig{}) },
+		ExecutorTypePython: func(c map[string]interface{}) (ExecutorConfig, error) { return decodeInto(c, &PythonConfig{}) },
+		ExecutorTypeBash:   func(c map[string]interface{}) (ExecutorConfig, error) { return decodeInto(c, &BashConfig{}) },
+		ExecutorTypeHTTP:   func(c map[string]interface{}) (ExecutorConfig, error) { return decodeInto(c, &HTTPConfig{}) },
+	}
+)
+
+// RegisterExecutorConfig registers the config decoder of an executor type.
+// ExecutorNode.Validate decodes and validates the config of nodes of that type.
+// Registering a type that already exists replaces its decoder.
+func RegisterExecutorConfig(executorType string, decoder ExecutorConfigDecoder) {
+	executorConfigsMu.Lock()
+	defer executorConfigsMu.Unlock()
+	executorConfigs[executorType] = decoder
+}
+
+// RegisteredExecutorConfigs returns the executor types with a config decoder, sorted.
+func RegisteredExecutorConfigs() []string {
+	executorConfigsMu.RLock()
+	defer executorConfigsMu.RUnlock()
+	types := make([]string, 0, len(executorConfigs))
+	for t := range executorConfigs {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// DecodeExecutorConfig decodes the raw config of an executor type. It returns
+// nil and no error for executor types without a registered decoder, such as
+// "custom".
+func DecodeExecutorConfig(executorType string, config map[string]interface{}) (ExecutorConfig, error) {
+	executorConfigsMu.RLock()
+	decoder, ok := executorConfigs[executorType]
+	executorConfigsMu.RUnlock()
+	if !ok {
+		return nil, nil
+	}
+	return decoder(config)
+}
+
+// DecodeConfig decodes a raw executor config into target, a pointer to a
+// config struct with JSON tags. Custom decoders can use it to implement
+// ExecutorConfigDecoder.
+func DecodeConfig(config map[string]interface{}, target interface{}) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("failed to decode config: %w", err)
+	}
+	return nil
+}
+
+func decodeInto(config map[string]interface{}, target ExecutorConfig) (ExecutorConfig, error) {
+	if err := DecodeConfig(config, target); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// LLMConfig configures an "llm" executor.
+type LLMConfig struct {
+	// Model is the LLM model identifier (e.g. "gpt-4", "claude-3-opus").
+	Model string `json:"model"`
+
+	// Temperature controls sampling, between 0 and 2. Nil uses the default (0.7).
+	Temperature *float64 `json:"temperature,omitempty"`
+
+	// MaxTokens limits the length of the response. Nil uses the default (2000).
+	MaxTokens *int `json:"max_tokens,omitempty"`
+
+	// SystemPrompt sets the context of the conversation.
+	SystemPrompt string `json:"system_prompt,omitempty"`
+
+	// Tools are the tool definitions available to the LLM.
+	Tools []map[string]interface{} `json:"tools,omitempty"`
+}
+
+// Validate checks the LLM configuration.
+func (c *LLMConfig) Validate() error {
+	if c.Model == "" {
+		return &ValidationError{Field: "model", Message: "LLM model cannot be empty"}
+	}
+	if c.Temperature != nil && (*c.Temperature < 0 || *c.Temperature > 2) {
+		return &ValidationError{Field: "temperature", Message: fmt.Sprintf("temperature %v must be between 0 and 2", *c.Temperature)}
+	}
+	if c.MaxTokens != nil && *c.MaxTokens < 1 {
+		return &ValidationError{Field: "max_tokens", Message: "max tokens must be at least 1"}
+	}
+	return nil
+}
+
+// ToolConfig configures a "tool" executor.
+type ToolConfig struct {
+	// ToolName is the name of the tool to execute.
+	ToolName string `json:"tool_name"`
+
+	// Parameters are static parameters passed to the tool.
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+
+	// Timeout is the execution timeout in seconds. Nil uses the default (300).
+	Timeout *int `json:"timeout,omitempty"`
+}
+
+// Validate checks the tool configuration.
+func (c *ToolConfig) Validate() error {
+	if c.ToolName == "" {
+		return &ValidationError{Field: "tool_name", Message: "tool name cannot be empty"}
+	}
+	return nil
+}
+
+// PythonConfig configures a "python" executor. Exactly one of Code and
+// ScriptPath must be set.
+type PythonConfig struct {
+	// Code is the Python code to execute.
+	Code string `json:"code,omitempty"`
+
+	// ScriptPath is the path of the Python script to execute.
+	ScriptPath string `json:"script_path,omitempty"`
+
+	// Requirements lists Python package dependencies.
+	Requirements []string `json:"requirements,omitempty"`
+
+	// Timeout is the execution timeout in seconds. Nil uses the default (300).
+	Timeout *int `json:"timeout,omitempty"`
+}
+
+// Validate checks the Python configuration.
+func (c *PythonConfig) Validate() error {
+	if (c.Code == "") == (c.ScriptPath == "") {
+		return &ValidationError{Field: "code", Message: "python executor must define exactly one of code or script_path"}
+	}
+	return nil
+}
+
+// BashConfig configures a "bash" executor.
+type BashConfig struct {
+	// Command is the Bash command to execute.
+	Command string `json:"command"`
+
+	// WorkingDir is the working directory of the command.
+	WorkingDir string `json:"working_dir,omitempty"`
+
+	// Environment holds environment variables for the command.
+	Environment map[string]interface{} `json:"environment,omitempty"`
+
+	// Timeout is the execution timeout in seconds. Nil uses the default (300).
+	Timeout *int `json:"timeout,omitempty"`
+}
+
+// Validate checks the Bash configuration.
+func (c *BashConfig) Validate() error {
+	if c.Command == "" {
+		return &ValidationError{Field: "command", Message: "command cannot be empty"}
+	}
+	return nil
+}
+
+// HTTPMethods lists the methods accepted by HTTPConfig.
+var HTTPMethods = []string{"GET", "POST", "PUT", "DELETE", "PATCH"}
+
+// HTTPConfig configures an "http" executor.
+type HTTPConfig struct {
+	// URL is the absolute URL of the endpoint.
+	URL string `json:"url"`
+
+	// Method is the HTTP method, one of HTTPMethods.
+	Method string `json:"method"`
+
+	// Headers holds the request headers.
+	Headers map[string]interface{} `json:"headers,omitempty"`
+
+	// Body is the request body, for POST, PUT and PATCH.
+	Body interface{} `json:"body,omitempty"`
+
+	// Timeout is the request timeout in seconds. Nil uses the default (30).
+	Timeout *int `json:"timeout,omitempty"`
+}
+
+// Validate checks the HTTP configuration.
+func (c *HTTPConfig) Validate() error {
+	if c.URL == "" {
+		return &ValidationError{Field: "url", Message: "URL cannot be empty"}
+	}
+	if u, err := url.Parse(c.URL); err != nil || !u.IsAbs() {
+		return &ValidationError{Field: "url", Message: fmt.Sprintf("'%s' is not an absolute URL", c.URL)}
+	}
+	if c.Method == "" {
+		return &ValidationError{Field: "method", Message: "HTTP method cannot be empty"}
+	}
+	for _, method := range HTTPMethods {
+		if c.Method == method {
+			return nil
+		}
+	}
+	return &ValidationError{Field: "method", Message: fmt.Sprintf("unsupported HTTP method '%s'", c.Method)}
+}
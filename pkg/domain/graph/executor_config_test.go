@@ -0,0 +1,138 @@
+package graph
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExecutorNode_ValidateConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		executorType string
+		config       map[string]interface{}
+		field        string
+	}{
+		{name: "llm", executorType: ExecutorTypeLLM, config: map[string]interface{}{"model": "gpt-4", "temperature": 0.2, "max_tokens": 100}},
+		{name: "llm without model", executorType: ExecutorTypeLLM, config: nil, field: "config.model"},
+		{name: "llm temperature too high", executorType: ExecutorTypeLLM, config: map[string]interface{}{"model": "gpt-4", "temperature": 2.5}, field: "config.temperature"},
+		{name: "llm zero max tokens", executorType: ExecutorTypeLLM, config: map[string]interface{}{"model": "gpt-4", "max_tokens": 0}, field: "config.max_tokens"},
+		{name: "llm temperature of wrong type", executorType: ExecutorTypeLLM, config: map[string]interface{}{"model": "gpt-4", "temperature": "hot"}, field: "config"},
+		{name: "tool", executorType: ExecutorTypeTool, config: map[string]interface{}{"tool_name": "search", "parameters": map[string]interface{}{"k": 3}}},
+		{name: "tool without name", executorType: ExecutorTypeTool, config: map[string]interface{}{"timeout": 10}, field: "config.tool_name"},
+		{name: "python code", executorType: ExecutorTypePython, config: map[string]interface{}{"code": "print(1)"}},
+		{name: "python script", executorType: ExecutorTypePython, config: map[string]interface{}{"script_path": "run.py", "requirements": []interface{}{"requests"}}},
+		{name: "python without code", executorType: ExecutorTypePython, config: map[string]interface{}{}, field: "config.code"},
+		{name: "python with code and script", executorType: ExecutorTypePython, config: map[string]interface{}{"code": "print(1)", "script_path": "run.py"}, field: "config.code"},
+		{name: "bash", executorType: ExecutorTypeBash, config: map[string]interface{}{"command": "ls", "environment": map[string]interface{}{"LANG": "C"}}},
+		{name: "bash without command", executorType: ExecutorTypeBash, config: map[string]interface{}{"working_dir": "/tmp"}, field: "config.command"},
+		{name: "http", executorType: ExecutorTypeHTTP, config: map[string]interface{}{"url": "https://example.com/hook", "method": "POST", "body": map[string]interface{}{"ok": true}}},
+		{name: "http without url", executorType: ExecutorTypeHTTP, config: map[string]interface{}{"method": "GET"}, field: "config.url"},
+		{name: "http relative url", executorType: ExecutorTypeHTTP, config: map[string]interface{}{"url": "/hook", "method": "GET"}, field: "config.url"},
+		{name: "http without method", executorType: ExecutorTypeHTTP, config: map[string]interface{}{"url": "https://example.com"}, field: "config.method"},
+		{name: "http unsupported method", executorType: ExecutorTypeHTTP, config: map[string]interface{}{"url": "https://example.com", "method": "TRACE"}, field: "config.method"},
+		{name: "custom accepts anything", executorType: ExecutorTypeCustom, config: nil},
+		{name: "unregistered type accepts anything", executorType: "teleport", config: map[string]interface{}{"x": 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &ExecutorNode{
+				BaseNode:     BaseNode{ID: "n", Type: NodeTypeExecutor},
+				ExecutorType: tt.executorType,
+				Config:       tt.config,
+			}
+			err := node.Validate()
+			if tt.field == "" {
+				if err != nil {
+					t.Errorf("unexpected validation error: %v", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("expected *ValidationError, got %v", err)
+			}
+			if ve.Field != tt.field {
+				t.Errorf("expected field %q, got %q (%v)", tt.field, ve.Field, err)
+			}
+		})
+	}
+}
+
+func TestExecutorNode_TypedConfig(t *testing.T) {
+	node := &ExecutorNode{
+		BaseNode:     BaseNode{ID: "call", Type: NodeTypeExecutor},
+		ExecutorType: ExecutorTypeHTTP,
+		Config:       map[string]interface{}{"url": "https://example.com", "method": "GET", "timeout": 5},
+	}
+
+	config, err := node.TypedConfig()
+	if err != nil {
+		t.Fatalf("TypedConfig failed: %v", err)
+	}
+	httpConfig, ok := config.(*HTTPConfig)
+	if !ok {
+		t.Fatalf("expected *HTTPConfig, got %T", config)
+	}
+	if httpConfig.URL != "https://example.com" || httpConfig.Method != "GET" || httpConfig.Timeout == nil || *httpConfig.Timeout != 5 {
+		t.Errorf("unexpected config: %+v", httpConfig)
+	}
+
+	node.ExecutorType = ExecutorTypeCustom
+	if config, err := node.TypedConfig(); config != nil || err != nil {
+		t.Errorf("expected no typed config for custom executors, got %v, %v", config, err)
+	}
+}
+
+type sqlConfig struct {
+	Query string `json:"query"`
+}
+
+func (c *sqlConfig) Validate() error {
+	if c.Query == "" {
+		return &ValidationError{Field: "query", Message: "query cannot be empty"}
+	}
+	return nil
+}
+
+func TestRegisterExecutorConfig(t *testing.T) {
+	RegisterExecutorConfig("sql", func(config map[string]interface{}) (ExecutorConfig, error) {
+		var c sqlConfig
+		if err := DecodeConfig(config, &c); err != nil {
+			return nil, err
+		}
+		return &c, nil
+	})
+	defer func() {
+		executorConfigsMu.Lock()
+		delete(executorConfigs, "sql")
+		executorConfigsMu.Unlock()
+	}()
+
+	found := false
+	for _, executorType := range RegisteredExecutorConfigs() {
+		if executorType == "sql" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected sql in registered executor configs, got %v", RegisteredExecutorConfigs())
+	}
+
+	node := &ExecutorNode{BaseNode: BaseNode{ID: "q", Type: NodeTypeExecutor}, ExecutorType: "sql"}
+	if err := node.Validate(); err == nil || err.Error() != "config.query: query cannot be empty" {
+		t.Errorf("expected query error, got %v", err)
+	}
+
+	node.Config = map[string]interface{}{"query": "SELECT 1"}
+	if err := node.Validate(); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+	config, err := node.TypedConfig()
+	if err != nil {
+		t.Fatalf("TypedConfig failed: %v", err)
+	}
+	if c, ok := config.(*sqlConfig); !ok || c.Query != "SELECT 1" {
+		t.Errorf("expected decoded sql config, got %#v", config)
+	}
+}
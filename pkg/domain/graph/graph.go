@@ -2,7 +2,10 @@ package graph
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/google/uuid"
 )
@@ -33,6 +36,9 @@ type Graph struct {
 
 	// Version is the schema version of this graph definition.
 	Version string `json:"version,omitempty"`
+
+	// Policy is the default execution policy of the nodes; see EffectivePolicy.
+	Policy *Policy `json:"policy,omitempty"`
 }
 
 // NewGraph creates a new graph with a generated UUID.
@@ -43,7 +49,7 @@ func NewGraph(name string) *Graph {
 		Nodes:    make(map[string]Node),
 		Edges:    make([]*Edge, 0),
 		Metadata: make(map[string]interface{}),
-		Version:  "1.0",
+		Version:  CurrentVersion,
 	}
 }
 
@@ -137,51 +143,175 @@ func (g *Graph) GetIncomingEdges(nodeID string) []*Edge {
 	return edges
 }
 
+// Successors returns the nodes that can run after the given node: the
+// targets of its edges, of its routes if it is a router, and of its error
+// edges.
+func (g *Graph) Successors(nodeID string) []string {
+	var next []string
+	for _, edge := range g.Edges {
+		if edge.From == nodeID {
+			next = append(next, edge.To)
+		}
+	}
+	if router, ok := g.GetNode(nodeID).(*RouterNode); ok {
+		for _, route := range router.Routes {
+			next = append(next, route.Target)
+		}
+		if router.DefaultRoute != "" {
+			next = append(next, router.DefaultRoute)
+		}
+	}
+	for _, h := range g.EffectivePolicy(nodeID).OnError {
+		next = append(next, h.Target)
+	}
+	return next
+}
+
 // Validate performs comprehensive validation of the graph structure.
+// It returns the first problem found; use ValidateAll to get all of them.
 func (g *Graph) Validate() error {
+	if errs := g.ValidateAll(); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// ValidateAll validates the graph structure and returns every problem found,
+// in a stable order. Each error carries the JSON Pointer of the offending
+// value in the JSON form of the graph. Inline subgraphs are validated
+// recursively, with pointers below their node's "graph" field.
+func (g *Graph) ValidateAll() []*ValidationError {
+	return g.validateAll("")
+}
+
+func (g *Graph) validateAll(pointer string) []*ValidationError {
+	var errs []*ValidationError
 	if g.ID == "" {
-		return &ValidationError{Field: "id", Message: "graph ID cannot be empty"}
+		errs = append(errs, &ValidationError{Field: "id", Pointer: pointer + "/id", Message: "graph ID cannot be empty"})
 	}
 
 	if len(g.Nodes) == 0 {
-		return &ValidationError{Field: "nodes", Message: "graph must have at least one node"}
+		errs = append(errs, &ValidationError{Field: "nodes", Pointer: pointer + "/nodes", Message: "graph must have at least one node"})
 	}
 
 	if g.EntryNode == "" {
-		return &ValidationError{Field: "entry_node", Message: "graph must have an entry node"}
+		errs = append(errs, &ValidationError{Field: "entry_node", Pointer: pointer + "/entry_node", Message: "graph must have an entry node"})
+	} else if g.GetNode(g.EntryNode) == nil {
+		errs = append(errs, &ValidationError{
+			Field:   "entry_node",
+			Pointer: pointer + "/entry_node",
+			Message: fmt.Sprintf("entry node '%s' does not exist", g.EntryNode),
+		})
 	}
 
-	// Verify entry node exists
-	if g.GetNode(g.EntryNode) == nil {
-		return &ValidationError{Field: "entry_node", Message: fmt.Sprintf("entry node '%s' does not exist", g.EntryNode)}
+	if g.Policy != nil {
+		errs = append(errs, g.validatePolicy(g.Policy, "policy", pointer+"/policy")...)
+	}
+
+	// Reject subgraphs that contain themselves before validating them recursively
+	recursive := false
+	if pointer == "" {
+		if err := g.validateComposition(pointer, []string{g.ID}, map[*Graph]bool{g: true}); err != nil {
+			errs = append(errs, err)
+			recursive = true
+		}
 	}
 
 	// Validate all nodes
-	for _, node := range g.Nodes {
+	ids := make([]string, 0, len(g.Nodes))
+	for id := range g.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		node := g.Nodes[id]
+		field := "nodes." + id
+		nodePointer := pointer + "/nodes/" + escapePointer(id)
+		if n, ok := node.(interface{ Base() *BaseNode }); ok && n.Base().Policy != nil {
+			errs = append(errs, g.validatePolicy(n.Base().Policy, field+".policy", nodePointer+"/policy")...)
+		}
+		if sub, ok := node.(*SubgraphNode); ok {
+			if err := sub.validateFields(); err != nil {
+				errs = append(errs, locate(err, field, nodePointer))
+			} else if sub.Graph != nil && !recursive {
+				for _, err := range sub.Graph.validateAll(nodePointer + "/graph") {
+					err.Field = field + ".graph." + err.Field
+					errs = append(errs, err)
+				}
+			}
+			continue
+		}
 		if err := node.Validate(); err != nil {
-			return fmt.Errorf("node '%s' validation failed: %w", node.GetID(), err)
+			errs = append(errs, locate(err, field, nodePointer))
 		}
 	}
 
 	// Validate all edges
 	for i, edge := range g.Edges {
+		edgePointer := fmt.Sprintf("%s/edges/%d", pointer, i)
 		if err := edge.Validate(); err != nil {
-			return fmt.Errorf("edge %d validation failed: %w", i, err)
+			errs = append(errs, locate(err, fmt.Sprintf("edges.%d", i), edgePointer))
+			continue
 		}
 
 		// Verify both nodes exist
 		if g.GetNode(edge.From) == nil {
-			return &ValidationError{Field: "edge.from", Message: fmt.Sprintf("edge references non-existent source node '%s'", edge.From)}
+			errs = append(errs, &ValidationError{
+				Field:   "edge.from",
+				Pointer: edgePointer + "/from",
+				Message: fmt.Sprintf("edge references non-existent source node '%s'", edge.From),
+			})
 		}
 		if g.GetNode(edge.To) == nil {
-			return &ValidationError{Field: "edge.to", Message: fmt.Sprintf("edge references non-existent target node '%s'", edge.To)}
+			errs = append(errs, &ValidationError{
+				Field:   "edge.to",
+				Pointer: edgePointer + "/to",
+				Message: fmt.Sprintf("edge references non-existent target node '%s'", edge.To),
+			})
 		}
 	}
 
 	// TODO: Add cycle detection for graphs that shouldn't have cycles
 	// TODO: Add reachability analysis to detect orphaned nodes
 
-	return nil
+	return errs
+}
+
+// validatePolicy validates a graph or node policy and checks that its error
+// handlers target nodes of the graph.
+func (g *Graph) validatePolicy(policy *Policy, field, pointer string) []*ValidationError {
+	if err := policy.Validate(); err != nil {
+		return []*ValidationError{locate(err, field, pointer)}
+	}
+	var errs []*ValidationError
+	for i, h := range policy.OnError {
+		if g.GetNode(h.Target) == nil {
+			errs = append(errs, &ValidationError{
+				Field:   fmt.Sprintf("%s.on_error.%d.target", field, i),
+				Pointer: fmt.Sprintf("%s/on_error/%d/target", pointer, i),
+				Message: fmt.Sprintf("error handler references non-existent node '%s'", h.Target),
+			})
+		}
+	}
+	return errs
+}
+
+// locate converts an error returned by a node or edge Validate method into a
+// ValidationError for the element at field and pointer. The dotted Field of a
+// ValidationError (e.g. "routes.0.target") is appended to both.
+func locate(err error, field, pointer string) *ValidationError {
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		return &ValidationError{Field: field, Pointer: pointer, Message: err.Error()}
+	}
+	located := &ValidationError{Field: field, Pointer: pointer, Message: ve.Message}
+	if ve.Field != "" {
+		located.Field += "." + ve.Field
+		if !strings.Contains(ve.Field, "/") {
+			located.Pointer += "/" + strings.ReplaceAll(ve.Field, ".", "/")
+		}
+	}
+	return located
 }
 
 // ToJSON serializes the graph to JSON.
@@ -194,8 +324,8 @@ func (g *Graph) ToJSON() (string, error) {
 }
 
 // FromJSON deserializes a graph from JSON.
-// Note: This is a basic implementation. Full deserialization with proper node type
-// handling should be implemented in the main dago repository.
+// Nodes are decoded into their concrete types based on their "type" field;
+// see RegisterNodeType for adding custom node types.
 func FromJSON(jsonStr string) (*Graph, error) {
 	var g Graph
 	if err := json.Unmarshal([]byte(jsonStr), &g); err != nil {
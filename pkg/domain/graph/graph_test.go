@@ -291,6 +291,44 @@ func TestGraphValidate(t *testing.T) {
 	}
 }
 
+func TestGraphValidateAll(t *testing.T) {
+	inner := NewGraph("inner")
+	inner.ID = "inner"
+	inner.Nodes["step"] = &ExecutorNode{BaseNode: BaseNode{ID: "step", Type: NodeTypeExecutor}}
+	inner.EntryNode = "step"
+
+	g := NewGraph("test")
+	g.Nodes["draft"] = &ExecutorNode{BaseNode: BaseNode{ID: "draft", Type: NodeTypeExecutor}, ExecutorType: "llm", Config: map[string]interface{}{"model": "gpt-4"}}
+	g.Nodes["check"] = &RouterNode{BaseNode: BaseNode{ID: "check", Type: NodeTypeRouter}, Routes: []Route{{Condition: "ok"}, {Condition: "late"}}}
+	g.Nodes["sub"] = &SubgraphNode{BaseNode: BaseNode{ID: "sub", Type: NodeTypeSubgraph}, Graph: inner}
+	g.Edges = []*Edge{NewEdge("draft", "check"), NewEdge("check", "check"), NewEdge("check", "gone")}
+	g.EntryNode = "missing"
+
+	errs := g.ValidateAll()
+	expected := []struct {
+		pointer string
+		field   string
+	}{
+		{"/entry_node", "entry_node"},
+		{"/nodes/check/routes/0/target", "nodes.check.routes.0.target"},
+		{"/nodes/sub/graph/nodes/step/executor_type", "nodes.sub.graph.nodes.step.executor_type"},
+		{"/edges/1", "edges.1.from/to"},
+		{"/edges/2/to", "edge.to"},
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, got %d: %v", len(expected), len(errs), errs)
+	}
+	for i, want := range expected {
+		if errs[i].Pointer != want.pointer || errs[i].Field != want.field {
+			t.Errorf("error %d: expected %s (%s), got %s (%s): %v", i, want.pointer, want.field, errs[i].Pointer, errs[i].Field, errs[i])
+		}
+	}
+
+	if err := g.Validate(); err == nil || err.Error() != errs[0].Error() {
+		t.Errorf("expected Validate to return the first error, got %v", err)
+	}
+}
+
 func TestGraphToJSON(t *testing.T) {
 	g := NewGraph("test")
 	node := &mockNode{id: "node-1", nodeType: NodeTypeExecutor}
@@ -0,0 +1,114 @@
+package graph
+
+import (
+	"reflect"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+)
+
+// MappingMetadataKey is the metadata key of the state mapping applied by start
+// and end nodes. Flatten records the input and output mappings of subgraphs
+// there, on the boundary nodes it inserts around the inlined nodes. The value
+// maps each state key written to the state key it is copied from.
+const MappingMetadataKey = "mapping"
+
+// MapInputs returns a copy of s in which each key of the input mapping holds
+// the value of the state key it maps to. The copy shares its values with s.
+func (n *ExecutorNode) MapInputs(s state.State) state.State {
+	return applyMapping(s, n.InputMapping)
+}
+
+// MapOutputs returns the state to store after the executor returned out for
+// the input state s. Without an output mapping it is out, except that the
+// keys MapInputs set are restored from s unless the executor changed them;
+// otherwise it is s with each state key named by the output mapping set to
+// the value of its output key, and every other change made by the executor
+// is discarded.
+func (n *ExecutorNode) MapOutputs(s, out state.State) state.State {
+	if len(n.OutputMapping) == 0 {
+		return n.unmapInputs(s, out)
+	}
+	mapping := make(map[string]string, len(n.OutputMapping))
+	for output, key := range n.OutputMapping {
+		mapping[key] = output
+	}
+	result := make(state.State, len(s)+len(mapping))
+	for k, v := range s {
+		result[k] = v
+	}
+	for key, output := range mapping {
+		if v, ok := out[output]; ok {
+			result[key] = v
+		}
+	}
+	return result
+}
+
+// unmapInputs returns out without the values MapInputs injected into the
+// input of the executor, so that input aliases are not stored as state.
+func (n *ExecutorNode) unmapInputs(s, out state.State) state.State {
+	var result state.State
+	for key, source := range n.InputMapping {
+		injected, ok := s[source]
+		if !ok {
+			continue
+		}
+		v, ok := out[key]
+		if !ok || !reflect.DeepEqual(v, injected) {
+			continue
+		}
+		if result == nil {
+			result = make(state.State, len(out))
+			for k, v := range out {
+				result[k] = v
+			}
+		}
+		if orig, had := s[key]; had {
+			result[key] = orig
+		} else {
+			delete(result, key)
+		}
+	}
+	if result == nil {
+		return out
+	}
+	return result
+}
+
+// applyMapping returns a copy of s in which each key of mapping holds the
+// value of the key it maps to. Keys whose source is missing are left as is.
+// Values are read from s before any is written, so mappings may swap keys.
+func applyMapping(s state.State, mapping map[string]string) state.State {
+	if len(mapping) == 0 {
+		return s
+	}
+	out := make(state.State, len(s)+len(mapping))
+	for k, v := range s {
+		out[k] = v
+	}
+	for key, source := range mapping {
+		if v, ok := s[source]; ok {
+			out[key] = v
+		}
+	}
+	return out
+}
+
+// metadataMapping returns the mapping stored under MappingMetadataKey, as set
+// by Flatten or decoded from JSON.
+func metadataMapping(metadata map[string]interface{}) map[string]string {
+	switch m := metadata[MappingMetadataKey].(type) {
+	case map[string]string:
+		return m
+	case map[string]interface{}:
+		mapping := make(map[string]string, len(m))
+		for key, source := range m {
+			if s, ok := source.(string); ok {
+				mapping[key] = s
+			}
+		}
+		return mapping
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,68 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+)
+
+func TestExecutorNode_Mappings(t *testing.T) {
+	node := &ExecutorNode{
+		BaseNode:      BaseNode{ID: "summarize", Type: NodeTypeExecutor},
+		InputMapping:  map[string]string{"text": "document", "missing": "absent"},
+		OutputMapping: map[string]string{"summary": "short"},
+	}
+	s := state.State{"document": "long text", "keep": 1}
+
+	in := node.MapInputs(s)
+	if in.Get("text") != "long text" || in.Has("missing") {
+		t.Errorf("unexpected inputs %v", in)
+	}
+	if s.Has("text") {
+		t.Error("MapInputs modified the state")
+	}
+
+	out := node.MapOutputs(s, state.State{"summary": "short text", "scratch": true})
+	if out.Get("short") != "short text" || out.Get("keep") != 1 || out.Has("scratch") || out.Has("summary") {
+		t.Errorf("unexpected outputs %v", out)
+	}
+
+	unmapped := &ExecutorNode{BaseNode: BaseNode{ID: "plain"}}
+	if in := unmapped.MapInputs(s); len(in) != len(s) {
+		t.Errorf("expected the state unchanged, got %v", in)
+	}
+	result := state.State{"scratch": true}
+	if out := unmapped.MapOutputs(s, result); !out.Has("scratch") {
+		t.Errorf("expected the executor state, got %v", out)
+	}
+}
+
+func TestExecutorNode_InputMappingOnly(t *testing.T) {
+	node := &ExecutorNode{
+		BaseNode:     BaseNode{ID: "summarize", Type: NodeTypeExecutor},
+		InputMapping: map[string]string{"text": "document", "topic": "subject", "tone": "style"},
+	}
+	s := state.State{"document": "long text", "subject": "go", "style": "dry", "tone": "formal"}
+
+	out := node.MapInputs(s)
+	out["summary"] = "short"
+	out["topic"] = "golang"
+	result := node.MapOutputs(s, out)
+
+	if result.Has("text") {
+		t.Errorf("expected the input alias to be dropped, got %v", result)
+	}
+	if result.Get("tone") != "formal" {
+		t.Errorf("expected the aliased key to keep its state value, got %v", result.Get("tone"))
+	}
+	if result.Get("topic") != "golang" || result.Get("summary") != "short" {
+		t.Errorf("expected the executor's changes to be kept, got %v", result)
+	}
+}
+
+func TestApplyMapping_Swap(t *testing.T) {
+	out := applyMapping(state.State{"a": 1, "b": 2}, map[string]string{"a": "b", "b": "a"})
+	if out.Get("a") != 2 || out.Get("b") != 1 {
+		t.Errorf("expected the keys swapped, got %v", out)
+	}
+}
@@ -0,0 +1,139 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// MergeConflict is a field that was changed differently on both sides of a merge.
+// Base, Ours and Theirs are nil where the field does not exist.
+type MergeConflict struct {
+	// Path is the dotted path of the field (e.g. "nodes.draft.config.model",
+	// "edges.draft->publish.condition").
+	Path string `json:"path"`
+
+	Base   interface{} `json:"base,omitempty"`
+	Ours   interface{} `json:"ours,omitempty"`
+	Theirs interface{} `json:"theirs,omitempty"`
+}
+
+// String formats the conflict for display.
+func (c MergeConflict) String() string {
+	return fmt.Sprintf("%s: base %s, ours %s, theirs %s", c.Path, formatValue(c.Base), formatValue(c.Ours), formatValue(c.Theirs))
+}
+
+// absent marks a missing key during a merge, so that deletions can be told
+// apart from null values.
+var absent = &struct{ absent bool }{true}
+
+// Merge performs a three-way merge of two graphs derived from base.
+//
+// Changes made on only one side are applied. Nodes, edges (matched by
+// EdgeKey) and object fields changed on both sides are merged recursively;
+// when both sides changed the same value differently, ours is kept and a
+// conflict is reported. Edges keep the order of ours, followed by the edges
+// only added in theirs.
+//
+// The merged graph is not validated: non-conflicting changes can still
+// combine into an invalid graph, e.g. an edge added to a node removed on the
+// other side.
+func Merge(base, ours, theirs *Graph) (*Graph, []MergeConflict, error) {
+	docs := make([]map[string]interface{}, 3)
+	edges := make([]map[string]interface{}, 3)
+	for i, g := range []*Graph{base, ours, theirs} {
+		doc, err := graphDocument(g)
+		if err != nil {
+			return nil, nil, err
+		}
+		keyed := make(map[string]interface{}, len(g.Edges))
+		for key, e := range keyedEdges(g.Edges) {
+			if keyed[key], err = toDocument(e); err != nil {
+				return nil, nil, fmt.Errorf("failed to convert edge '%s': %w", key, err)
+			}
+		}
+		delete(doc, "edges")
+		docs[i], edges[i] = doc, keyed
+	}
+
+	var conflicts []MergeConflict
+	merged, _ := mergeValue("", docs[0], docs[1], docs[2], &conflicts).(map[string]interface{})
+	mergedEdges, _ := mergeValue("edges", edges[0], edges[1], edges[2], &conflicts).(map[string]interface{})
+
+	ordered := make([]interface{}, 0, len(mergedEdges))
+	for _, g := range []*Graph{ours, theirs} {
+		for _, key := range orderedEdgeKeys(g.Edges) {
+			if e, ok := mergedEdges[key]; ok {
+				ordered = append(ordered, e)
+				delete(mergedEdges, key)
+			}
+		}
+	}
+	merged["edges"] = ordered
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode merged graph: %w", err)
+	}
+	var g Graph
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode merged graph: %w", err)
+	}
+	return &g, conflicts, nil
+}
+
+// mergeValue merges one value; any argument may be absent.
+func mergeValue(path string, base, ours, theirs interface{}, conflicts *[]MergeConflict) interface{} {
+	switch {
+	case reflect.DeepEqual(ours, theirs):
+		return ours
+	case reflect.DeepEqual(base, ours):
+		return theirs
+	case reflect.DeepEqual(base, theirs):
+		return ours
+	}
+
+	mapOurs, okOurs := ours.(map[string]interface{})
+	mapTheirs, okTheirs := theirs.(map[string]interface{})
+	if okOurs && okTheirs {
+		mapBase, _ := base.(map[string]interface{})
+		out := make(map[string]interface{})
+		keys := unionKeys(mapOurs, mapTheirs)
+		for _, key := range unionKeys(mapBase, nil) {
+			_, inOurs := mapOurs[key]
+			_, inTheirs := mapTheirs[key]
+			if !inOurs && !inTheirs {
+				keys = append(keys, key)
+			}
+		}
+		for _, key := range keys {
+			v := mergeValue(joinPath(path, key), lookupOrAbsent(mapBase, key), lookupOrAbsent(mapOurs, key), lookupOrAbsent(mapTheirs, key), conflicts)
+			if v != absent {
+				out[key] = v
+			}
+		}
+		return out
+	}
+
+	*conflicts = append(*conflicts, MergeConflict{
+		Path:   path,
+		Base:   presentOrNil(base),
+		Ours:   presentOrNil(ours),
+		Theirs: presentOrNil(theirs),
+	})
+	return ours
+}
+
+func lookupOrAbsent(m map[string]interface{}, key string) interface{} {
+	if v, ok := m[key]; ok {
+		return v
+	}
+	return absent
+}
+
+func presentOrNil(v interface{}) interface{} {
+	if v == absent {
+		return nil
+	}
+	return v
+}
@@ -0,0 +1,102 @@
+package graph
+
+import (
+	"testing"
+)
+
+func TestMerge_NonConflicting(t *testing.T) {
+	base := reviewGraph(t)
+	ours, _ := base.Clone()
+	theirs, _ := base.Clone()
+
+	ours.GetNode("draft").(*ExecutorNode).Config["model"] = "gpt-4o"
+	theirs.GetNode("draft").(*ExecutorNode).Config["temperature"] = 0.7
+	theirs.Description = "Review and publish"
+	if err := theirs.AddNode(&ExecutorNode{BaseNode: BaseNode{ID: "notify", Type: NodeTypeExecutor}, ExecutorType: "tool", Config: map[string]interface{}{"tool_name": "notify"}}); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	theirs.Edges = append(theirs.Edges, NewEdge("publish", "notify"))
+	ours.RemoveNode("publish")
+
+	merged, conflicts, err := Merge(base, ours, theirs)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if len(conflicts) != 0 {
+		t.Fatalf("expected no conflicts, got %v", conflicts)
+	}
+
+	config := merged.GetNode("draft").(*ExecutorNode).Config
+	if config["model"] != "gpt-4o" || config["temperature"] != 0.7 {
+		t.Errorf("expected both config changes, got %v", config)
+	}
+	if merged.Description != "Review and publish" {
+		t.Errorf("expected description from theirs, got %q", merged.Description)
+	}
+	if merged.GetNode("notify") == nil {
+		t.Error("expected node added by theirs")
+	}
+	if merged.GetNode("publish") != nil {
+		t.Error("expected node removed by ours to stay removed")
+	}
+
+	// The edge added in theirs points at a node removed in ours.
+	if err := merged.Validate(); err == nil {
+		t.Error("expected merged graph with dangling edge to be invalid")
+	}
+}
+
+func TestMerge_Conflicts(t *testing.T) {
+	base := reviewGraph(t)
+	ours, _ := base.Clone()
+	theirs, _ := base.Clone()
+
+	ours.GetNode("draft").(*ExecutorNode).Config["model"] = "gpt-4o"
+	theirs.GetNode("draft").(*ExecutorNode).Config["model"] = "claude"
+	ours.EntryNode = "draft"
+	theirs.EntryNode = "check"
+
+	merged, conflicts, err := Merge(base, ours, theirs)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if len(conflicts) != 2 {
+		t.Fatalf("expected 2 conflicts, got %v", conflicts)
+	}
+
+	paths := map[string]MergeConflict{}
+	for _, c := range conflicts {
+		paths[c.Path] = c
+	}
+	c, ok := paths["nodes.draft.config.model"]
+	if !ok || c.Base != "gpt-4" || c.Ours != "gpt-4o" || c.Theirs != "claude" {
+		t.Errorf("unexpected model conflict: %+v", paths)
+	}
+	if _, ok := paths["entry_node"]; !ok {
+		t.Errorf("expected entry_node conflict, got %+v", paths)
+	}
+
+	if merged.GetNode("draft").(*ExecutorNode).Config["model"] != "gpt-4o" || merged.EntryNode != "draft" {
+		t.Error("expected ours to win conflicts")
+	}
+}
+
+func TestMerge_DeleteModifyConflict(t *testing.T) {
+	base := reviewGraph(t)
+	ours, _ := base.Clone()
+	theirs, _ := base.Clone()
+
+	ours.RemoveNode("publish")
+	theirs.GetNode("publish").(*ExecutorNode).Config["tool_name"] = "http_put"
+
+	merged, conflicts, err := Merge(base, ours, theirs)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0].Path != "nodes.publish" || conflicts[0].Ours != nil {
+		t.Fatalf("expected a delete/modify conflict on publish, got %v", conflicts)
+	}
+	if merged.GetNode("publish") != nil {
+		t.Error("expected ours (deletion) to win")
+	}
+}
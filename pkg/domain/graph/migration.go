@@ -0,0 +1,147 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// CurrentVersion is the graph schema version written by this library.
+const CurrentVersion = "1.0"
+
+// unversioned is the version assumed for documents without a "version" field.
+const unversioned = "1.0"
+
+// Migration upgrades graph documents from one schema version to the next.
+//
+// Migrations operate on the JSON data model rather than on *Graph, since
+// documents written for an older schema may not decode into the current types.
+type Migration struct {
+	// From is the schema version the migration applies to.
+	From string
+
+	// To is the schema version the migration produces.
+	To string
+
+	// Description summarizes the change, for logs and tooling.
+	Description string
+
+	// Up rewrites the document in place. The "version" field is updated by
+	// the migrator after Up returns.
+	Up func(doc map[string]interface{}) error
+}
+
+// Migrator upgrades graph documents by chaining registered migrations.
+type Migrator struct {
+	mu         sync.RWMutex
+	migrations map[string]Migration
+}
+
+// NewMigrator creates a migrator without migrations.
+func NewMigrator() *Migrator {
+	return &Migrator{migrations: make(map[string]Migration)}
+}
+
+// Register adds a migration. Only one migration may start from each version.
+func (m *Migrator) Register(migration Migration) error {
+	if migration.From == "" || migration.To == "" {
+		return &ValidationError{Field: "migration", Message: "migration versions cannot be empty"}
+	}
+	if migration.From == migration.To {
+		return &ValidationError{Field: "migration", Message: fmt.Sprintf("migration from '%s' to itself", migration.From)}
+	}
+	if migration.Up == nil {
+		return &ValidationError{Field: "migration", Message: fmt.Sprintf("migration %s -> %s has no Up function", migration.From, migration.To)}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.migrations[migration.From]; ok {
+		return &ValidationError{Field: "migration", Message: fmt.Sprintf("a migration from '%s' (to '%s') is already registered", migration.From, existing.To)}
+	}
+	m.migrations[migration.From] = migration
+	return nil
+}
+
+// Plan returns the migrations that upgrade a document from one version to another.
+func (m *Migrator) Plan(from, to string) ([]Migration, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var plan []Migration
+	visited := map[string]bool{from: true}
+	for version := from; version != to; {
+		migration, ok := m.migrations[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration path from version '%s' to '%s'", from, to)
+		}
+		if visited[migration.To] {
+			return nil, fmt.Errorf("migration cycle detected at version '%s'", migration.To)
+		}
+		visited[migration.To] = true
+		plan = append(plan, migration)
+		version = migration.To
+	}
+	return plan, nil
+}
+
+// Migrate upgrades doc in place to the target version and returns the
+// migrations that were applied. Documents without a version are treated as
+// version 1.0.
+func (m *Migrator) Migrate(doc map[string]interface{}, to string) ([]Migration, error) {
+	from := unversioned
+	if v, ok := doc["version"].(string); ok && v != "" {
+		from = v
+	}
+
+	plan, err := m.Plan(from, to)
+	if err != nil {
+		return nil, err
+	}
+	for i, migration := range plan {
+		if err := migration.Up(doc); err != nil {
+			return plan[:i], fmt.Errorf("migration %s -> %s failed: %w", migration.From, migration.To, err)
+		}
+		doc["version"] = migration.To
+	}
+	return plan, nil
+}
+
+// MigrateJSON upgrades a JSON graph definition to the target version.
+func (m *Migrator) MigrateJSON(data []byte, to string) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse graph document: %w", err)
+	}
+	if _, err := m.Migrate(doc, to); err != nil {
+		return nil, err
+	}
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode migrated graph: %w", err)
+	}
+	return out, nil
+}
+
+// Load upgrades a JSON graph definition to CurrentVersion and decodes it.
+func (m *Migrator) Load(data []byte) (*Graph, error) {
+	migrated, err := m.MigrateJSON(data, CurrentVersion)
+	if err != nil {
+		return nil, err
+	}
+	return FromJSON(string(migrated))
+}
+
+var defaultMigrator = NewMigrator()
+
+// RegisterMigration registers a migration with the package-level migrator
+// used by Upgrade.
+func RegisterMigration(migration Migration) error {
+	return defaultMigrator.Register(migration)
+}
+
+// Upgrade decodes a JSON graph definition of any registered schema version,
+// migrating it to CurrentVersion first.
+func Upgrade(data []byte) (*Graph, error) {
+	return defaultMigrator.Load(data)
+}
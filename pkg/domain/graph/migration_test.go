@@ -0,0 +1,126 @@
+package graph
+
+import (
+	"errors"
+	"testing"
+)
+
+// renameStartMigration renames the pre-1.0 "start" field to "entry_node".
+var renameStartMigration = Migration{
+	From:        "0.9",
+	To:          "1.0",
+	Description: "rename start to entry_node",
+	Up: func(doc map[string]interface{}) error {
+		if start, ok := doc["start"]; ok {
+			doc["entry_node"] = start
+			delete(doc, "start")
+		}
+		return nil
+	},
+}
+
+func TestMigrator_Register(t *testing.T) {
+	noop := func(map[string]interface{}) error { return nil }
+
+	tests := []struct {
+		name        string
+		migration   Migration
+		expectError bool
+	}{
+		{name: "valid", migration: Migration{From: "0.8", To: "0.9", Up: noop}, expectError: false},
+		{name: "duplicate source version", migration: Migration{From: "0.8", To: "1.0", Up: noop}, expectError: true},
+		{name: "missing version", migration: Migration{From: "", To: "1.0", Up: noop}, expectError: true},
+		{name: "same version", migration: Migration{From: "1.0", To: "1.0", Up: noop}, expectError: true},
+		{name: "missing Up", migration: Migration{From: "0.7", To: "0.8"}, expectError: true},
+	}
+
+	m := NewMigrator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := m.Register(tt.migration)
+			if tt.expectError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMigrator_Plan(t *testing.T) {
+	m := NewMigrator()
+	noop := func(map[string]interface{}) error { return nil }
+	_ = m.Register(Migration{From: "0.8", To: "0.9", Up: noop})
+	_ = m.Register(renameStartMigration)
+
+	plan, err := m.Plan("0.8", "1.0")
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan) != 2 || plan[0].To != "0.9" || plan[1].To != "1.0" {
+		t.Errorf("unexpected plan: %+v", plan)
+	}
+
+	if plan, err := m.Plan("1.0", "1.0"); err != nil || len(plan) != 0 {
+		t.Errorf("expected empty plan for current version, got %v, %v", plan, err)
+	}
+	if _, err := m.Plan("0.5", "1.0"); err == nil {
+		t.Error("expected error for unknown version")
+	}
+
+	_ = m.Register(Migration{From: "1.0", To: "0.8", Up: noop})
+	if _, err := m.Plan("0.8", "2.0"); err == nil {
+		t.Error("expected error for migration cycle")
+	}
+}
+
+func TestMigrator_Load(t *testing.T) {
+	m := NewMigrator()
+	if err := m.Register(renameStartMigration); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	old := []byte(`{
+		"id": "legacy",
+		"version": "0.9",
+		"start": "greet",
+		"nodes": {"greet": {"id": "greet", "type": "executor", "executor_type": "llm", "config": {"model": "gpt-4"}}},
+		"edges": []
+	}`)
+	g, err := m.Load(old)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if g.Version != CurrentVersion || g.EntryNode != "greet" {
+		t.Errorf("graph not migrated: version=%q entry=%q", g.Version, g.EntryNode)
+	}
+	if err := g.Validate(); err != nil {
+		t.Errorf("migrated graph is invalid: %v", err)
+	}
+
+	// Unversioned documents are treated as 1.0 and left unchanged.
+	g, err = m.Load([]byte(`{"id": "current", "entry_node": "a", "nodes": {"a": {"id": "a", "type": "start"}}}`))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if g.Version != "" || g.EntryNode != "a" {
+		t.Errorf("unexpected graph: %+v", g)
+	}
+}
+
+func TestMigrator_MigrateFailure(t *testing.T) {
+	m := NewMigrator()
+	_ = m.Register(Migration{From: "0.9", To: "1.0", Up: func(map[string]interface{}) error {
+		return errors.New("unsupported node")
+	}})
+
+	doc := map[string]interface{}{"version": "0.9"}
+	applied, err := m.Migrate(doc, "1.0")
+	if err == nil {
+		t.Fatal("expected migration error")
+	}
+	if len(applied) != 0 || doc["version"] != "0.9" {
+		t.Errorf("failed migration should not bump the version: applied=%v doc=%v", applied, doc)
+	}
+}
@@ -2,6 +2,8 @@ package graph
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/aescanero/dago-libs/pkg/domain/state"
 )
@@ -21,8 +23,46 @@ const (
 
 	// NodeTypeEnd represents an exit point of the graph.
 	NodeTypeEnd NodeType = "end"
+
+	// NodeTypeSubgraph represents a node that runs another graph.
+	NodeTypeSubgraph NodeType = "subgraph"
+
+	// NodeTypeApproval represents a node that waits for human approval or input.
+	NodeTypeApproval NodeType = "approval"
 )
 
+// Built-in executor types. Downstream repositories may use other types and
+// register their configuration schemas with schema.Validator.
+const (
+	// ExecutorTypeLLM calls a language model.
+	ExecutorTypeLLM = "llm"
+
+	// ExecutorTypeTool invokes a registered tool.
+	ExecutorTypeTool = "tool"
+
+	// ExecutorTypePython runs Python code or a script.
+	ExecutorTypePython = "python"
+
+	// ExecutorTypeBash runs a shell command.
+	ExecutorTypeBash = "bash"
+
+	// ExecutorTypeHTTP sends an HTTP request.
+	ExecutorTypeHTTP = "http"
+
+	// ExecutorTypeCustom is handled by an implementation-specific executor.
+	ExecutorTypeCustom = "custom"
+)
+
+// BuiltinExecutorTypes returns the built-in executor types.
+func BuiltinExecutorTypes() []string {
+	return []string{ExecutorTypeLLM, ExecutorTypeTool, ExecutorTypePython, ExecutorTypeBash, ExecutorTypeHTTP, ExecutorTypeCustom}
+}
+
+// ErrEngineRequired is returned by executor, router and subgraph nodes when
+// executed directly: they depend on the executors, condition evaluator and
+// scheduling of an engine (see package engine).
+var ErrEngineRequired = errors.New("node must be run by an engine")
+
 // Node defines the interface that all graph nodes must implement.
 type Node interface {
 	// GetID returns the unique identifier for this node.
@@ -33,7 +73,6 @@ type Node interface {
 
 	// Execute runs the node's logic with the given context and state.
 	// It returns the updated state and any error that occurred.
-	// Implementations should be provided in the main dago repository, not here.
 	Execute(ctx context.Context, state state.State) (state.State, error)
 
 	// Validate checks if the node configuration is valid.
@@ -48,6 +87,9 @@ type BaseNode struct {
 	Name        string                 `json:"name,omitempty"`
 	Description string                 `json:"description,omitempty"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+
+	// Policy overrides the graph's default execution policy for this node.
+	Policy *Policy `json:"policy,omitempty"`
 }
 
 // GetID returns the node's ID.
@@ -60,6 +102,12 @@ func (n *BaseNode) GetType() NodeType {
 	return n.Type
 }
 
+// Base returns the node's common fields.
+// It is promoted to every node type that embeds BaseNode.
+func (n *BaseNode) Base() *BaseNode {
+	return n
+}
+
 // ExecutorNode represents a node that executes tasks like LLM calls or tool invocations.
 type ExecutorNode struct {
 	BaseNode
@@ -70,17 +118,19 @@ type ExecutorNode struct {
 	// The structure depends on the ExecutorType.
 	Config map[string]interface{} `json:"config"`
 
-	// InputMapping defines how to map state values to executor inputs.
+	// InputMapping maps executor input keys to the state keys they are read
+	// from; engines apply it with MapInputs.
 	InputMapping map[string]string `json:"input_mapping,omitempty"`
 
-	// OutputMapping defines how to map executor outputs back to state.
+	// OutputMapping maps executor output keys to the state keys they are
+	// written to; engines apply it with MapOutputs.
 	OutputMapping map[string]string `json:"output_mapping,omitempty"`
 }
 
-// Execute is a placeholder that should be implemented in the main repository.
+// Execute returns ErrEngineRequired: engines dispatch executor nodes to the
+// executor registered for their executor type.
 func (n *ExecutorNode) Execute(ctx context.Context, s state.State) (state.State, error) {
-	// TODO: Implementation should be in dago repository
-	panic("ExecutorNode.Execute must be implemented in the main dago repository")
+	return s, fmt.Errorf("executor node '%s': %w", n.ID, ErrEngineRequired)
 }
 
 // Validate checks if the executor node configuration is valid.
@@ -91,9 +141,29 @@ func (n *ExecutorNode) Validate() error {
 	if n.ExecutorType == "" {
 		return &ValidationError{Field: "executor_type", Message: "executor type cannot be empty"}
 	}
+	config, err := n.TypedConfig()
+	if err != nil {
+		return &ValidationError{Field: "config", Message: err.Error()}
+	}
+	if config != nil {
+		if err := config.Validate(); err != nil {
+			var ve *ValidationError
+			if errors.As(err, &ve) {
+				return &ValidationError{Field: "config." + ve.Field, Message: ve.Message}
+			}
+			return &ValidationError{Field: "config", Message: err.Error()}
+		}
+	}
 	return nil
 }
 
+// TypedConfig decodes Config according to the executor type (see
+// RegisterExecutorConfig). It returns nil and no error for executor types
+// without a registered config decoder.
+func (n *ExecutorNode) TypedConfig() (ExecutorConfig, error) {
+	return DecodeExecutorConfig(n.ExecutorType, n.Config)
+}
+
 // RouterNode represents a node that makes routing decisions based on state.
 type RouterNode struct {
 	BaseNode
@@ -118,10 +188,10 @@ type Route struct {
 	Description string `json:"description,omitempty"`
 }
 
-// Execute is a placeholder that should be implemented in the main repository.
+// Execute returns ErrEngineRequired: engines evaluate the routes of router
+// nodes with their condition evaluator.
 func (n *RouterNode) Execute(ctx context.Context, s state.State) (state.State, error) {
-	// TODO: Implementation should be in dago repository
-	panic("RouterNode.Execute must be implemented in the main dago repository")
+	return s, fmt.Errorf("router node '%s': %w", n.ID, ErrEngineRequired)
 }
 
 // Validate checks if the router node configuration is valid.
@@ -134,16 +204,60 @@ func (n *RouterNode) Validate() error {
 	}
 	for i, route := range n.Routes {
 		if route.Target == "" {
-			return &ValidationError{Field: "routes", Message: "route target cannot be empty at index " + string(rune(i))}
+			return &ValidationError{Field: fmt.Sprintf("routes.%d.target", i), Message: fmt.Sprintf("route target cannot be empty at index %d", i)}
 		}
 	}
 	return nil
 }
 
+// StartNode marks the entry point of a graph.
+// It performs no work and passes the state through, applying the mapping in
+// its metadata if any (see MappingMetadataKey).
+type StartNode struct {
+	BaseNode
+}
+
+// Execute returns the state with the node's metadata mapping applied.
+func (n *StartNode) Execute(ctx context.Context, s state.State) (state.State, error) {
+	return applyMapping(s, metadataMapping(n.Metadata)), nil
+}
+
+// Validate checks if the start node configuration is valid.
+func (n *StartNode) Validate() error {
+	if n.ID == "" {
+		return &ValidationError{Field: "id", Message: "start node ID cannot be empty"}
+	}
+	return nil
+}
+
+// EndNode marks an exit point of a graph.
+// It performs no work and passes the state through, applying the mapping in
+// its metadata if any (see MappingMetadataKey).
+type EndNode struct {
+	BaseNode
+}
+
+// Execute returns the state with the node's metadata mapping applied.
+func (n *EndNode) Execute(ctx context.Context, s state.State) (state.State, error) {
+	return applyMapping(s, metadataMapping(n.Metadata)), nil
+}
+
+// Validate checks if the end node configuration is valid.
+func (n *EndNode) Validate() error {
+	if n.ID == "" {
+		return &ValidationError{Field: "id", Message: "end node ID cannot be empty"}
+	}
+	return nil
+}
+
 // ValidationError represents a node validation error.
 type ValidationError struct {
 	Field   string
 	Message string
+
+	// Pointer is the JSON Pointer of the offending value in the graph
+	// definition. It is set by Graph.ValidateAll.
+	Pointer string
 }
 
 // Error implements the error interface.
@@ -160,6 +160,18 @@ func TestRouterNode_Validate(t *testing.T) {
 	}
 }
 
+func TestRouterNode_ValidateReportsRouteIndex(t *testing.T) {
+	node := &RouterNode{
+		BaseNode: BaseNode{ID: "router", Type: NodeTypeRouter},
+		Routes:   []Route{{Target: "a"}, {Target: "b"}, {Condition: "x"}},
+	}
+
+	expected := "routes.2.target: route target cannot be empty at index 2"
+	if err := node.Validate(); err == nil || err.Error() != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+}
+
 func TestValidationError_Error(t *testing.T) {
 	err := &ValidationError{
 		Field:   "test_field",
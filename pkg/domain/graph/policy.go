@@ -0,0 +1,420 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy declares how a node is executed: how long an attempt may take, how
+// failed attempts are retried, whether the node is safe to run again, and
+// which node handles its errors.
+//
+// Graph.Policy sets defaults for every node; BaseNode.Policy overrides them
+// field by field (see Graph.EffectivePolicy). Executors apply a policy with
+// Execute and route failures with ErrorTarget.
+type Policy struct {
+	// Timeout bounds each attempt. Zero means no timeout. Timeouts are
+	// cooperative: the context of the attempt is cancelled when it expires,
+	// and the executor must watch it to stop; an executor that ignores it
+	// runs to completion and its result, success or failure, is then
+	// reported as a timeout.
+	Timeout Duration `json:"timeout,omitempty"`
+
+	// Retry configures retries of failed attempts. Nil means no retries.
+	Retry *RetryPolicy `json:"retry,omitempty"`
+
+	// Idempotent declares that running the node again has no additional side
+	// effects, so it can be re-executed when recovering an interrupted
+	// execution. Nil inherits the graph default, which defaults to false.
+	// See IsIdempotent.
+	Idempotent *bool `json:"idempotent,omitempty"`
+
+	// IdempotencyKey is the template of the key executors pass to external
+	// systems to deduplicate side effects. Engines compute it with
+	// IdempotencyKeyFor and hand it to executors with WithIdempotencyKey.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+
+	// OnError lists the error edges of the node: when it fails after its
+	// retries, execution continues at the target of the first matching handler.
+	// Without a match, the execution fails.
+	OnError []ErrorHandler `json:"on_error,omitempty"`
+}
+
+// BackoffStrategy is the way the delay between retries grows.
+type BackoffStrategy string
+
+const (
+	// BackoffFixed waits InitialInterval between every attempt.
+	BackoffFixed BackoffStrategy = "fixed"
+
+	// BackoffExponential multiplies the delay by Multiplier after every retry.
+	BackoffExponential BackoffStrategy = "exponential"
+)
+
+// Defaults applied to unset RetryPolicy fields.
+const (
+	DefaultRetryInterval   = time.Second
+	DefaultRetryMultiplier = 2.0
+)
+
+// RetryPolicy configures retries of failed attempts.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int `json:"max_attempts"`
+
+	// Backoff is the backoff strategy. Empty means BackoffExponential.
+	Backoff BackoffStrategy `json:"backoff,omitempty"`
+
+	// InitialInterval is the delay before the first retry. Zero means
+	// DefaultRetryInterval.
+	InitialInterval Duration `json:"initial_interval,omitempty"`
+
+	// MaxInterval caps the delay between retries. Zero means no cap.
+	MaxInterval Duration `json:"max_interval,omitempty"`
+
+	// Multiplier is the growth factor of exponential backoff, at least 1.
+	// Zero means DefaultRetryMultiplier.
+	Multiplier float64 `json:"multiplier,omitempty"`
+
+	// Jitter randomizes each delay by up to this fraction of it, between 0
+	// and 1, to spread retries of concurrent executions.
+	Jitter float64 `json:"jitter,omitempty"`
+
+	// RetryOn lists the error kinds that are retried (see ErrorKind). Empty
+	// retries every error. Cancellation, rejections and waiting for input are
+	// never retried.
+	RetryOn []string `json:"retry_on,omitempty"`
+}
+
+// ErrorHandler is an error edge: it routes errors of the listed kinds to
+// Target.
+type ErrorHandler struct {
+	// Errors lists the error kinds handled (see ErrorKind). Empty handles
+	// every error.
+	Errors []string `json:"errors,omitempty"`
+
+	// Target is the ID of the node that handles the error.
+	Target string `json:"target"`
+}
+
+// Error kinds returned by ErrorKind.
+const (
+	ErrorKindTimeout   = "timeout"
+	ErrorKindCancelled = "cancelled"
+	ErrorKindError     = "error"
+
+	// ErrorKindWaitingForInput is the kind of *InputRequiredError. It pauses
+	// the execution and is never retried nor handled by error edges.
+	ErrorKindWaitingForInput = "waiting_for_input"
+
+	// ErrorKindRejected is the kind of ErrInputRejected. It is never retried.
+	ErrorKindRejected = "rejected"
+)
+
+// ErrorKind classifies an error for RetryPolicy.RetryOn and
+// ErrorHandler.Errors: "timeout" for exceeded deadlines, "cancelled" for
+// cancelled contexts, the kind reported by the first error in the chain with
+// an ErrorKind() string method, and "error" otherwise.
+func ErrorKind(err error) string {
+	var kinded interface{ ErrorKind() string }
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorKindTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrorKindCancelled
+	case errors.As(err, &kinded):
+		return kinded.ErrorKind()
+	}
+	return ErrorKindError
+}
+
+// Duration is a time.Duration encoded in JSON as a duration string such as
+// "30s" or "1m30s". A JSON number is read as seconds.
+type Duration time.Duration
+
+// MarshalJSON encodes the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON decodes a duration string or a number of seconds.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration '%s': %w", s, err)
+		}
+		*d = Duration(parsed)
+		return nil
+	}
+	seconds, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return fmt.Errorf("invalid duration %s: must be a string or a number of seconds", data)
+	}
+	*d = Duration(seconds * float64(time.Second))
+	return nil
+}
+
+// Validate checks the policy. Targets of error handlers are checked against
+// the graph by Graph.Validate.
+func (p *Policy) Validate() error {
+	if p.Timeout < 0 {
+		return &ValidationError{Field: "timeout", Message: "timeout cannot be negative"}
+	}
+	if p.Retry != nil {
+		if err := p.Retry.Validate(); err != nil {
+			var ve *ValidationError
+			if errors.As(err, &ve) {
+				return &ValidationError{Field: "retry." + ve.Field, Message: ve.Message}
+			}
+			return err
+		}
+	}
+	for i, h := range p.OnError {
+		if h.Target == "" {
+			return &ValidationError{Field: fmt.Sprintf("on_error.%d.target", i), Message: fmt.Sprintf("error handler target cannot be empty at index %d", i)}
+		}
+	}
+	return nil
+}
+
+// Validate checks the retry policy.
+func (r *RetryPolicy) Validate() error {
+	if r.MaxAttempts < 1 {
+		return &ValidationError{Field: "max_attempts", Message: "max attempts must be at least 1"}
+	}
+	switch r.Backoff {
+	case "", BackoffFixed, BackoffExponential:
+	default:
+		return &ValidationError{Field: "backoff", Message: fmt.Sprintf("unknown backoff strategy '%s'", r.Backoff)}
+	}
+	if r.InitialInterval < 0 {
+		return &ValidationError{Field: "initial_interval", Message: "initial interval cannot be negative"}
+	}
+	if r.MaxInterval < 0 {
+		return &ValidationError{Field: "max_interval", Message: "max interval cannot be negative"}
+	}
+	if r.Multiplier != 0 && r.Multiplier < 1 {
+		return &ValidationError{Field: "multiplier", Message: fmt.Sprintf("multiplier %v must be at least 1", r.Multiplier)}
+	}
+	if r.Jitter < 0 || r.Jitter > 1 {
+		return &ValidationError{Field: "jitter", Message: fmt.Sprintf("jitter %v must be between 0 and 1", r.Jitter)}
+	}
+	return nil
+}
+
+// Merge returns the policy p with the fields set in override replacing its
+// own. A retry policy or list of error handlers in override replaces p's as a
+// whole. Either policy may be nil.
+func (p *Policy) Merge(override *Policy) *Policy {
+	var merged Policy
+	if p != nil {
+		merged = *p
+	}
+	if override == nil {
+		return &merged
+	}
+	if override.Timeout != 0 {
+		merged.Timeout = override.Timeout
+	}
+	if override.Retry != nil {
+		merged.Retry = override.Retry
+	}
+	if override.Idempotent != nil {
+		merged.Idempotent = override.Idempotent
+	}
+	if override.IdempotencyKey != "" {
+		merged.IdempotencyKey = override.IdempotencyKey
+	}
+	if override.OnError != nil {
+		merged.OnError = override.OnError
+	}
+	return &merged
+}
+
+// EffectivePolicy returns the policy of a node: the graph's default policy
+// overridden by the node's own. Error handlers targeting the node itself are
+// dropped, so that a graph-wide handler does not handle its own errors. It
+// returns an empty policy for unknown nodes.
+func (g *Graph) EffectivePolicy(nodeID string) *Policy {
+	var own *Policy
+	if n, ok := g.GetNode(nodeID).(interface{ Base() *BaseNode }); ok {
+		own = n.Base().Policy
+	}
+	policy := g.Policy.Merge(own)
+	if len(policy.OnError) > 0 {
+		handlers := make([]ErrorHandler, 0, len(policy.OnError))
+		for _, h := range policy.OnError {
+			if h.Target != nodeID {
+				handlers = append(handlers, h)
+			}
+		}
+		policy.OnError = handlers
+	}
+	return policy
+}
+
+// IsIdempotent reports whether the node may be executed again.
+func (p *Policy) IsIdempotent() bool {
+	return p != nil && p.Idempotent != nil && *p.Idempotent
+}
+
+// IdempotencyKeyFor returns the idempotency key of a node in an execution.
+// The "{execution_id}" and "{node_id}" placeholders of IdempotencyKey are
+// replaced; an empty IdempotencyKey yields "<execution_id>:<node_id>".
+func (p *Policy) IdempotencyKeyFor(executionID, nodeID string) string {
+	if p == nil || p.IdempotencyKey == "" {
+		return executionID + ":" + nodeID
+	}
+	return strings.NewReplacer("{execution_id}", executionID, "{node_id}", nodeID).Replace(p.IdempotencyKey)
+}
+
+// idempotencyKeyKey is the context key of the idempotency key.
+type idempotencyKeyKey struct{}
+
+// WithIdempotencyKey returns a context carrying the idempotency key of the
+// node being executed. Engines set it on the context passed to executors; the
+// key is the same for every attempt and every run of the node in the
+// execution, including runs resumed after a crash.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyKey{}, key)
+}
+
+// IdempotencyKeyFromContext returns the idempotency key of the node being
+// executed, or "" if the engine does not report it.
+func IdempotencyKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyKey{}).(string)
+	return key
+}
+
+// ShouldRetry reports whether an attempt that failed with err is retried,
+// given the number of attempts made so far.
+func (r *RetryPolicy) ShouldRetry(attempts int, err error) bool {
+	if r == nil || err == nil || attempts >= r.MaxAttempts {
+		return false
+	}
+	kind := ErrorKind(err)
+	switch kind {
+	case ErrorKindCancelled, ErrorKindWaitingForInput, ErrorKindRejected:
+		return false
+	}
+	return len(r.RetryOn) == 0 || contains(r.RetryOn, kind)
+}
+
+// Delay returns the delay before the given retry, starting at 1, without
+// jitter.
+func (r *RetryPolicy) Delay(retry int) time.Duration {
+	if r == nil || retry < 1 {
+		return 0
+	}
+	delay := float64(r.InitialInterval)
+	if delay == 0 {
+		delay = float64(DefaultRetryInterval)
+	}
+	if r.Backoff != BackoffFixed {
+		multiplier := r.Multiplier
+		if multiplier == 0 {
+			multiplier = DefaultRetryMultiplier
+		}
+		delay *= math.Pow(multiplier, float64(retry-1))
+	}
+	if r.MaxInterval > 0 && delay > float64(r.MaxInterval) {
+		delay = float64(r.MaxInterval)
+	}
+	if delay > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(delay)
+}
+
+// jittered randomizes d by up to the Jitter fraction of it in either direction.
+func (r *RetryPolicy) jittered(d time.Duration) time.Duration {
+	if r.Jitter == 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 + r.Jitter*(2*rand.Float64()-1)))
+}
+
+// Execute runs fn under the policy. Each attempt gets a context bounded by
+// Timeout; failed attempts are retried as allowed by Retry, waiting the
+// backoff delay in between. It returns the number of attempts made and the
+// error of the last one. It stops early when ctx is done. A nil policy runs
+// fn once.
+//
+// Execute does not abandon an attempt at its timeout: it waits for fn to
+// return, so fn must return promptly once its context is done.
+func (p *Policy) Execute(ctx context.Context, fn func(ctx context.Context, attempt int) error) (int, error) {
+	var retry *RetryPolicy
+	if p != nil {
+		retry = p.Retry
+	}
+	for attempt := 1; ; attempt++ {
+		err := p.attempt(ctx, attempt, fn)
+		if err == nil || !retry.ShouldRetry(attempt, err) {
+			return attempt, err
+		}
+
+		timer := time.NewTimer(retry.jittered(retry.Delay(attempt)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempt, err
+		case <-timer.C:
+		}
+	}
+}
+
+func (p *Policy) attempt(ctx context.Context, attempt int, fn func(ctx context.Context, attempt int) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if p != nil && p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(p.Timeout))
+		defer cancel()
+	}
+	err := fn(ctx, attempt)
+	if ctx.Err() == context.DeadlineExceeded && !errors.Is(err, context.DeadlineExceeded) {
+		// The attempt ignored its context and returned after the timeout.
+		if err == nil {
+			return fmt.Errorf("%w: attempt completed after its timeout", context.DeadlineExceeded)
+		}
+		err = fmt.Errorf("%w: %w", context.DeadlineExceeded, err)
+	}
+	return err
+}
+
+// ErrorTarget returns the node that handles err: the target of the first
+// error handler matching its kind.
+func (p *Policy) ErrorTarget(err error) (string, bool) {
+	if p == nil || err == nil {
+		return "", false
+	}
+	kind := ErrorKind(err)
+	if kind == ErrorKindWaitingForInput {
+		return "", false
+	}
+	for _, h := range p.OnError {
+		if len(h.Errors) == 0 || contains(h.Errors, kind) {
+			return h.Target, true
+		}
+	}
+	return "", false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,407 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDuration_JSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		want        time.Duration
+		expectError bool
+	}{
+		{name: "string", input: `"1m30s"`, want: 90 * time.Second},
+		{name: "seconds", input: `30`, want: 30 * time.Second},
+		{name: "fractional seconds", input: `0.5`, want: 500 * time.Millisecond},
+		{name: "invalid string", input: `"soon"`, expectError: true},
+		{name: "invalid type", input: `true`, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d Duration
+			err := json.Unmarshal([]byte(tt.input), &d)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error, got %v", time.Duration(d))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if time.Duration(d) != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, time.Duration(d))
+			}
+		})
+	}
+
+	data, err := json.Marshal(Policy{Timeout: Duration(90 * time.Second)})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(data) != `{"timeout":"1m30s"}` {
+		t.Errorf("unexpected encoding: %s", data)
+	}
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		field  string
+	}{
+		{name: "empty", policy: Policy{}},
+		{name: "full", policy: Policy{Timeout: Duration(time.Second), Retry: &RetryPolicy{MaxAttempts: 3, Backoff: BackoffFixed, Multiplier: 1, Jitter: 0.5}, OnError: []ErrorHandler{{Target: "fallback"}}}},
+		{name: "negative timeout", policy: Policy{Timeout: -1}, field: "timeout"},
+		{name: "no attempts", policy: Policy{Retry: &RetryPolicy{}}, field: "retry.max_attempts"},
+		{name: "unknown backoff", policy: Policy{Retry: &RetryPolicy{MaxAttempts: 2, Backoff: "random"}}, field: "retry.backoff"},
+		{name: "negative interval", policy: Policy{Retry: &RetryPolicy{MaxAttempts: 2, InitialInterval: -1}}, field: "retry.initial_interval"},
+		{name: "negative max interval", policy: Policy{Retry: &RetryPolicy{MaxAttempts: 2, MaxInterval: -1}}, field: "retry.max_interval"},
+		{name: "shrinking multiplier", policy: Policy{Retry: &RetryPolicy{MaxAttempts: 2, Multiplier: 0.5}}, field: "retry.multiplier"},
+		{name: "jitter too high", policy: Policy{Retry: &RetryPolicy{MaxAttempts: 2, Jitter: 1.5}}, field: "retry.jitter"},
+		{name: "handler without target", policy: Policy{OnError: []ErrorHandler{{Target: "a"}, {}}}, field: "on_error.1.target"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if tt.field == "" {
+				if err != nil {
+					t.Errorf("unexpected validation error: %v", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("expected *ValidationError, got %v", err)
+			}
+			if ve.Field != tt.field {
+				t.Errorf("expected field %q, got %q", tt.field, ve.Field)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	tests := []struct {
+		name  string
+		retry RetryPolicy
+		want  []time.Duration
+	}{
+		{name: "defaults", retry: RetryPolicy{MaxAttempts: 4}, want: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}},
+		{name: "fixed", retry: RetryPolicy{MaxAttempts: 4, Backoff: BackoffFixed, InitialInterval: Duration(100 * time.Millisecond)}, want: []time.Duration{100 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond}},
+		{name: "capped", retry: RetryPolicy{MaxAttempts: 4, InitialInterval: Duration(time.Second), Multiplier: 3, MaxInterval: Duration(5 * time.Second)}, want: []time.Duration{time.Second, 3 * time.Second, 5 * time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, want := range tt.want {
+				if got := tt.retry.Delay(i + 1); got != want {
+					t.Errorf("retry %d: expected %v, got %v", i+1, want, got)
+				}
+			}
+		})
+	}
+
+	jittered := RetryPolicy{MaxAttempts: 2, InitialInterval: Duration(time.Second), Jitter: 0.5}
+	for i := 0; i < 20; i++ {
+		if d := jittered.jittered(jittered.Delay(1)); d < 500*time.Millisecond || d > 1500*time.Millisecond {
+			t.Fatalf("jittered delay %v out of range", d)
+		}
+	}
+}
+
+type kindError string
+
+func (e kindError) Error() string     { return string(e) }
+func (e kindError) ErrorKind() string { return string(e) }
+
+func TestErrorKind(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{err: context.DeadlineExceeded, want: ErrorKindTimeout},
+		{err: fmt.Errorf("call failed: %w", context.DeadlineExceeded), want: ErrorKindTimeout},
+		{err: context.Canceled, want: ErrorKindCancelled},
+		{err: fmt.Errorf("call failed: %w", kindError("rate_limited")), want: "rate_limited"},
+		{err: errors.New("boom"), want: ErrorKindError},
+	}
+
+	for _, tt := range tests {
+		if got := ErrorKind(tt.err); got != tt.want {
+			t.Errorf("ErrorKind(%v): expected %q, got %q", tt.err, tt.want, got)
+		}
+	}
+}
+
+func TestRetryPolicy_ShouldRetry(t *testing.T) {
+	retry := &RetryPolicy{MaxAttempts: 3, RetryOn: []string{ErrorKindTimeout, "rate_limited"}}
+
+	tests := []struct {
+		name     string
+		attempts int
+		err      error
+		want     bool
+	}{
+		{name: "timeout", attempts: 1, err: context.DeadlineExceeded, want: true},
+		{name: "listed kind", attempts: 2, err: kindError("rate_limited"), want: true},
+		{name: "attempts exhausted", attempts: 3, err: context.DeadlineExceeded, want: false},
+		{name: "unlisted kind", attempts: 1, err: errors.New("boom"), want: false},
+		{name: "success", attempts: 1, err: nil, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retry.ShouldRetry(tt.attempts, tt.err); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	all := &RetryPolicy{MaxAttempts: 3}
+	if !all.ShouldRetry(1, errors.New("boom")) {
+		t.Error("expected every error to be retried without retry_on")
+	}
+	if all.ShouldRetry(1, context.Canceled) {
+		t.Error("expected cancellation not to be retried")
+	}
+	var none *RetryPolicy
+	if none.ShouldRetry(1, errors.New("boom")) {
+		t.Error("expected nil retry policy not to retry")
+	}
+}
+
+func TestPolicy_Execute(t *testing.T) {
+	fast := &RetryPolicy{MaxAttempts: 3, Backoff: BackoffFixed, InitialInterval: Duration(time.Millisecond)}
+
+	t.Run("succeeds after retries", func(t *testing.T) {
+		policy := &Policy{Retry: fast}
+		attempts, err := policy.Execute(context.Background(), func(ctx context.Context, attempt int) error {
+			if attempt < 3 {
+				return errors.New("flaky")
+			}
+			return nil
+		})
+		if err != nil || attempts != 3 {
+			t.Errorf("expected success on attempt 3, got %d, %v", attempts, err)
+		}
+	})
+
+	t.Run("returns last error", func(t *testing.T) {
+		policy := &Policy{Retry: fast}
+		attempts, err := policy.Execute(context.Background(), func(ctx context.Context, attempt int) error {
+			return fmt.Errorf("attempt %d failed", attempt)
+		})
+		if attempts != 3 || err == nil || err.Error() != "attempt 3 failed" {
+			t.Errorf("expected attempt 3 to fail, got %d, %v", attempts, err)
+		}
+	})
+
+	t.Run("times out attempts", func(t *testing.T) {
+		policy := &Policy{Timeout: Duration(time.Millisecond), Retry: &RetryPolicy{MaxAttempts: 2, InitialInterval: Duration(time.Millisecond), RetryOn: []string{ErrorKindTimeout}}}
+		attempts, err := policy.Execute(context.Background(), func(ctx context.Context, attempt int) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		if attempts != 2 || ErrorKind(err) != ErrorKindTimeout {
+			t.Errorf("expected 2 timed out attempts, got %d, %v", attempts, err)
+		}
+	})
+
+	t.Run("marks errors after the timeout", func(t *testing.T) {
+		policy := &Policy{Timeout: Duration(time.Millisecond)}
+		_, err := policy.Execute(context.Background(), func(ctx context.Context, attempt int) error {
+			time.Sleep(5 * time.Millisecond)
+			return errors.New("too late")
+		})
+		if ErrorKind(err) != ErrorKindTimeout {
+			t.Errorf("expected a timeout, got %v", err)
+		}
+	})
+
+	t.Run("marks late successes as timeouts", func(t *testing.T) {
+		policy := &Policy{Timeout: Duration(time.Millisecond)}
+		_, err := policy.Execute(context.Background(), func(ctx context.Context, attempt int) error {
+			time.Sleep(5 * time.Millisecond)
+			return nil
+		})
+		if !errors.Is(err, context.DeadlineExceeded) || ErrorKind(err) != ErrorKindTimeout {
+			t.Errorf("expected a timeout, got %v", err)
+		}
+	})
+
+	t.Run("stops when cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		policy := &Policy{Retry: &RetryPolicy{MaxAttempts: 5, InitialInterval: Duration(time.Hour)}}
+		attempts, err := policy.Execute(ctx, func(ctx context.Context, attempt int) error {
+			cancel()
+			return errors.New("boom")
+		})
+		if attempts != 1 || err == nil {
+			t.Errorf("expected a single attempt, got %d, %v", attempts, err)
+		}
+	})
+
+	t.Run("nil policy", func(t *testing.T) {
+		var policy *Policy
+		attempts, err := policy.Execute(context.Background(), func(ctx context.Context, attempt int) error {
+			return errors.New("boom")
+		})
+		if attempts != 1 || err == nil {
+			t.Errorf("expected a single failed attempt, got %d, %v", attempts, err)
+		}
+	})
+}
+
+func TestPolicy_ErrorTarget(t *testing.T) {
+	policy := &Policy{OnError: []ErrorHandler{
+		{Errors: []string{ErrorKindTimeout}, Target: "slow"},
+		{Target: "fallback"},
+	}}
+
+	if target, ok := policy.ErrorTarget(context.DeadlineExceeded); !ok || target != "slow" {
+		t.Errorf("expected timeout to go to slow, got %q", target)
+	}
+	if target, ok := policy.ErrorTarget(errors.New("boom")); !ok || target != "fallback" {
+		t.Errorf("expected other errors to go to fallback, got %q", target)
+	}
+	if _, ok := (&Policy{}).ErrorTarget(errors.New("boom")); ok {
+		t.Error("expected no target without handlers")
+	}
+}
+
+func TestPolicy_IdempotencyKeyFor(t *testing.T) {
+	var policy *Policy
+	if key := policy.IdempotencyKeyFor("exec-1", "charge"); key != "exec-1:charge" {
+		t.Errorf("unexpected default key %q", key)
+	}
+	policy = &Policy{IdempotencyKey: "payments/{execution_id}/{node_id}"}
+	if key := policy.IdempotencyKeyFor("exec-1", "charge"); key != "payments/exec-1/charge" {
+		t.Errorf("unexpected key %q", key)
+	}
+	if policy.IsIdempotent() {
+		t.Error("expected nodes not to be idempotent by default")
+	}
+}
+
+func TestIdempotencyKeyFromContext(t *testing.T) {
+	if key := IdempotencyKeyFromContext(context.Background()); key != "" {
+		t.Errorf("expected no key, got %q", key)
+	}
+	ctx := WithIdempotencyKey(context.Background(), "exec-1:charge")
+	if key := IdempotencyKeyFromContext(ctx); key != "exec-1:charge" {
+		t.Errorf("unexpected key %q", key)
+	}
+}
+
+func policyGraph(t *testing.T) *Graph {
+	t.Helper()
+	idempotent := true
+	g, err := Build("payments").ID("payments").
+		DefaultPolicy(&Policy{
+			Timeout:    Duration(30 * time.Second),
+			Retry:      &RetryPolicy{MaxAttempts: 2},
+			Idempotent: &idempotent,
+			OnError:    []ErrorHandler{{Target: "alert"}},
+		}).
+		Start().
+		Tool("charge", "stripe", nil).
+		Policy(&Policy{Timeout: Duration(5 * time.Second), Retry: &RetryPolicy{MaxAttempts: 5}}).
+		OnError("refund", ErrorKindTimeout).
+		Tool("notify", "email", nil).
+		Node(&EndNode{BaseNode: BaseNode{ID: EndNodeID, Type: NodeTypeEnd}}).
+		At().Tool("refund", "stripe_refund", nil).
+		At().Tool("alert", "pager", nil).
+		Graph()
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+	return g
+}
+
+func TestGraph_EffectivePolicy(t *testing.T) {
+	g := policyGraph(t)
+	if err := g.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	charge := g.EffectivePolicy("charge")
+	if time.Duration(charge.Timeout) != 5*time.Second || charge.Retry.MaxAttempts != 5 || !charge.IsIdempotent() {
+		t.Errorf("expected node overrides on graph defaults, got %+v", charge)
+	}
+	if len(charge.OnError) != 1 || charge.OnError[0].Target != "refund" {
+		t.Errorf("expected node error handlers to replace the defaults, got %+v", charge.OnError)
+	}
+
+	notify := g.EffectivePolicy("notify")
+	if time.Duration(notify.Timeout) != 30*time.Second || notify.Retry.MaxAttempts != 2 {
+		t.Errorf("expected graph defaults, got %+v", notify)
+	}
+	if target, ok := notify.ErrorTarget(errors.New("boom")); !ok || target != "alert" {
+		t.Errorf("expected default error handler, got %q", target)
+	}
+
+	if alert := g.EffectivePolicy("alert"); len(alert.OnError) != 0 {
+		t.Errorf("expected the handler not to handle its own errors, got %+v", alert.OnError)
+	}
+	if g.Policy.OnError[0].Target != "alert" {
+		t.Error("EffectivePolicy modified the graph policy")
+	}
+}
+
+func TestGraphValidateAll_Policies(t *testing.T) {
+	g := policyGraph(t)
+	g.Policy.OnError = []ErrorHandler{{Target: "pager"}}
+	g.Nodes["charge"].(*ExecutorNode).Policy.Retry = &RetryPolicy{}
+	g.Nodes["notify"].(*ExecutorNode).Policy = &Policy{OnError: []ErrorHandler{{Target: "alert"}, {Target: "missing"}}}
+
+	var got []string
+	for _, err := range g.ValidateAll() {
+		got = append(got, err.Pointer)
+	}
+	want := []string{
+		"/policy/on_error/0/target",
+		"/nodes/charge/policy/retry/max_attempts",
+		"/nodes/notify/policy/on_error/1/target",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected errors at %v, got %v", want, got)
+	}
+}
+
+func TestFlatten_Policies(t *testing.T) {
+	sub := subgraphNode("pay")
+	sub.Graph = policyGraph(t)
+	g, err := Build("checkout").ID("checkout").
+		Start().
+		Node(sub).
+		End()
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+
+	flat, err := Flatten(context.Background(), g, nil)
+	if err != nil {
+		t.Fatalf("Flatten failed: %v", err)
+	}
+	if err := flat.Validate(); err != nil {
+		t.Fatalf("flattened graph is invalid: %v", err)
+	}
+
+	charge := flat.EffectivePolicy("pay.charge")
+	if time.Duration(charge.Timeout) != 5*time.Second || !charge.IsIdempotent() || charge.OnError[0].Target != "pay.refund" {
+		t.Errorf("expected the subgraph policies to be folded and renamed, got %+v", charge)
+	}
+	notify := flat.EffectivePolicy("pay.notify")
+	if time.Duration(notify.Timeout) != 30*time.Second || notify.OnError[0].Target != "pay.alert" {
+		t.Errorf("expected the subgraph default policy on inner nodes, got %+v", notify)
+	}
+}
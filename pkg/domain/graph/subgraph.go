@@ -0,0 +1,441 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+)
+
+// NamespaceSeparator separates the subgraph node ID from the inner node ID in
+// the node IDs of a flattened graph (e.g. "retrieval.search").
+const NamespaceSeparator = "."
+
+// GraphRef references a graph definition in GraphStorage.
+type GraphRef struct {
+	// ID is the ID of the referenced graph.
+	ID string `json:"id"`
+
+	// Version pins a specific version of the graph. If empty, the current
+	// version is used.
+	Version string `json:"version,omitempty"`
+}
+
+// Key returns the storage key of the referenced graph: the ID, or "ID@Version"
+// when a version is pinned.
+func (r GraphRef) Key() string {
+	if r.Version == "" {
+		return r.ID
+	}
+	return r.ID + "@" + r.Version
+}
+
+// SubgraphNode represents a node that runs another graph as a single step.
+// The graph is either referenced from storage (Ref) or defined inline (Graph).
+type SubgraphNode struct {
+	BaseNode
+	// Ref references a stored graph. Mutually exclusive with Graph.
+	Ref *GraphRef `json:"graph_ref,omitempty"`
+
+	// Graph is an inline graph definition. Mutually exclusive with Ref.
+	Graph *Graph `json:"graph,omitempty"`
+
+	// InputMapping maps subgraph state keys to the parent state keys they are read from.
+	// The values are copied when the subgraph is entered. If empty, the
+	// subgraph starts with a copy of the parent state.
+	InputMapping map[string]string `json:"input_mapping,omitempty"`
+
+	// OutputMapping maps subgraph state keys to the parent state keys they are written to.
+	// The values are copied when the subgraph exits. If empty, the final
+	// subgraph state is merged into the parent state.
+	OutputMapping map[string]string `json:"output_mapping,omitempty"`
+}
+
+// Execute returns ErrEngineRequired: engines flatten subgraphs before running
+// a graph (see Flatten).
+func (n *SubgraphNode) Execute(ctx context.Context, s state.State) (state.State, error) {
+	return s, fmt.Errorf("subgraph node '%s': %w", n.ID, ErrEngineRequired)
+}
+
+// Validate checks if the subgraph node configuration is valid.
+// Inline graphs are validated recursively.
+func (n *SubgraphNode) Validate() error {
+	if err := n.validateFields(); err != nil {
+		return err
+	}
+	if n.Graph != nil {
+		if err := n.Graph.Validate(); err != nil {
+			return fmt.Errorf("inline graph validation failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateFields checks the fields of the node itself, without the inline graph.
+func (n *SubgraphNode) validateFields() error {
+	if n.ID == "" {
+		return &ValidationError{Field: "id", Message: "subgraph node ID cannot be empty"}
+	}
+	if (n.Ref == nil) == (n.Graph == nil) {
+		return &ValidationError{Field: "graph", Message: "subgraph node must define exactly one of graph_ref or graph"}
+	}
+	if n.Ref != nil && n.Ref.ID == "" {
+		return &ValidationError{Field: "graph_ref.id", Message: "subgraph graph reference ID cannot be empty"}
+	}
+	return nil
+}
+
+// validateComposition detects subgraphs that (transitively) contain one of
+// the graphs in ancestors, which would recurse forever at execution time.
+// Only inline graphs can be followed here; references are checked by Flatten.
+// visiting tracks the inline graphs on the current path, so that graphs
+// without an ID cannot make validation loop. pointer is the JSON Pointer of g.
+func (g *Graph) validateComposition(pointer string, ancestors []string, visiting map[*Graph]bool) *ValidationError {
+	for _, node := range g.Nodes {
+		sub, ok := node.(*SubgraphNode)
+		if !ok {
+			continue
+		}
+
+		id := subgraphKey(sub)
+		nodePointer := pointer + "/nodes/" + escapePointer(sub.ID)
+		for _, ancestor := range ancestors {
+			if id != "" && id == ancestor {
+				return &ValidationError{
+					Field:   "nodes." + sub.ID,
+					Pointer: nodePointer,
+					Message: fmt.Sprintf("recursive subgraph: %s -> %s", strings.Join(ancestors, " -> "), id),
+				}
+			}
+		}
+
+		if sub.Graph != nil {
+			if visiting[sub.Graph] {
+				return &ValidationError{Field: "nodes." + sub.ID, Pointer: nodePointer, Message: "recursive subgraph: inline graph contains itself"}
+			}
+			visiting[sub.Graph] = true
+			err := sub.Graph.validateComposition(nodePointer+"/graph", append(append([]string(nil), ancestors...), id), visiting)
+			delete(visiting, sub.Graph)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GraphResolver loads the graphs referenced by subgraph nodes.
+type GraphResolver interface {
+	ResolveGraph(ctx context.Context, ref GraphRef) (*Graph, error)
+}
+
+// GraphLoader loads raw graph definitions by key.
+// ports.GraphStorage satisfies this interface.
+type GraphLoader interface {
+	Load(ctx context.Context, graphID string) ([]byte, error)
+}
+
+// storageResolver resolves references through a GraphLoader.
+type storageResolver struct {
+	loader GraphLoader
+	decode func([]byte) (*Graph, error)
+}
+
+// NewStorageResolver creates a GraphResolver that loads graphs by GraphRef.Key.
+// decode converts the stored bytes to a graph; if nil, they are parsed as JSON.
+func NewStorageResolver(loader GraphLoader, decode func([]byte) (*Graph, error)) GraphResolver {
+	if decode == nil {
+		decode = func(data []byte) (*Graph, error) { return FromJSON(string(data)) }
+	}
+	return &storageResolver{loader: loader, decode: decode}
+}
+
+// ResolveGraph loads and decodes the referenced graph.
+func (r *storageResolver) ResolveGraph(ctx context.Context, ref GraphRef) (*Graph, error) {
+	data, err := r.loader.Load(ctx, ref.Key())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load graph '%s': %w", ref.Key(), err)
+	}
+	g, err := r.decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode graph '%s': %w", ref.Key(), err)
+	}
+	return g, nil
+}
+
+// Flatten returns a copy of g in which every subgraph node is replaced by the
+// nodes of its graph, recursively.
+//
+// Inner node IDs are namespaced with the subgraph node ID ("sub.inner").
+// Edges into the subgraph node are redirected to the inner entry node, and
+// edges out of it leave from every inner exit node (end nodes, or nodes
+// without outgoing edges if the subgraph has no end nodes); a loop on the
+// subgraph node leads from the exits back to the entry. The subgraph's node
+// ID and mappings are recorded in the inner entry node's metadata under
+// "subgraph".
+//
+// A flattened graph has a single state: subgraph and parent keys share one
+// namespace. Mappings are applied by boundary nodes: an input mapping adds a
+// start node "sub.$input" before the inner entry node that copies the parent
+// keys to the subgraph keys, and an output mapping adds an end node
+// "sub.$output" after the exits that copies the subgraph keys to the parent
+// keys (see MappingMetadataKey).
+// The subgraph's default policy is folded into the policy of each inner node;
+// the policy of the subgraph node itself is not carried over.
+//
+// resolver may be nil if the graph only uses inline subgraphs. Flatten fails
+// if a subgraph (transitively) references one of its ancestors.
+func Flatten(ctx context.Context, g *Graph, resolver GraphResolver) (*Graph, error) {
+	return flatten(ctx, g, resolver, []string{g.ID})
+}
+
+func flatten(ctx context.Context, g *Graph, resolver GraphResolver, ancestors []string) (*Graph, error) {
+	out, err := g.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	for id, node := range g.Nodes {
+		sub, ok := node.(*SubgraphNode)
+		if !ok {
+			continue
+		}
+
+		key := subgraphKey(sub)
+		for _, ancestor := range ancestors {
+			if key != "" && key == ancestor {
+				return nil, &ValidationError{
+					Field:   "nodes." + id,
+					Message: fmt.Sprintf("recursive subgraph: %s -> %s", strings.Join(ancestors, " -> "), key),
+				}
+			}
+		}
+
+		inner, err := resolveSubgraph(ctx, sub, resolver)
+		if err != nil {
+			return nil, fmt.Errorf("subgraph node '%s': %w", id, err)
+		}
+
+		inner, err = flatten(ctx, inner, resolver, append(append([]string(nil), ancestors...), key))
+		if err != nil {
+			return nil, err
+		}
+		if err := inline(out, sub, inner); err != nil {
+			return nil, fmt.Errorf("subgraph node '%s': %w", id, err)
+		}
+	}
+	return out, nil
+}
+
+// subgraphKey returns the graph ID used for recursion detection.
+func subgraphKey(sub *SubgraphNode) string {
+	switch {
+	case sub.Ref != nil:
+		return sub.Ref.ID
+	case sub.Graph != nil:
+		return sub.Graph.ID
+	default:
+		return ""
+	}
+}
+
+// resolveSubgraph returns a private copy of the subgraph's graph.
+func resolveSubgraph(ctx context.Context, sub *SubgraphNode, resolver GraphResolver) (*Graph, error) {
+	if sub.Graph != nil {
+		return sub.Graph.Clone()
+	}
+	if sub.Ref == nil {
+		return nil, fmt.Errorf("subgraph node has no graph")
+	}
+	if resolver == nil {
+		return nil, fmt.Errorf("no resolver for graph reference '%s'", sub.Ref.Key())
+	}
+	return resolver.ResolveGraph(ctx, *sub.Ref)
+}
+
+// Names of the boundary nodes Flatten inserts to apply subgraph mappings,
+// below the subgraph node's namespace.
+const (
+	inputNodeName  = "$input"
+	outputNodeName = "$output"
+)
+
+// inline replaces sub in g with the namespaced nodes and edges of inner.
+func inline(g *Graph, sub *SubgraphNode, inner *Graph) error {
+	prefix := sub.ID + NamespaceSeparator
+	rename := func(id string) string { return prefix + id }
+
+	exits := make([]string, 0)
+	for id, node := range inner.Nodes {
+		if node.GetType() == NodeTypeEnd {
+			exits = append(exits, rename(id))
+		}
+	}
+	if len(exits) == 0 {
+		for id := range inner.Nodes {
+			if len(inner.GetOutgoingEdges(id)) == 0 {
+				exits = append(exits, rename(id))
+			}
+		}
+	}
+	sort.Strings(exits)
+
+	metadata := func() map[string]interface{} {
+		return map[string]interface{}{
+			"node":           sub.ID,
+			"graph_id":       inner.ID,
+			"input_mapping":  sub.InputMapping,
+			"output_mapping": sub.OutputMapping,
+		}
+	}
+
+	for id, node := range inner.Nodes {
+		based, ok := node.(interface{ Base() *BaseNode })
+		if !ok {
+			return fmt.Errorf("node '%s' of type '%s' cannot be namespaced", id, node.GetType())
+		}
+		if inner.Policy != nil || based.Base().Policy != nil {
+			// The subgraph's default policy is folded into its nodes.
+			policy := inner.EffectivePolicy(id)
+			for i := range policy.OnError {
+				policy.OnError[i].Target = rename(policy.OnError[i].Target)
+			}
+			based.Base().Policy = policy
+		}
+		based.Base().ID = rename(id)
+		if router, ok := node.(*RouterNode); ok {
+			for i := range router.Routes {
+				router.Routes[i].Target = rename(router.Routes[i].Target)
+			}
+			if router.DefaultRoute != "" {
+				router.DefaultRoute = rename(router.DefaultRoute)
+			}
+		}
+		if id == inner.EntryNode {
+			base := based.Base()
+			if base.Metadata == nil {
+				base.Metadata = make(map[string]interface{})
+			}
+			base.Metadata["subgraph"] = metadata()
+		}
+		if _, exists := g.Nodes[rename(id)]; exists {
+			return fmt.Errorf("namespaced node ID '%s' already exists", rename(id))
+		}
+		g.Nodes[rename(id)] = node
+	}
+
+	// Mappings are applied by boundary nodes around the inlined nodes.
+	entry := rename(inner.EntryNode)
+	var boundaries []*Edge
+	if len(sub.InputMapping) > 0 {
+		input := &StartNode{BaseNode: BaseNode{ID: rename(inputNodeName), Type: NodeTypeStart, Metadata: metadata()}}
+		input.Metadata[MappingMetadataKey] = copyMapping(sub.InputMapping)
+		if err := addBoundary(g, input); err != nil {
+			return err
+		}
+		boundaries = append(boundaries, NewEdge(input.ID, entry))
+		entry = input.ID
+	}
+	if len(sub.OutputMapping) > 0 {
+		output := &EndNode{BaseNode: BaseNode{ID: rename(outputNodeName), Type: NodeTypeEnd, Metadata: metadata()}}
+		mapping := make(map[string]string, len(sub.OutputMapping))
+		for key, parentKey := range sub.OutputMapping {
+			mapping[parentKey] = key
+		}
+		output.Metadata[MappingMetadataKey] = mapping
+		if err := addBoundary(g, output); err != nil {
+			return err
+		}
+		for _, exit := range exits {
+			boundaries = append(boundaries, NewEdge(exit, output.ID))
+		}
+		exits = []string{output.ID}
+	}
+
+	edges := make([]*Edge, 0, len(g.Edges)+len(inner.Edges)+len(boundaries))
+	for _, e := range g.Edges {
+		switch {
+		case e.To == sub.ID && e.From == sub.ID:
+			// A loop on the subgraph node runs the subgraph again.
+			for _, exit := range exits {
+				redirected := *e
+				redirected.From = exit
+				redirected.To = entry
+				edges = append(edges, &redirected)
+			}
+		case e.To == sub.ID:
+			redirected := *e
+			redirected.To = entry
+			edges = append(edges, &redirected)
+		case e.From == sub.ID:
+			for _, exit := range exits {
+				redirected := *e
+				redirected.From = exit
+				edges = append(edges, &redirected)
+			}
+		default:
+			edges = append(edges, e)
+		}
+	}
+	for _, e := range inner.Edges {
+		renamed := *e
+		renamed.From = rename(e.From)
+		renamed.To = rename(e.To)
+		edges = append(edges, &renamed)
+	}
+	g.Edges = append(edges, boundaries...)
+
+	delete(g.Nodes, sub.ID)
+	if g.EntryNode == sub.ID {
+		g.EntryNode = entry
+	}
+	redirectPolicy := func(policy *Policy) {
+		if policy == nil {
+			return
+		}
+		for i := range policy.OnError {
+			if policy.OnError[i].Target == sub.ID {
+				policy.OnError[i].Target = entry
+			}
+		}
+	}
+	redirectPolicy(g.Policy)
+	for _, node := range g.Nodes {
+		if based, ok := node.(interface{ Base() *BaseNode }); ok {
+			redirectPolicy(based.Base().Policy)
+		}
+		router, ok := node.(*RouterNode)
+		if !ok {
+			continue
+		}
+		for i := range router.Routes {
+			if router.Routes[i].Target == sub.ID {
+				router.Routes[i].Target = entry
+			}
+		}
+		if router.DefaultRoute == sub.ID {
+			router.DefaultRoute = entry
+		}
+	}
+	return nil
+}
+
+// addBoundary adds a boundary node inserted by inline.
+func addBoundary(g *Graph, node Node) error {
+	if _, exists := g.Nodes[node.GetID()]; exists {
+		return fmt.Errorf("namespaced node ID '%s' already exists", node.GetID())
+	}
+	g.Nodes[node.GetID()] = node
+	return nil
+}
+
+// copyMapping returns a copy of mapping.
+func copyMapping(mapping map[string]string) map[string]string {
+	out := make(map[string]string, len(mapping))
+	for k, v := range mapping {
+		out[k] = v
+	}
+	return out
+}
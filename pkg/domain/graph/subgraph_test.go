@@ -0,0 +1,356 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+)
+
+type mapLoader map[string]string
+
+func (l mapLoader) Load(ctx context.Context, graphID string) ([]byte, error) {
+	data, ok := l[graphID]
+	if !ok {
+		return nil, errors.New("graph not found")
+	}
+	return []byte(data), nil
+}
+
+func retrievalGraph(t *testing.T) *Graph {
+	t.Helper()
+	g, err := Build("retrieval").
+		ID("retrieval").
+		Start().
+		Tool("search", "vector_search", nil).
+		LLM("rerank", map[string]interface{}{"model": "gpt-4"}).
+		End()
+	if err != nil {
+		t.Fatalf("failed to build retrieval graph: %v", err)
+	}
+	return g
+}
+
+func subgraphNode(id string) *SubgraphNode {
+	return &SubgraphNode{BaseNode: BaseNode{ID: id, Type: NodeTypeSubgraph}}
+}
+
+func TestGraphRef_Key(t *testing.T) {
+	if key := (GraphRef{ID: "retrieval"}).Key(); key != "retrieval" {
+		t.Errorf("expected 'retrieval', got %q", key)
+	}
+	if key := (GraphRef{ID: "retrieval", Version: "2"}).Key(); key != "retrieval@2" {
+		t.Errorf("expected 'retrieval@2', got %q", key)
+	}
+}
+
+func TestSubgraphNode_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		node        *SubgraphNode
+		expectError bool
+	}{
+		{
+			name:        "reference",
+			node:        &SubgraphNode{BaseNode: BaseNode{ID: "sub"}, Ref: &GraphRef{ID: "retrieval"}},
+			expectError: false,
+		},
+		{
+			name:        "inline",
+			node:        &SubgraphNode{BaseNode: BaseNode{ID: "sub"}, Graph: retrievalGraph(t)},
+			expectError: false,
+		},
+		{
+			name:        "missing ID",
+			node:        &SubgraphNode{Ref: &GraphRef{ID: "retrieval"}},
+			expectError: true,
+		},
+		{
+			name:        "neither reference nor graph",
+			node:        &SubgraphNode{BaseNode: BaseNode{ID: "sub"}},
+			expectError: true,
+		},
+		{
+			name:        "both reference and graph",
+			node:        &SubgraphNode{BaseNode: BaseNode{ID: "sub"}, Ref: &GraphRef{ID: "retrieval"}, Graph: retrievalGraph(t)},
+			expectError: true,
+		},
+		{
+			name:        "empty reference ID",
+			node:        &SubgraphNode{BaseNode: BaseNode{ID: "sub"}, Ref: &GraphRef{}},
+			expectError: true,
+		},
+		{
+			name:        "invalid inline graph",
+			node:        &SubgraphNode{BaseNode: BaseNode{ID: "sub"}, Graph: NewGraph("empty")},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.node.Validate()
+			if tt.expectError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSubgraphNode_JSONRoundTrip(t *testing.T) {
+	sub := subgraphNode("retrieve")
+	sub.Ref = &GraphRef{ID: "retrieval", Version: "1.2"}
+	sub.InputMapping = map[string]string{"query": "question"}
+	sub.OutputMapping = map[string]string{"documents": "context"}
+
+	g, err := Build("qa").ID("qa").Start().Node(sub).End()
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+
+	jsonStr, err := g.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	decoded, err := FromJSON(jsonStr)
+	if err != nil {
+		t.Fatalf("FromJSON failed: %v", err)
+	}
+
+	got, ok := decoded.GetNode("retrieve").(*SubgraphNode)
+	if !ok {
+		t.Fatalf("expected *SubgraphNode, got %T", decoded.GetNode("retrieve"))
+	}
+	if got.Ref == nil || got.Ref.Key() != "retrieval@1.2" {
+		t.Errorf("unexpected reference: %+v", got.Ref)
+	}
+	if got.InputMapping["query"] != "question" || got.OutputMapping["documents"] != "context" {
+		t.Errorf("mappings not preserved: %+v", got)
+	}
+}
+
+func TestGraphValidate_RecursiveSubgraph(t *testing.T) {
+	// Direct self-reference.
+	sub := subgraphNode("self")
+	sub.Ref = &GraphRef{ID: "loop"}
+	g, err := Build("loop").ID("loop").Node(sub).Graph()
+	if err == nil || !strings.Contains(err.Error(), "recursive subgraph") {
+		t.Errorf("expected recursive subgraph error, got %v (graph %v)", err, g)
+	}
+
+	// Inline graph that references its parent.
+	inner := retrievalGraph(t)
+	back := subgraphNode("back")
+	back.Ref = &GraphRef{ID: "outer"}
+	if err := inner.AddNode(back); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	wrapper := subgraphNode("inner")
+	wrapper.Graph = inner
+	_, err = Build("outer").ID("outer").Node(wrapper).Graph()
+	if err == nil || !strings.Contains(err.Error(), "outer -> retrieval -> outer") {
+		t.Errorf("expected transitive recursion error, got %v", err)
+	}
+
+	// Inline graph without an ID that contains itself.
+	cyclic := retrievalGraph(t)
+	cyclic.ID = ""
+	self := subgraphNode("again")
+	self.Graph = cyclic
+	cyclic.Nodes["again"] = self
+	holder := retrievalGraph(t)
+	wrapper = subgraphNode("holder")
+	wrapper.Graph = cyclic
+	holder.Nodes["holder"] = wrapper
+	err = holder.Validate()
+	if err == nil || !strings.Contains(err.Error(), "inline graph contains itself") {
+		t.Errorf("expected recursion error for self-containing inline graph, got %v", err)
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	sub := subgraphNode("retrieve")
+	sub.Graph = retrievalGraph(t)
+	sub.InputMapping = map[string]string{"query": "question"}
+
+	g, err := Build("qa").ID("qa").
+		Start().
+		Node(sub).
+		LLM("answer", map[string]interface{}{"model": "gpt-4"}).
+		End()
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+
+	flat, err := Flatten(context.Background(), g, nil)
+	if err != nil {
+		t.Fatalf("Flatten failed: %v", err)
+	}
+	if err := flat.Validate(); err != nil {
+		t.Fatalf("flattened graph is invalid: %v", err)
+	}
+
+	if flat.GetNode("retrieve") != nil {
+		t.Error("subgraph node should be replaced")
+	}
+	for _, id := range []string{"retrieve.start", "retrieve.search", "retrieve.rerank", "retrieve.end"} {
+		node := flat.GetNode(id)
+		if node == nil {
+			t.Errorf("expected namespaced node %q", id)
+			continue
+		}
+		if node.GetID() != id {
+			t.Errorf("expected node ID %q, got %q", id, node.GetID())
+		}
+	}
+
+	hasEdge := func(from, to string) bool {
+		for _, e := range flat.Edges {
+			if e.From == from && e.To == to {
+				return true
+			}
+		}
+		return false
+	}
+	for _, e := range [][2]string{
+		{"start", "retrieve.$input"},
+		{"retrieve.$input", "retrieve.start"},
+		{"retrieve.start", "retrieve.search"},
+		{"retrieve.rerank", "retrieve.end"},
+		{"retrieve.end", "answer"},
+	} {
+		if !hasEdge(e[0], e[1]) {
+			t.Errorf("missing edge %s->%s", e[0], e[1])
+		}
+	}
+
+	entry := flat.GetNode("retrieve.start").(*StartNode)
+	meta, ok := entry.Metadata["subgraph"].(map[string]interface{})
+	if !ok || meta["node"] != "retrieve" {
+		t.Errorf("expected subgraph metadata on entry node, got %v", entry.Metadata)
+	}
+
+	// The input mapping is applied by a boundary node; there is no output mapping.
+	input, ok := flat.GetNode("retrieve.$input").(*StartNode)
+	if !ok {
+		t.Fatalf("expected an input boundary node, got %v", flat.GetNode("retrieve.$input"))
+	}
+	mapped, err := input.Execute(context.Background(), state.State{"question": "what is go?"})
+	if err != nil || mapped.Get("query") != "what is go?" {
+		t.Errorf("expected the question mapped to the query, got %v, %v", mapped, err)
+	}
+	if flat.GetNode("retrieve.$output") != nil {
+		t.Error("unexpected output boundary node")
+	}
+
+	// The original graph is left untouched.
+	if g.GetNode("retrieve") == nil || sub.Graph.GetNode("search").GetID() != "search" {
+		t.Error("Flatten modified its input")
+	}
+}
+
+func TestFlatten_OutputMappingAndLoops(t *testing.T) {
+	sub := subgraphNode("retrieve")
+	sub.Graph = retrievalGraph(t)
+	sub.OutputMapping = map[string]string{"documents": "context"}
+	g := NewGraph("qa")
+	g.EntryNode = "retrieve"
+	if err := g.AddNode(sub); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	// AddEdge rejects loops on a node, but decoded graphs may contain them.
+	g.Edges = append(g.Edges, &Edge{From: "retrieve", To: "retrieve", Condition: "state.retry == true"})
+
+	flat, err := Flatten(context.Background(), g, nil)
+	if err != nil {
+		t.Fatalf("Flatten failed: %v", err)
+	}
+	if flat.EntryNode != "retrieve.start" {
+		t.Errorf("expected the inner entry node as entry, got %q", flat.EntryNode)
+	}
+
+	var edges []string
+	for _, e := range flat.Edges {
+		edges = append(edges, e.From+"->"+e.To)
+		if e.From == "retrieve.$output" && e.Condition != "state.retry == true" {
+			t.Errorf("expected the loop to keep its condition, got %+v", e)
+		}
+	}
+	for _, want := range []string{"retrieve.end->retrieve.$output", "retrieve.$output->retrieve.start"} {
+		if !strings.Contains(strings.Join(edges, " "), want) {
+			t.Errorf("missing edge %s in %v", want, edges)
+		}
+	}
+
+	// Boundary mappings survive a JSON round trip.
+	clone, err := flat.Clone()
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	mapped, err := clone.GetNode("retrieve.$output").Execute(context.Background(), state.State{"documents": []interface{}{"a"}})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if docs, ok := mapped.Get("context").([]interface{}); !ok || len(docs) != 1 {
+		t.Errorf("expected the documents mapped to the context, got %v", mapped)
+	}
+}
+
+func TestFlatten_ReferencesAndRouters(t *testing.T) {
+	inner := retrievalGraph(t)
+	innerJSON, err := inner.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	resolver := NewStorageResolver(mapLoader{"retrieval@1": innerJSON}, nil)
+
+	sub := subgraphNode("retrieve")
+	sub.Ref = &GraphRef{ID: "retrieval", Version: "1"}
+	g, err := Build("qa").ID("qa").
+		Start().
+		Router("needs_context", When("state.needs_context == true", "retrieve")).Default("answer").
+		Node(sub).
+		LLM("answer", map[string]interface{}{"model": "gpt-4"}).
+		End()
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+
+	flat, err := Flatten(context.Background(), g, resolver)
+	if err != nil {
+		t.Fatalf("Flatten failed: %v", err)
+	}
+	if err := flat.Validate(); err != nil {
+		t.Fatalf("flattened graph is invalid: %v", err)
+	}
+	router := flat.GetNode("needs_context").(*RouterNode)
+	if router.Routes[0].Target != "retrieve.start" {
+		t.Errorf("expected route to inner entry, got %q", router.Routes[0].Target)
+	}
+
+	if _, err := Flatten(context.Background(), g, nil); err == nil {
+		t.Error("expected error without resolver")
+	}
+
+	// A stored graph that references the graph being flattened.
+	loop := subgraphNode("loop")
+	loop.Ref = &GraphRef{ID: "qa"}
+	cyclic := retrievalGraph(t)
+	if err := cyclic.AddNode(loop); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	cyclicJSON, err := cyclic.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON failed: %v", err)
+	}
+	resolver = NewStorageResolver(mapLoader{"retrieval@1": cyclicJSON}, nil)
+	_, err = Flatten(context.Background(), g, resolver)
+	if err == nil || !strings.Contains(err.Error(), "qa -> retrieval -> qa") {
+		t.Errorf("expected recursion error, got %v", err)
+	}
+}
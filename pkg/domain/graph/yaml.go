@@ -0,0 +1,394 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IncludeKey is the mapping key that pulls the contents of another YAML file
+// into the mapping that contains it.
+//
+// The value is a path (or a list of paths) relative to the including file.
+// Included files must contain a mapping, whose keys are merged into the
+// including mapping; keys written next to $include take precedence. A mapping
+// that consists only of $include is replaced by the included document, which
+// may then be of any kind.
+const IncludeKey = "$include"
+
+// MaxYAMLNodes caps the number of nodes a YAML document expands to, aliases
+// and includes included, so that documents aliasing anchors over and over
+// ("billion laughs") are rejected instead of exhausting memory.
+const MaxYAMLNodes = 100000
+
+// Position is a location in a YAML source document.
+type Position struct {
+	// File is the name of the source file, or empty for in-memory documents.
+	File string `json:"file,omitempty"`
+
+	// Line is the 1-based line number.
+	Line int `json:"line"`
+
+	// Column is the 1-based column number.
+	Column int `json:"column"`
+}
+
+// String formats the position as "file:line:column".
+func (p Position) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// YAMLDocument is a YAML graph definition converted to the JSON data model,
+// with includes and anchors resolved.
+type YAMLDocument struct {
+	// Data is the document as JSON-compatible maps, slices and scalars.
+	Data interface{}
+
+	// Positions maps JSON Pointers (RFC 6901) within Data to their source position.
+	// Mapping entries point at their key.
+	Positions map[string]Position
+}
+
+// PositionOf returns the source position of the value at pointer, falling back
+// to the closest ancestor that has a known position.
+func (d *YAMLDocument) PositionOf(pointer string) (Position, bool) {
+	for {
+		if pos, ok := d.Positions[pointer]; ok {
+			return pos, true
+		}
+		if pointer == "" {
+			return Position{}, false
+		}
+		idx := strings.LastIndex(pointer, "/")
+		if idx < 0 {
+			pointer = ""
+		} else {
+			pointer = pointer[:idx]
+		}
+	}
+}
+
+// JSON returns the document encoded as JSON.
+func (d *YAMLDocument) JSON() ([]byte, error) {
+	return json.Marshal(d.Data)
+}
+
+// ParseYAML parses a YAML document into the JSON data model.
+//
+// name is the path of the document within fsys and is used to resolve
+// $include paths and to label positions. If fsys is nil, documents using
+// $include are rejected.
+func ParseYAML(data []byte, name string, fsys fs.FS) (*YAMLDocument, error) {
+	p := &yamlParser{
+		fsys:      fsys,
+		positions: make(map[string]Position),
+		including: make(map[string]bool),
+		aliasing:  make(map[*yaml.Node]bool),
+	}
+	value, err := p.parseDocument(data, name)
+	if err != nil {
+		return nil, err
+	}
+	return &YAMLDocument{Data: value, Positions: p.positions}, nil
+}
+
+// FromYAML deserializes a graph from a YAML document.
+// YAML anchors, aliases and merge keys are supported; $include is not, as
+// there is no file system to resolve it against (see LoadYAMLFile).
+func FromYAML(data []byte) (*Graph, error) {
+	doc, err := ParseYAML(data, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return fromYAMLDocument(doc)
+}
+
+// LoadYAMLFile reads a YAML graph definition from disk, resolving $include
+// paths relative to the file.
+func LoadYAMLFile(filename string) (*Graph, error) {
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+	return FromYAMLFS(os.DirFS(dir), base)
+}
+
+// FromYAMLFS reads a YAML graph definition from fsys, resolving $include
+// paths relative to the file.
+func FromYAMLFS(fsys fs.FS, name string) (*Graph, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read YAML graph: %w", err)
+	}
+	doc, err := ParseYAML(data, name, fsys)
+	if err != nil {
+		return nil, err
+	}
+	return fromYAMLDocument(doc)
+}
+
+func fromYAMLDocument(doc *YAMLDocument) (*Graph, error) {
+	data, err := doc.JSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert YAML graph to JSON: %w", err)
+	}
+	var g Graph
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal graph from YAML: %w", err)
+	}
+	return &g, nil
+}
+
+// ToYAML serializes the graph to YAML.
+func (g *Graph) ToYAML() (string, error) {
+	data, err := json.Marshal(g)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal graph: %w", err)
+	}
+
+	// Decode into a yaml.Node so that keys keep the JSON field order.
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return "", fmt.Errorf("failed to convert graph to YAML: %w", err)
+	}
+	clearStyle(&node)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return "", fmt.Errorf("failed to marshal graph to YAML: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return "", fmt.Errorf("failed to marshal graph to YAML: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// clearStyle switches JSON flow style to YAML block style.
+func clearStyle(n *yaml.Node) {
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!str" {
+		n.Style = 0
+	}
+	if n.Kind == yaml.MappingNode || n.Kind == yaml.SequenceNode {
+		n.Style = 0
+	}
+	for _, c := range n.Content {
+		clearStyle(c)
+	}
+}
+
+// yamlParser converts yaml.Node trees to the JSON data model.
+type yamlParser struct {
+	fsys      fs.FS
+	positions map[string]Position
+	including map[string]bool
+	aliasing  map[*yaml.Node]bool // anchors being expanded
+	nodes     int
+}
+
+func (p *yamlParser) parseDocument(data []byte, name string) (interface{}, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML %s: %w", displayName(name), err)
+	}
+	if root.Kind == 0 {
+		return nil, nil
+	}
+	return p.convert(&root, name, "")
+}
+
+func (p *yamlParser) convert(n *yaml.Node, file, pointer string) (interface{}, error) {
+	p.nodes++
+	if p.nodes > MaxYAMLNodes {
+		return nil, fmt.Errorf("%s: document expands to more than %d nodes", Position{File: file, Line: n.Line, Column: n.Column}, MaxYAMLNodes)
+	}
+	if _, ok := p.positions[pointer]; !ok {
+		p.positions[pointer] = Position{File: file, Line: n.Line, Column: n.Column}
+	}
+
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil, nil
+		}
+		return p.convert(n.Content[0], file, pointer)
+	case yaml.AliasNode:
+		if p.aliasing[n.Alias] {
+			return nil, fmt.Errorf("%s: alias *%s refers to itself", Position{File: file, Line: n.Line, Column: n.Column}, n.Value)
+		}
+		p.aliasing[n.Alias] = true
+		v, err := p.convert(n.Alias, file, pointer)
+		delete(p.aliasing, n.Alias)
+		return v, err
+	case yaml.SequenceNode:
+		out := make([]interface{}, 0, len(n.Content))
+		for i, item := range n.Content {
+			v, err := p.convert(item, file, fmt.Sprintf("%s/%d", pointer, i))
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	case yaml.MappingNode:
+		return p.convertMapping(n, file, pointer)
+	case yaml.ScalarNode:
+		var v interface{}
+		if err := n.Decode(&v); err != nil {
+			return nil, fmt.Errorf("%s: %w", Position{File: file, Line: n.Line, Column: n.Column}, err)
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("%s: unsupported YAML node kind %d", Position{File: file, Line: n.Line, Column: n.Column}, n.Kind)
+	}
+}
+
+func (p *yamlParser) convertMapping(n *yaml.Node, file, pointer string) (interface{}, error) {
+	out := make(map[string]interface{})
+	var includes []*yaml.Node
+	var merges []*yaml.Node
+
+	// Explicit keys are converted first so that they take precedence over
+	// merged (<<) and included ($include) keys.
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		keyNode, valNode := n.Content[i], n.Content[i+1]
+		switch {
+		case keyNode.Tag == "!!merge" || keyNode.Value == "<<":
+			merges = append(merges, valNode)
+			continue
+		case keyNode.Value == IncludeKey:
+			includes = append(includes, valNode)
+			continue
+		}
+
+		key := keyNode.Value
+		childPointer := pointer + "/" + escapePointer(key)
+		p.positions[childPointer] = Position{File: file, Line: keyNode.Line, Column: keyNode.Column}
+		v, err := p.convert(valNode, file, childPointer)
+		if err != nil {
+			return nil, err
+		}
+		out[key] = v
+	}
+
+	for _, m := range merges {
+		sources := []*yaml.Node{m}
+		if m.Kind == yaml.SequenceNode {
+			sources = m.Content
+		}
+		for _, src := range sources {
+			v, err := p.convert(src, file, pointer)
+			if err != nil {
+				return nil, err
+			}
+			merged, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: merge key requires a mapping", Position{File: file, Line: src.Line, Column: src.Column})
+			}
+			for k, val := range merged {
+				if _, exists := out[k]; !exists {
+					out[k] = val
+				}
+			}
+		}
+	}
+
+	for _, inc := range includes {
+		included, err := p.include(inc, file, pointer)
+		if err != nil {
+			return nil, err
+		}
+		if len(n.Content) == 2 && len(included) == 1 {
+			if _, isMap := included[0].(map[string]interface{}); !isMap {
+				return included[0], nil
+			}
+		}
+		for _, v := range included {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: included document must be a mapping to merge with other keys", Position{File: file, Line: inc.Line, Column: inc.Column})
+			}
+			for k, val := range m {
+				if _, exists := out[k]; !exists {
+					out[k] = val
+				}
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// include loads the documents referenced by an $include value.
+func (p *yamlParser) include(n *yaml.Node, file, pointer string) ([]interface{}, error) {
+	pos := Position{File: file, Line: n.Line, Column: n.Column}
+	if p.fsys == nil {
+		return nil, fmt.Errorf("%s: %s is not supported without a file system", pos, IncludeKey)
+	}
+
+	var paths []string
+	switch n.Kind {
+	case yaml.ScalarNode:
+		paths = []string{n.Value}
+	case yaml.SequenceNode:
+		for _, item := range n.Content {
+			if item.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("%s: %s entries must be paths", pos, IncludeKey)
+			}
+			paths = append(paths, item.Value)
+		}
+	default:
+		return nil, fmt.Errorf("%s: %s must be a path or a list of paths", pos, IncludeKey)
+	}
+
+	out := make([]interface{}, 0, len(paths))
+	for _, rel := range paths {
+		target := path.Clean(path.Join(path.Dir(file), rel))
+		if p.including[target] {
+			return nil, fmt.Errorf("%s: include cycle detected at %s", pos, target)
+		}
+
+		data, err := fs.ReadFile(p.fsys, target)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to include %s: %w", pos, rel, err)
+		}
+
+		p.including[target] = true
+		var root yaml.Node
+		if err := yaml.Unmarshal(data, &root); err != nil {
+			delete(p.including, target)
+			return nil, fmt.Errorf("failed to parse YAML %s: %w", target, err)
+		}
+		v, err := p.convert(&root, target, pointer)
+		delete(p.including, target)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// escapePointer escapes a key for use as a JSON Pointer reference token.
+func escapePointer(key string) string {
+	key = strings.ReplaceAll(key, "~", "~0")
+	return strings.ReplaceAll(key, "/", "~1")
+}
+
+func displayName(name string) string {
+	if name == "" {
+		return "document"
+	}
+	return name
+}
@@ -0,0 +1,146 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadYAMLFile_IncludesAndAnchors(t *testing.T) {
+	g, err := LoadYAMLFile("testdata/pipeline.yaml")
+	if err != nil {
+		t.Fatalf("LoadYAMLFile failed: %v", err)
+	}
+
+	if g.ID != "pipeline" || g.Version != "1.0" {
+		t.Errorf("unexpected graph header: id=%q version=%q", g.ID, g.Version)
+	}
+	if g.NodeCount() != 3 || g.EdgeCount() != 2 {
+		t.Errorf("expected 3 nodes and 2 edges, got %d and %d", g.NodeCount(), g.EdgeCount())
+	}
+
+	summarize, ok := g.GetNode("summarize").(*ExecutorNode)
+	if !ok {
+		t.Fatalf("expected included node 'summarize', got %T", g.GetNode("summarize"))
+	}
+	if summarize.Config["model"] != "gpt-4" {
+		t.Errorf("included node config not decoded: %v", summarize.Config)
+	}
+
+	draft := g.GetNode("draft").(*ExecutorNode)
+	if draft.Config["model"] != "gpt-4" {
+		t.Errorf("expected anchored model to be merged, got %v", draft.Config)
+	}
+	if draft.Config["temperature"] != 0.9 {
+		t.Errorf("expected explicit key to override merged value, got %v", draft.Config["temperature"])
+	}
+
+	if err := g.Validate(); err != nil {
+		t.Errorf("loaded graph is invalid: %v", err)
+	}
+}
+
+func TestParseYAML_Positions(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.yaml":  {Data: []byte("id: g\nnodes:\n  $include: nodes.yaml\nentry_node: a\n")},
+		"nodes.yaml": {Data: []byte("a:\n  id: a\n  type: start\n")},
+	}
+	doc, err := ParseYAML(fsys["main.yaml"].Data, "main.yaml", fsys)
+	if err != nil {
+		t.Fatalf("ParseYAML failed: %v", err)
+	}
+
+	pos, ok := doc.PositionOf("/entry_node")
+	if !ok || pos.File != "main.yaml" || pos.Line != 4 || pos.Column != 1 {
+		t.Errorf("unexpected position for /entry_node: %v", pos)
+	}
+
+	pos, ok = doc.PositionOf("/nodes/a/type")
+	if !ok || pos.File != "nodes.yaml" || pos.Line != 3 || pos.Column != 3 {
+		t.Errorf("unexpected position for included key: %v", pos)
+	}
+
+	pos, ok = doc.PositionOf("/nodes/a/type/missing")
+	if !ok || pos.Line != 3 {
+		t.Errorf("expected fallback to ancestor position, got %v", pos)
+	}
+}
+
+func TestParseYAML_IncludeErrors(t *testing.T) {
+	if _, err := FromYAML([]byte("nodes:\n  $include: other.yaml\n")); err == nil {
+		t.Error("expected error for $include without a file system")
+	}
+
+	fsys := fstest.MapFS{
+		"a.yaml": {Data: []byte("x:\n  $include: b.yaml\n")},
+		"b.yaml": {Data: []byte("y:\n  $include: a.yaml\n")},
+	}
+	_, err := ParseYAML(fsys["a.yaml"].Data, "a.yaml", fsys)
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected include cycle error, got %v", err)
+	}
+}
+
+func TestGraphToYAMLRoundTrip(t *testing.T) {
+	g := NewGraph("yaml")
+	g.Version = "1.0"
+	_ = g.AddNode(&ExecutorNode{
+		BaseNode:     BaseNode{ID: "llm", Type: NodeTypeExecutor},
+		ExecutorType: "llm",
+		Config:       map[string]interface{}{"model": "gpt-4", "stop": "true"},
+	})
+	g.EntryNode = "llm"
+
+	out, err := g.ToYAML()
+	if err != nil {
+		t.Fatalf("ToYAML failed: %v", err)
+	}
+	if strings.Contains(out, "{") {
+		t.Errorf("expected block style YAML, got:\n%s", out)
+	}
+
+	back, err := FromYAML([]byte(out))
+	if err != nil {
+		t.Fatalf("FromYAML failed: %v\n%s", err, out)
+	}
+	if back.Version != "1.0" {
+		t.Errorf("expected version string '1.0', got %q", back.Version)
+	}
+	exec := back.GetNode("llm").(*ExecutorNode)
+	if exec.Config["stop"] != "true" {
+		t.Errorf("expected string 'true' to survive round trip, got %#v", exec.Config["stop"])
+	}
+}
+
+func TestParseYAML_AliasCycle(t *testing.T) {
+	for _, doc := range []string{
+		"a: &x\n  b: *x\n",
+		"a: &x\n  <<: *x\n  b: 1\n",
+	} {
+		if _, err := ParseYAML([]byte(doc), "", nil); err == nil || !strings.Contains(err.Error(), "refers to itself") {
+			t.Errorf("expected a cycle error for %q, got %v", doc, err)
+		}
+	}
+}
+
+func TestParseYAML_AliasExpansionLimit(t *testing.T) {
+	doc := `a: &a ["lol","lol","lol","lol","lol","lol","lol","lol","lol"]
+b: &b [*a,*a,*a,*a,*a,*a,*a,*a,*a]
+c: &c [*b,*b,*b,*b,*b,*b,*b,*b,*b]
+d: &d [*c,*c,*c,*c,*c,*c,*c,*c,*c]
+e: &e [*d,*d,*d,*d,*d,*d,*d,*d,*d]
+f: &f [*e,*e,*e,*e,*e,*e,*e,*e,*e]
+g: &g [*f,*f,*f,*f,*f,*f,*f,*f,*f]
+h: &h [*g,*g,*g,*g,*g,*g,*g,*g,*g]
+i: &i [*h,*h,*h,*h,*h,*h,*h,*h,*h]
+`
+	if _, err := ParseYAML([]byte(doc), "", nil); err == nil || !strings.Contains(err.Error(), "more than") {
+		t.Errorf("expected the expansion to be rejected, got %v", err)
+	}
+
+	// Reusing anchors within the limit still works.
+	g, err := FromYAML([]byte("id: g\nname: g\nversion: \"1\"\nmeta: &m {owner: team}\nmetadata: *m\n"))
+	if err != nil || g.Metadata["owner"] != "team" {
+		t.Errorf("expected the alias to expand, got %v (%v)", g, err)
+	}
+}
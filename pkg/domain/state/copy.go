@@ -0,0 +1,218 @@
+package state
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+// DeepCopier is implemented by values that know how to copy themselves.
+// DeepCopyValue uses it for types outside the JSON-compatible value universe.
+type DeepCopier interface {
+	DeepCopy() interface{}
+}
+
+// DeepCopy returns a deep copy of the state without a JSON round trip.
+// Numeric types, time.Time and other scalar values keep their Go types.
+func (s State) DeepCopy() State {
+	if s == nil {
+		return nil
+	}
+	out := make(State, len(s))
+	for k, v := range s {
+		out[k] = DeepCopyValue(v)
+	}
+	return out
+}
+
+// DeepCopyValue returns a deep copy of a value.
+//
+// Maps and slices of the shapes produced by encoding/json (and their common
+// typed variants) are copied recursively without reflection. Scalars,
+// time.Time and json.Number are returned as-is since they are immutable.
+// Values implementing DeepCopier are copied through DeepCopy. Other structs,
+// pointers, maps, slices and arrays are copied recursively with reflection,
+// preserving pointers shared within the value and cycles; unexported struct
+// fields are copied shallowly. Lazy values, channels and funcs are shared by
+// reference rather than failing the copy.
+func DeepCopyValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case nil, bool, string, json.Number, time.Time,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64,
+		float32, float64:
+		return val
+	case map[string]interface{}:
+		return copyMap(val)
+	case State:
+		return val.DeepCopy()
+	case []interface{}:
+		if val == nil {
+			return val
+		}
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = DeepCopyValue(item)
+		}
+		return out
+	case []map[string]interface{}:
+		if val == nil {
+			return val
+		}
+		out := make([]map[string]interface{}, len(val))
+		for i, item := range val {
+			out[i] = copyMap(item)
+		}
+		return out
+	case map[string]string:
+		if val == nil {
+			return val
+		}
+		out := make(map[string]string, len(val))
+		for k, item := range val {
+			out[k] = item
+		}
+		return out
+	case []string:
+		return copySlice(val)
+	case []int:
+		return copySlice(val)
+	case []int64:
+		return copySlice(val)
+	case []float64:
+		return copySlice(val)
+	case []bool:
+		return copySlice(val)
+	case []byte:
+		return copySlice(val)
+	case *time.Time:
+		if val == nil {
+			return val
+		}
+		t := *val
+		return &t
+	case DeepCopier:
+		return val.DeepCopy()
+	case LazyValue:
+		return val
+	default:
+		c := copier{seen: make(map[seenKey]reflect.Value)}
+		return c.copy(reflect.ValueOf(val)).Interface()
+	}
+}
+
+// copier deep-copies values of any type with reflection. It remembers the
+// copy of each pointer, map and slice, so that values referenced twice are
+// copied once and cycles terminate.
+type copier struct {
+	seen map[seenKey]reflect.Value
+}
+
+// seenKey identifies a copied pointer, map or slice.
+type seenKey struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+func (c *copier) copy(v reflect.Value) reflect.Value {
+	if v.CanInterface() {
+		switch val := v.Interface().(type) {
+		case time.Time, json.Number, LazyValue:
+			return v
+		case DeepCopier:
+			if v.Kind() != reflect.Pointer || !v.IsNil() {
+				if out := reflect.ValueOf(val.DeepCopy()); out.IsValid() && out.Type().AssignableTo(v.Type()) {
+					return out
+				}
+			}
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		key := seenKey{ptr: v.Pointer(), typ: v.Type()}
+		if out, ok := c.seen[key]; ok {
+			return out
+		}
+		out := reflect.New(v.Type().Elem())
+		c.seen[key] = out
+		out.Elem().Set(c.copy(v.Elem()))
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		key := seenKey{ptr: v.Pointer(), typ: v.Type()}
+		if out, ok := c.seen[key]; ok {
+			return out
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		c.seen[key] = out
+		for iter := v.MapRange(); iter.Next(); {
+			out.SetMapIndex(c.copy(iter.Key()), c.copy(iter.Value()))
+		}
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		key := seenKey{ptr: v.Pointer(), typ: v.Type(), len: v.Len()}
+		if out, ok := c.seen[key]; ok {
+			return out
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		c.seen[key] = out
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(c.copy(v.Index(i)))
+		}
+		return out
+	case reflect.Array:
+		out := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(c.copy(v.Index(i)))
+		}
+		return out
+	case reflect.Struct:
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if field := out.Field(i); field.CanSet() {
+				field.Set(c.copy(v.Field(i)))
+			}
+		}
+		return out
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(c.copy(v.Elem()))
+		return out
+	default:
+		return v
+	}
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = DeepCopyValue(v)
+	}
+	return out
+}
+
+func copySlice[T any](s []T) []T {
+	if s == nil {
+		return nil
+	}
+	out := make([]T, len(s))
+	copy(out, s)
+	return out
+}
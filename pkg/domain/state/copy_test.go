@@ -0,0 +1,185 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeepCopyPreservesTypes(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 123, time.UTC)
+	s := NewState()
+	s.Set("int", 42)
+	s.Set("int64", int64(7))
+	s.Set("time", now)
+	s.Set("ch", make(chan int))
+	s.Set("fn", func() {})
+
+	c := s.DeepCopy()
+
+	if v, ok := c["int"].(int); !ok || v != 42 {
+		t.Errorf("expected int 42, got %T %v", c["int"], c["int"])
+	}
+	if v, ok := c["int64"].(int64); !ok || v != 7 {
+		t.Errorf("expected int64 7, got %T %v", c["int64"], c["int64"])
+	}
+	if v, ok := c["time"].(time.Time); !ok || !v.Equal(now) || v.Nanosecond() != 123 {
+		t.Errorf("expected time with full precision, got %v", c["time"])
+	}
+	if c["ch"] == nil || c["fn"] == nil {
+		t.Error("expected non-JSON values to be carried over")
+	}
+}
+
+func TestDeepCopyIsIndependent(t *testing.T) {
+	s := NewState()
+	s.Set("map", map[string]interface{}{
+		"list":   []interface{}{"a", map[string]interface{}{"k": "v"}},
+		"labels": map[string]string{"env": "dev"},
+	})
+	s.Set("tags", []string{"x", "y"})
+
+	c, err := s.Copy()
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+
+	m := s["map"].(map[string]interface{})
+	m["list"].([]interface{})[1].(map[string]interface{})["k"] = "changed"
+	m["labels"].(map[string]string)["env"] = "prod"
+	s["tags"].([]string)[0] = "changed"
+
+	cm := c["map"].(map[string]interface{})
+	if got := cm["list"].([]interface{})[1].(map[string]interface{})["k"]; got != "v" {
+		t.Errorf("nested map in copy was modified: %v", got)
+	}
+	if got := cm["labels"].(map[string]string)["env"]; got != "dev" {
+		t.Errorf("typed map in copy was modified: %v", got)
+	}
+	if got := c["tags"].([]string)[0]; got != "x" {
+		t.Errorf("typed slice in copy was modified: %v", got)
+	}
+}
+
+type copierValue struct{ n int }
+
+func (c *copierValue) DeepCopy() interface{} {
+	return &copierValue{n: c.n}
+}
+
+func TestDeepCopyValue_DeepCopier(t *testing.T) {
+	orig := &copierValue{n: 1}
+	copied := DeepCopyValue(orig).(*copierValue)
+	if copied == orig {
+		t.Error("expected DeepCopier to produce a new value")
+	}
+	if copied.n != 1 {
+		t.Errorf("expected n=1, got %d", copied.n)
+	}
+}
+
+type document struct {
+	Title  string
+	Tags   []string
+	Author *author
+	Meta   map[string]interface{}
+	hidden *author
+}
+
+type author struct {
+	Name  string
+	Other *author
+}
+
+func TestDeepCopyValue_StructsAndPointers(t *testing.T) {
+	shared := &author{Name: "ada"}
+	shared.Other = shared
+	hidden := &author{Name: "hidden"}
+	orig := &document{
+		Title:  "draft",
+		Tags:   []string{"a"},
+		Author: shared,
+		Meta:   map[string]interface{}{"editor": shared, "rev": []int{1}},
+		hidden: hidden,
+	}
+
+	copied := DeepCopyValue(orig).(*document)
+	if copied == orig || copied.Author == orig.Author {
+		t.Fatal("expected pointers to be copied")
+	}
+	orig.Title = "changed"
+	orig.Tags[0] = "changed"
+	orig.Author.Name = "changed"
+	orig.Meta["rev"].([]int)[0] = 2
+
+	if copied.Title != "draft" || copied.Tags[0] != "a" || copied.Author.Name != "ada" || copied.Meta["rev"].([]int)[0] != 1 {
+		t.Errorf("copy was modified through the original: %+v", copied)
+	}
+	if copied.Author.Other != copied.Author || copied.Meta["editor"] != copied.Author {
+		t.Error("expected shared pointers and cycles to be preserved in the copy")
+	}
+	if copied.hidden != hidden {
+		t.Error("expected unexported fields to be copied shallowly")
+	}
+
+	value := DeepCopyValue(author{Name: "grace", Other: shared}).(author)
+	if value.Other == shared || value.Other.Name != "changed" {
+		t.Errorf("expected struct values to copy their pointers, got %+v", value)
+	}
+}
+
+// benchmarkState builds a state resembling an LLM-heavy execution.
+func benchmarkState() State {
+	s := NewState()
+	for i := 0; i < 50; i++ {
+		s.Set(fmt.Sprintf("message_%d", i), map[string]interface{}{
+			"role":    "assistant",
+			"content": strings.Repeat("lorem ipsum ", 200),
+			"tokens":  1200,
+			"tools":   []interface{}{"search", "fetch", map[string]interface{}{"name": "calc"}},
+		})
+	}
+	return s
+}
+
+// jsonCopy is the previous Copy implementation, kept for comparison.
+func jsonCopy(s State) (State, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	var out State
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func BenchmarkCopyJSON(b *testing.B) {
+	s := benchmarkState()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := jsonCopy(s); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDeepCopy(b *testing.B) {
+	s := benchmarkState()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = s.DeepCopy()
+	}
+}
+
+func BenchmarkOverlayFork(b *testing.B) {
+	o := NewOverlay(benchmarkState())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		branch := o.Fork()
+		branch.Set("step", i)
+	}
+}
@@ -0,0 +1,154 @@
+package state
+
+import "sort"
+
+// layer is one level of an Overlay. Layers below the top of an Overlay are
+// frozen and may be shared by several overlays.
+type layer struct {
+	parent  *layer
+	values  map[string]interface{}
+	deleted map[string]struct{}
+}
+
+func (l *layer) lookup(key string) (interface{}, bool) {
+	for cur := l; cur != nil; cur = cur.parent {
+		if _, ok := cur.deleted[key]; ok {
+			return nil, false
+		}
+		if val, ok := cur.values[key]; ok {
+			return val, true
+		}
+	}
+	return nil, false
+}
+
+// Overlay is a copy-on-write view over a State, intended for large states
+// shared between parallel branches of an execution.
+//
+// Creating an overlay or forking one is O(1): writes go to a private top layer
+// while reads fall through to shared, frozen layers. Values returned by Get may
+// be shared with other overlays and must be treated as read-only; use
+// GetMutable to obtain a private deep copy that can be modified in place.
+//
+// An Overlay is not safe for concurrent use, but distinct overlays forked from
+// the same parent may be used from different goroutines.
+type Overlay struct {
+	top *layer
+}
+
+// NewOverlay creates a copy-on-write view over base.
+// The base state is not copied and must not be modified while overlays use it.
+func NewOverlay(base State) *Overlay {
+	root := &layer{values: base}
+	return &Overlay{top: &layer{parent: root}}
+}
+
+// Get retrieves a read-only value by key.
+func (o *Overlay) Get(key string) interface{} {
+	val, _ := o.top.lookup(key)
+	return val
+}
+
+// GetMutable retrieves a value that may be modified in place.
+// Values inherited from shared layers are deep copied into this overlay first.
+func (o *Overlay) GetMutable(key string) interface{} {
+	if val, ok := o.top.values[key]; ok {
+		return val
+	}
+	val, ok := o.top.parent.lookup(key)
+	if !ok {
+		return nil
+	}
+	copied := DeepCopyValue(val)
+	o.Set(key, copied)
+	return copied
+}
+
+// Has checks if a key exists in the overlay.
+func (o *Overlay) Has(key string) bool {
+	_, ok := o.top.lookup(key)
+	return ok
+}
+
+// Set stores a value in this overlay only.
+func (o *Overlay) Set(key string, value interface{}) {
+	if o.top.values == nil {
+		o.top.values = make(map[string]interface{})
+	}
+	o.top.values[key] = value
+	delete(o.top.deleted, key)
+}
+
+// Delete removes a key from this overlay's view.
+func (o *Overlay) Delete(key string) {
+	delete(o.top.values, key)
+	if _, ok := o.top.parent.lookup(key); ok {
+		if o.top.deleted == nil {
+			o.top.deleted = make(map[string]struct{})
+		}
+		o.top.deleted[key] = struct{}{}
+	}
+}
+
+// Fork returns a new overlay that shares the current contents of o.
+// Subsequent writes to either overlay are not visible to the other.
+func (o *Overlay) Fork() *Overlay {
+	// An empty top layer adds nothing to freeze; sharing its parent keeps
+	// lookup chains short when a single state is forked many times.
+	if len(o.top.values) == 0 && len(o.top.deleted) == 0 {
+		return &Overlay{top: &layer{parent: o.top.parent}}
+	}
+	frozen := o.top
+	o.top = &layer{parent: frozen}
+	return &Overlay{top: &layer{parent: frozen}}
+}
+
+// Keys returns all keys visible in the overlay, sorted.
+func (o *Overlay) Keys() []string {
+	seen := make(map[string]bool)
+	hidden := make(map[string]bool)
+	for cur := o.top; cur != nil; cur = cur.parent {
+		for k := range cur.values {
+			if !hidden[k] {
+				seen[k] = true
+			}
+		}
+		for k := range cur.deleted {
+			if !seen[k] {
+				hidden[k] = true
+			}
+		}
+	}
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Changes returns the values set and the keys deleted on this overlay since it
+// was created or last forked. This is the delta a parallel branch contributes.
+func (o *Overlay) Changes() (State, []string) {
+	set := make(State, len(o.top.values))
+	for k, v := range o.top.values {
+		set[k] = v
+	}
+	deleted := make([]string, 0, len(o.top.deleted))
+	for k := range o.top.deleted {
+		deleted = append(deleted, k)
+	}
+	sort.Strings(deleted)
+	return set, deleted
+}
+
+// State materializes the overlay into a plain State.
+// The result shares values with the underlying layers; use DeepCopy on it
+// if the values need to be modified.
+func (o *Overlay) State() State {
+	out := make(State)
+	for _, k := range o.Keys() {
+		out[k], _ = o.top.lookup(k)
+	}
+	return out
+}
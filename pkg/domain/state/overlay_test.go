@@ -0,0 +1,89 @@
+package state
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOverlayReadThroughAndWrite(t *testing.T) {
+	base := NewState()
+	base.Set("a", 1)
+	base.Set("b", 2)
+
+	o := NewOverlay(base)
+	o.Set("a", 10)
+	o.Delete("b")
+	o.Set("c", 3)
+
+	if got := o.Get("a"); got != 10 {
+		t.Errorf("expected a=10, got %v", got)
+	}
+	if o.Has("b") {
+		t.Error("expected b to be deleted in overlay")
+	}
+	if base.Get("a") != 1 || !base.Has("b") {
+		t.Error("base state must not be modified by overlay writes")
+	}
+	if keys := o.Keys(); !reflect.DeepEqual(keys, []string{"a", "c"}) {
+		t.Errorf("expected keys [a c], got %v", keys)
+	}
+
+	set, deleted := o.Changes()
+	if len(set) != 2 || set["a"] != 10 || set["c"] != 3 {
+		t.Errorf("unexpected changes %v", set)
+	}
+	if !reflect.DeepEqual(deleted, []string{"b"}) {
+		t.Errorf("expected deleted [b], got %v", deleted)
+	}
+}
+
+func TestOverlayForkIsolation(t *testing.T) {
+	o := NewOverlay(State{"shared": "v"})
+	o.Set("before", true)
+
+	left := o.Fork()
+	right := o.Fork()
+	left.Set("branch", "left")
+	right.Set("branch", "right")
+	o.Set("after", true)
+
+	if left.Get("branch") != "left" || right.Get("branch") != "right" {
+		t.Error("expected forks to have independent writes")
+	}
+	if !left.Has("before") || !right.Has("shared") {
+		t.Error("expected forks to see parent state at fork time")
+	}
+	if left.Has("after") || o.Has("branch") {
+		t.Error("expected writes after fork to be isolated")
+	}
+}
+
+func TestOverlayGetMutable(t *testing.T) {
+	base := State{"doc": map[string]interface{}{"title": "draft"}}
+	o := NewOverlay(base)
+
+	doc := o.GetMutable("doc").(map[string]interface{})
+	doc["title"] = "final"
+
+	if base["doc"].(map[string]interface{})["title"] != "draft" {
+		t.Error("GetMutable must not expose shared values")
+	}
+	if o.Get("doc").(map[string]interface{})["title"] != "final" {
+		t.Error("expected mutation to be visible through the overlay")
+	}
+	if o.GetMutable("missing") != nil {
+		t.Error("expected nil for missing key")
+	}
+}
+
+func TestOverlayState(t *testing.T) {
+	o := NewOverlay(State{"a": 1, "b": 2})
+	o.Delete("a")
+	o.Set("c", 3)
+
+	got := o.State()
+	want := State{"b": 2, "c": 3}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -10,21 +10,83 @@ import (
 // It can store any JSON-serializable data.
 type State map[string]interface{}
 
+// LazyValue is a state value whose content is loaded on first access.
+// Typical implementations are references to large values offloaded to a
+// blob store. Accessors such as Get resolve lazy values transparently without
+// modifying the state, so a state can be read from several goroutines;
+// implementations should cache the loaded value and must be safe for
+// concurrent use.
+type LazyValue interface {
+	// Resolve loads and returns the underlying value.
+	Resolve() (interface{}, error)
+}
+
 // NewState creates a new empty State.
 func NewState() State {
 	return make(State)
 }
 
 // Get retrieves a value from the state by key.
-// Returns nil if the key doesn't exist.
+// Returns nil if the key doesn't exist or a lazy value cannot be resolved;
+// use GetE to tell the two apart.
 func (s State) Get(key string) interface{} {
-	return s[key]
+	val, _ := s.lookup(key)
+	return val
+}
+
+// GetE retrieves a value from the state by key, resolving it if it is a LazyValue.
+// Unlike Get, it reports errors that occur while loading lazy values.
+// It returns nil and no error if the key doesn't exist.
+func (s State) GetE(key string) (interface{}, error) {
+	val, ok := s[key]
+	if !ok {
+		return nil, nil
+	}
+	lazy, ok := val.(LazyValue)
+	if !ok {
+		return val, nil
+	}
+	resolved, err := lazy.Resolve()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve state key '%s': %w", key, err)
+	}
+	return resolved, nil
+}
+
+// ResolveAll resolves every LazyValue in the state and replaces it with its
+// value. Unlike the accessors it modifies the state, so it must not be called
+// while other goroutines read the state.
+func (s State) ResolveAll() error {
+	for key, val := range s {
+		if _, ok := val.(LazyValue); !ok {
+			continue
+		}
+		resolved, err := s.GetE(key)
+		if err != nil {
+			return err
+		}
+		s[key] = resolved
+	}
+	return nil
+}
+
+// lookup returns the value for key, resolving lazy values.
+// A lazy value that fails to resolve is reported as missing.
+func (s State) lookup(key string) (interface{}, bool) {
+	if _, ok := s[key]; !ok {
+		return nil, false
+	}
+	val, err := s.GetE(key)
+	if err != nil {
+		return nil, false
+	}
+	return val, true
 }
 
 // GetString retrieves a string value from the state.
 // Returns empty string and false if the key doesn't exist or value is not a string.
 func (s State) GetString(key string) (string, bool) {
-	val, ok := s[key]
+	val, ok := s.lookup(key)
 	if !ok {
 		return "", false
 	}
@@ -35,7 +97,7 @@ func (s State) GetString(key string) (string, bool) {
 // GetInt retrieves an int value from the state.
 // Returns 0 and false if the key doesn't exist or value is not convertible to int.
 func (s State) GetInt(key string) (int, bool) {
-	val, ok := s[key]
+	val, ok := s.lookup(key)
 	if !ok {
 		return 0, false
 	}
@@ -55,7 +117,7 @@ func (s State) GetInt(key string) (int, bool) {
 // GetBool retrieves a boolean value from the state.
 // Returns false and false if the key doesn't exist or value is not a bool.
 func (s State) GetBool(key string) (bool, bool) {
-	val, ok := s[key]
+	val, ok := s.lookup(key)
 	if !ok {
 		return false, false
 	}
@@ -80,20 +142,10 @@ func (s State) Has(key string) bool {
 }
 
 // Copy creates a deep copy of the state.
-// Note: This uses JSON marshaling/unmarshaling for deep copy.
-// TODO: Consider more efficient deep copy implementation for performance-critical paths.
+// It is equivalent to DeepCopy and never returns an error; the error result
+// is kept for backward compatibility.
 func (s State) Copy() (State, error) {
-	data, err := json.Marshal(s)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal state: %w", err)
-	}
-
-	var copy State
-	if err := json.Unmarshal(data, &copy); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
-	}
-
-	return copy, nil
+	return s.DeepCopy(), nil
 }
 
 // Merge merges another state into this state.
@@ -1,6 +1,7 @@
 package state
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -311,3 +312,52 @@ func TestStateClear(t *testing.T) {
 		t.Error("expected all keys to be cleared")
 	}
 }
+
+type fakeLazy struct {
+	value interface{}
+	err   error
+	calls int
+}
+
+func (f *fakeLazy) Resolve() (interface{}, error) {
+	f.calls++
+	return f.value, f.err
+}
+
+func TestStateLazyValue(t *testing.T) {
+	s := NewState()
+	lazy := &fakeLazy{value: "loaded"}
+	s.Set("doc", lazy)
+
+	if val, ok := s.GetString("doc"); !ok || val != "loaded" {
+		t.Errorf("expected lazy value to resolve to 'loaded', got %q (ok=%v)", val, ok)
+	}
+	if s["doc"] != lazy {
+		t.Error("expected reads to leave the state unchanged")
+	}
+
+	if err := s.ResolveAll(); err != nil {
+		t.Fatalf("ResolveAll failed: %v", err)
+	}
+	if s["doc"] != "loaded" || lazy.calls != 2 {
+		t.Errorf("expected ResolveAll to store the resolved value, got %v after %d calls", s["doc"], lazy.calls)
+	}
+}
+
+func TestStateLazyValueError(t *testing.T) {
+	s := NewState()
+	s.Set("doc", &fakeLazy{err: errors.New("boom")})
+
+	if val := s.Get("doc"); val != nil {
+		t.Errorf("expected nil for unresolvable value, got %v", val)
+	}
+	if _, err := s.GetE("doc"); err == nil {
+		t.Error("expected GetE to report the error")
+	}
+	if val, err := s.GetE("missing"); val != nil || err != nil {
+		t.Errorf("expected nil and no error for a missing key, got %v, %v", val, err)
+	}
+	if err := s.ResolveAll(); err == nil {
+		t.Error("expected ResolveAll to report the error")
+	}
+}
@@ -0,0 +1,364 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+)
+
+// ConditionEvaluator evaluates edge and route conditions against the state.
+type ConditionEvaluator interface {
+	Evaluate(condition string, s state.State) (bool, error)
+}
+
+// ConditionFunc adapts a function to a ConditionEvaluator.
+type ConditionFunc func(condition string, s state.State) (bool, error)
+
+// Evaluate calls f.
+func (f ConditionFunc) Evaluate(condition string, s state.State) (bool, error) {
+	return f(condition, s)
+}
+
+// EvaluateCondition is the default condition evaluator. An empty condition is
+// true. Conditions are expressions over state paths and literals:
+//
+//	state.approved == true
+//	state.review.score >= 0.8 && !state.escalate
+//	state.status != "done" || (state.retries < 3)
+//
+// Paths start with an optional "state." prefix and walk nested maps. Literals
+// are numbers, quoted strings, true, false and null. Operators are ==, !=, <,
+// <=, >, >=, &&, || and !. A value standing alone is true unless it is
+// missing, null, false, zero, or an empty string, slice or map.
+func EvaluateCondition(condition string, s state.State) (bool, error) {
+	if strings.TrimSpace(condition) == "" {
+		return true, nil
+	}
+	tokens, err := tokenize(condition)
+	if err != nil {
+		return false, fmt.Errorf("invalid condition %q: %w", condition, err)
+	}
+	p := &parser{tokens: tokens, state: s}
+	v, err := p.or()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return false, fmt.Errorf("invalid condition %q: %w", condition, err)
+	}
+	return truthy(v), nil
+}
+
+type tokenKind int
+
+const (
+	tokenPath tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// operators are matched longest first.
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")"}
+
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			j := i + 1
+			var b strings.Builder
+			for j < len(input) && input[j] != c {
+				if input[j] == '\\' && j+1 < len(input) {
+					j++
+				}
+				b.WriteByte(input[j])
+				j++
+			}
+			if j >= len(input) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, token{tokenString, b.String()})
+			i = j + 1
+		case c == '-' || c == '.' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(input) && (input[j] == '.' || input[j] == 'e' || input[j] == 'E' || (input[j] >= '0' && input[j] <= '9')) {
+				j++
+			}
+			tokens = append(tokens, token{tokenNumber, input[i:j]})
+			i = j
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			j := i + 1
+			for j < len(input) && (input[j] == '_' || input[j] == '.' || input[j] == '-' ||
+				(input[j] >= 'a' && input[j] <= 'z') || (input[j] >= 'A' && input[j] <= 'Z') || (input[j] >= '0' && input[j] <= '9')) {
+				j++
+			}
+			tokens = append(tokens, token{tokenPath, input[i:j]})
+			i = j
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(input[i:], op) {
+					tokens = append(tokens, token{tokenOperator, op})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q", c)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+// parser evaluates the tokens of a condition by recursive descent.
+type parser struct {
+	tokens []token
+	pos    int
+	state  state.State
+}
+
+func (p *parser) accept(op string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenOperator && p.tokens[p.pos].text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) or() (interface{}, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = truthy(left) || truthy(right)
+	}
+	return left, nil
+}
+
+func (p *parser) and() (interface{}, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = truthy(left) && truthy(right)
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (interface{}, error) {
+	if p.accept("!") {
+		v, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return !truthy(v), nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (interface{}, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if !p.accept(op) {
+			continue
+		}
+		right, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		return compare(op, left, right)
+	}
+	return left, nil
+}
+
+func (p *parser) operand() (interface{}, error) {
+	if p.accept("(") {
+		v, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		return v, nil
+	}
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of condition")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	switch t.kind {
+	case tokenString:
+		return t.text, nil
+	case tokenNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.text)
+		}
+		return f, nil
+	case tokenPath:
+		switch t.text {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null", "nil":
+			return nil, nil
+		}
+		return lookupPath(p.state, t.text), nil
+	default:
+		return nil, fmt.Errorf("unexpected %q", t.text)
+	}
+}
+
+// lookupPath resolves a dotted path in s, walking nested maps.
+func lookupPath(s state.State, path string) interface{} {
+	path = strings.TrimPrefix(path, "state.")
+	// Prefer a top-level key containing dots, e.g. "_input.review".
+	if s.Has(path) {
+		return s.Get(path)
+	}
+	parts := strings.Split(path, ".")
+	var current interface{} = map[string]interface{}(s)
+	for _, part := range parts {
+		switch m := current.(type) {
+		case map[string]interface{}:
+			current = m[part]
+		case state.State:
+			current = m.Get(part)
+		default:
+			return nil
+		}
+	}
+	return current
+}
+
+func compare(op string, left, right interface{}) (bool, error) {
+	if l, ok := toFloat(left); ok {
+		if r, ok := toFloat(right); ok {
+			switch op {
+			case "==":
+				return l == r, nil
+			case "!=":
+				return l != r, nil
+			case "<":
+				return l < r, nil
+			case "<=":
+				return l <= r, nil
+			case ">":
+				return l > r, nil
+			default:
+				return l >= r, nil
+			}
+		}
+	}
+	if l, ok := left.(string); ok {
+		if r, ok := right.(string); ok {
+			switch op {
+			case "==":
+				return l == r, nil
+			case "!=":
+				return l != r, nil
+			case "<":
+				return l < r, nil
+			case "<=":
+				return l <= r, nil
+			case ">":
+				return l > r, nil
+			default:
+				return l >= r, nil
+			}
+		}
+	}
+	switch op {
+	case "==":
+		return reflect.DeepEqual(left, right), nil
+	case "!=":
+		return !reflect.DeepEqual(left, right), nil
+	default:
+		return false, fmt.Errorf("cannot compare %T and %T with %s", left, right, op)
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case interface{ Float64() (float64, error) }:
+		f, err := n.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func truthy(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return val
+	case string:
+		return val != ""
+	}
+	if f, ok := toFloat(v); ok {
+		return f != 0
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return rv.Len() > 0
+	case reflect.Ptr, reflect.Interface:
+		return !rv.IsNil()
+	}
+	return true
+}
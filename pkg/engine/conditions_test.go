@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+)
+
+func TestEvaluateCondition(t *testing.T) {
+	s := state.State{
+		"approved": true,
+		"score":    0.85,
+		"retries":  2,
+		"count":    json.Number("3"),
+		"status":   "open",
+		"items":    []interface{}{},
+		"review":   map[string]interface{}{"approved": false, "reviewer": "ann"},
+		"_input.x": "raw",
+	}
+
+	tests := []struct {
+		condition string
+		want      bool
+	}{
+		{"", true},
+		{"state.approved", true},
+		{"state.approved == true", true},
+		{"state.missing", false},
+		{"state.missing == null", true},
+		{"!state.items", true},
+		{"state.score >= 0.8", true},
+		{"state.score > 0.9", false},
+		{"state.retries < 3 && state.count == 3", true},
+		{"state.status == 'open'", true},
+		{`state.status != "open" || state.review.reviewer == "ann"`, true},
+		{"state.review.approved", false},
+		{"!(state.review.approved || state.score < 0.5)", true},
+		{"state._input.x == 'raw'", true},
+		{"approved && -1 < retries", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			got, err := EvaluateCondition(tt.condition, s)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEvaluateCondition_Invalid(t *testing.T) {
+	for _, condition := range []string{
+		"state.a ==",
+		"(state.a",
+		"state.a # 1",
+		"'open",
+		"state.status < 3",
+		"state.a state.b",
+	} {
+		if _, err := EvaluateCondition(condition, state.State{"status": "open"}); err == nil {
+			t.Errorf("expected %q to be invalid", condition)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+// Package engine provides a reference engine that runs graph.Graph
+// executions.
+//
+// The engine walks a graph from its entry node in steps. Every pending node
+// runs concurrently on a private copy of the state; when the step ends the
+// state changes of the nodes are applied in order and the nodes following
+// them become pending. A node is pending at most once, so branches joining at
+// a node in the same step run it once.
+//
+// Executor nodes are dispatched to the Executor registered for their executor
+// type; the main dago repository registers its LLM, tool and code executors.
+// Router routes and edge conditions are evaluated by a ConditionEvaluator,
+// EvaluateCondition by default. Start, end, approval and custom node types
+// run their own Execute method, and subgraphs are flattened before the run.
+//
+// Each node runs under the graph.Policy returned by Graph.EffectivePolicy:
+// attempts are bounded by the policy timeout and retried, and failures
+// matching an error handler continue at its target instead of failing the
+// execution. Executors read the node's idempotency key with
+// graph.IdempotencyKeyFromContext, and must return once their context is done
+// for timeouts to take effect. Node states are reported in the Result and ports.Events are
+// published on the configured ports.EventBus.
+//
+// With WithStorage, executions are durable: each node completion is recorded
+// in a checkpoint together with its state changes, approval nodes pause the
+// execution through an approval.Service, and Engine implements both
+// approval.Continuer and checkpoint.Resumer to continue paused executions and
+// recover executions orphaned by dead workers.
+package engine
@@ -0,0 +1,386 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aescanero/dago-libs/pkg/approval"
+	"github.com/aescanero/dago-libs/pkg/checkpoint"
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-libs/pkg/events"
+	"github.com/aescanero/dago-libs/pkg/ports"
+	"github.com/aescanero/dago-libs/pkg/utils/logging"
+)
+
+// DefaultMaxSteps is the default limit of scheduling steps per run.
+const DefaultMaxSteps = 1000
+
+var (
+	// ErrNoExecutor is returned when no executor is registered for the
+	// executor type of a node.
+	ErrNoExecutor = errors.New("no executor registered")
+
+	// ErrNoRoute is returned when no route of a router matches and it has no
+	// default route.
+	ErrNoRoute = errors.New("no route matched")
+
+	// ErrMaxSteps is returned when an execution exceeds the step limit, e.g.
+	// because of a loop that never exits.
+	ErrMaxSteps = errors.New("maximum number of steps exceeded")
+
+	// ErrNotDurable is returned when pausing or resuming an execution without
+	// storage configured.
+	ErrNotDurable = errors.New("engine has no storage configured")
+)
+
+// Executor runs executor nodes of one executor type. It receives a private
+// copy of the state and returns the updated state.
+type Executor interface {
+	Execute(ctx context.Context, node *graph.ExecutorNode, s state.State) (state.State, error)
+}
+
+// ExecutorFunc adapts a function to an Executor.
+type ExecutorFunc func(ctx context.Context, node *graph.ExecutorNode, s state.State) (state.State, error)
+
+// Execute calls f.
+func (f ExecutorFunc) Execute(ctx context.Context, node *graph.ExecutorNode, s state.State) (state.State, error) {
+	return f(ctx, node, s)
+}
+
+// Result is the outcome of running an execution until it completes, fails or
+// pauses.
+type Result struct {
+	// ExecutionID identifies the execution.
+	ExecutionID string
+
+	// Status is completed, failed, cancelled or waiting_for_input.
+	Status domain.ExecutionStatus
+
+	// State is the execution state when the run stopped.
+	State state.State
+
+	// NodeStates holds the latest run of each node that ran.
+	NodeStates map[string]*domain.NodeState
+
+	// Checkpoint records the completed and pending nodes.
+	Checkpoint *checkpoint.Checkpoint
+
+	// PendingInput is the request the execution waits on, if paused.
+	PendingInput *graph.InputRequest
+
+	// Error is the error that failed the execution, if any.
+	Error error
+}
+
+// Engine runs graphs. Executor nodes are dispatched to the executors
+// registered for their executor type; routers, approvals and start and end
+// nodes are handled by the engine.
+type Engine struct {
+	mu        sync.RWMutex
+	executors map[string]Executor
+	graphs    map[string]*graph.Graph
+
+	conditions     ConditionEvaluator
+	resolver       graph.GraphResolver
+	loader         graph.GraphLoader
+	events         ports.EventBus
+	topic          string
+	executions     ports.ExecutionStorage
+	states         state.Manager
+	recorder       *checkpoint.Recorder
+	approvals      *approval.Service
+	logger         *logging.Logger
+	workerID       string
+	maxSteps       int
+	maxConcurrency int
+	now            func() time.Time
+}
+
+// NewEngine creates an engine without executors. Register executors with
+// Register and configure optional collaborators with the With methods.
+func NewEngine() *Engine {
+	return &Engine{
+		executors:  make(map[string]Executor),
+		graphs:     make(map[string]*graph.Graph),
+		conditions: ConditionFunc(EvaluateCondition),
+		maxSteps:   DefaultMaxSteps,
+		now:        time.Now,
+	}
+}
+
+// Register registers the executor of an executor type, replacing any
+// previous one.
+func (e *Engine) Register(executorType string, executor Executor) *Engine {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.executors[executorType] = executor
+	return e
+}
+
+// WithConditions sets the evaluator of edge and route conditions. The
+// default is EvaluateCondition.
+func (e *Engine) WithConditions(c ConditionEvaluator) *Engine {
+	e.conditions = c
+	return e
+}
+
+// WithResolver sets the resolver of subgraph references. Graphs with subgraph
+// nodes are flattened before they run.
+func (e *Engine) WithResolver(r graph.GraphResolver) *Engine {
+	e.resolver = r
+	return e
+}
+
+// WithGraphs sets where Continue and ResumeFrom load the graphs of executions
+// that did not start in this engine, by graph ID. ports.GraphStorage
+// satisfies graph.GraphLoader.
+func (e *Engine) WithGraphs(loader graph.GraphLoader) *Engine {
+	e.loader = loader
+	return e
+}
+
+// WithEvents publishes execution events to topic on bus.
+func (e *Engine) WithEvents(bus ports.EventBus, topic string) *Engine {
+	e.events = bus
+	e.topic = topic
+	return e
+}
+
+// WithStorage makes executions durable: execution metadata is saved in
+// executions and the state, with its checkpoint, in states. Durable
+// executions can pause for input and be resumed after a crash.
+func (e *Engine) WithStorage(executions ports.ExecutionStorage, states state.Manager) *Engine {
+	e.executions = executions
+	e.states = states
+	e.recorder = checkpoint.NewRecorder(states, executions).WithClock(func() time.Time { return e.now() })
+	return e
+}
+
+// WithApprovals sets the service that pauses executions reaching an approval
+// node. It requires WithStorage; set the engine as the service's Continuer.
+func (e *Engine) WithApprovals(service *approval.Service) *Engine {
+	e.approvals = service
+	return e
+}
+
+// WithLogger sets the logger of errors that do not fail executions, such as
+// event publishing errors.
+func (e *Engine) WithLogger(logger *logging.Logger) *Engine {
+	e.logger = logger
+	return e
+}
+
+// WithWorkerID sets the worker ID recorded on the executions the engine runs.
+func (e *Engine) WithWorkerID(id string) *Engine {
+	e.workerID = id
+	return e
+}
+
+// WithMaxSteps sets the limit of scheduling steps per run.
+func (e *Engine) WithMaxSteps(n int) *Engine {
+	e.maxSteps = n
+	return e
+}
+
+// WithMaxConcurrency limits the number of nodes running at once. Zero means
+// no limit.
+func (e *Engine) WithMaxConcurrency(n int) *Engine {
+	e.maxConcurrency = n
+	return e
+}
+
+// WithClock sets the clock used for timestamps.
+func (e *Engine) WithClock(now func() time.Time) *Engine {
+	e.now = now
+	return e
+}
+
+// Run starts a new execution of g with a generated execution ID.
+func (e *Engine) Run(ctx context.Context, g *graph.Graph, initial state.State) (*Result, error) {
+	return e.Start(ctx, uuid.New().String(), g, initial)
+}
+
+// Start starts a new execution of g from its entry node and runs it until it
+// completes, fails or pauses. The returned error is the execution error, also
+// reported in the result.
+func (e *Engine) Start(ctx context.Context, executionID string, g *graph.Graph, initial state.State) (*Result, error) {
+	g, err := e.prepare(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+	st := initial.DeepCopy()
+	if st == nil {
+		st = state.NewState()
+	}
+	cp := &checkpoint.Checkpoint{Pending: []string{g.EntryNode}, UpdatedAt: e.now()}
+
+	if e.recorder != nil {
+		metadata := ports.ExecutionMetadata{
+			ExecutionID: executionID,
+			GraphID:     g.ID,
+			StartedAt:   e.now(),
+			WorkerID:    e.workerID,
+		}
+		if err := e.recorder.Start(ctx, metadata, st, g.EntryNode); err != nil {
+			return nil, err
+		}
+	}
+	e.track(executionID, g)
+	e.publish(ctx, executionID, "", events.GraphStartedPayload{GraphID: g.ID})
+
+	x := e.newExecution(executionID, g, st, cp)
+	return x.run(ctx)
+}
+
+// Continue continues a durable execution after the response to its pending
+// input has been delivered. It implements approval.Continuer.
+func (e *Engine) Continue(ctx context.Context, executionID, nodeID string) error {
+	_, err := e.Resume(ctx, executionID)
+	return err
+}
+
+// ResumeFrom resumes a durable execution from cp, the checkpoint loaded by
+// the recovering worker. It implements checkpoint.Resumer for crash recovery.
+// Executions interrupted while running nodes that are not idempotent fail
+// with checkpoint.ErrNotIdempotent instead of running them again.
+func (e *Engine) ResumeFrom(ctx context.Context, metadata ports.ExecutionMetadata, cp *checkpoint.Checkpoint) error {
+	if cp == nil {
+		return fmt.Errorf("execution '%s' has no checkpoint", metadata.ExecutionID)
+	}
+	_, err := e.resume(ctx, metadata.ExecutionID, cp)
+	return err
+}
+
+// Resume runs a durable execution from its last checkpoint until it
+// completes, fails or pauses again.
+func (e *Engine) Resume(ctx context.Context, executionID string) (*Result, error) {
+	return e.resume(ctx, executionID, nil)
+}
+
+// resume runs a durable execution from the checkpoint recovered from a
+// crash or, if recovered is nil, from the checkpoint in its state. When
+// recovering, it fails the execution if nodes that are not idempotent had
+// started.
+func (e *Engine) resume(ctx context.Context, executionID string, recovered *checkpoint.Checkpoint) (*Result, error) {
+	if e.recorder == nil {
+		return nil, fmt.Errorf("execution '%s': %w", executionID, ErrNotDurable)
+	}
+	metadata, err := e.executions.Load(ctx, executionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load execution '%s': %w", executionID, err)
+	}
+	g, err := e.graphOf(ctx, executionID, metadata.GraphID)
+	if err != nil {
+		return nil, err
+	}
+	st, err := e.states.GetState(ctx, executionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state of execution '%s': %w", executionID, err)
+	}
+	st = st.DeepCopy()
+	cp := recovered
+	if cp == nil {
+		var ok bool
+		if cp, ok = checkpoint.FromState(st); !ok {
+			return nil, fmt.Errorf("execution '%s' has no checkpoint", executionID)
+		}
+	}
+	st.Delete(checkpoint.StateKey)
+
+	if e.workerID != "" && metadata.WorkerID != e.workerID {
+		metadata.WorkerID = e.workerID
+		if err := e.executions.Save(ctx, *metadata); err != nil {
+			return nil, fmt.Errorf("failed to save execution '%s': %w", executionID, err)
+		}
+	}
+	x := e.newExecution(executionID, g, st, cp)
+	e.track(executionID, g)
+	if recovered != nil {
+		if unsafe := cp.Unsafe(g); len(unsafe) > 0 {
+			return x.fail(ctx, fmt.Errorf("execution '%s': %w: %s", executionID, checkpoint.ErrNotIdempotent, strings.Join(unsafe, ", ")))
+		}
+	}
+	e.publish(ctx, executionID, "", events.GraphResumedPayload{GraphID: g.ID, Pending: cp.Pending})
+	return x.run(ctx)
+}
+
+// prepare validates g and flattens its subgraphs.
+func (e *Engine) prepare(ctx context.Context, g *graph.Graph) (*graph.Graph, error) {
+	if err := g.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid graph '%s': %w", g.ID, err)
+	}
+	for _, node := range g.Nodes {
+		if _, ok := node.(*graph.SubgraphNode); ok {
+			flat, err := graph.Flatten(ctx, g, e.resolver)
+			if err != nil {
+				return nil, fmt.Errorf("failed to flatten graph '%s': %w", g.ID, err)
+			}
+			return flat, nil
+		}
+	}
+	return g, nil
+}
+
+// track remembers the graph of a running execution for Continue.
+func (e *Engine) track(executionID string, g *graph.Graph) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.graphs[executionID] = g
+}
+
+// untrack forgets the graph of a finished execution.
+func (e *Engine) untrack(executionID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.graphs, executionID)
+}
+
+// graphOf returns the graph of an execution, loading it by ID if the
+// execution did not start in this engine.
+func (e *Engine) graphOf(ctx context.Context, executionID, graphID string) (*graph.Graph, error) {
+	e.mu.RLock()
+	g, ok := e.graphs[executionID]
+	e.mu.RUnlock()
+	if ok {
+		return g, nil
+	}
+	if e.loader == nil {
+		return nil, fmt.Errorf("execution '%s': graph '%s' is unknown and no graph loader is configured", executionID, graphID)
+	}
+	g, err := graph.NewStorageResolver(e.loader, nil).ResolveGraph(ctx, graph.GraphRef{ID: graphID})
+	if err != nil {
+		return nil, err
+	}
+	return e.prepare(ctx, g)
+}
+
+func (e *Engine) executor(executorType string) (Executor, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	executor, ok := e.executors[executorType]
+	return executor, ok
+}
+
+// publish publishes an execution event. Publishing errors are logged, not
+// returned, so that an unavailable event bus does not fail executions.
+func (e *Engine) publish(ctx context.Context, executionID, nodeID string, payload events.Payload) {
+	if e.events == nil {
+		return
+	}
+	event, err := events.NewEvent(executionID, nodeID, payload)
+	if err == nil {
+		event.Timestamp = e.now()
+		err = e.events.Publish(ctx, e.topic, event)
+	}
+	if err != nil && e.logger != nil {
+		e.logger.WithExecutionID(executionID).Error("failed to publish event", "type", string(payload.EventType()), "error", err)
+	}
+}
@@ -0,0 +1,672 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/approval"
+	"github.com/aescanero/dago-libs/pkg/checkpoint"
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-libs/pkg/events"
+	"github.com/aescanero/dago-libs/pkg/internal/fakes"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// memoryBus is a minimal ports.EventBus recording published events.
+type memoryBus struct {
+	mu     sync.Mutex
+	events []ports.Event
+}
+
+func (b *memoryBus) Publish(ctx context.Context, topic string, event ports.Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = append(b.events, event)
+	return nil
+}
+
+func (b *memoryBus) Subscribe(ctx context.Context, topic string, handler ports.EventHandler, opts ports.SubscribeOptions) (ports.Subscription, error) {
+	return nil, errors.New("not supported")
+}
+
+func (b *memoryBus) Unsubscribe(ctx context.Context, topic string) error { return nil }
+
+func (b *memoryBus) Close() error { return nil }
+
+func (b *memoryBus) count(eventType ports.EventType, nodeID string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := 0
+	for _, e := range b.events {
+		if e.Type == eventType && e.NodeID == nodeID {
+			n++
+		}
+	}
+	return n
+}
+
+// mark is an executor that records its node ID in state.
+var mark = ExecutorFunc(func(ctx context.Context, node *graph.ExecutorNode, s state.State) (state.State, error) {
+	s.Set(node.ID, "done")
+	return s, nil
+})
+
+// mustBuild returns the graph built by a builder, or panics.
+func mustBuild(g *graph.Graph, err error) *graph.Graph {
+	if err != nil {
+		panic(fmt.Sprintf("build failed: %v", err))
+	}
+	return g
+}
+
+func TestEngine_Run(t *testing.T) {
+	g := mustBuild(graph.Build("review").ID("review").
+		Start().
+		Tool("fetch", "http_get", nil).
+		Router("check", graph.When("state.score >= 0.8", "publish")).Default("end").
+		At().Tool("publish", "http_post", nil).
+		End())
+
+	bus := &memoryBus{}
+	engine := NewEngine().Register(graph.ExecutorTypeTool, ExecutorFunc(func(ctx context.Context, node *graph.ExecutorNode, s state.State) (state.State, error) {
+		if node.ID == "fetch" {
+			s.Set("score", 0.9)
+		}
+		return mark(ctx, node, s)
+	})).WithEvents(bus, "executions")
+
+	result, err := engine.Run(context.Background(), g, state.State{"url": "https://example.com"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.Status != domain.ExecutionStatusCompleted {
+		t.Errorf("expected completed, got %s", result.Status)
+	}
+	if result.State.Get("publish") != "done" || result.State.Get("url") != "https://example.com" {
+		t.Errorf("unexpected final state %v", result.State)
+	}
+	for _, id := range []string{"start", "fetch", "check", "publish", "end"} {
+		if ns := result.NodeStates[id]; ns == nil || ns.Status != domain.ExecutionStatusCompleted {
+			t.Errorf("expected node %s completed, got %+v", id, ns)
+		}
+	}
+	if result.Checkpoint.Sequence != 5 || len(result.Checkpoint.Pending) != 0 {
+		t.Errorf("unexpected checkpoint %+v", result.Checkpoint)
+	}
+	if bus.count(ports.EventTypeGraphStarted, "") != 1 || bus.count(ports.EventTypeGraphCompleted, "") != 1 ||
+		bus.count(ports.EventTypeNodeStarted, "publish") != 1 || bus.count(ports.EventTypeNodeCompleted, "publish") != 1 {
+		t.Errorf("unexpected events %+v", bus.events)
+	}
+	for _, event := range bus.events {
+		if event.Type != ports.EventTypeNodeCompleted || event.NodeID != "fetch" {
+			continue
+		}
+		payload, err := events.DecodeAs[events.NodeCompletedPayload](event)
+		if err != nil || payload.Output["score"] != 0.9 || len(payload.Next) != 1 || payload.Next[0] != "check" {
+			t.Errorf("unexpected node.completed payload %+v, %v", payload, err)
+		}
+	}
+}
+
+func TestEngine_RunConcurrent(t *testing.T) {
+	g := mustBuild(graph.Build("fanout").ID("fanout").
+		Start().Then("a", "b").
+		Tool("a", "search", nil).Then("merge").
+		At("b").Tool("b", "search", nil).Then("merge").
+		Tool("merge", "summarize", nil).
+		End())
+
+	var running, peak, merges int32
+	engine := NewEngine().Register(graph.ExecutorTypeTool, ExecutorFunc(func(ctx context.Context, node *graph.ExecutorNode, s state.State) (state.State, error) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		if node.ID == "merge" {
+			atomic.AddInt32(&merges, 1)
+		}
+		time.Sleep(20 * time.Millisecond)
+		return mark(ctx, node, s)
+	}))
+
+	result, err := engine.Run(context.Background(), g, nil)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if peak < 2 {
+		t.Errorf("expected a and b to run concurrently, peak was %d", peak)
+	}
+	if merges != 1 {
+		t.Errorf("expected merge to run once, ran %d times", merges)
+	}
+	if result.State.Get("a") != "done" || result.State.Get("b") != "done" || result.State.Get("merge") != "done" {
+		t.Errorf("expected the outputs of every branch, got %v", result.State)
+	}
+}
+
+func TestEngine_RunUnevenJoin(t *testing.T) {
+	// d joins a short branch (b) and a long one (c, e).
+	g := mustBuild(graph.Build("join").ID("join").
+		Start().Then("b", "c").
+		Tool("b", "search", nil).Then("d").
+		At("c").Tool("c", "search", nil).
+		Tool("e", "search", nil).
+		Tool("d", "summarize", nil).
+		End())
+
+	var mu sync.Mutex
+	var runs []string
+	engine := NewEngine().Register(graph.ExecutorTypeTool, ExecutorFunc(func(ctx context.Context, node *graph.ExecutorNode, s state.State) (state.State, error) {
+		mu.Lock()
+		runs = append(runs, node.ID)
+		mu.Unlock()
+		if node.ID == "d" && (s.Get("b") != "done" || s.Get("e") != "done") {
+			return nil, fmt.Errorf("d ran before its predecessors: %v", s)
+		}
+		return mark(ctx, node, s)
+	}))
+
+	result, err := engine.Run(context.Background(), g, nil)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	count := 0
+	for _, id := range runs {
+		if id == "d" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("expected d to run once, got runs %v", runs)
+	}
+	if len(result.Checkpoint.Waiting) != 0 {
+		t.Errorf("expected no waiting nodes, got %v", result.Checkpoint.Waiting)
+	}
+}
+
+func TestEngine_RunJoinSkippedBranch(t *testing.T) {
+	// The router takes one branch; the join does not wait for the other.
+	g := mustBuild(graph.Build("choice").ID("choice").
+		Start().
+		Router("pick", graph.When("state.fast == true", "quick")).Default("slow").
+		At().Tool("quick", "search", nil).Then("merge").
+		At("slow").Tool("slow", "search", nil).Then("merge").
+		Tool("merge", "summarize", nil).
+		End())
+
+	result, err := NewEngine().Register(graph.ExecutorTypeTool, mark).Run(context.Background(), g, state.State{"fast": true})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.State.Get("merge") != "done" || result.State.Has("slow") {
+		t.Errorf("expected merge to run after quick only, got %v", result.State)
+	}
+}
+
+func TestEngine_Policies(t *testing.T) {
+	g := mustBuild(graph.Build("payments").ID("payments").
+		Start().
+		Tool("charge", "stripe", nil).
+		Policy(&graph.Policy{Retry: &graph.RetryPolicy{MaxAttempts: 3, InitialInterval: graph.Duration(time.Millisecond)}}).
+		Tool("ship", "warehouse", nil).
+		OnError("refund").
+		Node(&graph.EndNode{BaseNode: graph.BaseNode{ID: graph.EndNodeID, Type: graph.NodeTypeEnd}}).
+		At().Tool("refund", "stripe_refund", nil).
+		Graph())
+
+	var charges int32
+	engine := NewEngine().Register(graph.ExecutorTypeTool, ExecutorFunc(func(ctx context.Context, node *graph.ExecutorNode, s state.State) (state.State, error) {
+		switch node.ID {
+		case "charge":
+			if atomic.AddInt32(&charges, 1) < 3 {
+				return s, errors.New("gateway unavailable")
+			}
+		case "ship":
+			return s, errors.New("out of stock")
+		}
+		return mark(ctx, node, s)
+	}))
+
+	result, err := engine.Run(context.Background(), g, nil)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.NodeStates["charge"].Metadata["attempts"] != 3 {
+		t.Errorf("expected charge to succeed on the third attempt, got %+v", result.NodeStates["charge"])
+	}
+	if ns := result.NodeStates["ship"]; ns.Status != domain.ExecutionStatusFailed || ns.Error != "out of stock" {
+		t.Errorf("expected ship to fail, got %+v", ns)
+	}
+	if result.State.Get("refund") != "done" || result.NodeStates["end"] != nil {
+		t.Errorf("expected the failure to be routed to refund only, got %v", result.State)
+	}
+}
+
+func TestEngine_IdempotencyKeys(t *testing.T) {
+	g := mustBuild(graph.Build("payments").ID("payments").
+		Start().
+		Tool("charge", "stripe", nil).
+		Policy(&graph.Policy{IdempotencyKey: "payments/{execution_id}/{node_id}"}).
+		Tool("ship", "warehouse", nil).
+		End())
+
+	var mu sync.Mutex
+	keys := map[string]string{}
+	engine := NewEngine().Register(graph.ExecutorTypeTool, ExecutorFunc(func(ctx context.Context, node *graph.ExecutorNode, s state.State) (state.State, error) {
+		mu.Lock()
+		keys[node.ID] = graph.IdempotencyKeyFromContext(ctx)
+		mu.Unlock()
+		return s, nil
+	}))
+
+	result, err := engine.Run(context.Background(), g, nil)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if want := "payments/" + result.ExecutionID + "/charge"; keys["charge"] != want {
+		t.Errorf("expected charge key %q, got %q", want, keys["charge"])
+	}
+	if want := result.ExecutionID + ":ship"; keys["ship"] != want {
+		t.Errorf("expected the default key %q for ship, got %q", want, keys["ship"])
+	}
+}
+
+func TestEngine_Failures(t *testing.T) {
+	tests := []struct {
+		name  string
+		graph func(t *testing.T) *graph.Graph
+		want  error
+	}{
+		{
+			name: "no executor",
+			graph: func(t *testing.T) *graph.Graph {
+				return mustBuild(graph.Build("g").Start().LLM("draft", map[string]interface{}{"model": "gpt-4"}).End())
+			},
+			want: ErrNoExecutor,
+		},
+		{
+			name: "no route",
+			graph: func(t *testing.T) *graph.Graph {
+				return mustBuild(graph.Build("g").Start().
+					Router("check", graph.When("state.ok", "end")).
+					Node(&graph.EndNode{BaseNode: graph.BaseNode{ID: graph.EndNodeID, Type: graph.NodeTypeEnd}}).
+					Graph())
+			},
+			want: ErrNoRoute,
+		},
+		{
+			name: "endless loop",
+			graph: func(t *testing.T) *graph.Graph {
+				return mustBuild(graph.Build("g").Start().
+					Tool("poll", "status", nil).
+					Router("check", graph.When("state.ready", "end")).Default("poll").
+					Node(&graph.EndNode{BaseNode: graph.BaseNode{ID: graph.EndNodeID, Type: graph.NodeTypeEnd}}).
+					Graph())
+			},
+			want: ErrMaxSteps,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executions := fakes.Executions{}
+			engine := NewEngine().Register(graph.ExecutorTypeTool, mark).WithMaxSteps(20).
+				WithStorage(executions, fakes.States{})
+			result, err := engine.Start(context.Background(), "exec-1", tt.graph(t), nil)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+			if result.Status != domain.ExecutionStatusFailed || executions["exec-1"].Status != ports.ExecutionStatusFailed {
+				t.Errorf("expected a failed execution, got %s and %+v", result.Status, executions["exec-1"])
+			}
+		})
+	}
+}
+
+func TestEngine_Cancel(t *testing.T) {
+	g := mustBuild(graph.Build("g").Start().Tool("wait", "sleep", nil).End())
+	ctx, cancel := context.WithCancel(context.Background())
+	engine := NewEngine().Register(graph.ExecutorTypeTool, ExecutorFunc(func(ctx context.Context, node *graph.ExecutorNode, s state.State) (state.State, error) {
+		cancel()
+		<-ctx.Done()
+		return s, ctx.Err()
+	}))
+
+	bus := &memoryBus{}
+	result, err := engine.WithEvents(bus, "executions").Run(ctx, g, nil)
+	if !errors.Is(err, context.Canceled) || result.Status != domain.ExecutionStatusCancelled {
+		t.Errorf("expected a cancelled execution, got %v, %v", result.Status, err)
+	}
+	if bus.count(ports.EventType(domain.EventTypeGraphCancelled), "") != 1 || bus.count(ports.EventTypeGraphFailed, "") != 0 {
+		t.Errorf("expected graph.cancelled rather than graph.failed, got %+v", bus.events)
+	}
+}
+
+func TestEngine_Approval(t *testing.T) {
+	g := mustBuild(graph.Build("publish").ID("publish").
+		Start().
+		Tool("draft", "write", nil).
+		Approval("review", "Publish this draft?").
+		OnError("draft", graph.ErrorKindRejected).
+		Router("check", graph.When("state.review.approved", "publish")).
+		At().Tool("publish", "http_post", nil).
+		End())
+
+	executions := fakes.Executions{}
+	states := fakes.States{}
+	bus := &memoryBus{}
+	approvals := approval.NewService(executions, states)
+	engine := NewEngine().Register(graph.ExecutorTypeTool, mark).
+		WithStorage(executions, states).
+		WithApprovals(approvals).
+		WithEvents(bus, "executions")
+	approvals.WithContinuer(engine)
+
+	result, err := engine.Start(context.Background(), "exec-1", g, nil)
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if result.Status != domain.ExecutionStatusWaitingForInput || result.PendingInput == nil ||
+		executions["exec-1"].Status != ports.ExecutionStatusWaitingForInput {
+		t.Fatalf("expected the execution to wait for input, got %+v", result)
+	}
+
+	// A rejection goes back to drafting, which asks for approval again.
+	pending, _ := approvals.Pending(context.Background(), "exec-1")
+	if err := approvals.Resume(context.Background(), "exec-1", graph.InputResponse{RequestID: pending.ID}); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if executions["exec-1"].Status != ports.ExecutionStatusWaitingForInput || bus.count(ports.EventTypeNodeStarted, "draft") != 2 {
+		t.Fatalf("expected the draft to be revised and reviewed again, got %+v", executions["exec-1"])
+	}
+
+	pending, _ = approvals.Pending(context.Background(), "exec-1")
+	if err := approvals.Resume(context.Background(), "exec-1", graph.InputResponse{RequestID: pending.ID, Approved: true}); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if executions["exec-1"].Status != ports.ExecutionStatusCompleted {
+		t.Fatalf("expected the execution to complete, got %+v", executions["exec-1"])
+	}
+	if states["exec-1"].Get("publish") != "done" || bus.count(ports.EventTypeGraphResumed, "") != 2 {
+		t.Errorf("expected the draft to be published, got %v", states["exec-1"])
+	}
+}
+
+func TestEngine_ParallelApprovals(t *testing.T) {
+	g := mustBuild(graph.Build("publish").ID("publish").
+		Start().Then("legal", "brand").
+		Approval("legal", "Legal review?").Then("publish").
+		At("brand").Approval("brand", "Brand review?").Then("publish").
+		Tool("publish", "http_post", nil).
+		End())
+
+	executions := fakes.Executions{}
+	states := fakes.States{}
+	approvals := approval.NewService(executions, states)
+	engine := NewEngine().Register(graph.ExecutorTypeTool, mark).
+		WithStorage(executions, states).
+		WithApprovals(approvals)
+	approvals.WithContinuer(engine)
+
+	if _, err := engine.Start(context.Background(), "exec-1", g, nil); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	// Both approvals wait in the same step; they are asked for one at a time.
+	var asked []string
+	for range 2 {
+		pending, err := approvals.Pending(context.Background(), "exec-1")
+		if err != nil || pending == nil {
+			t.Fatalf("expected a pending request after %v, got %v", asked, err)
+		}
+		asked = append(asked, pending.NodeID)
+		if err := approvals.Resume(context.Background(), "exec-1", graph.InputResponse{RequestID: pending.ID, Approved: true}); err != nil {
+			t.Fatalf("Resume failed: %v", err)
+		}
+	}
+	if asked[0] != "legal" || asked[1] != "brand" {
+		t.Errorf("expected legal then brand to be asked, got %v", asked)
+	}
+	if executions["exec-1"].Status != ports.ExecutionStatusCompleted || states["exec-1"].Get("publish") != "done" {
+		t.Errorf("expected the execution to complete after both approvals, got %+v", executions["exec-1"])
+	}
+}
+
+func TestEngine_ApprovalRequiresStorage(t *testing.T) {
+	g := mustBuild(graph.Build("g").Start().Approval("review", "ok?").End())
+	if _, err := NewEngine().Run(context.Background(), g, nil); !errors.Is(err, ErrNotDurable) {
+		t.Errorf("expected ErrNotDurable, got %v", err)
+	}
+}
+
+func TestEngine_Recover(t *testing.T) {
+	g := mustBuild(graph.Build("etl").ID("etl").
+		Start().
+		Tool("extract", "db", nil).
+		Tool("load", "warehouse", nil).
+		End())
+
+	// A worker completed extract and died before load.
+	executions := fakes.Executions{}
+	states := fakes.States{}
+	recorder := checkpoint.NewRecorder(states, executions)
+	metadata := ports.ExecutionMetadata{ExecutionID: "exec-1", GraphID: "etl", WorkerID: "dead"}
+	if err := recorder.Start(context.Background(), metadata, nil, "start"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	for _, c := range []checkpoint.Completion{
+		{NodeID: "start", Next: []string{"extract"}},
+		{NodeID: "extract", Output: state.State{"extract": "done"}, Next: []string{"load"}},
+	} {
+		if err := recorder.Complete(context.Background(), "exec-1", c); err != nil {
+			t.Fatalf("Complete failed: %v", err)
+		}
+	}
+
+	var ran []string
+	data, _ := g.ToJSON()
+	engine := NewEngine().
+		Register(graph.ExecutorTypeTool, ExecutorFunc(func(ctx context.Context, node *graph.ExecutorNode, s state.State) (state.State, error) {
+			ran = append(ran, node.ID)
+			return mark(ctx, node, s)
+		})).
+		WithStorage(executions, states).
+		WithGraphs(graphLoader{"etl": []byte(data)}).
+		WithWorkerID("w2")
+
+	recovery := checkpoint.NewRecovery(recorder, memoryWorkers{}, "w2")
+	recovered, err := recovery.Recover(context.Background(), engine)
+	if err != nil || len(recovered) != 1 {
+		t.Fatalf("expected exec-1 to be recovered, got %v, %v", recovered, err)
+	}
+	if len(ran) != 1 || ran[0] != "load" {
+		t.Errorf("expected only load to run, ran %v", ran)
+	}
+	if executions["exec-1"].Status != ports.ExecutionStatusCompleted || states["exec-1"].Get("load") != "done" {
+		t.Errorf("expected the execution to complete, got %+v", executions["exec-1"])
+	}
+}
+
+func TestEngine_RecoverInFlight(t *testing.T) {
+	tests := []struct {
+		name       string
+		idempotent bool
+		want       ports.ExecutionStatus
+	}{
+		{"not idempotent", false, ports.ExecutionStatusFailed},
+		{"idempotent", true, ports.ExecutionStatusCompleted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := mustBuild(graph.Build("etl").ID("etl").
+				Start().
+				Tool("extract", "db", nil).
+				Tool("load", "warehouse", nil).
+				Policy(&graph.Policy{Idempotent: &tt.idempotent}).
+				End())
+
+			// A worker completed extract and died while running load.
+			executions := fakes.Executions{}
+			states := fakes.States{}
+			recorder := checkpoint.NewRecorder(states, executions)
+			metadata := ports.ExecutionMetadata{ExecutionID: "exec-1", GraphID: "etl", WorkerID: "dead"}
+			if err := recorder.Start(context.Background(), metadata, nil, "start"); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+			for _, c := range []checkpoint.Completion{
+				{NodeID: "start", Next: []string{"extract"}},
+				{NodeID: "extract", Output: state.State{"extract": "done"}, Next: []string{"load"}},
+			} {
+				if err := recorder.Complete(context.Background(), "exec-1", c); err != nil {
+					t.Fatalf("Complete failed: %v", err)
+				}
+			}
+			if err := recorder.Begin(context.Background(), "exec-1", "load"); err != nil {
+				t.Fatalf("Begin failed: %v", err)
+			}
+
+			var ran []string
+			data, _ := g.ToJSON()
+			engine := NewEngine().
+				Register(graph.ExecutorTypeTool, ExecutorFunc(func(ctx context.Context, node *graph.ExecutorNode, s state.State) (state.State, error) {
+					ran = append(ran, node.ID)
+					return mark(ctx, node, s)
+				})).
+				WithStorage(executions, states).
+				WithGraphs(graphLoader{"etl": []byte(data)}).
+				WithWorkerID("w2")
+
+			_, err := checkpoint.NewRecovery(recorder, memoryWorkers{}, "w2").Recover(context.Background(), engine)
+			if tt.idempotent != (err == nil) || !tt.idempotent && !errors.Is(err, checkpoint.ErrNotIdempotent) {
+				t.Fatalf("unexpected recovery error %v", err)
+			}
+			if status := executions["exec-1"].Status; status != tt.want {
+				t.Errorf("expected status %s, got %s", tt.want, status)
+			}
+			if tt.idempotent != (len(ran) == 1) {
+				t.Errorf("expected load to run again only if idempotent, ran %v", ran)
+			}
+		})
+	}
+}
+
+func TestEngine_Subgraph(t *testing.T) {
+	inner := mustBuild(graph.Build("retrieval").ID("retrieval").Start().Tool("search", "search", nil).End())
+	g := mustBuild(graph.Build("rag").ID("rag").
+		Start().
+		Node(&graph.SubgraphNode{BaseNode: graph.BaseNode{ID: "retrieve", Type: graph.NodeTypeSubgraph}, Graph: inner}).
+		Tool("answer", "llm", nil).
+		End())
+
+	result, err := NewEngine().Register(graph.ExecutorTypeTool, mark).Run(context.Background(), g, nil)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.State.Get("retrieve.search") != "done" || result.State.Get("answer") != "done" {
+		t.Errorf("expected the subgraph to run inline, got %v", result.State)
+	}
+}
+
+func TestEngine_SubgraphMappings(t *testing.T) {
+	inner := mustBuild(graph.Build("retrieval").ID("retrieval").Start().Tool("search", "search", nil).End())
+	g := mustBuild(graph.Build("rag").ID("rag").
+		Start().
+		Node(&graph.SubgraphNode{
+			BaseNode:      graph.BaseNode{ID: "retrieve", Type: graph.NodeTypeSubgraph},
+			Graph:         inner,
+			InputMapping:  map[string]string{"query": "question"},
+			OutputMapping: map[string]string{"documents": "context"},
+		}).
+		Tool("answer", "answer", nil).
+		End())
+
+	var answered interface{}
+	engine := NewEngine().Register(graph.ExecutorTypeTool, ExecutorFunc(func(ctx context.Context, node *graph.ExecutorNode, s state.State) (state.State, error) {
+		switch node.ID {
+		case "retrieve.search":
+			query, _ := s.GetString("query")
+			s.Set("documents", []string{"about " + query})
+		case "answer":
+			answered = s.Get("context")
+		}
+		return s, nil
+	}))
+
+	result, err := engine.Run(context.Background(), g, state.State{"question": "go"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if docs, ok := answered.([]string); !ok || len(docs) != 1 || docs[0] != "about go" {
+		t.Errorf("expected the mapped documents to reach answer, got %v", answered)
+	}
+	if result.State.Get("query") != "go" {
+		t.Errorf("expected the mapped input in state, got %v", result.State)
+	}
+}
+
+func TestEngine_ExecutorMappings(t *testing.T) {
+	g := mustBuild(graph.Build("summary").ID("summary").
+		Start().
+		Tool("summarize", "summarize", nil).
+		Inputs(map[string]string{"text": "document"}).
+		Outputs(map[string]string{"summary": "short"}).
+		End())
+
+	engine := NewEngine().Register(graph.ExecutorTypeTool, ExecutorFunc(func(ctx context.Context, node *graph.ExecutorNode, s state.State) (state.State, error) {
+		text, _ := s.GetString("text")
+		s.Set("summary", text[:4])
+		s.Set("scratch", true)
+		return s, nil
+	}))
+
+	result, err := engine.Run(context.Background(), g, state.State{"document": "long text"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.State.Get("short") != "long" || result.State.Has("summary") || result.State.Has("scratch") || result.State.Has("text") {
+		t.Errorf("expected only the mapped output in state, got %v", result.State)
+	}
+}
+
+func TestNodesRequireAnEngine(t *testing.T) {
+	nodes := []graph.Node{
+		&graph.ExecutorNode{BaseNode: graph.BaseNode{ID: "x"}},
+		&graph.RouterNode{BaseNode: graph.BaseNode{ID: "r"}},
+		&graph.SubgraphNode{BaseNode: graph.BaseNode{ID: "s"}},
+	}
+	for _, node := range nodes {
+		if _, err := node.Execute(context.Background(), state.State{}); !errors.Is(err, graph.ErrEngineRequired) {
+			t.Errorf("expected ErrEngineRequired from %T, got %v", node, err)
+		}
+	}
+}
+
+// graphLoader is a minimal graph.GraphLoader.
+type graphLoader map[string][]byte
+
+func (l graphLoader) Load(ctx context.Context, graphID string) ([]byte, error) {
+	data, ok := l[graphID]
+	if !ok {
+		return nil, fmt.Errorf("graph not found")
+	}
+	return data, nil
+}
+
+// memoryWorkers is a ports.WorkerRegistry without live workers.
+type memoryWorkers struct {
+	ports.WorkerRegistry
+}
+
+func (memoryWorkers) ListWorkers(ctx context.Context, filter ports.WorkerFilter) ([]ports.WorkerInfo, error) {
+	return nil, nil
+}
@@ -0,0 +1,450 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/aescanero/dago-libs/pkg/approval"
+	"github.com/aescanero/dago-libs/pkg/checkpoint"
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-libs/pkg/events"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// execution is a run of a graph. It advances in steps: every pending node
+// runs concurrently on a private copy of the state, then the outcomes are
+// applied in pending order, which decides conflicting writes. A node with
+// several predecessors joins them: it is scheduled once every predecessor
+// that can still run has completed (see checkpoint.Checkpoint.Ready).
+type execution struct {
+	engine *Engine
+	id     string
+	graph  *graph.Graph
+	state  state.State
+	cp     *checkpoint.Checkpoint
+
+	mu    sync.Mutex
+	nodes map[string]*domain.NodeState
+}
+
+// outcome is the result of running a node in a step.
+type outcome struct {
+	nodeID   string
+	output   state.State
+	deleted  []string
+	next     []string
+	attempts int
+
+	// handled is a node error routed to an error edge.
+	handled error
+
+	// err is a node error that fails the execution.
+	err error
+
+	// waiting reports that the node waits for input.
+	waiting bool
+}
+
+func (e *Engine) newExecution(executionID string, g *graph.Graph, st state.State, cp *checkpoint.Checkpoint) *execution {
+	return &execution{
+		engine: e,
+		id:     executionID,
+		graph:  g,
+		state:  st,
+		cp:     cp,
+		nodes:  make(map[string]*domain.NodeState),
+	}
+}
+
+func (x *execution) run(ctx context.Context) (*Result, error) {
+	for step := 0; len(x.cp.Pending) > 0; step++ {
+		if step >= x.engine.maxSteps {
+			return x.fail(ctx, fmt.Errorf("execution '%s': %w (%d)", x.id, ErrMaxSteps, x.engine.maxSteps))
+		}
+		if err := ctx.Err(); err != nil {
+			return x.cancel(ctx, err)
+		}
+
+		ids := append([]string(nil), x.cp.Pending...)
+		if x.engine.recorder != nil {
+			if err := x.engine.recorder.Begin(ctx, x.id, ids...); err != nil {
+				return x.fail(ctx, err)
+			}
+		}
+		x.cp.Start(ids, x.engine.now())
+
+		// An execution has a single pending input, so it pauses on the first
+		// node of the step that waits for input. The other waiting nodes stay
+		// pending and ask for their input, one at a time, as the execution
+		// is resumed.
+		var waiting, failed *outcome
+		for _, o := range x.step(ctx, ids) {
+			switch {
+			case o.waiting:
+				if waiting == nil {
+					waiting = o
+				}
+			case o.err != nil:
+				if failed == nil {
+					failed = o
+				}
+			default:
+				if err := x.complete(ctx, o); err != nil {
+					return x.fail(ctx, err)
+				}
+			}
+		}
+		if failed != nil {
+			if err := ctx.Err(); err != nil {
+				return x.cancel(ctx, err)
+			}
+			return x.fail(ctx, failed.err)
+		}
+		if waiting != nil {
+			return x.pause(ctx, waiting.nodeID)
+		}
+	}
+	return x.finish(ctx)
+}
+
+// step runs the given nodes concurrently and returns their outcomes in order.
+func (x *execution) step(ctx context.Context, ids []string) []*outcome {
+	outcomes := make([]*outcome, len(ids))
+	var sem chan struct{}
+	if x.engine.maxConcurrency > 0 {
+		sem = make(chan struct{}, x.engine.maxConcurrency)
+	}
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			if sem != nil {
+				sem <- struct{}{}
+				defer func() { <-sem }()
+			}
+			outcomes[i] = x.runNode(ctx, id)
+		}(i, id)
+	}
+	wg.Wait()
+	return outcomes
+}
+
+// runNode runs a node under its effective policy.
+func (x *execution) runNode(ctx context.Context, id string) *outcome {
+	o := &outcome{nodeID: id}
+	node := x.graph.GetNode(id)
+	if node == nil {
+		o.err = fmt.Errorf("node '%s' not found", id)
+		return o
+	}
+	if n, ok := node.(*graph.ExecutorNode); ok {
+		if _, ok := x.engine.executor(n.ExecutorType); !ok {
+			o.err = fmt.Errorf("node '%s': %w for executor type '%s'", id, ErrNoExecutor, n.ExecutorType)
+			x.failed(ctx, id, o.err)
+			return o
+		}
+	}
+	x.started(ctx, id)
+
+	policy := x.graph.EffectivePolicy(id)
+	var out state.State
+	var routed []string
+	attempts, err := policy.Execute(graph.WithIdempotencyKey(ctx, policy.IdempotencyKeyFor(x.id, id)), func(ctx context.Context, attempt int) error {
+		var err error
+		out, routed, err = x.execute(ctx, node, x.state.DeepCopy())
+		return err
+	})
+	o.attempts = attempts
+	if out != nil {
+		o.output, o.deleted = changes(x.state, out)
+	}
+
+	var waiting *graph.InputRequiredError
+	switch {
+	case err == nil:
+		o.next = routed
+		if _, ok := node.(*graph.RouterNode); !ok {
+			o.next, err = x.follow(id, out)
+		}
+		if err != nil {
+			o.err = fmt.Errorf("node '%s': %w", id, err)
+			x.failed(ctx, id, o.err)
+		}
+	case errors.As(err, &waiting):
+		o.waiting = true
+	case ctx.Err() != nil:
+		o.err = fmt.Errorf("node '%s': %w", id, err)
+		x.failed(ctx, id, o.err)
+	default:
+		if target, ok := policy.ErrorTarget(err); ok {
+			o.handled = err
+			o.next = []string{target}
+		} else {
+			o.err = fmt.Errorf("node '%s': %w", id, err)
+			x.failed(ctx, id, o.err)
+		}
+	}
+	return o
+}
+
+// execute runs a node on s. Executors receive s with the node's input mapping
+// applied and their output goes through its output mapping; routers return
+// the route taken.
+func (x *execution) execute(ctx context.Context, node graph.Node, s state.State) (state.State, []string, error) {
+	switch n := node.(type) {
+	case *graph.ExecutorNode:
+		executor, _ := x.engine.executor(n.ExecutorType)
+		out, err := executor.Execute(ctx, n, n.MapInputs(s))
+		if out == nil {
+			return s, nil, err
+		}
+		return n.MapOutputs(s, out), nil, err
+	case *graph.RouterNode:
+		target, err := x.route(n, s)
+		if err != nil {
+			return s, nil, err
+		}
+		return s, []string{target}, nil
+	default:
+		out, err := node.Execute(ctx, s)
+		if out == nil {
+			out = s
+		}
+		return out, nil, err
+	}
+}
+
+// route returns the target of the first matching route of a router, or its
+// default route.
+func (x *execution) route(n *graph.RouterNode, s state.State) (string, error) {
+	for _, r := range n.Routes {
+		ok, err := x.engine.conditions.Evaluate(r.Condition, s)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return r.Target, nil
+		}
+	}
+	if n.DefaultRoute != "" {
+		return n.DefaultRoute, nil
+	}
+	return "", fmt.Errorf("router '%s': %w", n.ID, ErrNoRoute)
+}
+
+// follow returns the targets of the outgoing edges of a node whose condition
+// holds on s.
+func (x *execution) follow(id string, s state.State) ([]string, error) {
+	var next []string
+	for _, edge := range x.graph.GetOutgoingEdges(id) {
+		ok, err := x.engine.conditions.Evaluate(edge.Condition, s)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			next = append(next, edge.To)
+		}
+	}
+	return next, nil
+}
+
+// complete applies the outcome of a completed or handled node to the state
+// and the checkpoint.
+func (x *execution) complete(ctx context.Context, o *outcome) error {
+	completion := checkpoint.Completion{
+		NodeID:   o.nodeID,
+		Output:   o.output,
+		Deleted:  o.deleted,
+		Next:     o.next,
+		Attempts: o.attempts,
+		Graph:    x.graph,
+	}
+	if x.engine.recorder != nil {
+		if err := x.engine.recorder.Complete(ctx, x.id, completion); err != nil {
+			return err
+		}
+	}
+	x.cp.Record(completion, x.engine.now())
+	for key, value := range o.output {
+		x.state.Set(key, value)
+	}
+	for _, key := range o.deleted {
+		x.state.Delete(key)
+	}
+
+	now := x.engine.now()
+	ns := x.nodeState(o.nodeID)
+	ns.CompletedAt = &now
+	ns.Output = o.output
+	ns.Metadata = map[string]interface{}{"attempts": o.attempts}
+	if o.handled != nil {
+		ns.Status = domain.ExecutionStatusFailed
+		ns.Error = o.handled.Error()
+		x.engine.publish(ctx, x.id, o.nodeID, events.NodeFailedPayload{
+			Error:     o.handled.Error(),
+			ErrorKind: graph.ErrorKind(o.handled),
+			Attempts:  o.attempts,
+			HandledBy: o.next[0],
+		})
+		return nil
+	}
+	ns.Status = domain.ExecutionStatusCompleted
+	ns.Error = ""
+	x.engine.publish(ctx, x.id, o.nodeID, events.NodeCompletedPayload{Output: o.output, Attempts: o.attempts, Next: o.next})
+	return nil
+}
+
+// started records the start of a node.
+func (x *execution) started(ctx context.Context, id string) {
+	now := x.engine.now()
+	x.mu.Lock()
+	x.nodes[id] = &domain.NodeState{NodeID: id, Status: domain.ExecutionStatusRunning, StartedAt: &now}
+	x.mu.Unlock()
+	payload := events.NodeStartedPayload{}
+	if node := x.graph.GetNode(id); node != nil {
+		payload.NodeType = string(node.GetType())
+	}
+	x.engine.publish(ctx, x.id, id, payload)
+}
+
+// failed records the unhandled failure of a node.
+func (x *execution) failed(ctx context.Context, id string, err error) {
+	now := x.engine.now()
+	ns := x.nodeState(id)
+	x.mu.Lock()
+	ns.Status = domain.ExecutionStatusFailed
+	ns.Error = err.Error()
+	ns.CompletedAt = &now
+	x.mu.Unlock()
+	x.engine.publish(ctx, x.id, id, events.NodeFailedPayload{Error: err.Error(), ErrorKind: graph.ErrorKind(err)})
+}
+
+func (x *execution) nodeState(id string) *domain.NodeState {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	ns, ok := x.nodes[id]
+	if !ok {
+		ns = &domain.NodeState{NodeID: id}
+		x.nodes[id] = ns
+	}
+	return ns
+}
+
+// pause persists the input request of a node waiting for input.
+func (x *execution) pause(ctx context.Context, nodeID string) (*Result, error) {
+	now := x.engine.now()
+	var req *graph.InputRequest
+	if n, ok := x.graph.GetNode(nodeID).(*graph.ApprovalNode); ok {
+		req = n.NewRequest(x.id, x.state, now)
+	} else {
+		req = &graph.InputRequest{ID: uuid.New().String(), ExecutionID: x.id, NodeID: nodeID, RequestedAt: now}
+	}
+
+	if x.engine.recorder == nil {
+		return x.fail(ctx, fmt.Errorf("node '%s' is waiting for input: %w", nodeID, ErrNotDurable))
+	}
+	approvals := x.engine.approvals
+	if approvals == nil {
+		approvals = approval.NewService(x.engine.executions, x.engine.states).WithClock(x.engine.now)
+	}
+	if err := approvals.Pause(ctx, req); err != nil {
+		return x.fail(ctx, err)
+	}
+
+	ns := x.nodeState(nodeID)
+	ns.Status = domain.ExecutionStatusWaitingForInput
+	x.engine.publish(ctx, x.id, nodeID, events.NodeWaitingForInputPayload{RequestID: req.ID, Prompt: req.Prompt})
+
+	result := x.result(domain.ExecutionStatusWaitingForInput, nil)
+	result.PendingInput = req
+	return result, nil
+}
+
+func (x *execution) finish(ctx context.Context) (*Result, error) {
+	x.engine.untrack(x.id)
+	if err := x.save(ctx, ports.ExecutionStatusCompleted, nil); err != nil {
+		return x.result(domain.ExecutionStatusFailed, err), err
+	}
+	x.engine.publish(ctx, x.id, "", events.GraphCompletedPayload{GraphID: x.graph.ID})
+	return x.result(domain.ExecutionStatusCompleted, nil), nil
+}
+
+func (x *execution) fail(ctx context.Context, err error) (*Result, error) {
+	x.engine.untrack(x.id)
+	if saveErr := x.save(ctx, ports.ExecutionStatusFailed, err); saveErr != nil {
+		err = errors.Join(err, saveErr)
+	}
+	x.engine.publish(ctx, x.id, "", events.GraphFailedPayload{GraphID: x.graph.ID, Error: err.Error()})
+	return x.result(domain.ExecutionStatusFailed, err), err
+}
+
+func (x *execution) cancel(ctx context.Context, err error) (*Result, error) {
+	x.engine.untrack(x.id)
+	ctx = context.WithoutCancel(ctx)
+	err = fmt.Errorf("execution '%s' cancelled: %w", x.id, err)
+	if saveErr := x.save(ctx, ports.ExecutionStatusCancelled, err); saveErr != nil {
+		err = errors.Join(err, saveErr)
+	}
+	x.engine.publish(ctx, x.id, "", events.GraphCancelledPayload{GraphID: x.graph.ID, Reason: err.Error()})
+	return x.result(domain.ExecutionStatusCancelled, err), err
+}
+
+// save records the final status of a durable execution.
+func (x *execution) save(ctx context.Context, status ports.ExecutionStatus, cause error) error {
+	if x.engine.executions == nil {
+		return nil
+	}
+	metadata, err := x.engine.executions.Load(ctx, x.id)
+	if err != nil {
+		return fmt.Errorf("failed to load execution '%s': %w", x.id, err)
+	}
+	now := x.engine.now()
+	metadata.Status = status
+	metadata.CompletedAt = &now
+	if cause != nil {
+		metadata.Error = cause.Error()
+	}
+	if err := x.engine.executions.Save(ctx, *metadata); err != nil {
+		return fmt.Errorf("failed to save execution '%s': %w", x.id, err)
+	}
+	return nil
+}
+
+func (x *execution) result(status domain.ExecutionStatus, err error) *Result {
+	return &Result{
+		ExecutionID: x.id,
+		Status:      status,
+		State:       x.state,
+		NodeStates:  x.nodes,
+		Checkpoint:  x.cp,
+		Error:       err,
+	}
+}
+
+// changes returns the keys of after that differ from before, and the keys of
+// before missing from after.
+func changes(before, after state.State) (state.State, []string) {
+	output := state.NewState()
+	for key, value := range after {
+		if old, ok := before[key]; !ok || !reflect.DeepEqual(old, value) {
+			output[key] = value
+		}
+	}
+	var deleted []string
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			deleted = append(deleted, key)
+		}
+	}
+	sort.Strings(deleted)
+	return output, deleted
+}
@@ -0,0 +1,384 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// ErrBusClosed is returned by MemoryBus operations after Close.
+var ErrBusClosed = errors.New("event bus closed")
+
+// MemoryBus is an in-memory ports.EventBus, the reference implementation of
+// its delivery semantics. It is meant for tests and single-process
+// deployments: events live in memory and are lost when the process exits.
+//
+// An event published to a topic is queued once per consumer group subscribed
+// to a pattern matching the topic, if it passes the filter of the group;
+// events published to a topic without subscriptions are dropped. A named
+// group outlives its last subscription, so events published while it has no
+// members wait for the next one. Every subscription runs its handler in its
+// own goroutine, one event at a time.
+type MemoryBus struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	topics map[string]map[string]*group // by topic pattern, then group key
+	subs   map[string]*subscription
+	timers map[*time.Timer]struct{}
+	closed bool
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// group is a consumer group of a topic pattern, whose members share its
+// queue and filter.
+type group struct {
+	key     string
+	topic   string
+	durable bool
+	filter  *ports.EventFilter
+	queue   []delivery
+	members map[string]*subscription
+}
+
+// delivery is an event queued for a group.
+type delivery struct {
+	topic    string
+	event    ports.Event
+	attempts int
+}
+
+// subscription is the ports.Subscription of a MemoryBus.
+type subscription struct {
+	bus     *MemoryBus
+	id      string
+	topic   string
+	group   *group
+	handler ports.EventHandler
+	opts    ports.SubscribeOptions
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped bool
+}
+
+// NewMemoryBus creates an empty in-memory event bus.
+func NewMemoryBus() *MemoryBus {
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &MemoryBus{
+		topics: make(map[string]map[string]*group),
+		subs:   make(map[string]*subscription),
+		timers: make(map[*time.Timer]struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// Publish queues the event for every consumer group whose pattern matches
+// topic and whose filter the event passes. The topic must not contain
+// wildcards.
+func (b *MemoryBus) Publish(ctx context.Context, topic string, event ports.Event) error {
+	if err := ValidateTopic(topic); err != nil {
+		return fmt.Errorf("failed to publish: %w", err)
+	}
+	if IsPattern(topic) {
+		return fmt.Errorf("failed to publish: %w: '%s' has wildcards", ErrInvalidTopic, topic)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrBusClosed
+	}
+	for pattern, groups := range b.topics {
+		if !MatchTopic(pattern, topic) {
+			continue
+		}
+		for _, g := range groups {
+			if Matches(g.filter, event) {
+				g.queue = append(g.queue, delivery{topic: topic, event: event})
+			}
+		}
+	}
+	b.cond.Broadcast()
+	return nil
+}
+
+// Subscribe registers handler for the events of the topics matching a
+// pattern. The subscription joins opts.Group, creating it if needed; a group
+// with members keeps its filter, and subscribing to it with another filter
+// fails.
+func (b *MemoryBus) Subscribe(ctx context.Context, topic string, handler ports.EventHandler, opts ports.SubscribeOptions) (ports.Subscription, error) {
+	if err := ValidateTopic(topic); err != nil {
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+	if handler == nil {
+		return nil, fmt.Errorf("failed to subscribe to '%s': handler is required", topic)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrBusClosed
+	}
+
+	s := &subscription{bus: b, id: uuid.New().String(), topic: topic, handler: handler, opts: opts}
+
+	key := opts.Group
+	if key == "" {
+		key = "\x00" + s.id
+	}
+	groups, ok := b.topics[topic]
+	if !ok {
+		groups = make(map[string]*group)
+		b.topics[topic] = groups
+	}
+	g, ok := groups[key]
+	if !ok {
+		g = &group{key: key, topic: topic, durable: opts.Group != "", members: make(map[string]*subscription)}
+		groups[key] = g
+	}
+	if len(g.members) > 0 && !reflect.DeepEqual(g.filter, opts.Filter) {
+		return nil, fmt.Errorf("failed to subscribe to '%s': group '%s' has a different filter", topic, opts.Group)
+	}
+	g.filter = opts.Filter
+	s.group = g
+	s.ctx, s.cancel = context.WithCancel(b.ctx)
+	g.members[s.id] = s
+	b.subs[s.id] = s
+
+	b.wg.Add(1)
+	go s.run()
+	return s, nil
+}
+
+// Unsubscribe removes every subscription and consumer group of a topic
+// pattern. Queued events are dropped.
+func (b *MemoryBus) Unsubscribe(ctx context.Context, topic string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, g := range b.topics[topic] {
+		for _, s := range g.members {
+			b.stop(s)
+		}
+	}
+	delete(b.topics, topic)
+	b.cond.Broadcast()
+	return nil
+}
+
+// Close stops every subscription and waits for running handlers to return.
+// It must not be called from a handler.
+func (b *MemoryBus) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	for t := range b.timers {
+		t.Stop()
+	}
+	b.timers = nil
+	for _, s := range b.subs {
+		s.stopped = true
+	}
+	b.cancel()
+	b.cond.Broadcast()
+	b.mu.Unlock()
+
+	b.wg.Wait()
+	return nil
+}
+
+// Pending returns the number of events queued for a consumer group of a topic
+// pattern, excluding events being handled or waiting for redelivery.
+func (b *MemoryBus) Pending(topic, group string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if g, ok := b.topics[topic][group]; ok {
+		return len(g.queue)
+	}
+	return 0
+}
+
+// stop removes a subscription from its group. The group is removed with its
+// last member unless it is named. The caller holds b.mu.
+func (b *MemoryBus) stop(s *subscription) {
+	if s.stopped {
+		return
+	}
+	s.stopped = true
+	s.cancel()
+	delete(b.subs, s.id)
+	g := s.group
+	delete(g.members, s.id)
+	if len(g.members) == 0 && !g.durable {
+		delete(b.topics[g.topic], g.key)
+		if len(b.topics[g.topic]) == 0 {
+			delete(b.topics, g.topic)
+		}
+	}
+}
+
+// requeue puts a delivery back in its group queue after delay. The caller
+// holds b.mu.
+func (b *MemoryBus) requeue(g *group, d delivery, delay time.Duration) {
+	if b.closed {
+		return
+	}
+	if delay <= 0 {
+		g.queue = append(g.queue, d)
+		b.cond.Broadcast()
+		return
+	}
+	var t *time.Timer
+	t = time.AfterFunc(delay, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.closed {
+			return
+		}
+		delete(b.timers, t)
+		g.queue = append(g.queue, d)
+		b.cond.Broadcast()
+	})
+	b.timers[t] = struct{}{}
+}
+
+// ID implements ports.Subscription.
+func (s *subscription) ID() string { return s.id }
+
+// Topic implements ports.Subscription.
+func (s *subscription) Topic() string { return s.topic }
+
+// Group implements ports.Subscription.
+func (s *subscription) Group() string { return s.opts.Group }
+
+// Unsubscribe implements ports.Subscription. The event being handled, if any,
+// has its context cancelled and, unless acknowledged, returns to the group.
+func (s *subscription) Unsubscribe(ctx context.Context) error {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.stop(s)
+	s.bus.cond.Broadcast()
+	return nil
+}
+
+// run delivers the events of the group to the subscription until it stops.
+func (s *subscription) run() {
+	b := s.bus
+	defer b.wg.Done()
+	for {
+		b.mu.Lock()
+		for !s.stopped && len(s.group.queue) == 0 {
+			b.cond.Wait()
+		}
+		if s.stopped {
+			b.mu.Unlock()
+			return
+		}
+		d := s.group.queue[0]
+		s.group.queue = s.group.queue[1:]
+		b.mu.Unlock()
+
+		s.deliver(d)
+	}
+}
+
+// deliver calls the handler and acknowledges, retries or dead-letters the
+// event depending on its result.
+func (s *subscription) deliver(d delivery) {
+	d.attempts++
+	err := s.handle(d.event, d.attempts)
+	if err == nil {
+		return
+	}
+
+	b := s.bus
+	b.mu.Lock()
+	if s.stopped {
+		// Not acknowledged: hand the event to the rest of the group.
+		d.attempts--
+		if _, ok := b.topics[s.topic][s.group.key]; ok {
+			s.group.queue = append([]delivery{d}, s.group.queue...)
+			b.cond.Broadcast()
+		}
+		b.mu.Unlock()
+		return
+	}
+	retry := s.opts.Retry
+	if retry == nil {
+		retry = &graph.RetryPolicy{MaxAttempts: ports.DefaultMaxDeliveries}
+	}
+	if !errors.Is(err, ports.ErrPoisonEvent) && retry.ShouldRetry(d.attempts, err) {
+		b.requeue(s.group, d, retry.Delay(d.attempts))
+		b.mu.Unlock()
+		return
+	}
+	b.mu.Unlock()
+
+	topic := s.opts.DeadLetterTopic
+	if topic == "" {
+		topic = d.topic + ports.DeadLetterSuffix
+	}
+	_ = b.Publish(context.Background(), topic, deadLetter(d, s, err))
+}
+
+// handle calls the handler, turning a panic into an error.
+func (s *subscription) handle(event ports.Event, attempt int) (err error) {
+	ctx := WithDeliveryAttempt(s.ctx, attempt)
+	if s.opts.AckTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.opts.AckTimeout)
+		defer cancel()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked on event '%s': %v", event.ID, r)
+		}
+	}()
+	return s.handler(ctx, event)
+}
+
+// deliveryAttemptKey is the context key of the delivery attempt.
+type deliveryAttemptKey struct{}
+
+// WithDeliveryAttempt returns a context carrying the number of the delivery
+// of an event, starting at 1. Event buses set it on the context passed to
+// their handlers.
+func WithDeliveryAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, deliveryAttemptKey{}, attempt)
+}
+
+// DeliveryAttempt returns the number of the delivery of the event being
+// handled, starting at 1, or 0 if the bus does not report it.
+func DeliveryAttempt(ctx context.Context) int {
+	attempt, _ := ctx.Value(deliveryAttemptKey{}).(int)
+	return attempt
+}
+
+// deadLetter returns the event of d annotated with why it was dead-lettered.
+func deadLetter(d delivery, s *subscription, err error) ports.Event {
+	event := d.event
+	metadata := make(map[string]interface{}, len(event.Metadata)+4)
+	for k, v := range event.Metadata {
+		metadata[k] = v
+	}
+	metadata[ports.MetadataDeadLetterTopic] = d.topic
+	metadata[ports.MetadataDeadLetterGroup] = s.opts.Group
+	metadata[ports.MetadataDeadLetterError] = err.Error()
+	metadata[ports.MetadataDeliveries] = d.attempts
+	event.Metadata = metadata
+	return event
+}
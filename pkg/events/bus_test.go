@@ -0,0 +1,322 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// fastRetry redelivers quickly so tests do not wait on backoff.
+func fastRetry(attempts int) *graph.RetryPolicy {
+	return &graph.RetryPolicy{MaxAttempts: attempts, Backoff: graph.BackoffFixed, InitialInterval: graph.Duration(time.Millisecond)}
+}
+
+// forward returns a handler sending every event to ch.
+func forward(ch chan<- ports.Event) ports.EventHandler {
+	return func(ctx context.Context, event ports.Event) error {
+		ch <- event
+		return nil
+	}
+}
+
+func receive(t *testing.T, ch <-chan ports.Event) ports.Event {
+	t.Helper()
+	select {
+	case event := <-ch:
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for an event")
+		return ports.Event{}
+	}
+}
+
+func expectNone(t *testing.T, ch <-chan ports.Event) {
+	t.Helper()
+	select {
+	case event := <-ch:
+		t.Fatalf("unexpected event %+v", event)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func mustSubscribe(t *testing.T, bus *MemoryBus, topic string, handler ports.EventHandler, opts ports.SubscribeOptions) ports.Subscription {
+	t.Helper()
+	sub, err := bus.Subscribe(context.Background(), topic, handler, opts)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	return sub
+}
+
+func TestMemoryBus_Broadcast(t *testing.T) {
+	bus := NewMemoryBus()
+	defer bus.Close()
+	a, b := make(chan ports.Event, 1), make(chan ports.Event, 1)
+	mustSubscribe(t, bus, "executions", forward(a), ports.SubscribeOptions{})
+	mustSubscribe(t, bus, "executions", forward(b), ports.SubscribeOptions{})
+
+	if err := bus.Publish(context.Background(), "executions", ports.Event{ID: "e1"}); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if receive(t, a).ID != "e1" || receive(t, b).ID != "e1" {
+		t.Error("expected every subscription to receive the event")
+	}
+}
+
+func TestMemoryBus_ConsumerGroup(t *testing.T) {
+	bus := NewMemoryBus()
+	defer bus.Close()
+
+	var mu sync.Mutex
+	seen := make(map[string]int)
+	var wg sync.WaitGroup
+	wg.Add(20)
+	member := func(ctx context.Context, event ports.Event) error {
+		mu.Lock()
+		seen[event.ID]++
+		mu.Unlock()
+		wg.Done()
+		return nil
+	}
+	audit := make(chan ports.Event, 10)
+	mustSubscribe(t, bus, "executions", member, ports.SubscribeOptions{Group: "projector"})
+	mustSubscribe(t, bus, "executions", member, ports.SubscribeOptions{Group: "projector"})
+	mustSubscribe(t, bus, "executions", member, ports.SubscribeOptions{Group: "notifier"})
+	mustSubscribe(t, bus, "executions", forward(audit), ports.SubscribeOptions{})
+
+	for _, id := range []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"} {
+		if err := bus.Publish(context.Background(), "executions", ports.Event{ID: id}); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+	wg.Wait()
+	for id, n := range seen {
+		if n != 2 {
+			t.Errorf("expected event %s once per group, got %d deliveries", id, n)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		receive(t, audit)
+	}
+}
+
+func TestMemoryBus_RetryWithBackoff(t *testing.T) {
+	bus := NewMemoryBus()
+	defer bus.Close()
+	var attempts atomic.Int32
+	acked := make(chan ports.Event, 1)
+	mustSubscribe(t, bus, "executions", func(ctx context.Context, event ports.Event) error {
+		if attempts.Add(1) < 3 {
+			return errors.New("store unavailable")
+		}
+		acked <- event
+		return nil
+	}, ports.SubscribeOptions{Retry: fastRetry(5)})
+	dead := make(chan ports.Event, 1)
+	mustSubscribe(t, bus, "executions.dlq", forward(dead), ports.SubscribeOptions{})
+
+	bus.Publish(context.Background(), "executions", ports.Event{ID: "e1"})
+	if receive(t, acked).ID != "e1" || attempts.Load() != 3 {
+		t.Errorf("expected acknowledgement on the third delivery, got %d", attempts.Load())
+	}
+	expectNone(t, dead)
+}
+
+func TestMemoryBus_DeadLetter(t *testing.T) {
+	bus := NewMemoryBus()
+	defer bus.Close()
+	dead := make(chan ports.Event, 2)
+	mustSubscribe(t, bus, "executions.dlq", forward(dead), ports.SubscribeOptions{})
+	mustSubscribe(t, bus, "poison", forward(dead), ports.SubscribeOptions{})
+
+	var attempts atomic.Int32
+	mustSubscribe(t, bus, "executions", func(ctx context.Context, event ports.Event) error {
+		attempts.Add(1)
+		return errors.New("boom")
+	}, ports.SubscribeOptions{Group: "projector", Retry: fastRetry(3)})
+	mustSubscribe(t, bus, "commands", func(ctx context.Context, event ports.Event) error {
+		return fmt.Errorf("malformed command: %w", ports.ErrPoisonEvent)
+	}, ports.SubscribeOptions{DeadLetterTopic: "poison"})
+
+	bus.Publish(context.Background(), "executions", ports.Event{ID: "e1", Metadata: map[string]interface{}{"tenant": "acme"}})
+	event := receive(t, dead)
+	if event.ID != "e1" || attempts.Load() != 3 {
+		t.Fatalf("expected e1 dead-lettered after 3 deliveries, got %s after %d", event.ID, attempts.Load())
+	}
+	want := map[string]interface{}{
+		"tenant":                      "acme",
+		ports.MetadataDeadLetterTopic: "executions",
+		ports.MetadataDeadLetterGroup: "projector",
+		ports.MetadataDeadLetterError: "boom",
+		ports.MetadataDeliveries:      3,
+	}
+	for k, v := range want {
+		if event.Metadata[k] != v {
+			t.Errorf("expected metadata %s=%v, got %v", k, v, event.Metadata[k])
+		}
+	}
+
+	bus.Publish(context.Background(), "commands", ports.Event{ID: "c1"})
+	if event := receive(t, dead); event.ID != "c1" || event.Metadata[ports.MetadataDeliveries] != 1 {
+		t.Errorf("expected the poison event dead-lettered at once, got %+v", event)
+	}
+}
+
+func TestMemoryBus_AckTimeoutAndPanic(t *testing.T) {
+	bus := NewMemoryBus()
+	defer bus.Close()
+	dead := make(chan ports.Event, 1)
+	mustSubscribe(t, bus, "executions.dlq", forward(dead), ports.SubscribeOptions{})
+
+	var attempts atomic.Int32
+	acked := make(chan ports.Event, 1)
+	mustSubscribe(t, bus, "executions", func(ctx context.Context, event ports.Event) error {
+		if attempts.Add(1) == 1 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		acked <- event
+		return nil
+	}, ports.SubscribeOptions{Retry: fastRetry(2), AckTimeout: 10 * time.Millisecond})
+	mustSubscribe(t, bus, "panics", func(ctx context.Context, event ports.Event) error {
+		panic("bad handler")
+	}, ports.SubscribeOptions{Retry: fastRetry(1), DeadLetterTopic: "executions.dlq"})
+
+	bus.Publish(context.Background(), "executions", ports.Event{ID: "e1"})
+	if receive(t, acked).ID != "e1" {
+		t.Error("expected the timed out event to be redelivered")
+	}
+	bus.Publish(context.Background(), "panics", ports.Event{ID: "p1"})
+	if receive(t, dead).ID != "p1" {
+		t.Error("expected the event to be dead-lettered after the panic")
+	}
+}
+
+func TestMemoryBus_SubscriptionUnsubscribe(t *testing.T) {
+	bus := NewMemoryBus()
+	defer bus.Close()
+	a, b := make(chan ports.Event, 1), make(chan ports.Event, 1)
+	subA := mustSubscribe(t, bus, "executions", forward(a), ports.SubscribeOptions{})
+	mustSubscribe(t, bus, "executions", forward(b), ports.SubscribeOptions{})
+	if subA.Topic() != "executions" || subA.ID() == "" {
+		t.Errorf("unexpected subscription %s on %s", subA.ID(), subA.Topic())
+	}
+
+	if err := subA.Unsubscribe(context.Background()); err != nil {
+		t.Fatalf("Unsubscribe failed: %v", err)
+	}
+	bus.Publish(context.Background(), "executions", ports.Event{ID: "e1"})
+	if receive(t, b).ID != "e1" {
+		t.Error("expected the remaining subscription to receive the event")
+	}
+	expectNone(t, a)
+}
+
+func TestMemoryBus_DurableGroup(t *testing.T) {
+	bus := NewMemoryBus()
+	defer bus.Close()
+	first := make(chan ports.Event, 1)
+	sub := mustSubscribe(t, bus, "executions", forward(first), ports.SubscribeOptions{Group: "projector"})
+	if sub.Group() != "projector" {
+		t.Errorf("expected group projector, got %s", sub.Group())
+	}
+	sub.Unsubscribe(context.Background())
+
+	bus.Publish(context.Background(), "executions", ports.Event{ID: "e1"})
+	if n := bus.Pending("executions", "projector"); n != 1 {
+		t.Fatalf("expected the event to wait for the group, got %d pending", n)
+	}
+	second := make(chan ports.Event, 1)
+	mustSubscribe(t, bus, "executions", forward(second), ports.SubscribeOptions{Group: "projector"})
+	if receive(t, second).ID != "e1" {
+		t.Error("expected the next member to receive the queued event")
+	}
+
+	bus.Unsubscribe(context.Background(), "executions")
+	bus.Publish(context.Background(), "executions", ports.Event{ID: "e2"})
+	if n := bus.Pending("executions", "projector"); n != 0 {
+		t.Errorf("expected the topic to have no groups, got %d pending", n)
+	}
+}
+
+func TestMemoryBus_Close(t *testing.T) {
+	bus := NewMemoryBus()
+	mustSubscribe(t, bus, "executions", func(ctx context.Context, event ports.Event) error { return nil }, ports.SubscribeOptions{})
+	if err := bus.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := bus.Publish(context.Background(), "executions", ports.Event{}); !errors.Is(err, ErrBusClosed) {
+		t.Errorf("expected ErrBusClosed, got %v", err)
+	}
+	if _, err := bus.Subscribe(context.Background(), "executions", forward(nil), ports.SubscribeOptions{}); !errors.Is(err, ErrBusClosed) {
+		t.Errorf("expected ErrBusClosed, got %v", err)
+	}
+}
+
+func TestMemoryBus_TopicPatternsAndFilters(t *testing.T) {
+	bus := NewMemoryBus()
+	defer bus.Close()
+	all, nodes := make(chan ports.Event, 4), make(chan ports.Event, 4)
+	mustSubscribe(t, bus, "executions.>", forward(all), ports.SubscribeOptions{})
+	mustSubscribe(t, bus, "executions.*", forward(nodes), ports.SubscribeOptions{
+		Filter: &ports.EventFilter{Types: []ports.EventType{"node.*"}, ExecutionID: "exec-1"},
+	})
+
+	published := []struct {
+		topic string
+		event ports.Event
+	}{
+		{"executions.exec-1", ports.Event{ID: "1", Type: ports.EventTypeGraphStarted, ExecutionID: "exec-1"}},
+		{"executions.exec-1", ports.Event{ID: "2", Type: ports.EventTypeNodeStarted, ExecutionID: "exec-1"}},
+		{"executions.exec-2", ports.Event{ID: "3", Type: ports.EventTypeNodeStarted, ExecutionID: "exec-2"}},
+		{"executions.exec-1.audit", ports.Event{ID: "4", Type: ports.EventTypeNodeCompleted, ExecutionID: "exec-1"}},
+	}
+	for _, p := range published {
+		if err := bus.Publish(context.Background(), p.topic, p.event); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+	for i := 0; i < 4; i++ {
+		receive(t, all)
+	}
+	if event := receive(t, nodes); event.ID != "2" {
+		t.Errorf("expected only event 2 to pass the filter, got %s", event.ID)
+	}
+	expectNone(t, nodes)
+
+	if err := bus.Publish(context.Background(), "executions.*", ports.Event{}); !errors.Is(err, ErrInvalidTopic) {
+		t.Errorf("expected ErrInvalidTopic publishing to a pattern, got %v", err)
+	}
+	if _, err := bus.Subscribe(context.Background(), "executions.>.x", forward(all), ports.SubscribeOptions{}); !errors.Is(err, ErrInvalidTopic) {
+		t.Errorf("expected ErrInvalidTopic, got %v", err)
+	}
+	_, err := bus.Subscribe(context.Background(), "executions.*", forward(nodes), ports.SubscribeOptions{Group: "g", Filter: &ports.EventFilter{NodeID: "a"}})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if _, err := bus.Subscribe(context.Background(), "executions.*", forward(nodes), ports.SubscribeOptions{Group: "g"}); err == nil {
+		t.Error("expected an error joining a group with another filter")
+	}
+}
+
+func TestMemoryBus_DeadLetterTopicOfPattern(t *testing.T) {
+	bus := NewMemoryBus()
+	defer bus.Close()
+	dead := make(chan ports.Event, 1)
+	mustSubscribe(t, bus, "executions.exec-1.dlq", forward(dead), ports.SubscribeOptions{})
+	mustSubscribe(t, bus, "executions.*", func(ctx context.Context, event ports.Event) error {
+		return errors.New("boom")
+	}, ports.SubscribeOptions{Retry: fastRetry(1)})
+
+	bus.Publish(context.Background(), "executions.exec-1", ports.Event{ID: "e1"})
+	if event := receive(t, dead); event.Metadata[ports.MetadataDeadLetterTopic] != "executions.exec-1" {
+		t.Errorf("expected the published topic in metadata, got %v", event.Metadata)
+	}
+}
@@ -0,0 +1,243 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+const (
+	// CloudEventsSpecVersion is the CloudEvents specification version
+	// produced by ToCloudEvent.
+	CloudEventsSpecVersion = "1.0"
+
+	// CloudEventTypePrefix prefixes event types in the CloudEvents type
+	// attribute, e.g. "io.dago.node.completed".
+	CloudEventTypePrefix = "io.dago."
+
+	// ContentTypeJSON is the datacontenttype of mapped events.
+	ContentTypeJSON = "application/json"
+)
+
+// CloudEvents extension attributes carrying the fields of ports.Event that
+// have no CloudEvents counterpart.
+const (
+	ExtensionExecutionID   = "executionid"
+	ExtensionSchemaVersion = "schemaversion"
+)
+
+// ErrInvalidCloudEvent is returned when a CloudEvent lacks required
+// attributes or cannot be mapped back to an event.
+var ErrInvalidCloudEvent = errors.New("invalid cloud event")
+
+// CloudEvent is an event in the CloudEvents 1.0 JSON format. Extension
+// attributes are serialized as top-level members.
+type CloudEvent struct {
+	SpecVersion     string
+	ID              string
+	Source          string
+	Type            string
+	Subject         string
+	Time            time.Time
+	DataContentType string
+	Data            json.RawMessage
+	Extensions      map[string]interface{}
+}
+
+// ToCloudEvent maps an event to a CloudEvent with the given source, e.g.
+// "/dago/orchestrator". The node ID becomes the subject, the execution ID and
+// schema version become extensions, and metadata entries with valid
+// extension names and scalar values are kept as extensions.
+func ToCloudEvent(event ports.Event, source string) (CloudEvent, error) {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return CloudEvent{}, fmt.Errorf("failed to encode data of event '%s': %w", event.ID, err)
+	}
+	version := event.SchemaVersion
+	if version == "" {
+		version = SchemaVersion
+	}
+
+	ce := CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              event.ID,
+		Source:          source,
+		Type:            CloudEventTypePrefix + string(event.Type),
+		Subject:         event.NodeID,
+		Time:            event.Timestamp,
+		DataContentType: ContentTypeJSON,
+		Data:            data,
+		Extensions:      map[string]interface{}{ExtensionSchemaVersion: version},
+	}
+	if event.ExecutionID != "" {
+		ce.Extensions[ExtensionExecutionID] = event.ExecutionID
+	}
+	for name, value := range event.Metadata {
+		if !validExtensionName(name) || reserved[name] {
+			continue
+		}
+		switch value.(type) {
+		case string, bool, int, int32, int64, float64:
+			if _, exists := ce.Extensions[name]; !exists {
+				ce.Extensions[name] = value
+			}
+		}
+	}
+	return ce, nil
+}
+
+// FromCloudEvent maps a CloudEvent produced by ToCloudEvent back to an
+// event. Extensions other than the execution ID and schema version become
+// metadata.
+func FromCloudEvent(ce CloudEvent) (ports.Event, error) {
+	if ce.ID == "" || ce.Type == "" {
+		return ports.Event{}, fmt.Errorf("%w: id and type are required", ErrInvalidCloudEvent)
+	}
+	eventType, ok := strings.CutPrefix(ce.Type, CloudEventTypePrefix)
+	if !ok {
+		return ports.Event{}, fmt.Errorf("%w: type %q lacks prefix %q", ErrInvalidCloudEvent, ce.Type, CloudEventTypePrefix)
+	}
+
+	event := ports.Event{
+		ID:        ce.ID,
+		Type:      ports.EventType(eventType),
+		Timestamp: ce.Time,
+		NodeID:    ce.Subject,
+	}
+	for name, value := range ce.Extensions {
+		switch name {
+		case ExtensionExecutionID:
+			event.ExecutionID, _ = value.(string)
+		case ExtensionSchemaVersion:
+			event.SchemaVersion, _ = value.(string)
+		default:
+			if event.Metadata == nil {
+				event.Metadata = make(map[string]interface{})
+			}
+			event.Metadata[name] = value
+		}
+	}
+	if err := CheckVersion(event.SchemaVersion); err != nil {
+		return ports.Event{}, err
+	}
+	if len(ce.Data) > 0 && string(ce.Data) != "null" {
+		if err := json.Unmarshal(ce.Data, &event.Data); err != nil {
+			return ports.Event{}, fmt.Errorf("%w: data of event '%s' is not a JSON object: %v", ErrInvalidCloudEvent, ce.ID, err)
+		}
+	}
+	return event, nil
+}
+
+// reserved holds the CloudEvents context attribute names, which extensions
+// must not use.
+var reserved = map[string]bool{
+	"specversion":     true,
+	"id":              true,
+	"source":          true,
+	"type":            true,
+	"subject":         true,
+	"time":            true,
+	"datacontenttype": true,
+	"dataschema":      true,
+	"data":            true,
+	"data_base64":     true,
+}
+
+// validExtensionName reports whether name is a valid CloudEvents attribute
+// name: lower-case ASCII letters and digits only.
+func validExtensionName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalJSON encodes the CloudEvent in the structured JSON format.
+func (ce CloudEvent) MarshalJSON() ([]byte, error) {
+	out := make(map[string]interface{}, len(ce.Extensions)+8)
+	for name, value := range ce.Extensions {
+		out[name] = value
+	}
+	out["specversion"] = ce.SpecVersion
+	out["id"] = ce.ID
+	out["source"] = ce.Source
+	out["type"] = ce.Type
+	if ce.Subject != "" {
+		out["subject"] = ce.Subject
+	}
+	if !ce.Time.IsZero() {
+		out["time"] = ce.Time.Format(time.RFC3339Nano)
+	}
+	if ce.DataContentType != "" {
+		out["datacontenttype"] = ce.DataContentType
+	}
+	if len(ce.Data) > 0 {
+		out["data"] = ce.Data
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes a CloudEvent in the structured JSON format. Members
+// other than the context attributes and data become extensions.
+func (ce *CloudEvent) UnmarshalJSON(raw []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &members); err != nil {
+		return err
+	}
+
+	*ce = CloudEvent{}
+	strs := map[string]*string{
+		"specversion":     &ce.SpecVersion,
+		"id":              &ce.ID,
+		"source":          &ce.Source,
+		"type":            &ce.Type,
+		"subject":         &ce.Subject,
+		"datacontenttype": &ce.DataContentType,
+	}
+	for name, value := range members {
+		if field, ok := strs[name]; ok {
+			if err := json.Unmarshal(value, field); err != nil {
+				return fmt.Errorf("%w: attribute %s: %v", ErrInvalidCloudEvent, name, err)
+			}
+			continue
+		}
+		switch name {
+		case "time":
+			var t string
+			if err := json.Unmarshal(value, &t); err != nil {
+				return fmt.Errorf("%w: attribute time: %v", ErrInvalidCloudEvent, err)
+			}
+			parsed, err := time.Parse(time.RFC3339Nano, t)
+			if err != nil {
+				return fmt.Errorf("%w: attribute time: %v", ErrInvalidCloudEvent, err)
+			}
+			ce.Time = parsed
+		case "data":
+			ce.Data = append(json.RawMessage(nil), value...)
+		case "dataschema", "data_base64":
+			// Not produced by ToCloudEvent.
+		default:
+			var v interface{}
+			if err := json.Unmarshal(value, &v); err != nil {
+				return fmt.Errorf("%w: extension %s: %v", ErrInvalidCloudEvent, name, err)
+			}
+			if ce.Extensions == nil {
+				ce.Extensions = make(map[string]interface{})
+			}
+			ce.Extensions[name] = v
+		}
+	}
+	if ce.SpecVersion != CloudEventsSpecVersion {
+		return fmt.Errorf("%w: unsupported specversion %q", ErrInvalidCloudEvent, ce.SpecVersion)
+	}
+	return nil
+}
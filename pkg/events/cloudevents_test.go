@@ -0,0 +1,103 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+func TestCloudEvent_RoundTrip(t *testing.T) {
+	event := ports.Event{
+		ID:            "evt-1",
+		Type:          ports.EventTypeNodeFailed,
+		SchemaVersion: SchemaVersion,
+		Timestamp:     time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC),
+		ExecutionID:   "exec-1",
+		NodeID:        "fetch",
+		Data:          map[string]interface{}{"error": "timeout", "attempts": float64(3)},
+		Metadata:      map[string]interface{}{"tenant": "acme", "Bad-Name": "x", "nested": map[string]interface{}{}},
+	}
+
+	ce, err := ToCloudEvent(event, "/dago/orchestrator")
+	if err != nil {
+		t.Fatalf("ToCloudEvent failed: %v", err)
+	}
+	raw, err := json.Marshal(ce)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var members map[string]interface{}
+	if err := json.Unmarshal(raw, &members); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	want := map[string]interface{}{
+		"specversion":     "1.0",
+		"id":              "evt-1",
+		"source":          "/dago/orchestrator",
+		"type":            "io.dago.node.failed",
+		"subject":         "fetch",
+		"time":            "2025-01-02T03:04:05.000000006Z",
+		"datacontenttype": "application/json",
+		"data":            map[string]interface{}{"error": "timeout", "attempts": float64(3)},
+		"executionid":     "exec-1",
+		"schemaversion":   "1.0",
+		"tenant":          "acme",
+	}
+	if !reflect.DeepEqual(members, want) {
+		t.Errorf("expected %v, got %v", want, members)
+	}
+
+	var decoded CloudEvent
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("UnmarshalJSON failed: %v", err)
+	}
+	back, err := FromCloudEvent(decoded)
+	if err != nil {
+		t.Fatalf("FromCloudEvent failed: %v", err)
+	}
+	event.Metadata = map[string]interface{}{"tenant": "acme"}
+	if !reflect.DeepEqual(back, event) {
+		t.Errorf("expected %+v, got %+v", event, back)
+	}
+
+	payload, err := Decode(back)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if want := (NodeFailedPayload{Error: "timeout", Attempts: 3}); payload != want {
+		t.Errorf("expected %+v, got %+v", want, payload)
+	}
+}
+
+func TestFromCloudEvent_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		ce   CloudEvent
+		want error
+	}{
+		{"missing id", CloudEvent{Type: "io.dago.node.started"}, ErrInvalidCloudEvent},
+		{"foreign type", CloudEvent{ID: "1", Type: "com.example.thing"}, ErrInvalidCloudEvent},
+		{"array data", CloudEvent{ID: "1", Type: "io.dago.node.started", Data: json.RawMessage(`[1]`)}, ErrInvalidCloudEvent},
+		{"newer version", CloudEvent{ID: "1", Type: "io.dago.node.started", Extensions: map[string]interface{}{"schemaversion": "2.0"}}, ErrUnsupportedVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := FromCloudEvent(tt.ce); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestCloudEvent_UnmarshalSpecVersion(t *testing.T) {
+	var ce CloudEvent
+	err := json.Unmarshal([]byte(`{"specversion":"0.3","id":"1","source":"s","type":"io.dago.node.started"}`), &ce)
+	if !errors.Is(err, ErrInvalidCloudEvent) {
+		t.Errorf("expected ErrInvalidCloudEvent, got %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+// Package events defines typed payloads for the events published on the
+// ports.EventBus, a versioned event envelope, and the mapping of events to
+// CloudEvents 1.0.
+//
+// Each event type has a payload struct, such as NodeCompletedPayload or
+// ToolExecutedPayload. NewEvent builds an event from a payload, storing its
+// JSON form in ports.Event.Data and stamping the envelope with
+// SchemaVersion; Decode and DecodeAs turn the data back into the payload.
+// Minor schema versions only add optional fields, so consumers decode events
+// of any minor version; events of a newer major version are rejected with
+// ErrUnsupportedVersion. Events without a version are treated as 1.0.
+//
+// ToCloudEvent and FromCloudEvent map events to and from CloudEvents, whose
+// JSON encoding (CloudEvent.MarshalJSON) can be forwarded as is to brokers
+// and webhooks that understand the format. The event type is prefixed with
+// CloudEventTypePrefix, the node ID becomes the subject, and the execution
+// ID and schema version travel as extension attributes.
+//
+// MemoryBus is an in-memory ports.EventBus implementing its delivery
+// semantics: at-least-once delivery acknowledged by handler results,
+// consumer groups, redelivery with backoff and dead-letter topics.
+// Subscriptions take topic patterns (see MatchTopic) and an optional
+// ports.EventFilter, applied before events are queued (see Matches).
+package events
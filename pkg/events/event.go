@@ -0,0 +1,167 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// SchemaVersion is the version of the event envelope and payload schemas
+// written by this package. Minor versions only add optional fields; a new
+// major version may change or remove fields.
+const SchemaVersion = "1.0"
+
+var (
+	// ErrUnknownEventType is returned when decoding an event whose type has
+	// no registered payload.
+	ErrUnknownEventType = errors.New("unknown event type")
+
+	// ErrUnsupportedVersion is returned when decoding an event written with a
+	// newer major schema version.
+	ErrUnsupportedVersion = errors.New("unsupported event schema version")
+)
+
+var (
+	payloadsMu sync.RWMutex
+	payloads   = map[ports.EventType]reflect.Type{}
+)
+
+func init() {
+	for _, p := range []Payload{
+		GraphSubmittedPayload{},
+		GraphStartedPayload{},
+		GraphCompletedPayload{},
+		GraphFailedPayload{},
+		GraphCancelledPayload{},
+		GraphResumedPayload{},
+		NodeReadyPayload{},
+		NodeStartedPayload{},
+		NodeCompletedPayload{},
+		NodeFailedPayload{},
+		NodeWaitingForInputPayload{},
+		StateChangedPayload{},
+		ToolExecutedPayload{},
+	} {
+		Register(p)
+	}
+}
+
+// Register registers the payload type of its event type, so that Decode can
+// decode events of custom types. The payload must be a struct value.
+func Register(p Payload) {
+	payloadsMu.Lock()
+	defer payloadsMu.Unlock()
+	payloads[p.EventType()] = reflect.TypeOf(p)
+}
+
+// NewEvent creates an event carrying payload, with a generated ID, the
+// current time and the current schema version.
+func NewEvent(executionID, nodeID string, payload Payload) (ports.Event, error) {
+	data, err := Encode(payload)
+	if err != nil {
+		return ports.Event{}, err
+	}
+	return ports.Event{
+		ID:            uuid.New().String(),
+		Type:          payload.EventType(),
+		SchemaVersion: SchemaVersion,
+		Timestamp:     time.Now().UTC(),
+		ExecutionID:   executionID,
+		NodeID:        nodeID,
+		Data:          data,
+	}, nil
+}
+
+// Encode returns the JSON form of a payload, as stored in Event.Data.
+func Encode(payload Payload) (map[string]interface{}, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s payload: %w", payload.EventType(), err)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("failed to encode %s payload: %w", payload.EventType(), err)
+	}
+	return data, nil
+}
+
+// Decode returns the typed payload of an event, e.g. a NodeCompletedPayload
+// for a node.completed event. Unknown fields, written by newer minor
+// versions, are ignored.
+func Decode(event ports.Event) (Payload, error) {
+	return decode(event.Type, event.SchemaVersion, event.Data)
+}
+
+// DecodeDomain returns the typed payload of a domain.Event.
+func DecodeDomain(event domain.Event) (Payload, error) {
+	return decode(ports.EventType(event.Type), event.SchemaVersion, event.Data)
+}
+
+// DecodeAs decodes the payload of an event into the payload type T.
+func DecodeAs[T Payload](event ports.Event) (T, error) {
+	var zero T
+	payload, err := Decode(event)
+	if err != nil {
+		return zero, err
+	}
+	typed, ok := payload.(T)
+	if !ok {
+		return zero, fmt.Errorf("event '%s' of type %s does not carry a %T", event.ID, event.Type, zero)
+	}
+	return typed, nil
+}
+
+func decode(eventType ports.EventType, version string, data map[string]interface{}) (Payload, error) {
+	if err := CheckVersion(version); err != nil {
+		return nil, err
+	}
+	payloadsMu.RLock()
+	t, ok := payloads[eventType]
+	payloadsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEventType, eventType)
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s payload: %w", eventType, err)
+	}
+	ptr := reflect.New(t)
+	if err := json.Unmarshal(raw, ptr.Interface()); err != nil {
+		return nil, fmt.Errorf("failed to decode %s payload: %w", eventType, err)
+	}
+	return ptr.Elem().Interface().(Payload), nil
+}
+
+// CheckVersion checks that events of a schema version can be decoded: its
+// major version must not be newer than SchemaVersion. An empty version is
+// treated as 1.0, the version of events written before versioning.
+func CheckVersion(version string) error {
+	if version == "" {
+		return nil
+	}
+	major, err := majorVersion(version)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrUnsupportedVersion, version)
+	}
+	current, _ := majorVersion(SchemaVersion)
+	if major > current {
+		return fmt.Errorf("%w: %s (supported: %s)", ErrUnsupportedVersion, version, SchemaVersion)
+	}
+	return nil
+}
+
+func majorVersion(version string) (int, error) {
+	major, _, _ := strings.Cut(version, ".")
+	return strconv.Atoi(major)
+}
@@ -0,0 +1,106 @@
+package events
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+func TestNewEvent_Decode(t *testing.T) {
+	payload := NodeCompletedPayload{
+		Output:   map[string]interface{}{"summary": "ok", "score": 0.5},
+		Attempts: 2,
+		Next:     []string{"review"},
+	}
+	event, err := NewEvent("exec-1", "summarize", payload)
+	if err != nil {
+		t.Fatalf("NewEvent failed: %v", err)
+	}
+	if event.ID == "" || event.Timestamp.IsZero() {
+		t.Errorf("expected a generated ID and timestamp, got %+v", event)
+	}
+	if event.Type != ports.EventTypeNodeCompleted || event.SchemaVersion != SchemaVersion {
+		t.Errorf("expected a versioned node.completed event, got %s %s", event.Type, event.SchemaVersion)
+	}
+	if event.Data["attempts"] != float64(2) {
+		t.Errorf("expected the JSON form in data, got %v", event.Data)
+	}
+
+	got, err := Decode(event)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, payload) {
+		t.Errorf("expected %+v, got %+v", payload, got)
+	}
+
+	typed, err := DecodeAs[NodeCompletedPayload](event)
+	if err != nil || typed.Attempts != 2 {
+		t.Errorf("expected the typed payload, got %+v, %v", typed, err)
+	}
+	if _, err := DecodeAs[NodeFailedPayload](event); err == nil {
+		t.Error("expected an error decoding as the wrong payload type")
+	}
+}
+
+func TestDecode_Versions(t *testing.T) {
+	data := map[string]interface{}{"tool_name": "search", "duration_ms": 12, "added_in_1_3": true}
+
+	for _, version := range []string{"", "1.0", "1.3"} {
+		event := ports.Event{Type: ports.EventTypeToolExecuted, SchemaVersion: version, Data: data}
+		got, err := Decode(event)
+		if err != nil {
+			t.Errorf("version %q: unexpected error: %v", version, err)
+			continue
+		}
+		if want := (ToolExecutedPayload{ToolName: "search", DurationMs: 12}); !reflect.DeepEqual(got, want) {
+			t.Errorf("version %q: expected %+v, got %+v", version, want, got)
+		}
+	}
+
+	for _, version := range []string{"2.0", "x"} {
+		event := ports.Event{Type: ports.EventTypeToolExecuted, SchemaVersion: version, Data: data}
+		if _, err := Decode(event); !errors.Is(err, ErrUnsupportedVersion) {
+			t.Errorf("version %q: expected ErrUnsupportedVersion, got %v", version, err)
+		}
+	}
+}
+
+func TestDecode_UnknownType(t *testing.T) {
+	_, err := Decode(ports.Event{Type: "custom.unknown"})
+	if !errors.Is(err, ErrUnknownEventType) {
+		t.Fatalf("expected ErrUnknownEventType, got %v", err)
+	}
+
+	Register(customPayload{})
+	got, err := Decode(ports.Event{Type: "custom.happened", Data: map[string]interface{}{"count": 3}})
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if got != (customPayload{Count: 3}) {
+		t.Errorf("expected the registered payload, got %+v", got)
+	}
+}
+
+func TestDecodeDomain(t *testing.T) {
+	event := domain.Event{
+		Type: domain.EventTypeGraphCancelled,
+		Data: map[string]interface{}{"graph_id": "g1", "reason": "user"},
+	}
+	got, err := DecodeDomain(event)
+	if err != nil {
+		t.Fatalf("DecodeDomain failed: %v", err)
+	}
+	if want := (GraphCancelledPayload{GraphID: "g1", Reason: "user"}); got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+type customPayload struct {
+	Count int `json:"count"`
+}
+
+func (customPayload) EventType() ports.EventType { return "custom.happened" }
@@ -0,0 +1,108 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// Topic pattern wildcards.
+const (
+	// WildcardSegment matches exactly one segment of a topic.
+	WildcardSegment = "*"
+
+	// WildcardTail, as the last segment of a pattern, matches one or more
+	// trailing segments.
+	WildcardTail = ">"
+)
+
+// ErrInvalidTopic is returned for malformed topics and topic patterns.
+var ErrInvalidTopic = errors.New("invalid topic")
+
+// ValidateTopic checks a topic pattern: non-empty dot-separated segments,
+// with WildcardTail only as the last segment.
+func ValidateTopic(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("%w: topic is empty", ErrInvalidTopic)
+	}
+	segments := strings.Split(pattern, ".")
+	for i, segment := range segments {
+		if segment == "" {
+			return fmt.Errorf("%w: '%s' has an empty segment", ErrInvalidTopic, pattern)
+		}
+		if segment == WildcardTail && i != len(segments)-1 {
+			return fmt.Errorf("%w: '%s' has '%s' before its last segment", ErrInvalidTopic, pattern, WildcardTail)
+		}
+	}
+	return nil
+}
+
+// IsPattern reports whether a topic contains wildcards.
+func IsPattern(topic string) bool {
+	for _, segment := range strings.Split(topic, ".") {
+		if segment == WildcardSegment || segment == WildcardTail {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchTopic reports whether a topic matches a pattern. "executions.*.node"
+// matches "executions.exec-1.node", and "executions.>" matches every topic
+// under "executions".
+func MatchTopic(pattern, topic string) bool {
+	if pattern == topic {
+		return true
+	}
+	p := strings.Split(pattern, ".")
+	t := strings.Split(topic, ".")
+	for i, segment := range p {
+		if segment == WildcardTail && i == len(p)-1 {
+			return len(t) > i
+		}
+		if i >= len(t) || (segment != WildcardSegment && segment != t[i]) {
+			return false
+		}
+	}
+	return len(p) == len(t)
+}
+
+// Matches reports whether an event satisfies every criterion of a filter.
+// A nil filter matches every event.
+func Matches(filter *ports.EventFilter, event ports.Event) bool {
+	if filter == nil {
+		return true
+	}
+	if len(filter.Types) > 0 {
+		matched := false
+		for _, t := range filter.Types {
+			if MatchTopic(string(t), string(event.Type)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if filter.ExecutionID != "" && event.ExecutionID != filter.ExecutionID {
+		return false
+	}
+	if filter.NodeID != "" && event.NodeID != filter.NodeID {
+		return false
+	}
+	if !filter.Since.IsZero() && event.Timestamp.Before(filter.Since) {
+		return false
+	}
+	if !filter.Until.IsZero() && event.Timestamp.After(filter.Until) {
+		return false
+	}
+	for key, value := range filter.Labels {
+		if v, ok := event.Metadata[key].(string); !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
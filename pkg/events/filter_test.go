@@ -0,0 +1,82 @@
+package events
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		pattern, topic string
+		want           bool
+	}{
+		{"executions", "executions", true},
+		{"executions", "executions.exec-1", false},
+		{"executions.*", "executions.exec-1", true},
+		{"executions.*", "executions.exec-1.node", false},
+		{"executions.*.node", "executions.exec-1.node", true},
+		{"executions.*.node", "executions.exec-1.graph", false},
+		{"executions.>", "executions.exec-1", true},
+		{"executions.>", "executions.exec-1.node.completed", true},
+		{"executions.>", "executions", false},
+		{">", "executions", true},
+		{"*", "executions.exec-1", false},
+		{"node.*", "node.completed", true},
+		{"node.*", "graph.completed", false},
+	}
+	for _, tt := range tests {
+		if got := MatchTopic(tt.pattern, tt.topic); got != tt.want {
+			t.Errorf("MatchTopic(%q, %q) = %v, want %v", tt.pattern, tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTopic(t *testing.T) {
+	for _, pattern := range []string{"executions", "executions.*.node", "executions.>", ">"} {
+		if err := ValidateTopic(pattern); err != nil {
+			t.Errorf("%q: unexpected error: %v", pattern, err)
+		}
+	}
+	for _, pattern := range []string{"", "executions.", ".executions", "a..b", "executions.>.node"} {
+		if err := ValidateTopic(pattern); !errors.Is(err, ErrInvalidTopic) {
+			t.Errorf("%q: expected ErrInvalidTopic, got %v", pattern, err)
+		}
+	}
+	if !IsPattern("executions.*") || !IsPattern("executions.>") || IsPattern("executions.exec-1") {
+		t.Error("unexpected IsPattern result")
+	}
+}
+
+func TestMatches(t *testing.T) {
+	at := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	event := ports.Event{Type: ports.EventTypeNodeCompleted, ExecutionID: "exec-1", NodeID: "draft", Timestamp: at,
+		Metadata: map[string]interface{}{"tenant": "acme", "attempt": 2}}
+
+	tests := []struct {
+		name   string
+		filter *ports.EventFilter
+		want   bool
+	}{
+		{"nil", nil, true},
+		{"empty", &ports.EventFilter{}, true},
+		{"type pattern", &ports.EventFilter{Types: []ports.EventType{"graph.*", "node.*"}}, true},
+		{"other types", &ports.EventFilter{Types: []ports.EventType{"graph.*"}}, false},
+		{"execution", &ports.EventFilter{ExecutionID: "exec-1", NodeID: "draft"}, true},
+		{"other execution", &ports.EventFilter{ExecutionID: "exec-2"}, false},
+		{"other node", &ports.EventFilter{NodeID: "review"}, false},
+		{"in range", &ports.EventFilter{Since: at, Until: at}, true},
+		{"too early", &ports.EventFilter{Since: at.Add(time.Second)}, false},
+		{"too late", &ports.EventFilter{Until: at.Add(-time.Second)}, false},
+		{"labels", &ports.EventFilter{Labels: map[string]string{"tenant": "acme"}}, true},
+		{"other label value", &ports.EventFilter{Labels: map[string]string{"tenant": "globex"}}, false},
+		{"non-string label", &ports.EventFilter{Labels: map[string]string{"attempt": "2"}}, false},
+	}
+	for _, tt := range tests {
+		if got := Matches(tt.filter, event); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
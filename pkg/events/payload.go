@@ -0,0 +1,188 @@
+package events
+
+import (
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// Payload is the typed data of an event of a given type.
+type Payload interface {
+	EventType() ports.EventType
+}
+
+// GraphSubmittedPayload is the payload of domain.EventTypeGraphSubmitted.
+type GraphSubmittedPayload struct {
+	GraphID string `json:"graph_id"`
+
+	// Inputs holds the initial state of the execution.
+	Inputs map[string]interface{} `json:"inputs,omitempty"`
+}
+
+// EventType returns domain.EventTypeGraphSubmitted.
+func (GraphSubmittedPayload) EventType() ports.EventType {
+	return ports.EventType(domain.EventTypeGraphSubmitted)
+}
+
+// GraphStartedPayload is the payload of ports.EventTypeGraphStarted.
+type GraphStartedPayload struct {
+	GraphID string `json:"graph_id"`
+}
+
+// EventType returns ports.EventTypeGraphStarted.
+func (GraphStartedPayload) EventType() ports.EventType {
+	return ports.EventTypeGraphStarted
+}
+
+// GraphCompletedPayload is the payload of ports.EventTypeGraphCompleted.
+type GraphCompletedPayload struct {
+	GraphID string `json:"graph_id"`
+}
+
+// EventType returns ports.EventTypeGraphCompleted.
+func (GraphCompletedPayload) EventType() ports.EventType {
+	return ports.EventTypeGraphCompleted
+}
+
+// GraphFailedPayload is the payload of ports.EventTypeGraphFailed.
+type GraphFailedPayload struct {
+	GraphID string `json:"graph_id"`
+
+	// Error describes the failure.
+	Error string `json:"error"`
+
+	// Cancelled reports that the execution was cancelled rather than failed.
+	//
+	// Deprecated: cancellations are published as GraphCancelledPayload;
+	// the field is only read from events published before.
+	Cancelled bool `json:"cancelled,omitempty"`
+}
+
+// EventType returns ports.EventTypeGraphFailed.
+func (GraphFailedPayload) EventType() ports.EventType {
+	return ports.EventTypeGraphFailed
+}
+
+// GraphCancelledPayload is the payload of domain.EventTypeGraphCancelled.
+type GraphCancelledPayload struct {
+	GraphID string `json:"graph_id"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// EventType returns domain.EventTypeGraphCancelled.
+func (GraphCancelledPayload) EventType() ports.EventType {
+	return ports.EventType(domain.EventTypeGraphCancelled)
+}
+
+// GraphResumedPayload is the payload of ports.EventTypeGraphResumed.
+type GraphResumedPayload struct {
+	GraphID string `json:"graph_id"`
+
+	// Pending lists the nodes the execution resumes at.
+	Pending []string `json:"pending,omitempty"`
+}
+
+// EventType returns ports.EventTypeGraphResumed.
+func (GraphResumedPayload) EventType() ports.EventType {
+	return ports.EventTypeGraphResumed
+}
+
+// NodeReadyPayload is the payload of domain.EventTypeNodeReady.
+type NodeReadyPayload struct {
+	NodeType string `json:"node_type,omitempty"`
+}
+
+// EventType returns domain.EventTypeNodeReady.
+func (NodeReadyPayload) EventType() ports.EventType {
+	return ports.EventType(domain.EventTypeNodeReady)
+}
+
+// NodeStartedPayload is the payload of ports.EventTypeNodeStarted.
+type NodeStartedPayload struct {
+	NodeType string `json:"node_type,omitempty"`
+}
+
+// EventType returns ports.EventTypeNodeStarted.
+func (NodeStartedPayload) EventType() ports.EventType {
+	return ports.EventTypeNodeStarted
+}
+
+// NodeCompletedPayload is the payload of ports.EventTypeNodeCompleted.
+type NodeCompletedPayload struct {
+	// Output holds the state keys the node set.
+	Output map[string]interface{} `json:"output,omitempty"`
+
+	// Attempts is the number of attempts the node took.
+	Attempts int `json:"attempts,omitempty"`
+
+	// Next lists the nodes scheduled after this one.
+	Next []string `json:"next,omitempty"`
+}
+
+// EventType returns ports.EventTypeNodeCompleted.
+func (NodeCompletedPayload) EventType() ports.EventType {
+	return ports.EventTypeNodeCompleted
+}
+
+// NodeFailedPayload is the payload of ports.EventTypeNodeFailed.
+type NodeFailedPayload struct {
+	// Error describes the failure.
+	Error string `json:"error"`
+
+	// ErrorKind is the kind matched by error handlers (see graph.ErrorKind).
+	ErrorKind string `json:"error_kind,omitempty"`
+
+	// Attempts is the number of attempts the node took.
+	Attempts int `json:"attempts,omitempty"`
+
+	// HandledBy is the node an error edge routed the failure to, if any.
+	HandledBy string `json:"handled_by,omitempty"`
+}
+
+// EventType returns ports.EventTypeNodeFailed.
+func (NodeFailedPayload) EventType() ports.EventType {
+	return ports.EventTypeNodeFailed
+}
+
+// NodeWaitingForInputPayload is the payload of ports.EventTypeNodeWaitingForInput.
+type NodeWaitingForInputPayload struct {
+	// RequestID is the ID of the pending graph.InputRequest.
+	RequestID string `json:"request_id"`
+
+	// Prompt is the question shown to the human, if any.
+	Prompt string `json:"prompt,omitempty"`
+}
+
+// EventType returns ports.EventTypeNodeWaitingForInput.
+func (NodeWaitingForInputPayload) EventType() ports.EventType {
+	return ports.EventTypeNodeWaitingForInput
+}
+
+// StateChangedPayload is the payload of ports.EventTypeStateChanged.
+type StateChangedPayload struct {
+	// Changed holds the state keys set and their new values.
+	Changed map[string]interface{} `json:"changed,omitempty"`
+
+	// Deleted lists the state keys removed.
+	Deleted []string `json:"deleted,omitempty"`
+}
+
+// EventType returns ports.EventTypeStateChanged.
+func (StateChangedPayload) EventType() ports.EventType {
+	return ports.EventTypeStateChanged
+}
+
+// ToolExecutedPayload is the payload of ports.EventTypeToolExecuted.
+type ToolExecutedPayload struct {
+	ToolName string                 `json:"tool_name"`
+	Input    map[string]interface{} `json:"input,omitempty"`
+	Output   interface{}            `json:"output,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+
+	// DurationMs is how long the tool ran, in milliseconds.
+	DurationMs int64 `json:"duration_ms,omitempty"`
+}
+
+// EventType returns ports.EventTypeToolExecuted.
+func (ToolExecutedPayload) EventType() ports.EventType {
+	return ports.EventTypeToolExecuted
+}
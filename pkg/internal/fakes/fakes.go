@@ -0,0 +1,112 @@
+// Package fakes provides in-memory implementations of storage ports for the
+// tests of the packages of this module. They are not safe for concurrent use.
+package fakes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// Executions is a ports.ExecutionStorage and ports.ExecutionStatusSwapper
+// keeping execution metadata by execution ID.
+type Executions map[string]ports.ExecutionMetadata
+
+// Save stores metadata.
+func (m Executions) Save(ctx context.Context, metadata ports.ExecutionMetadata) error {
+	m[metadata.ExecutionID] = metadata
+	return nil
+}
+
+// Load returns a copy of the metadata of an execution.
+func (m Executions) Load(ctx context.Context, executionID string) (*ports.ExecutionMetadata, error) {
+	metadata, ok := m[executionID]
+	if !ok {
+		return nil, fmt.Errorf("execution not found")
+	}
+	return &metadata, nil
+}
+
+// UpdateStatus sets the status of an execution.
+func (m Executions) UpdateStatus(ctx context.Context, executionID string, status ports.ExecutionStatus) error {
+	metadata := m[executionID]
+	metadata.Status = status
+	m[executionID] = metadata
+	return nil
+}
+
+// CompareAndSwapStatus sets the status of an execution to to if it is from.
+func (m Executions) CompareAndSwapStatus(ctx context.Context, executionID string, from, to ports.ExecutionStatus) (bool, error) {
+	metadata, ok := m[executionID]
+	if !ok || metadata.Status != from {
+		return false, nil
+	}
+	metadata.Status = to
+	m[executionID] = metadata
+	return true, nil
+}
+
+// List returns the executions with the given status, or all of them.
+func (m Executions) List(ctx context.Context, status *ports.ExecutionStatus) ([]ports.ExecutionMetadata, error) {
+	var list []ports.ExecutionMetadata
+	for _, metadata := range m {
+		if status == nil || metadata.Status == *status {
+			list = append(list, metadata)
+		}
+	}
+	return list, nil
+}
+
+// Delete removes an execution.
+func (m Executions) Delete(ctx context.Context, executionID string) error {
+	delete(m, executionID)
+	return nil
+}
+
+// States is a state.Manager keeping states by execution ID. It has no
+// snapshots.
+type States map[string]state.State
+
+// Initialize stores the initial state of an execution.
+func (m States) Initialize(ctx context.Context, executionID string, initial state.State) error {
+	m[executionID] = initial
+	return nil
+}
+
+// GetState returns the state of an execution, or nil.
+func (m States) GetState(ctx context.Context, executionID string) (state.State, error) {
+	return m[executionID], nil
+}
+
+// UpdateState stores the state returned by updateFn, unless it fails.
+func (m States) UpdateState(ctx context.Context, executionID string, updateFn func(state.State) (state.State, error)) error {
+	updated, err := updateFn(m[executionID])
+	if err != nil {
+		return err
+	}
+	m[executionID] = updated
+	return nil
+}
+
+// DeleteState removes the state of an execution.
+func (m States) DeleteState(ctx context.Context, executionID string) error {
+	delete(m, executionID)
+	return nil
+}
+
+// SaveSnapshot does nothing.
+func (m States) SaveSnapshot(ctx context.Context, executionID, name string) error {
+	return nil
+}
+
+// LoadSnapshot always fails.
+func (m States) LoadSnapshot(ctx context.Context, executionID, name string) (state.State, error) {
+	return nil, fmt.Errorf("no snapshots")
+}
+
+// ListSnapshots returns no snapshots.
+func (m States) ListSnapshots(ctx context.Context, executionID string) ([]string, error) {
+	return nil, nil
+}
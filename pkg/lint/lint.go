@@ -0,0 +1,238 @@
+// Package lint reports likely mistakes in graph definitions that are
+// structurally valid.
+//
+// Graph.Validate and the JSON schemas reject graphs that cannot be executed.
+// The rules in this package look for graphs that can be executed but probably
+// do not do what their author intended, such as nodes that can never be
+// reached or router routes that can never be taken.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+)
+
+// Severity ranks findings.
+type Severity string
+
+const (
+	// SeverityError marks findings that are almost certainly bugs.
+	SeverityError Severity = "error"
+
+	// SeverityWarning marks findings that are likely mistakes.
+	SeverityWarning Severity = "warning"
+)
+
+// Finding is a problem reported by a rule.
+type Finding struct {
+	// Rule is the name of the rule that reported the finding.
+	Rule string `json:"rule"`
+
+	// Severity ranks the finding.
+	Severity Severity `json:"severity"`
+
+	// Pointer is the JSON Pointer (RFC 6901) of the offending element in the
+	// graph definition (e.g. "/nodes/check/routes/1").
+	Pointer string `json:"pointer"`
+
+	// Message describes the problem.
+	Message string `json:"message"`
+}
+
+// String formats the finding as "severity: pointer: message (rule)".
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s: %s (%s)", f.Severity, f.Pointer, f.Message, f.Rule)
+}
+
+// Rule is a single lint check.
+type Rule struct {
+	// Name identifies the rule in findings.
+	Name string
+
+	// Description explains what the rule reports.
+	Description string
+
+	// Check returns the findings for g.
+	Check func(g *graph.Graph) []Finding
+}
+
+// DefaultRules are the rules applied by Check when none are given.
+var DefaultRules = []Rule{
+	{
+		Name:        "unused-node",
+		Description: "nodes that cannot be reached from the entry node",
+		Check:       checkUnusedNodes,
+	},
+	{
+		Name:        "unreachable-route",
+		Description: "router routes that target unknown nodes or are shadowed by an earlier route",
+		Check:       checkUnreachableRoutes,
+	},
+	{
+		Name:        "missing-default-route",
+		Description: "routers without a default route, which fail when no condition matches",
+		Check:       checkMissingDefaultRoutes,
+	},
+}
+
+// Check applies rules (DefaultRules if none are given) to g and returns the
+// findings ordered by pointer.
+func Check(g *graph.Graph, rules ...Rule) []Finding {
+	if len(rules) == 0 {
+		rules = DefaultRules
+	}
+	var findings []Finding
+	for _, rule := range rules {
+		for _, f := range rule.Check(g) {
+			if f.Rule == "" {
+				f.Rule = rule.Name
+			}
+			findings = append(findings, f)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Pointer < findings[j].Pointer })
+	return findings
+}
+
+// HasErrors reports whether any finding has error severity.
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func checkUnusedNodes(g *graph.Graph) []Finding {
+	reachable := Reachable(g)
+	var findings []Finding
+	for _, id := range sortedNodeIDs(g) {
+		if !reachable[id] {
+			findings = append(findings, Finding{
+				Rule:     "unused-node",
+				Severity: SeverityWarning,
+				Pointer:  nodePointer(id),
+				Message:  fmt.Sprintf("node '%s' is not reachable from the entry node '%s'", id, g.EntryNode),
+			})
+		}
+	}
+	return findings
+}
+
+func checkUnreachableRoutes(g *graph.Graph) []Finding {
+	var findings []Finding
+	for _, id := range sortedNodeIDs(g) {
+		router, ok := g.Nodes[id].(*graph.RouterNode)
+		if !ok {
+			continue
+		}
+		seen := make(map[string]int)
+		catchAll := -1
+		for i, route := range router.Routes {
+			pointer := fmt.Sprintf("%s/routes/%d", nodePointer(id), i)
+			condition := strings.TrimSpace(route.Condition)
+			switch {
+			case g.GetNode(route.Target) == nil:
+				findings = append(findings, Finding{
+					Rule:     "unreachable-route",
+					Severity: SeverityError,
+					Pointer:  pointer,
+					Message:  fmt.Sprintf("route targets unknown node '%s'", route.Target),
+				})
+			case catchAll >= 0:
+				findings = append(findings, Finding{
+					Rule:     "unreachable-route",
+					Severity: SeverityWarning,
+					Pointer:  pointer,
+					Message:  fmt.Sprintf("route is never taken: route %d has no condition and always matches", catchAll),
+				})
+			case condition != "" && seen[condition] > 0:
+				findings = append(findings, Finding{
+					Rule:     "unreachable-route",
+					Severity: SeverityWarning,
+					Pointer:  pointer,
+					Message:  fmt.Sprintf("route is never taken: route %d has the same condition", seen[condition]-1),
+				})
+			}
+			if condition == "" && catchAll < 0 {
+				catchAll = i
+			}
+			if _, ok := seen[condition]; !ok {
+				seen[condition] = i + 1
+			}
+		}
+		if router.DefaultRoute != "" && g.GetNode(router.DefaultRoute) == nil {
+			findings = append(findings, Finding{
+				Rule:     "unreachable-route",
+				Severity: SeverityError,
+				Pointer:  nodePointer(id) + "/default_route",
+				Message:  fmt.Sprintf("default route targets unknown node '%s'", router.DefaultRoute),
+			})
+		}
+	}
+	return findings
+}
+
+func checkMissingDefaultRoutes(g *graph.Graph) []Finding {
+	var findings []Finding
+	for _, id := range sortedNodeIDs(g) {
+		router, ok := g.Nodes[id].(*graph.RouterNode)
+		if !ok || router.DefaultRoute != "" {
+			continue
+		}
+		hasCatchAll := false
+		for _, route := range router.Routes {
+			if strings.TrimSpace(route.Condition) == "" {
+				hasCatchAll = true
+			}
+		}
+		if !hasCatchAll {
+			findings = append(findings, Finding{
+				Rule:     "missing-default-route",
+				Severity: SeverityWarning,
+				Pointer:  nodePointer(id),
+				Message:  fmt.Sprintf("router '%s' has no default route and fails when no condition matches", id),
+			})
+		}
+	}
+	return findings
+}
+
+// Reachable returns the nodes reachable from the entry node through edges,
+// router routes and error edges.
+func Reachable(g *graph.Graph) map[string]bool {
+	reachable := make(map[string]bool)
+	if g.GetNode(g.EntryNode) == nil {
+		return reachable
+	}
+	stack := []string{g.EntryNode}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if reachable[id] {
+			continue
+		}
+		reachable[id] = true
+		stack = append(stack, g.Successors(id)...)
+	}
+	return reachable
+}
+
+func sortedNodeIDs(g *graph.Graph) []string {
+	ids := make([]string, 0, len(g.Nodes))
+	for id := range g.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// nodePointer returns the JSON Pointer of a node in a graph definition.
+func nodePointer(id string) string {
+	id = strings.ReplaceAll(id, "~", "~0")
+	return "/nodes/" + strings.ReplaceAll(id, "/", "~1")
+}
@@ -0,0 +1,145 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+)
+
+func TestCheck_CleanGraph(t *testing.T) {
+	g, err := graph.Build("review").
+		Start().
+		LLM("draft", map[string]interface{}{"model": "gpt-4"}).
+		Router("check", graph.When("state.approved == true", "publish")).
+		Default("draft").
+		Tool("publish", "http_post", nil).
+		End()
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+
+	if findings := Check(g); len(findings) != 0 {
+		t.Errorf("expected no findings, got %v", findings)
+	}
+}
+
+func TestCheck_Findings(t *testing.T) {
+	g, err := graph.Build("review").
+		Start().
+		LLM("draft", map[string]interface{}{"model": "gpt-4"}).
+		Router("check",
+			graph.When("state.approved == true", "publish"),
+			graph.When("", "draft"),
+			graph.When("state.approved == true", "publish"),
+		).
+		Tool("publish", "http_post", nil).
+		End()
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+	orphan := &graph.ExecutorNode{BaseNode: graph.BaseNode{ID: "orphan", Type: graph.NodeTypeExecutor}, ExecutorType: "llm", Config: map[string]interface{}{"model": "gpt-4"}}
+	if err := g.AddNode(orphan); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+	router := &graph.RouterNode{
+		BaseNode:     graph.BaseNode{ID: "triage", Type: graph.NodeTypeRouter},
+		Routes:       []graph.Route{{Condition: "state.urgent", Target: "missing"}},
+		DefaultRoute: "gone",
+	}
+	if err := g.AddNode(router); err != nil {
+		t.Fatalf("AddNode failed: %v", err)
+	}
+
+	findings := Check(g)
+
+	expected := []struct {
+		rule     string
+		severity Severity
+		pointer  string
+	}{
+		{"unreachable-route", SeverityWarning, "/nodes/check/routes/2"},
+		{"unused-node", SeverityWarning, "/nodes/orphan"},
+		{"unused-node", SeverityWarning, "/nodes/triage"},
+		{"unreachable-route", SeverityError, "/nodes/triage/default_route"},
+		{"unreachable-route", SeverityError, "/nodes/triage/routes/0"},
+	}
+	if len(findings) != len(expected) {
+		t.Fatalf("expected %d findings, got %d: %v", len(expected), len(findings), findings)
+	}
+	for _, want := range expected {
+		found := false
+		for _, f := range findings {
+			if f.Rule == want.rule && f.Severity == want.severity && f.Pointer == want.pointer {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("missing finding %+v in %v", want, findings)
+		}
+	}
+	if !HasErrors(findings) {
+		t.Error("expected HasErrors to be true")
+	}
+}
+
+func TestCheck_CustomRules(t *testing.T) {
+	g := graph.NewGraph("empty")
+	rule := Rule{
+		Name: "has-description",
+		Check: func(g *graph.Graph) []Finding {
+			if g.Description == "" {
+				return []Finding{{Severity: SeverityWarning, Pointer: "/description", Message: "graph has no description"}}
+			}
+			return nil
+		},
+	}
+
+	findings := Check(g, rule)
+	if len(findings) != 1 || findings[0].Rule != "has-description" {
+		t.Errorf("expected finding from custom rule, got %v", findings)
+	}
+	if HasErrors(findings) {
+		t.Error("expected warnings only")
+	}
+}
+
+func TestReachable(t *testing.T) {
+	g, err := graph.Build("chain").Start().LLM("a", map[string]interface{}{"model": "gpt-4"}).End()
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+	reachable := Reachable(g)
+	for _, id := range []string{"start", "a", "end"} {
+		if !reachable[id] {
+			t.Errorf("expected %s to be reachable", id)
+		}
+	}
+
+	// Nodes reached only through error edges are reachable.
+	g.Nodes["recover"] = &graph.ExecutorNode{BaseNode: graph.BaseNode{ID: "recover", Type: graph.NodeTypeExecutor}, ExecutorType: graph.ExecutorTypeBash}
+	g.Policy = &graph.Policy{OnError: []graph.ErrorHandler{{Target: "recover"}}}
+	if !Reachable(g)["recover"] {
+		t.Error("expected the error handler to be reachable")
+	}
+
+	g.EntryNode = "missing"
+	if len(Reachable(g)) != 0 {
+		t.Error("expected no reachable nodes without a valid entry node")
+	}
+}
+
+func TestCheck_MissingDefaultRoute(t *testing.T) {
+	g, err := graph.Build("review").
+		Start().
+		Router("check", graph.When("state.approved == true", "publish")).
+		Tool("publish", "http_post", nil).
+		End()
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+
+	findings := Check(g)
+	if len(findings) != 1 || findings[0].Rule != "missing-default-route" || findings[0].Pointer != "/nodes/check" {
+		t.Errorf("expected missing-default-route finding, got %v", findings)
+	}
+}
@@ -0,0 +1,35 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// Deduplicate wraps an event handler so that consumer handles each event
+// once, by Event.ID, although the relay and the bus deliver events at least
+// once. An event is recorded as processed after handler succeeds; events
+// whose handler fails are delivered to it again.
+//
+// Concurrent deliveries of the same event may both run handler: the store
+// is checked before handling, not locked. Subscribe with a consumer group
+// so that one member handles each delivery.
+func Deduplicate(consumer string, store ports.ProcessedEventStore, handler ports.EventHandler) ports.EventHandler {
+	return func(ctx context.Context, event ports.Event) error {
+		processed, err := store.IsProcessed(ctx, consumer, event.ID)
+		if err != nil {
+			return fmt.Errorf("failed to check event '%s': %w", event.ID, err)
+		}
+		if processed {
+			return nil
+		}
+		if err := handler(ctx, event); err != nil {
+			return err
+		}
+		if err := store.MarkProcessed(ctx, consumer, event.ID); err != nil {
+			return fmt.Errorf("failed to record event '%s': %w", event.ID, err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,51 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+func TestDeduplicate(t *testing.T) {
+	var handled []string
+	fail := true
+	handler := Deduplicate("billing", NewMemoryProcessed(0), func(ctx context.Context, event ports.Event) error {
+		if event.ID == "2" && fail {
+			fail = false
+			return errors.New("transient")
+		}
+		handled = append(handled, event.ID)
+		return nil
+	})
+
+	for _, id := range []string{"1", "1", "2", "2", "2"} {
+		handler(context.Background(), ports.Event{ID: id})
+	}
+	if len(handled) != 2 || handled[0] != "1" || handled[1] != "2" {
+		t.Errorf("expected each event handled once, after the failure was retried, got %v", handled)
+	}
+}
+
+// brokenProcessed is a ports.ProcessedEventStore that fails.
+type brokenProcessed struct{}
+
+func (brokenProcessed) IsProcessed(ctx context.Context, consumer, eventID string) (bool, error) {
+	return false, errors.New("unavailable")
+}
+
+func (brokenProcessed) MarkProcessed(ctx context.Context, consumer, eventID string) error {
+	return errors.New("unavailable")
+}
+
+func TestDeduplicate_StoreError(t *testing.T) {
+	called := false
+	handler := Deduplicate("billing", brokenProcessed{}, func(ctx context.Context, event ports.Event) error {
+		called = true
+		return nil
+	})
+	if err := handler(context.Background(), ports.Event{ID: "1"}); err == nil || called {
+		t.Errorf("expected the event to be nacked without handling it, got %v (handled %v)", err, called)
+	}
+}
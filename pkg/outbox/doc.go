@@ -0,0 +1,18 @@
+// Package outbox implements the transactional outbox pattern, publishing
+// the events of a state change exactly when the change is stored.
+//
+// A worker that saves state with ports.StateStorage and then publishes with
+// ports.EventBus.Publish leaves subscribers out of sync if it crashes in
+// between. Instead, it writes the state and the events (built with
+// NewMessage) in one ports.OutboxStorage.SaveWithEvents transaction, and a
+// Relay publishes the stored events to the bus in the background, removing
+// them once published.
+//
+// The relay publishes every event at least once: an event published just
+// before a crash is published again when the relay restarts. Deduplicate
+// wraps the handlers of consumers so that they handle each Event.ID once,
+// recording handled events in a ports.ProcessedEventStore.
+//
+// MemoryStore and MemoryProcessed are in-memory implementations of the
+// ports, for tests and single-process deployments.
+package outbox
@@ -0,0 +1,186 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// MemoryStore is an in-memory ports.OutboxStorage, for tests and
+// single-process deployments. A mutex stands in for the storage transaction.
+type MemoryStore struct {
+	mu       sync.Mutex
+	states   map[string]state.State
+	messages []ports.OutboxMessage
+	dead     []ports.OutboxMessage
+	now      func() time.Time
+}
+
+// NewMemoryStore creates an empty in-memory outbox storage.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{states: make(map[string]state.State), now: time.Now}
+}
+
+// WithClock sets the clock stamping messages written without CreatedAt.
+func (s *MemoryStore) WithClock(now func() time.Time) *MemoryStore {
+	s.now = now
+	return s
+}
+
+// SaveWithEvents stores a copy of the state and appends the messages.
+func (s *MemoryStore) SaveWithEvents(ctx context.Context, executionID string, st state.State, messages []ports.OutboxMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[executionID] = st.DeepCopy()
+	for _, m := range messages {
+		if m.CreatedAt.IsZero() {
+			m.CreatedAt = s.now()
+		}
+		s.messages = append(s.messages, m)
+	}
+	return nil
+}
+
+// State returns a copy of the state saved for an execution.
+func (s *MemoryStore) State(executionID string) (state.State, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.states[executionID]
+	if !ok {
+		return nil, false
+	}
+	return st.DeepCopy(), true
+}
+
+// PendingMessages returns up to limit messages in the order they were
+// written. A limit of zero or less returns every message.
+func (s *MemoryStore) PendingMessages(ctx context.Context, limit int) ([]ports.OutboxMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.messages)
+	if limit > 0 && limit < n {
+		n = limit
+	}
+	return append([]ports.OutboxMessage(nil), s.messages[:n]...), nil
+}
+
+// MarkPublished removes the messages of the events.
+func (s *MemoryStore) MarkPublished(ctx context.Context, eventIDs ...string) error {
+	published := make(map[string]bool, len(eventIDs))
+	for _, id := range eventIDs {
+		published[id] = true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.messages[:0]
+	for _, m := range s.messages {
+		if !published[m.Event.ID] {
+			kept = append(kept, m)
+		}
+	}
+	clear(s.messages[len(kept):])
+	s.messages = kept
+	return nil
+}
+
+// MarkFailed records a failed attempt to publish the message of an event.
+func (s *MemoryStore) MarkFailed(ctx context.Context, eventID string, cause error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.messages {
+		if s.messages[i].Event.ID == eventID {
+			s.messages[i].Attempts++
+			if cause != nil {
+				s.messages[i].LastError = cause.Error()
+			}
+		}
+	}
+	return nil
+}
+
+// MarkDead records a last failed attempt and moves the message of an event
+// to the dead messages.
+func (s *MemoryStore) MarkDead(ctx context.Context, eventID string, cause error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.messages[:0]
+	for _, m := range s.messages {
+		if m.Event.ID != eventID {
+			kept = append(kept, m)
+			continue
+		}
+		m.Attempts++
+		if cause != nil {
+			m.LastError = cause.Error()
+		}
+		s.dead = append(s.dead, m)
+	}
+	clear(s.messages[len(kept):])
+	s.messages = kept
+	return nil
+}
+
+// DeadMessages returns the messages parked by MarkDead, oldest first.
+func (s *MemoryStore) DeadMessages() []ports.OutboxMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ports.OutboxMessage(nil), s.dead...)
+}
+
+// MemoryProcessed is an in-memory ports.ProcessedEventStore remembering the
+// most recent events of each consumer.
+type MemoryProcessed struct {
+	mu       sync.Mutex
+	capacity int
+	seen     map[string]map[string]bool // by consumer, then event ID
+	order    map[string][]string
+}
+
+// NewMemoryProcessed creates a store remembering up to capacity events per
+// consumer, forgetting the oldest first. Zero or less remembers every
+// event.
+func NewMemoryProcessed(capacity int) *MemoryProcessed {
+	return &MemoryProcessed{
+		capacity: capacity,
+		seen:     make(map[string]map[string]bool),
+		order:    make(map[string][]string),
+	}
+}
+
+// IsProcessed reports whether consumer has handled the event.
+func (p *MemoryProcessed) IsProcessed(ctx context.Context, consumer, eventID string) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.seen[consumer][eventID], nil
+}
+
+// MarkProcessed records that consumer has handled the event.
+func (p *MemoryProcessed) MarkProcessed(ctx context.Context, consumer, eventID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	seen := p.seen[consumer]
+	if seen == nil {
+		seen = make(map[string]bool)
+		p.seen[consumer] = seen
+	}
+	if seen[eventID] {
+		return nil
+	}
+	seen[eventID] = true
+	if p.capacity <= 0 {
+		return nil
+	}
+	order := append(p.order[consumer], eventID)
+	if len(order) > p.capacity {
+		delete(seen, order[0])
+		order = order[1:]
+	}
+	p.order[consumer] = order
+	return nil
+}
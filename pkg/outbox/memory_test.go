@@ -0,0 +1,69 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+var epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func message(id string) ports.OutboxMessage {
+	return ports.OutboxMessage{Topic: "executions", Event: ports.Event{ID: id, Type: ports.EventTypeNodeCompleted, ExecutionID: "exec-1"}}
+}
+
+func TestMemoryStore_SaveWithEvents(t *testing.T) {
+	store := NewMemoryStore().WithClock(func() time.Time { return epoch })
+	st := state.State{"draft": "hello"}
+	if err := store.SaveWithEvents(context.Background(), "exec-1", st, []ports.OutboxMessage{message("1"), message("2")}); err != nil {
+		t.Fatalf("SaveWithEvents failed: %v", err)
+	}
+	st["draft"] = "changed"
+
+	saved, ok := store.State("exec-1")
+	if !ok || saved["draft"] != "hello" {
+		t.Errorf("expected a copy of the saved state, got %v", saved)
+	}
+	pending, _ := store.PendingMessages(context.Background(), 1)
+	if len(pending) != 1 || pending[0].Event.ID != "1" || !pending[0].CreatedAt.Equal(epoch) {
+		t.Errorf("expected the first message stamped by the clock, got %+v", pending)
+	}
+}
+
+func TestMemoryStore_Mark(t *testing.T) {
+	store := NewMemoryStore()
+	store.SaveWithEvents(context.Background(), "exec-1", state.NewState(), []ports.OutboxMessage{message("1"), message("2"), message("3")})
+
+	store.MarkFailed(context.Background(), "2", errors.New("bus down"))
+	store.MarkPublished(context.Background(), "1", "3")
+
+	pending, _ := store.PendingMessages(context.Background(), 0)
+	if len(pending) != 1 || pending[0].Event.ID != "2" {
+		t.Fatalf("expected only message 2 pending, got %+v", pending)
+	}
+	if pending[0].Attempts != 1 || pending[0].LastError != "bus down" {
+		t.Errorf("expected the failure to be recorded, got %+v", pending[0])
+	}
+}
+
+func TestMemoryProcessed(t *testing.T) {
+	ctx := context.Background()
+	processed := NewMemoryProcessed(2)
+	for _, id := range []string{"1", "2", "3"} {
+		processed.MarkProcessed(ctx, "billing", id)
+	}
+
+	if ok, _ := processed.IsProcessed(ctx, "billing", "1"); ok {
+		t.Error("expected the oldest event to be forgotten")
+	}
+	if ok, _ := processed.IsProcessed(ctx, "billing", "3"); !ok {
+		t.Error("expected event 3 to be processed")
+	}
+	if ok, _ := processed.IsProcessed(ctx, "audit", "3"); ok {
+		t.Error("expected consumers to be tracked separately")
+	}
+}
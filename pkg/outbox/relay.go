@@ -0,0 +1,167 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/events"
+	"github.com/aescanero/dago-libs/pkg/ports"
+	"github.com/aescanero/dago-libs/pkg/utils/logging"
+)
+
+// Relay defaults.
+const (
+	// DefaultInterval is how often Run polls the outbox.
+	DefaultInterval = time.Second
+
+	// DefaultBatchSize is the number of messages read from the outbox at a
+	// time.
+	DefaultBatchSize = 100
+
+	// DefaultMaxAttempts is the number of attempts to publish a message
+	// before it is parked.
+	DefaultMaxAttempts = 10
+)
+
+// NewMessage builds the outbox message of a typed event payload, to be
+// passed to ports.OutboxStorage.SaveWithEvents.
+func NewMessage(topic, executionID, nodeID string, payload events.Payload) (ports.OutboxMessage, error) {
+	event, err := events.NewEvent(executionID, nodeID, payload)
+	if err != nil {
+		return ports.OutboxMessage{}, err
+	}
+	return ports.OutboxMessage{Topic: topic, Event: event, CreatedAt: event.Timestamp}, nil
+}
+
+// Relay publishes the messages of a ports.OutboxStorage to a ports.EventBus,
+// in the order they were written, and removes them once published.
+//
+// A message is removed after it is published, so a crash in between
+// publishes it again on restart: consumers deduplicate events by ID (see
+// Deduplicate). Run a single relay per outbox to keep events in order.
+//
+// A message that fails to publish holds back the messages behind it until
+// it has failed the maximum number of attempts; it is then parked with
+// ports.OutboxStorage.MarkDead and the relay moves on, so that a message the
+// bus always rejects does not block the outbox.
+type Relay struct {
+	store       ports.OutboxStorage
+	bus         ports.EventBus
+	interval    time.Duration
+	batchSize   int
+	maxAttempts int
+	logger      *logging.Logger
+}
+
+// NewRelay creates a relay from an outbox to an event bus.
+func NewRelay(store ports.OutboxStorage, bus ports.EventBus) *Relay {
+	return &Relay{
+		store:       store,
+		bus:         bus,
+		interval:    DefaultInterval,
+		batchSize:   DefaultBatchSize,
+		maxAttempts: DefaultMaxAttempts,
+	}
+}
+
+// WithInterval sets how often Run polls the outbox.
+func (r *Relay) WithInterval(d time.Duration) *Relay {
+	r.interval = d
+	return r
+}
+
+// WithBatchSize sets the number of messages read from the outbox at a time.
+func (r *Relay) WithBatchSize(n int) *Relay {
+	r.batchSize = n
+	return r
+}
+
+// WithMaxAttempts sets the number of attempts to publish a message before it
+// is parked. Zero or less retries messages forever.
+func (r *Relay) WithMaxAttempts(n int) *Relay {
+	r.maxAttempts = n
+	return r
+}
+
+// WithLogger logs failed relays from Run and parked messages.
+func (r *Relay) WithLogger(logger *logging.Logger) *Relay {
+	r.logger = logger
+	return r
+}
+
+// Flush publishes the pending messages until the outbox is empty, and
+// returns the number of messages published. It stops at the first message
+// that fails to publish, recording the failure with MarkFailed, so that
+// later messages are not published before it; a message failing its last
+// attempt is parked with MarkDead instead, and Flush goes on with the next
+// message.
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	published := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return published, err
+		}
+		messages, err := r.store.PendingMessages(ctx, r.batchSize)
+		if err != nil {
+			return published, fmt.Errorf("failed to read outbox: %w", err)
+		}
+		if len(messages) == 0 {
+			return published, nil
+		}
+		ids := make([]string, 0, len(messages))
+		var publishErr error
+		for _, m := range messages {
+			err := r.bus.Publish(ctx, m.Topic, m.Event)
+			if err == nil {
+				ids = append(ids, m.Event.ID)
+				continue
+			}
+			if r.maxAttempts > 0 && m.Attempts+1 >= r.maxAttempts && ctx.Err() == nil {
+				if markErr := r.store.MarkDead(ctx, m.Event.ID, err); markErr != nil {
+					publishErr = fmt.Errorf("failed to park event '%s' after %d attempts: %w", m.Event.ID, m.Attempts+1, markErr)
+					break
+				}
+				if r.logger != nil {
+					r.logger.Error("parked outbox message", "event_id", m.Event.ID, "topic", m.Topic, "attempts", m.Attempts+1, "error", err)
+				}
+				continue
+			}
+			publishErr = fmt.Errorf("failed to publish event '%s' to '%s': %w", m.Event.ID, m.Topic, err)
+			if markErr := r.store.MarkFailed(ctx, m.Event.ID, err); markErr != nil {
+				publishErr = errors.Join(publishErr, fmt.Errorf("failed to record failure of event '%s': %w", m.Event.ID, markErr))
+			}
+			break
+		}
+		if len(ids) > 0 {
+			if err := r.store.MarkPublished(ctx, ids...); err != nil {
+				return published, errors.Join(publishErr, fmt.Errorf("failed to mark events published: %w", err))
+			}
+			published += len(ids)
+		}
+		if publishErr != nil {
+			return published, publishErr
+		}
+		if len(messages) < r.batchSize {
+			return published, nil
+		}
+	}
+}
+
+// Run flushes the outbox every interval until ctx is cancelled. Failed
+// flushes are logged and retried at the next interval.
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.Flush(ctx); err != nil && ctx.Err() == nil && r.logger != nil {
+			r.logger.Error("failed to relay outbox", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
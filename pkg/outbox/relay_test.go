@@ -0,0 +1,227 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-libs/pkg/events"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// recordingBus is a ports.EventBus recording published events, failing
+// the publishes of the event IDs in fail.
+type recordingBus struct {
+	mu        sync.Mutex
+	published []string
+	fail      map[string]bool
+}
+
+func (b *recordingBus) Publish(ctx context.Context, topic string, event ports.Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.fail[event.ID] {
+		return errors.New("bus down")
+	}
+	b.published = append(b.published, event.ID)
+	return nil
+}
+
+func (b *recordingBus) Subscribe(ctx context.Context, topic string, handler ports.EventHandler, opts ports.SubscribeOptions) (ports.Subscription, error) {
+	return nil, errors.New("not supported")
+}
+
+func (b *recordingBus) Unsubscribe(ctx context.Context, topic string) error { return nil }
+
+func (b *recordingBus) Close() error { return nil }
+
+func (b *recordingBus) ids() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.Join(b.published, ",")
+}
+
+// crashingStore is a MemoryStore whose first MarkPublished fails, as if the
+// relay crashed after publishing.
+type crashingStore struct {
+	*MemoryStore
+	crashed bool
+}
+
+func (s *crashingStore) MarkPublished(ctx context.Context, eventIDs ...string) error {
+	if !s.crashed {
+		s.crashed = true
+		return errors.New("connection lost")
+	}
+	return s.MemoryStore.MarkPublished(ctx, eventIDs...)
+}
+
+func saveMessages(t *testing.T, store ports.OutboxStorage, ids ...string) {
+	t.Helper()
+	var messages []ports.OutboxMessage
+	for _, id := range ids {
+		messages = append(messages, message(id))
+	}
+	if err := store.SaveWithEvents(context.Background(), "exec-1", state.NewState(), messages); err != nil {
+		t.Fatalf("SaveWithEvents failed: %v", err)
+	}
+}
+
+func TestNewMessage(t *testing.T) {
+	m, err := NewMessage("executions", "exec-1", "draft", events.NodeCompletedPayload{Attempts: 2})
+	if err != nil {
+		t.Fatalf("NewMessage failed: %v", err)
+	}
+	if m.Topic != "executions" || m.Event.ID == "" || m.Event.Type != ports.EventTypeNodeCompleted || m.Event.NodeID != "draft" {
+		t.Errorf("unexpected message %+v", m)
+	}
+	payload, err := events.DecodeAs[events.NodeCompletedPayload](m.Event)
+	if err != nil || payload.Attempts != 2 {
+		t.Errorf("expected the payload to round-trip, got %+v (%v)", payload, err)
+	}
+}
+
+func TestRelay_Flush(t *testing.T) {
+	store := NewMemoryStore()
+	bus := &recordingBus{}
+	saveMessages(t, store, "1", "2", "3", "4", "5")
+
+	n, err := NewRelay(store, bus).WithBatchSize(2).Flush(context.Background())
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if n != 5 || bus.ids() != "1,2,3,4,5" {
+		t.Errorf("expected 5 events published in order, got %d: %s", n, bus.ids())
+	}
+	if pending, _ := store.PendingMessages(context.Background(), 0); len(pending) != 0 {
+		t.Errorf("expected an empty outbox, got %+v", pending)
+	}
+}
+
+func TestRelay_PublishFailure(t *testing.T) {
+	store := NewMemoryStore()
+	bus := &recordingBus{fail: map[string]bool{"2": true}}
+	saveMessages(t, store, "1", "2", "3")
+	relay := NewRelay(store, bus)
+
+	n, err := relay.Flush(context.Background())
+	if err == nil || n != 1 || bus.ids() != "1" {
+		t.Fatalf("expected the relay to stop at event 2, got %d: %s (%v)", n, bus.ids(), err)
+	}
+	pending, _ := store.PendingMessages(context.Background(), 0)
+	if len(pending) != 2 || pending[0].Event.ID != "2" || pending[0].Attempts != 1 {
+		t.Fatalf("expected events 2 and 3 pending with the failure recorded, got %+v", pending)
+	}
+
+	bus.fail = nil
+	if _, err := relay.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if bus.ids() != "1,2,3" {
+		t.Errorf("expected the remaining events in order, got %s", bus.ids())
+	}
+}
+
+func TestRelay_ParksPoisonMessages(t *testing.T) {
+	store := NewMemoryStore()
+	bus := &recordingBus{fail: map[string]bool{"2": true}}
+	saveMessages(t, store, "1", "2", "3", "4")
+	relay := NewRelay(store, bus).WithMaxAttempts(3).WithBatchSize(2)
+
+	for i := 0; i < 2; i++ {
+		if _, err := relay.Flush(context.Background()); err == nil {
+			t.Fatalf("expected flush %d to stop at event 2", i+1)
+		}
+	}
+	if bus.ids() != "1" {
+		t.Fatalf("expected only event 1 published before the last attempt, got %s", bus.ids())
+	}
+
+	n, err := relay.Flush(context.Background())
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if n != 2 || bus.ids() != "1,3,4" {
+		t.Errorf("expected the events behind the poison message published, got %d: %s", n, bus.ids())
+	}
+	if pending, _ := store.PendingMessages(context.Background(), 0); len(pending) != 0 {
+		t.Errorf("expected an empty outbox, got %+v", pending)
+	}
+	dead := store.DeadMessages()
+	if len(dead) != 1 || dead[0].Event.ID != "2" || dead[0].Attempts != 3 || dead[0].LastError != "bus down" {
+		t.Errorf("expected event 2 parked after 3 attempts, got %+v", dead)
+	}
+}
+
+func TestRelay_RedeliveryIsDeduplicated(t *testing.T) {
+	store := &crashingStore{MemoryStore: NewMemoryStore()}
+	bus := events.NewMemoryBus()
+	defer bus.Close()
+
+	handled := make(chan string, 10)
+	processed := NewMemoryProcessed(100)
+	_, err := bus.Subscribe(context.Background(), "executions", Deduplicate("billing", processed, func(ctx context.Context, event ports.Event) error {
+		handled <- event.ID
+		return nil
+	}), ports.SubscribeOptions{Group: "billing"})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	saveMessages(t, store, "1", "2")
+	relay := NewRelay(store, bus)
+	if _, err := relay.Flush(context.Background()); err == nil {
+		t.Fatal("expected the first flush to fail after publishing")
+	}
+	if n, err := relay.Flush(context.Background()); err != nil || n != 2 {
+		t.Fatalf("expected the events to be published again, got %d (%v)", n, err)
+	}
+
+	var ids []string
+	for len(ids) < 2 {
+		select {
+		case id := <-handled:
+			ids = append(ids, id)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for events, got %v", ids)
+		}
+	}
+	// Wait for the redelivered events to be acknowledged.
+	deadline := time.Now().Add(2 * time.Second)
+	for bus.Pending("executions", "billing") > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case id := <-handled:
+		t.Errorf("expected duplicates to be skipped, got event %s again", id)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if strings.Join(ids, ",") != "1,2" {
+		t.Errorf("expected events 1 and 2, got %v", ids)
+	}
+}
+
+func TestRelay_Run(t *testing.T) {
+	store := NewMemoryStore()
+	bus := &recordingBus{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- NewRelay(store, bus).WithInterval(time.Millisecond).Run(ctx) }()
+
+	saveMessages(t, store, "1")
+	deadline := time.Now().Add(2 * time.Second)
+	for bus.ids() != "1" {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the relay")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
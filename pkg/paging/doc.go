@@ -0,0 +1,22 @@
+// Package paging implements cursor pagination for ports.EventPager and
+// ports.ExecutionPager.
+//
+// Results are sorted by timestamp then ID, in the ports.SortOrder of the
+// request, and a cursor encodes the sort key of the last result of a page, so
+// pages stay stable while new events and executions are stored. Cursors are
+// opaque base64url strings bound to their sort order.
+//
+// PageEvents and PageExecutions page an in-memory slice, for stores that
+// load their candidates before filtering. QueryPage and ListPage fetch a page
+// from any store, using its pager when it implements one and paging its full
+// results otherwise. Events and Executions iterate over every matching
+// result, fetching one page at a time from pagers; stores without a pager are
+// loaded once and iterated over in memory, which only suits small stores:
+//
+//	for event, err := range paging.Events(ctx, store, filter, ports.PageRequest{Limit: 500}) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+package paging
@@ -0,0 +1,116 @@
+package paging
+
+import (
+	"context"
+	"iter"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/events"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// PageEvents returns the page of the events matching a filter, sorted by
+// timestamp then ID. It implements ports.EventPager for stores that can load
+// every candidate event.
+func PageEvents(all []ports.Event, filter ports.EventFilter, page ports.PageRequest) (ports.EventPage, error) {
+	r, err := Parse(page)
+	if err != nil {
+		return ports.EventPage{}, err
+	}
+	out, next := window(matchEvents(all, filter), r, eventKey)
+	return ports.EventPage{Events: out, NextCursor: next}, nil
+}
+
+func matchEvents(all []ports.Event, filter ports.EventFilter) []ports.Event {
+	var matched []ports.Event
+	for _, event := range all {
+		if events.Matches(&filter, event) {
+			matched = append(matched, event)
+		}
+	}
+	return matched
+}
+
+func eventKey(e ports.Event) (time.Time, string) { return e.Timestamp, e.ID }
+
+// QueryPage returns a page of the events of store matching a filter. Stores
+// implementing ports.EventPager page the query themselves; the events of
+// other stores are loaded with EventStore.Query and paged with PageEvents.
+func QueryPage(ctx context.Context, store ports.EventStore, filter ports.EventFilter, page ports.PageRequest) (ports.EventPage, error) {
+	if pager, ok := store.(ports.EventPager); ok {
+		return pager.QueryPage(ctx, filter, page)
+	}
+	if _, err := Parse(page); err != nil {
+		return ports.EventPage{}, err
+	}
+	all, err := store.Query(ctx, filter)
+	if err != nil {
+		return ports.EventPage{}, err
+	}
+	return PageEvents(all, filter, page)
+}
+
+// Events iterates over every event matching a filter. Stores implementing
+// ports.EventPager are read a page of limit events at a time; the events of
+// other stores are loaded once with EventStore.Query and iterated over in
+// memory. Iteration stops at the first error, which is yielded with a zero
+// event.
+func Events(ctx context.Context, store ports.EventStore, filter ports.EventFilter, page ports.PageRequest) iter.Seq2[ports.Event, error] {
+	return func(yield func(ports.Event, error) bool) {
+		if err := ctx.Err(); err != nil {
+			yield(ports.Event{}, err)
+			return
+		}
+		pager, ok := store.(ports.EventPager)
+		if !ok {
+			all, err := queryAll(ctx, store, filter, page)
+			if err != nil {
+				yield(ports.Event{}, err)
+				return
+			}
+			for _, event := range all {
+				if !yield(event, nil) {
+					return
+				}
+			}
+			return
+		}
+		for {
+			result, err := pager.QueryPage(ctx, filter, page)
+			if err != nil {
+				yield(ports.Event{}, err)
+				return
+			}
+			for _, event := range result.Events {
+				if !yield(event, nil) {
+					return
+				}
+			}
+			if result.NextCursor == "" {
+				return
+			}
+			page.Cursor = result.NextCursor
+			if err := ctx.Err(); err != nil {
+				yield(ports.Event{}, err)
+				return
+			}
+		}
+	}
+}
+
+// queryAll returns the events of a store without a pager that match a
+// filter and follow the cursor of page, in the page order.
+func queryAll(ctx context.Context, store ports.EventStore, filter ports.EventFilter, page ports.PageRequest) ([]ports.Event, error) {
+	r, err := Parse(page)
+	if err != nil {
+		return nil, err
+	}
+	all, err := store.Query(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	matched := matchEvents(all, filter)
+	r.Limit = len(matched)
+	out, _ := window(matched, r, eventKey)
+	return out, nil
+}
@@ -0,0 +1,205 @@
+package paging
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+var epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func event(id string, seconds int, executionID string) ports.Event {
+	return ports.Event{
+		ID:          id,
+		Type:        ports.EventTypeNodeCompleted,
+		ExecutionID: executionID,
+		Timestamp:   epoch.Add(time.Duration(seconds) * time.Second),
+	}
+}
+
+func eventIDs(list []ports.Event) string {
+	var ids []string
+	for _, e := range list {
+		ids = append(ids, e.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+// pagedEvents is a minimal ports.EventStore and ports.EventPager counting its
+// queries and page queries.
+type pagedEvents struct {
+	events  []ports.Event
+	queries int
+	pages   int
+	err     error
+}
+
+func (s *pagedEvents) Store(ctx context.Context, event ports.Event) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *pagedEvents) Query(ctx context.Context, filter ports.EventFilter) ([]ports.Event, error) {
+	s.queries++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.events, nil
+}
+
+func (s *pagedEvents) QueryPage(ctx context.Context, filter ports.EventFilter, page ports.PageRequest) (ports.EventPage, error) {
+	s.pages++
+	if s.err != nil {
+		return ports.EventPage{}, s.err
+	}
+	return PageEvents(s.events, filter, page)
+}
+
+func (s *pagedEvents) GetByID(ctx context.Context, id string) (*ports.Event, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *pagedEvents) GetByExecutionID(ctx context.Context, executionID string) ([]ports.Event, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestPageEvents(t *testing.T) {
+	all := []ports.Event{
+		event("c", 1, "exec-1"),
+		event("a", 0, "exec-1"),
+		event("x", 0, "exec-2"),
+		event("b", 1, "exec-1"),
+		event("d", 2, "exec-1"),
+	}
+	filter := ports.EventFilter{ExecutionID: "exec-1"}
+
+	var pages []string
+	page := ports.PageRequest{Limit: 2}
+	for {
+		result, err := PageEvents(all, filter, page)
+		if err != nil {
+			t.Fatalf("PageEvents failed: %v", err)
+		}
+		pages = append(pages, eventIDs(result.Events))
+		if result.NextCursor == "" {
+			break
+		}
+		page.Cursor = result.NextCursor
+	}
+	if strings.Join(pages, "|") != "a,b|c,d" {
+		t.Errorf("expected pages a,b|c,d sorted by timestamp then ID, got %v", pages)
+	}
+	if eventIDs(all) != "c,a,x,b,d" {
+		t.Errorf("expected the input to be left unsorted, got %s", eventIDs(all))
+	}
+}
+
+func TestPageEvents_Descending(t *testing.T) {
+	all := []ports.Event{event("a", 0, "exec-1"), event("b", 1, "exec-1"), event("c", 1, "exec-1"), event("d", 2, "exec-1")}
+
+	first, err := PageEvents(all, ports.EventFilter{}, ports.PageRequest{Limit: 3, Order: ports.SortDescending})
+	if err != nil {
+		t.Fatalf("PageEvents failed: %v", err)
+	}
+	if eventIDs(first.Events) != "d,c,b" || first.NextCursor == "" {
+		t.Fatalf("expected d,c,b and a cursor, got %s %q", eventIDs(first.Events), first.NextCursor)
+	}
+	// Events stored after the first page do not shift the next one.
+	all = append(all, event("e", 3, "exec-1"))
+	second, err := PageEvents(all, ports.EventFilter{}, ports.PageRequest{Limit: 3, Order: ports.SortDescending, Cursor: first.NextCursor})
+	if err != nil {
+		t.Fatalf("PageEvents failed: %v", err)
+	}
+	if eventIDs(second.Events) != "a" || second.NextCursor != "" {
+		t.Errorf("expected the last page a, got %s %q", eventIDs(second.Events), second.NextCursor)
+	}
+}
+
+func TestPageEvents_Labels(t *testing.T) {
+	labelled := event("a", 0, "exec-1")
+	labelled.Metadata = map[string]interface{}{"tenant": "acme"}
+	other := event("b", 0, "exec-1")
+	other.Metadata = map[string]interface{}{"tenant": "globex"}
+
+	result, err := PageEvents([]ports.Event{labelled, other, event("c", 0, "exec-1")}, ports.EventFilter{Labels: map[string]string{"tenant": "acme"}}, ports.PageRequest{})
+	if err != nil {
+		t.Fatalf("PageEvents failed: %v", err)
+	}
+	if eventIDs(result.Events) != "a" {
+		t.Errorf("expected only the labelled event, got %s", eventIDs(result.Events))
+	}
+}
+
+func TestEvents(t *testing.T) {
+	store := &pagedEvents{}
+	for i, id := range []string{"a", "b", "c", "d", "e"} {
+		store.Store(context.Background(), event(id, i, "exec-1"))
+	}
+
+	var got []ports.Event
+	for e, err := range Events(context.Background(), store, ports.EventFilter{}, ports.PageRequest{Limit: 2}) {
+		if err != nil {
+			t.Fatalf("Events failed: %v", err)
+		}
+		got = append(got, e)
+	}
+	if eventIDs(got) != "a,b,c,d,e" || store.pages != 3 {
+		t.Errorf("expected every event over 3 pages, got %s over %d", eventIDs(got), store.pages)
+	}
+
+	store.pages = 0
+	for range Events(context.Background(), store, ports.EventFilter{}, ports.PageRequest{Limit: 2}) {
+		break
+	}
+	if store.pages != 1 {
+		t.Errorf("expected breaking out to stop fetching pages, got %d", store.pages)
+	}
+}
+
+// unpagedEvents hides the pager of a store.
+type unpagedEvents struct {
+	ports.EventStore
+}
+
+func TestQueryPage_WithoutPager(t *testing.T) {
+	store := &pagedEvents{}
+	for i, id := range []string{"a", "b", "c"} {
+		store.Store(context.Background(), event(id, i, "exec-1"))
+	}
+	store.Store(context.Background(), event("x", 1, "exec-2"))
+
+	var got []ports.Event
+	for e, err := range Events(context.Background(), unpagedEvents{store}, ports.EventFilter{ExecutionID: "exec-1"}, ports.PageRequest{Limit: 2}) {
+		if err != nil {
+			t.Fatalf("Events failed: %v", err)
+		}
+		got = append(got, e)
+	}
+	if eventIDs(got) != "a,b,c" || store.pages != 0 || store.queries != 1 {
+		t.Errorf("expected the events of exec-1 from a single Query, got %s over %d queries and %d page queries", eventIDs(got), store.queries, store.pages)
+	}
+	if _, err := QueryPage(context.Background(), unpagedEvents{store}, ports.EventFilter{}, ports.PageRequest{Cursor: "invalid"}); err == nil {
+		t.Error("expected an invalid cursor to be rejected")
+	}
+}
+
+func TestEvents_Errors(t *testing.T) {
+	store := &pagedEvents{err: errors.New("unavailable")}
+	for _, err := range Events(context.Background(), store, ports.EventFilter{}, ports.PageRequest{}) {
+		if err == nil || err.Error() != "unavailable" {
+			t.Errorf("expected the store error, got %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, err := range Events(ctx, &pagedEvents{}, ports.EventFilter{}, ports.PageRequest{}) {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package paging
+
+import (
+	"context"
+	"iter"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// MatchExecution reports whether an execution satisfies every criterion of
+// a filter.
+func MatchExecution(filter ports.ExecutionFilter, m ports.ExecutionMetadata) bool {
+	if len(filter.Statuses) > 0 {
+		matched := false
+		for _, status := range filter.Statuses {
+			if m.Status == status {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if filter.GraphID != "" && m.GraphID != filter.GraphID {
+		return false
+	}
+	if !filter.StartedAfter.IsZero() && m.StartedAt.Before(filter.StartedAfter) {
+		return false
+	}
+	if !filter.StartedBefore.IsZero() && !m.StartedAt.Before(filter.StartedBefore) {
+		return false
+	}
+	for key, value := range filter.Labels {
+		if v, ok := m.Metadata[key].(string); !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// PageExecutions returns the page of the executions matching a filter,
+// sorted by start time then execution ID. It implements
+// ports.ExecutionPager for storages that can load every candidate execution.
+func PageExecutions(all []ports.ExecutionMetadata, filter ports.ExecutionFilter, page ports.PageRequest) (ports.ExecutionPage, error) {
+	r, err := Parse(page)
+	if err != nil {
+		return ports.ExecutionPage{}, err
+	}
+	out, next := window(matchExecutions(all, filter), r, executionKey)
+	return ports.ExecutionPage{Executions: out, NextCursor: next}, nil
+}
+
+func matchExecutions(all []ports.ExecutionMetadata, filter ports.ExecutionFilter) []ports.ExecutionMetadata {
+	var matched []ports.ExecutionMetadata
+	for _, m := range all {
+		if MatchExecution(filter, m) {
+			matched = append(matched, m)
+		}
+	}
+	return matched
+}
+
+func executionKey(m ports.ExecutionMetadata) (time.Time, string) { return m.StartedAt, m.ExecutionID }
+
+// ListPage returns a page of the executions of storage matching a filter.
+// Storages implementing ports.ExecutionPager page the listing themselves; the
+// executions of other storages are loaded with ExecutionStorage.List and
+// paged with PageExecutions.
+func ListPage(ctx context.Context, storage ports.ExecutionStorage, filter ports.ExecutionFilter, page ports.PageRequest) (ports.ExecutionPage, error) {
+	if pager, ok := storage.(ports.ExecutionPager); ok {
+		return pager.ListPage(ctx, filter, page)
+	}
+	if _, err := Parse(page); err != nil {
+		return ports.ExecutionPage{}, err
+	}
+	all, err := list(ctx, storage, filter)
+	if err != nil {
+		return ports.ExecutionPage{}, err
+	}
+	return PageExecutions(all, filter, page)
+}
+
+// list loads the candidate executions of a filter with ExecutionStorage.List.
+func list(ctx context.Context, storage ports.ExecutionStorage, filter ports.ExecutionFilter) ([]ports.ExecutionMetadata, error) {
+	var status *ports.ExecutionStatus
+	if len(filter.Statuses) == 1 {
+		status = &filter.Statuses[0]
+	}
+	return storage.List(ctx, status)
+}
+
+// Executions iterates over every execution matching a filter. Storages
+// implementing ports.ExecutionPager are read a page at a time; the
+// executions of other storages are loaded once with ExecutionStorage.List
+// and iterated over in memory. Iteration stops at the first error, which is
+// yielded with a zero execution.
+func Executions(ctx context.Context, storage ports.ExecutionStorage, filter ports.ExecutionFilter, page ports.PageRequest) iter.Seq2[ports.ExecutionMetadata, error] {
+	return func(yield func(ports.ExecutionMetadata, error) bool) {
+		if err := ctx.Err(); err != nil {
+			yield(ports.ExecutionMetadata{}, err)
+			return
+		}
+		pager, ok := storage.(ports.ExecutionPager)
+		if !ok {
+			all, err := listAll(ctx, storage, filter, page)
+			if err != nil {
+				yield(ports.ExecutionMetadata{}, err)
+				return
+			}
+			for _, m := range all {
+				if !yield(m, nil) {
+					return
+				}
+			}
+			return
+		}
+		for {
+			result, err := pager.ListPage(ctx, filter, page)
+			if err != nil {
+				yield(ports.ExecutionMetadata{}, err)
+				return
+			}
+			for _, m := range result.Executions {
+				if !yield(m, nil) {
+					return
+				}
+			}
+			if result.NextCursor == "" {
+				return
+			}
+			page.Cursor = result.NextCursor
+			if err := ctx.Err(); err != nil {
+				yield(ports.ExecutionMetadata{}, err)
+				return
+			}
+		}
+	}
+}
+
+// listAll returns the executions of a storage without a pager that match a
+// filter and follow the cursor of page, in the page order.
+func listAll(ctx context.Context, storage ports.ExecutionStorage, filter ports.ExecutionFilter, page ports.PageRequest) ([]ports.ExecutionMetadata, error) {
+	r, err := Parse(page)
+	if err != nil {
+		return nil, err
+	}
+	all, err := list(ctx, storage, filter)
+	if err != nil {
+		return nil, err
+	}
+	matched := matchExecutions(all, filter)
+	r.Limit = len(matched)
+	out, _ := window(matched, r, executionKey)
+	return out, nil
+}
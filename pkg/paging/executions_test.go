@@ -0,0 +1,201 @@
+package paging
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+func execution(id string, seconds int, graphID string, status ports.ExecutionStatus) ports.ExecutionMetadata {
+	return ports.ExecutionMetadata{
+		ExecutionID: id,
+		GraphID:     graphID,
+		Status:      status,
+		StartedAt:   epoch.Add(time.Duration(seconds) * time.Second),
+	}
+}
+
+func executionIDs(list []ports.ExecutionMetadata) string {
+	var ids []string
+	for _, m := range list {
+		ids = append(ids, m.ExecutionID)
+	}
+	return strings.Join(ids, ",")
+}
+
+// pagedExecutions is a minimal ports.ExecutionStorage and
+// ports.ExecutionPager.
+type pagedExecutions []ports.ExecutionMetadata
+
+func (s pagedExecutions) Save(ctx context.Context, metadata ports.ExecutionMetadata) error {
+	return errors.New("read-only")
+}
+
+func (s pagedExecutions) Load(ctx context.Context, executionID string) (*ports.ExecutionMetadata, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s pagedExecutions) UpdateStatus(ctx context.Context, executionID string, status ports.ExecutionStatus) error {
+	return errors.New("read-only")
+}
+
+func (s pagedExecutions) List(ctx context.Context, status *ports.ExecutionStatus) ([]ports.ExecutionMetadata, error) {
+	var list []ports.ExecutionMetadata
+	for _, m := range s {
+		if status == nil || m.Status == *status {
+			list = append(list, m)
+		}
+	}
+	return list, nil
+}
+
+func (s pagedExecutions) ListPage(ctx context.Context, filter ports.ExecutionFilter, page ports.PageRequest) (ports.ExecutionPage, error) {
+	return PageExecutions(s, filter, page)
+}
+
+func (s pagedExecutions) Delete(ctx context.Context, executionID string) error {
+	return errors.New("read-only")
+}
+
+func TestMatchExecution(t *testing.T) {
+	m := execution("exec-1", 10, "graph-1", ports.ExecutionStatusFailed)
+	m.Metadata = map[string]interface{}{"tenant": "acme", "attempt": 2}
+
+	tests := []struct {
+		name   string
+		filter ports.ExecutionFilter
+		want   bool
+	}{
+		{"empty", ports.ExecutionFilter{}, true},
+		{"statuses", ports.ExecutionFilter{Statuses: []ports.ExecutionStatus{ports.ExecutionStatusCompleted, ports.ExecutionStatusFailed}}, true},
+		{"other statuses", ports.ExecutionFilter{Statuses: []ports.ExecutionStatus{ports.ExecutionStatusRunning}}, false},
+		{"graph", ports.ExecutionFilter{GraphID: "graph-1"}, true},
+		{"other graph", ports.ExecutionFilter{GraphID: "graph-2"}, false},
+		{"started after, inclusive", ports.ExecutionFilter{StartedAfter: epoch.Add(10 * time.Second)}, true},
+		{"started after, later", ports.ExecutionFilter{StartedAfter: epoch.Add(11 * time.Second)}, false},
+		{"started before, exclusive", ports.ExecutionFilter{StartedBefore: epoch.Add(10 * time.Second)}, false},
+		{"started before, later", ports.ExecutionFilter{StartedBefore: epoch.Add(11 * time.Second)}, true},
+		{"labels", ports.ExecutionFilter{Labels: map[string]string{"tenant": "acme"}}, true},
+		{"other label value", ports.ExecutionFilter{Labels: map[string]string{"tenant": "globex"}}, false},
+		{"non-string label", ports.ExecutionFilter{Labels: map[string]string{"attempt": "2"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchExecution(tt.filter, m); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPageExecutions(t *testing.T) {
+	all := []ports.ExecutionMetadata{
+		execution("exec-3", 2, "graph-1", ports.ExecutionStatusRunning),
+		execution("exec-1", 0, "graph-1", ports.ExecutionStatusCompleted),
+		execution("exec-2", 1, "graph-2", ports.ExecutionStatusFailed),
+		execution("exec-4", 3, "graph-1", ports.ExecutionStatusFailed),
+	}
+	filter := ports.ExecutionFilter{GraphID: "graph-1", Statuses: []ports.ExecutionStatus{ports.ExecutionStatusCompleted, ports.ExecutionStatusFailed}}
+
+	result, err := PageExecutions(all, filter, ports.PageRequest{Limit: 1, Order: ports.SortDescending})
+	if err != nil {
+		t.Fatalf("PageExecutions failed: %v", err)
+	}
+	if executionIDs(result.Executions) != "exec-4" || result.NextCursor == "" {
+		t.Fatalf("expected exec-4 and a cursor, got %s %q", executionIDs(result.Executions), result.NextCursor)
+	}
+	result, err = PageExecutions(all, filter, ports.PageRequest{Limit: 1, Order: ports.SortDescending, Cursor: result.NextCursor})
+	if err != nil {
+		t.Fatalf("PageExecutions failed: %v", err)
+	}
+	if executionIDs(result.Executions) != "exec-1" || result.NextCursor != "" {
+		t.Errorf("expected the last page exec-1, got %s %q", executionIDs(result.Executions), result.NextCursor)
+	}
+}
+
+func TestExecutions(t *testing.T) {
+	storage := pagedExecutions{
+		execution("exec-2", 1, "graph-1", ports.ExecutionStatusRunning),
+		execution("exec-1", 0, "graph-1", ports.ExecutionStatusRunning),
+		execution("exec-3", 2, "graph-1", ports.ExecutionStatusRunning),
+	}
+
+	var got []ports.ExecutionMetadata
+	for m, err := range Executions(context.Background(), storage, ports.ExecutionFilter{}, ports.PageRequest{Limit: 2}) {
+		if err != nil {
+			t.Fatalf("Executions failed: %v", err)
+		}
+		got = append(got, m)
+	}
+	if executionIDs(got) != "exec-1,exec-2,exec-3" {
+		t.Errorf("expected every execution in start order, got %s", executionIDs(got))
+	}
+}
+
+// unpagedExecutions hides the pager of a storage.
+type unpagedExecutions struct {
+	ports.ExecutionStorage
+}
+
+func TestListPage_WithoutPager(t *testing.T) {
+	storage := unpagedExecutions{pagedExecutions{
+		execution("exec-2", 1, "graph-1", ports.ExecutionStatusRunning),
+		execution("exec-1", 0, "graph-1", ports.ExecutionStatusCompleted),
+		execution("exec-3", 2, "graph-2", ports.ExecutionStatusRunning),
+		execution("exec-4", 3, "graph-1", ports.ExecutionStatusRunning),
+	}}
+
+	filter := ports.ExecutionFilter{GraphID: "graph-1", Statuses: []ports.ExecutionStatus{ports.ExecutionStatusRunning}}
+	page, err := ListPage(context.Background(), storage, filter, ports.PageRequest{Limit: 1})
+	if err != nil {
+		t.Fatalf("ListPage failed: %v", err)
+	}
+	if executionIDs(page.Executions) != "exec-2" || page.NextCursor == "" {
+		t.Fatalf("expected exec-2 and a next page, got %s %q", executionIDs(page.Executions), page.NextCursor)
+	}
+	page, err = ListPage(context.Background(), storage, filter, ports.PageRequest{Limit: 1, Cursor: page.NextCursor})
+	if err != nil || executionIDs(page.Executions) != "exec-4" || page.NextCursor != "" {
+		t.Errorf("expected exec-4 on the last page, got %s %q, %v", executionIDs(page.Executions), page.NextCursor, err)
+	}
+}
+
+// listedExecutions hides the pager of a storage and counts its listings.
+type listedExecutions struct {
+	ports.ExecutionStorage
+	lists int
+}
+
+func (s *listedExecutions) List(ctx context.Context, status *ports.ExecutionStatus) ([]ports.ExecutionMetadata, error) {
+	s.lists++
+	return s.ExecutionStorage.List(ctx, status)
+}
+
+func TestExecutions_WithoutPager(t *testing.T) {
+	storage := &listedExecutions{ExecutionStorage: pagedExecutions{
+		execution("exec-2", 1, "graph-1", ports.ExecutionStatusRunning),
+		execution("exec-1", 0, "graph-1", ports.ExecutionStatusRunning),
+		execution("exec-3", 2, "graph-2", ports.ExecutionStatusRunning),
+		execution("exec-4", 3, "graph-1", ports.ExecutionStatusRunning),
+	}}
+
+	first, err := ListPage(context.Background(), storage, ports.ExecutionFilter{GraphID: "graph-1"}, ports.PageRequest{Limit: 1})
+	if err != nil {
+		t.Fatalf("ListPage failed: %v", err)
+	}
+	storage.lists = 0
+
+	var got []ports.ExecutionMetadata
+	for m, err := range Executions(context.Background(), storage, ports.ExecutionFilter{GraphID: "graph-1"}, ports.PageRequest{Limit: 1, Cursor: first.NextCursor}) {
+		if err != nil {
+			t.Fatalf("Executions failed: %v", err)
+		}
+		got = append(got, m)
+	}
+	if executionIDs(got) != "exec-2,exec-4" || storage.lists != 1 {
+		t.Errorf("expected the executions after the cursor from a single List, got %s over %d lists", executionIDs(got), storage.lists)
+	}
+}
@@ -0,0 +1,125 @@
+package paging
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// ErrInvalidCursor is returned for cursors that were not returned by a
+// previous page, or are used with another sort order.
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+// ErrInvalidOrder is returned for unknown sort orders.
+var ErrInvalidOrder = errors.New("invalid sort order")
+
+// Cursor is the position after which a page starts: the sort key of the last
+// result of the previous page.
+type Cursor struct {
+	Timestamp time.Time       `json:"t"`
+	ID        string          `json:"id"`
+	Order     ports.SortOrder `json:"o"`
+}
+
+// EncodeCursor returns the opaque form of a cursor.
+func EncodeCursor(c Cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor returned by EncodeCursor.
+func DecodeCursor(s string) (Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Cursor{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	return c, nil
+}
+
+// Request is a validated ports.PageRequest.
+type Request struct {
+	// Limit is the page size, between 1 and ports.MaxPageLimit.
+	Limit int
+
+	// Order is ports.SortAscending or ports.SortDescending.
+	Order ports.SortOrder
+
+	// After is the decoded cursor, nil for the first page.
+	After *Cursor
+}
+
+// Parse validates a page request, applying the default limit and order.
+func Parse(page ports.PageRequest) (Request, error) {
+	r := Request{Limit: page.Limit, Order: page.Order}
+	if r.Limit <= 0 {
+		r.Limit = ports.DefaultPageLimit
+	}
+	if r.Limit > ports.MaxPageLimit {
+		r.Limit = ports.MaxPageLimit
+	}
+	switch r.Order {
+	case "":
+		r.Order = ports.SortAscending
+	case ports.SortAscending, ports.SortDescending:
+	default:
+		return Request{}, fmt.Errorf("%w: %q", ErrInvalidOrder, page.Order)
+	}
+	if page.Cursor != "" {
+		c, err := DecodeCursor(page.Cursor)
+		if err != nil {
+			return Request{}, err
+		}
+		if c.Order != r.Order {
+			return Request{}, fmt.Errorf("%w: cursor of a %s page used for a %s page", ErrInvalidCursor, c.Order, r.Order)
+		}
+		r.After = &c
+	}
+	return r, nil
+}
+
+// Before reports whether the sort key (t, id) comes before (u, other) in
+// the request order.
+func (r Request) Before(t time.Time, id string, u time.Time, other string) bool {
+	less := t.Before(u) || (t.Equal(u) && id < other)
+	if r.Order == ports.SortDescending {
+		return !less && !(t.Equal(u) && id == other)
+	}
+	return less
+}
+
+// Cursor returns the cursor of a page ending with the sort key (t, id).
+func (r Request) Cursor(t time.Time, id string) string {
+	return EncodeCursor(Cursor{Timestamp: t, ID: id, Order: r.Order})
+}
+
+// window returns the page of items, sorted by key, that follows the
+// request cursor, and the cursor of the next page.
+func window[T any](items []T, r Request, key func(T) (time.Time, string)) ([]T, string) {
+	sort.SliceStable(items, func(i, j int) bool {
+		ti, idi := key(items[i])
+		tj, idj := key(items[j])
+		return r.Before(ti, idi, tj, idj)
+	})
+	start := 0
+	if r.After != nil {
+		start = sort.Search(len(items), func(i int) bool {
+			t, id := key(items[i])
+			return r.Before(r.After.Timestamp, r.After.ID, t, id)
+		})
+	}
+	end := start + r.Limit
+	if end >= len(items) {
+		return items[start:], ""
+	}
+	t, id := key(items[end-1])
+	return items[start:end], r.Cursor(t, id)
+}
@@ -0,0 +1,46 @@
+package paging
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	c := Cursor{Timestamp: time.Date(2025, 1, 1, 0, 0, 0, 5, time.UTC), ID: "evt-1", Order: ports.SortDescending}
+	decoded, err := DecodeCursor(EncodeCursor(c))
+	if err != nil {
+		t.Fatalf("DecodeCursor failed: %v", err)
+	}
+	if !decoded.Timestamp.Equal(c.Timestamp) || decoded.ID != c.ID || decoded.Order != c.Order {
+		t.Errorf("expected %+v, got %+v", c, decoded)
+	}
+}
+
+func TestParse(t *testing.T) {
+	r, err := Parse(ports.PageRequest{})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if r.Limit != ports.DefaultPageLimit || r.Order != ports.SortAscending || r.After != nil {
+		t.Errorf("expected the defaults, got %+v", r)
+	}
+	if r, _ := Parse(ports.PageRequest{Limit: ports.MaxPageLimit + 1}); r.Limit != ports.MaxPageLimit {
+		t.Errorf("expected the limit to be capped, got %d", r.Limit)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	if _, err := Parse(ports.PageRequest{Order: "sideways"}); !errors.Is(err, ErrInvalidOrder) {
+		t.Errorf("expected ErrInvalidOrder, got %v", err)
+	}
+	if _, err := Parse(ports.PageRequest{Cursor: "not a cursor!"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor for garbage, got %v", err)
+	}
+	cursor := EncodeCursor(Cursor{ID: "evt-1", Order: ports.SortAscending})
+	if _, err := Parse(ports.PageRequest{Cursor: cursor, Order: ports.SortDescending}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor for another order, got %v", err)
+	}
+}
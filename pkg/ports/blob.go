@@ -0,0 +1,33 @@
+package ports
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrBlobNotFound is returned by BlobStore implementations when a digest is unknown.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore defines the interface for content-addressed storage of large values.
+// Blobs are identified by the digest of their content (e.g. "sha256:<hex>"),
+// so storing the same bytes twice yields the same digest and a single copy.
+//
+// Every blob is retained by one or more owners (typically execution IDs).
+// A blob is garbage collected once its last owner releases it.
+type BlobStore interface {
+	// Put stores data on behalf of owner and returns its content digest.
+	// Storing content that already exists only records the additional owner.
+	Put(ctx context.Context, owner string, data []byte) (string, error)
+
+	// Get retrieves the content for a digest.
+	// Returns ErrBlobNotFound if the digest is unknown.
+	Get(ctx context.Context, digest string) ([]byte, error)
+
+	// Exists checks if a blob with the given digest is stored.
+	Exists(ctx context.Context, digest string) (bool, error)
+
+	// Release drops every reference held by owner and deletes the blobs
+	// that are no longer referenced by any owner.
+	// Returns the number of blobs deleted.
+	Release(ctx context.Context, owner string) (int, error)
+}
@@ -7,8 +7,12 @@
 // Ports include:
 //   - LLMClient: Interface for Large Language Model providers
 //   - ToolExecutor: Interface for executing tools (Python, Bash, HTTP, etc.)
-//   - EventBus: Interface for event publishing and subscription (Redis Streams)
+//   - EventBus: Interface for at-least-once event delivery with consumer groups (Redis Streams)
 //   - StateStorage: Interface for persisting execution state (Redis)
+//   - BlobStore: Interface for content-addressed storage of large state values
+//   - SnapshotStore: Interface for snapshots of execution states projected from events
+//   - OutboxStorage: Interface for saving state and its events in one transaction
+//   - WebhookRegistry: Interface for managing webhook endpoints notified of events
 //   - MetricsCollector: Interface for collecting system metrics (Prometheus)
 //
 // This design allows for:
@@ -2,7 +2,11 @@ package ports
 
 import (
 	"context"
+	"errors"
 	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
 )
 
 // EventType represents the type of event.
@@ -27,6 +31,13 @@ const (
 	// EventTypeNodeFailed is emitted when a node execution fails.
 	EventTypeNodeFailed EventType = "node.failed"
 
+	// EventTypeNodeWaitingForInput is emitted when a node pauses the execution
+	// until a human responds.
+	EventTypeNodeWaitingForInput EventType = "node.waiting_for_input"
+
+	// EventTypeGraphResumed is emitted when a paused execution resumes.
+	EventTypeGraphResumed EventType = "graph.resumed"
+
 	// EventTypeStateChanged is emitted when execution state is modified.
 	EventTypeStateChanged EventType = "state.changed"
 
@@ -42,6 +53,10 @@ type Event struct {
 	// Type is the type of event.
 	Type EventType `json:"type"`
 
+	// SchemaVersion is the version of the envelope and payload schema the
+	// event was encoded with (see package events). Empty means version 1.0.
+	SchemaVersion string `json:"schema_version,omitempty"`
+
 	// Timestamp is when the event occurred.
 	Timestamp time.Time `json:"timestamp"`
 
@@ -51,27 +66,111 @@ type Event struct {
 	// NodeID is the ID of the node this event relates to (if applicable).
 	NodeID string `json:"node_id,omitempty"`
 
-	// Data contains event-specific payload data.
+	// Data contains event-specific payload data, the JSON form of the
+	// typed payload of Type (see package events).
 	Data map[string]interface{} `json:"data,omitempty"`
 
 	// Metadata contains additional event metadata.
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
 
+// ErrPoisonEvent may be wrapped by the error of an EventHandler to
+// dead-letter an event immediately instead of redelivering it.
+var ErrPoisonEvent = errors.New("poison event")
+
 // EventHandler is a function that processes events.
+//
+// Returning nil acknowledges the event. Returning an error negatively
+// acknowledges it: the event is redelivered after a backoff delay, following
+// the Retry policy of the subscription, and moved to its dead-letter topic
+// once the attempts are exhausted, the error kind is not retried, or the
+// error wraps ErrPoisonEvent. Delivery is at least once, so a handler may see
+// an event again; Event.ID identifies redeliveries.
 type EventHandler func(ctx context.Context, event Event) error
 
+// DeadLetterSuffix is appended to a topic to name its default dead-letter
+// topic.
+const DeadLetterSuffix = ".dlq"
+
+// DefaultMaxDeliveries is the number of deliveries of an event, including the
+// first one, when a subscription has no Retry policy.
+const DefaultMaxDeliveries = 5
+
+// Metadata keys set on dead-lettered events.
+const (
+	// MetadataDeadLetterTopic is the topic the event was consumed from.
+	MetadataDeadLetterTopic = "dead_letter_topic"
+
+	// MetadataDeadLetterGroup is the consumer group that gave up on the event.
+	MetadataDeadLetterGroup = "dead_letter_group"
+
+	// MetadataDeadLetterError is the error of the last delivery.
+	MetadataDeadLetterError = "dead_letter_error"
+
+	// MetadataDeliveries is the number of deliveries made.
+	MetadataDeliveries = "deliveries"
+)
+
+// SubscribeOptions configures the delivery of events to a subscription.
+type SubscribeOptions struct {
+	// Group is the consumer group of the subscription. The subscriptions of a
+	// group share the events of the topic: each event is delivered to one of
+	// them. Empty means a group of its own, which receives every event.
+	Group string
+
+	// Retry governs redelivery of negatively acknowledged events:
+	// MaxAttempts is the number of deliveries before the event is
+	// dead-lettered, and the delay between deliveries follows its backoff.
+	// Nil means DefaultMaxDeliveries with the default backoff.
+	Retry *graph.RetryPolicy
+
+	// DeadLetterTopic receives the events the subscription gave up on, with
+	// the MetadataDeadLetter* keys set. Empty means the topic the event was
+	// published to, followed by DeadLetterSuffix.
+	DeadLetterTopic string
+
+	// Filter restricts the events delivered to the subscription. The bus
+	// applies it before queuing, so filtered out events are never delivered
+	// nor acknowledged. Subscriptions sharing a group share its filter.
+	Filter *EventFilter
+
+	// AckTimeout bounds each handler call: the handler context is cancelled
+	// and the event negatively acknowledged when it expires. Zero means no
+	// timeout.
+	AckTimeout time.Duration
+}
+
+// Subscription is a handle to a subscription created by EventBus.Subscribe.
+type Subscription interface {
+	// ID is a unique identifier for this subscription.
+	ID() string
+
+	// Topic is the topic pattern subscribed to.
+	Topic() string
+
+	// Group is the consumer group of the subscription.
+	Group() string
+
+	// Unsubscribe stops deliveries to this subscription only. Events it has
+	// not acknowledged are redelivered to the other members of its group.
+	Unsubscribe(ctx context.Context) error
+}
+
 // EventBus defines the interface for event publishing and subscription.
 // For MVP, this is implemented using Redis Streams.
 type EventBus interface {
 	// Publish sends an event to a topic.
 	Publish(ctx context.Context, topic string, event Event) error
 
-	// Subscribe registers a handler for events on a topic.
-	// The handler will be called for each event received.
-	Subscribe(ctx context.Context, topic string, handler EventHandler) error
+	// Subscribe registers a handler for events on the topics matching a
+	// pattern. Topics are dot-separated segments, such as
+	// "executions.exec-1.node"; in a pattern, "*" matches one segment and a
+	// final ">" matches one or more trailing segments.
+	// The handler will be called for each event received, with the delivery
+	// semantics described by EventHandler and opts.
+	Subscribe(ctx context.Context, topic string, handler EventHandler, opts SubscribeOptions) (Subscription, error)
 
-	// Unsubscribe removes a subscription from a topic.
+	// Unsubscribe removes every subscription made with a topic pattern.
 	Unsubscribe(ctx context.Context, topic string) error
 
 	// Close closes the event bus and cleans up resources.
@@ -81,6 +180,8 @@ type EventBus interface {
 // EventFilter defines criteria for filtering events.
 type EventFilter struct {
 	// Types filters events by type. If empty, all types are included.
+	// Types may be patterns with the wildcards of topic patterns, such as
+	// "node.*".
 	Types []EventType `json:"types,omitempty"`
 
 	// ExecutionID filters events by execution ID.
@@ -89,11 +190,15 @@ type EventFilter struct {
 	// NodeID filters events by node ID.
 	NodeID string `json:"node_id,omitempty"`
 
-	// Since filters events after this timestamp.
+	// Since filters events at or after this timestamp.
 	Since time.Time `json:"since,omitempty"`
 
-	// Until filters events before this timestamp.
+	// Until filters events at or before this timestamp.
 	Until time.Time `json:"until,omitempty"`
+
+	// Labels filters events whose Metadata has each key set to the given
+	// string value.
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 // EventStore defines the interface for persisting and querying events.
@@ -110,3 +215,38 @@ type EventStore interface {
 	// GetByExecutionID retrieves all events for a specific execution.
 	GetByExecutionID(ctx context.Context, executionID string) ([]Event, error)
 }
+
+// Snapshot is the domain.GraphState of an execution folded from its events,
+// up to and including the event LastEventID.
+type Snapshot struct {
+	// ExecutionID is the ID of the execution.
+	ExecutionID string `json:"execution_id"`
+
+	// State is the projected state.
+	State *domain.GraphState `json:"state"`
+
+	// Version is the number of events folded into State.
+	Version int `json:"version"`
+
+	// LastEventID is the ID of the last event folded into State.
+	LastEventID string `json:"last_event_id"`
+
+	// LastEventTime is the timestamp of the last event folded into State.
+	LastEventTime time.Time `json:"last_event_time"`
+
+	// CreatedAt is when the snapshot was taken.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SnapshotStore defines the interface for persisting snapshots of projected
+// execution states, so that long executions are not folded from their first
+// event every time.
+type SnapshotStore interface {
+	// SaveSnapshot persists a snapshot, replacing the previous snapshot of
+	// its execution.
+	SaveSnapshot(ctx context.Context, snapshot Snapshot) error
+
+	// LoadSnapshot retrieves the latest snapshot of an execution.
+	// Returns nil and no error if the execution has no snapshot.
+	LoadSnapshot(ctx context.Context, executionID string) (*Snapshot, error)
+}
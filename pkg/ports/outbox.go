@@ -0,0 +1,68 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+)
+
+// OutboxMessage is an event waiting in a transactional outbox to be
+// published to the EventBus.
+type OutboxMessage struct {
+	// Topic is the topic the event is published to.
+	Topic string `json:"topic"`
+
+	// Event is the event to publish. Event.ID identifies the message.
+	Event Event `json:"event"`
+
+	// CreatedAt is when the message was written to the outbox.
+	CreatedAt time.Time `json:"created_at"`
+
+	// Attempts is the number of failed attempts to publish the message.
+	Attempts int `json:"attempts,omitempty"`
+
+	// LastError is the error of the last failed attempt.
+	LastError string `json:"last_error,omitempty"`
+}
+
+// OutboxStorage defines the interface for persisting state changes together
+// with the events they produce (the transactional outbox pattern).
+//
+// Publishing after saving loses events when the process crashes in
+// between; writing both in one transaction and relaying the outbox to the
+// EventBus afterwards publishes every event at least once. Consumers
+// deduplicate redelivered events by Event.ID (see ProcessedEventStore).
+type OutboxStorage interface {
+	// SaveWithEvents persists the state of an execution and appends messages
+	// to the outbox in a single transaction: either both are stored or
+	// neither is.
+	SaveWithEvents(ctx context.Context, executionID string, state state.State, messages []OutboxMessage) error
+
+	// PendingMessages returns up to limit messages not yet published, in the
+	// order they were written.
+	PendingMessages(ctx context.Context, limit int) ([]OutboxMessage, error)
+
+	// MarkPublished removes published messages from the outbox.
+	MarkPublished(ctx context.Context, eventIDs ...string) error
+
+	// MarkFailed records a failed attempt to publish a message, incrementing
+	// its Attempts and setting its LastError.
+	MarkFailed(ctx context.Context, eventID string, cause error) error
+
+	// MarkDead records a last failed attempt like MarkFailed and parks the
+	// message: it is no longer returned by PendingMessages, but is kept for
+	// inspection or manual replay.
+	MarkDead(ctx context.Context, eventID string, cause error) error
+}
+
+// ProcessedEventStore defines the interface for recording the events a
+// consumer has handled, so that events delivered more than once are handled
+// once.
+type ProcessedEventStore interface {
+	// IsProcessed reports whether consumer has handled the event.
+	IsProcessed(ctx context.Context, consumer, eventID string) (bool, error)
+
+	// MarkProcessed records that consumer has handled the event.
+	MarkProcessed(ctx context.Context, consumer, eventID string) error
+}
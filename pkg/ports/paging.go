@@ -0,0 +1,100 @@
+package ports
+
+import (
+	"context"
+	"time"
+)
+
+// SortOrder is the order of paginated results.
+type SortOrder string
+
+const (
+	// SortAscending returns the oldest results first.
+	SortAscending SortOrder = "asc"
+
+	// SortDescending returns the newest results first.
+	SortDescending SortOrder = "desc"
+)
+
+// Page size limits of PageRequest.
+const (
+	// DefaultPageLimit is the page size used when PageRequest.Limit is zero.
+	DefaultPageLimit = 100
+
+	// MaxPageLimit caps PageRequest.Limit.
+	MaxPageLimit = 1000
+)
+
+// PageRequest selects a page of results.
+type PageRequest struct {
+	// Limit is the maximum number of results. Zero means DefaultPageLimit;
+	// larger values are capped at MaxPageLimit.
+	Limit int `json:"limit,omitempty"`
+
+	// Cursor is the NextCursor of the previous page. Empty means the first
+	// page. A cursor is only valid with the filter and order it was returned
+	// for.
+	Cursor string `json:"cursor,omitempty"`
+
+	// Order is the sort order, by timestamp then ID. Empty means
+	// SortAscending.
+	Order SortOrder `json:"order,omitempty"`
+}
+
+// EventPager is implemented by event stores that paginate queries natively.
+// It is optional: callers detect it on an EventStore with a type assertion
+// and otherwise page the results of EventStore.Query (see paging.QueryPage).
+type EventPager interface {
+	// QueryPage retrieves a page of the events matching the filter
+	// criteria, sorted by timestamp then ID.
+	QueryPage(ctx context.Context, filter EventFilter, page PageRequest) (EventPage, error)
+}
+
+// ExecutionPager is implemented by execution storages that paginate listings
+// natively. It is optional: callers detect it on an ExecutionStorage with a
+// type assertion and otherwise page the results of ExecutionStorage.List
+// (see paging.ListPage).
+type ExecutionPager interface {
+	// ListPage returns a page of the executions matching filter, sorted by
+	// start time then execution ID.
+	ListPage(ctx context.Context, filter ExecutionFilter, page PageRequest) (ExecutionPage, error)
+}
+
+// EventPage is a page of events returned by EventPager.QueryPage.
+type EventPage struct {
+	// Events are the events of the page.
+	Events []Event `json:"events"`
+
+	// NextCursor selects the next page. Empty means this is the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ExecutionFilter defines criteria for filtering executions.
+type ExecutionFilter struct {
+	// Statuses filters executions by status. If empty, all statuses are
+	// included.
+	Statuses []ExecutionStatus `json:"statuses,omitempty"`
+
+	// GraphID filters executions by graph ID.
+	GraphID string `json:"graph_id,omitempty"`
+
+	// StartedAfter filters executions started at or after this timestamp.
+	StartedAfter time.Time `json:"started_after,omitempty"`
+
+	// StartedBefore filters executions started before this timestamp.
+	StartedBefore time.Time `json:"started_before,omitempty"`
+
+	// Labels filters executions whose Metadata has each key set to the
+	// given string value.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// ExecutionPage is a page of executions returned by
+// ExecutionPager.ListPage, sorted by start time then execution ID.
+type ExecutionPage struct {
+	// Executions are the executions of the page.
+	Executions []ExecutionMetadata `json:"executions"`
+
+	// NextCursor selects the next page. Empty means this is the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
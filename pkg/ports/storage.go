@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
 	"github.com/aescanero/dago-libs/pkg/domain/state"
 )
 
@@ -77,9 +78,17 @@ type ExecutionMetadata struct {
 	// CurrentNodeID is the ID of the currently executing node.
 	CurrentNodeID string `json:"current_node_id,omitempty"`
 
+	// WorkerID is the ID of the worker running the execution, used to detect
+	// executions orphaned by a dead worker.
+	WorkerID string `json:"worker_id,omitempty"`
+
 	// Error contains error information if the execution failed.
 	Error string `json:"error,omitempty"`
 
+	// PendingInput is the request an execution in ExecutionStatusWaitingForInput
+	// is waiting on.
+	PendingInput *graph.InputRequest `json:"pending_input,omitempty"`
+
 	// Metadata contains additional execution-specific data.
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
@@ -102,8 +111,22 @@ const (
 
 	// ExecutionStatusCancelled indicates the execution was cancelled.
 	ExecutionStatusCancelled ExecutionStatus = "cancelled"
+
+	// ExecutionStatusWaitingForInput indicates the execution is paused until a
+	// human responds to its PendingInput.
+	ExecutionStatusWaitingForInput ExecutionStatus = "waiting_for_input"
 )
 
+// ExecutionStatusSwapper is implemented by execution storages that update
+// statuses conditionally. It is optional: callers detect it on an
+// ExecutionStorage with a type assertion, e.g. approval.Service.Resume to
+// resume an execution once when several responses arrive concurrently.
+type ExecutionStatusSwapper interface {
+	// CompareAndSwapStatus atomically sets the status of an execution to
+	// to if it is from, and reports whether it did.
+	CompareAndSwapStatus(ctx context.Context, executionID string, from, to ExecutionStatus) (bool, error)
+}
+
 // ExecutionStorage defines the interface for persisting execution metadata.
 type ExecutionStorage interface {
 	// Save persists execution metadata.
@@ -0,0 +1,94 @@
+package ports
+
+import (
+	"context"
+	"time"
+)
+
+// WebhookEndpoint is a URL notified of execution events.
+type WebhookEndpoint struct {
+	// ID is the unique identifier of the endpoint.
+	ID string `json:"id"`
+
+	// URL receives the events as HTTP POST requests.
+	URL string `json:"url"`
+
+	// Secret is the key of the HMAC signature of each request. It is not
+	// encoded to JSON, so that endpoints can be listed or logged without
+	// leaking it; registries store it explicitly.
+	Secret string `json:"-"`
+
+	// Filter selects the events delivered to the endpoint, among the event
+	// types of the dispatcher. Empty Types means all of them.
+	Filter EventFilter `json:"filter"`
+
+	// Disabled endpoints receive no events.
+	Disabled bool `json:"disabled,omitempty"`
+
+	// CreatedAt is when the endpoint was registered.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDelivery records one attempt to deliver an event to a webhook
+// endpoint.
+type WebhookDelivery struct {
+	// ID is the unique identifier of the delivery, shared by its attempts.
+	ID string `json:"id"`
+
+	// EndpointID is the ID of the endpoint.
+	EndpointID string `json:"endpoint_id"`
+
+	// EventID is the ID of the delivered event.
+	EventID string `json:"event_id"`
+
+	// EventType is the type of the delivered event.
+	EventType EventType `json:"event_type"`
+
+	// Attempt is the number of the attempt, starting at 1, as reported by
+	// the event bus.
+	Attempt int `json:"attempt"`
+
+	// StatusCode is the HTTP status of the response, zero if none was
+	// received.
+	StatusCode int `json:"status_code,omitempty"`
+
+	// Error describes why the attempt failed.
+	Error string `json:"error,omitempty"`
+
+	// Succeeded reports whether the endpoint accepted the event.
+	Succeeded bool `json:"succeeded"`
+
+	// Duration is how long the attempt took.
+	Duration time.Duration `json:"duration"`
+
+	// Timestamp is when the attempt started.
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// WebhookRegistry defines the interface for managing webhook endpoints.
+type WebhookRegistry interface {
+	// RegisterEndpoint saves an endpoint, replacing any endpoint with the
+	// same ID.
+	RegisterEndpoint(ctx context.Context, endpoint WebhookEndpoint) error
+
+	// UnregisterEndpoint removes an endpoint.
+	UnregisterEndpoint(ctx context.Context, endpointID string) error
+
+	// GetEndpoint retrieves an endpoint.
+	GetEndpoint(ctx context.Context, endpointID string) (*WebhookEndpoint, error)
+
+	// ListEndpoints retrieves every registered endpoint.
+	ListEndpoints(ctx context.Context) ([]WebhookEndpoint, error)
+}
+
+// WebhookDeliveryLog defines the interface for recording webhook delivery
+// attempts.
+type WebhookDeliveryLog interface {
+	// RecordDelivery appends a delivery attempt to the log.
+	RecordDelivery(ctx context.Context, delivery WebhookDelivery) error
+
+	// ListDeliveries returns up to limit of the most recent attempts to
+	// deliver to an endpoint, newest first. A limit of zero returns every
+	// attempt.
+	ListDeliveries(ctx context.Context, endpointID string, limit int) ([]WebhookDelivery, error)
+}
@@ -0,0 +1,145 @@
+package projection
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/events"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// NewGraphState returns the empty state events of an execution are folded
+// into.
+func NewGraphState() *domain.GraphState {
+	return &domain.GraphState{
+		Status:     domain.ExecutionStatusPending,
+		NodeStates: make(map[string]*domain.NodeState),
+	}
+}
+
+// Apply folds an event into gs. Events of types without a registered
+// payload, and payloads that do not affect the execution state such as
+// events.ToolExecutedPayload, are ignored.
+func Apply(gs *domain.GraphState, event ports.Event) error {
+	payload, err := events.Decode(event)
+	if err != nil {
+		if errors.Is(err, events.ErrUnknownEventType) {
+			return nil
+		}
+		return fmt.Errorf("failed to apply event '%s': %w", event.ID, err)
+	}
+	if gs.NodeStates == nil {
+		gs.NodeStates = make(map[string]*domain.NodeState)
+	}
+	at := event.Timestamp
+
+	switch p := payload.(type) {
+	case events.GraphSubmittedPayload:
+		setGraphID(gs, p.GraphID)
+		gs.Status = domain.ExecutionStatusSubmitted
+		gs.SubmittedAt = at
+		if p.Inputs != nil {
+			gs.Inputs = p.Inputs
+		}
+	case events.GraphStartedPayload:
+		setGraphID(gs, p.GraphID)
+		gs.Status = domain.ExecutionStatusRunning
+		if gs.SubmittedAt.IsZero() {
+			gs.SubmittedAt = at
+		}
+		if gs.StartedAt == nil {
+			gs.StartedAt = timePtr(at)
+		}
+	case events.GraphResumedPayload:
+		setGraphID(gs, p.GraphID)
+		gs.Status = domain.ExecutionStatusRunning
+		gs.CompletedAt = nil
+		gs.Error = ""
+	case events.GraphCompletedPayload:
+		setGraphID(gs, p.GraphID)
+		gs.Status = domain.ExecutionStatusCompleted
+		gs.CompletedAt = timePtr(at)
+	case events.GraphFailedPayload:
+		setGraphID(gs, p.GraphID)
+		gs.Status = domain.ExecutionStatusFailed
+		if p.Cancelled {
+			gs.Status = domain.ExecutionStatusCancelled
+		}
+		gs.Error = p.Error
+		gs.CompletedAt = timePtr(at)
+	case events.GraphCancelledPayload:
+		setGraphID(gs, p.GraphID)
+		gs.Status = domain.ExecutionStatusCancelled
+		gs.Error = p.Reason
+		gs.CompletedAt = timePtr(at)
+	case events.NodeReadyPayload:
+		ns := nodeState(gs, event.NodeID)
+		ns.Status = domain.ExecutionStatusPending
+	case events.NodeStartedPayload:
+		ns := nodeState(gs, event.NodeID)
+		ns.Status = domain.ExecutionStatusRunning
+		ns.StartedAt = timePtr(at)
+		ns.CompletedAt = nil
+		ns.Error = ""
+		if p.NodeType != "" {
+			setMetadata(ns, "node_type", p.NodeType)
+		}
+	case events.NodeCompletedPayload:
+		ns := nodeState(gs, event.NodeID)
+		ns.Status = domain.ExecutionStatusCompleted
+		ns.Output = p.Output
+		ns.Error = ""
+		ns.CompletedAt = timePtr(at)
+		if p.Attempts > 0 {
+			setMetadata(ns, "attempts", p.Attempts)
+		}
+	case events.NodeFailedPayload:
+		ns := nodeState(gs, event.NodeID)
+		ns.Status = domain.ExecutionStatusFailed
+		ns.Error = p.Error
+		ns.CompletedAt = timePtr(at)
+		if p.Attempts > 0 {
+			setMetadata(ns, "attempts", p.Attempts)
+		}
+		if p.ErrorKind != "" {
+			setMetadata(ns, "error_kind", p.ErrorKind)
+		}
+		if p.HandledBy != "" {
+			setMetadata(ns, "handled_by", p.HandledBy)
+		}
+	case events.NodeWaitingForInputPayload:
+		ns := nodeState(gs, event.NodeID)
+		ns.Status = domain.ExecutionStatusWaitingForInput
+		setMetadata(ns, "request_id", p.RequestID)
+		gs.Status = domain.ExecutionStatusWaitingForInput
+	}
+	return nil
+}
+
+func setGraphID(gs *domain.GraphState, graphID string) {
+	if graphID != "" {
+		gs.GraphID = graphID
+	}
+}
+
+func nodeState(gs *domain.GraphState, nodeID string) *domain.NodeState {
+	ns, ok := gs.NodeStates[nodeID]
+	if !ok {
+		ns = &domain.NodeState{NodeID: nodeID, Status: domain.ExecutionStatusPending}
+		gs.NodeStates[nodeID] = ns
+	}
+	return ns
+}
+
+func setMetadata(ns *domain.NodeState, key string, value interface{}) {
+	if ns.Metadata == nil {
+		ns.Metadata = make(map[string]interface{})
+	}
+	ns.Metadata[key] = value
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
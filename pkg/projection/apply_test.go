@@ -0,0 +1,94 @@
+package projection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/events"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+var epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// event builds an event of payload at epoch plus the given seconds.
+func event(id string, seconds int, nodeID string, payload events.Payload) ports.Event {
+	e, err := events.NewEvent("exec-1", nodeID, payload)
+	if err != nil {
+		panic(err)
+	}
+	e.ID = id
+	e.Timestamp = epoch.Add(time.Duration(seconds) * time.Second)
+	return e
+}
+
+func TestProject(t *testing.T) {
+	gs, err := Project([]ports.Event{
+		event("1", 0, "", events.GraphSubmittedPayload{GraphID: "review", Inputs: map[string]interface{}{"url": "u"}}),
+		event("2", 1, "", events.GraphStartedPayload{GraphID: "review"}),
+		event("3", 2, "fetch", events.NodeStartedPayload{NodeType: "executor"}),
+		event("4", 3, "fetch", events.NodeCompletedPayload{Output: map[string]interface{}{"body": "ok"}, Attempts: 2, Next: []string{"draft"}}),
+		event("5", 4, "draft", events.NodeStartedPayload{}),
+		event("6", 5, "draft", events.NodeFailedPayload{Error: "timeout", ErrorKind: "timeout", HandledBy: "fallback"}),
+		event("7", 5, "draft", events.ToolExecutedPayload{ToolName: "search"}),
+		{ID: "8", Type: "custom.audit", Timestamp: epoch.Add(6 * time.Second)},
+		event("9", 7, "", events.GraphFailedPayload{GraphID: "review", Error: "cancelled", Cancelled: true}),
+	})
+	if err != nil {
+		t.Fatalf("Project failed: %v", err)
+	}
+
+	if gs.GraphID != "review" || gs.Status != domain.ExecutionStatusCancelled || gs.Error != "cancelled" {
+		t.Errorf("unexpected execution %s %s %q", gs.GraphID, gs.Status, gs.Error)
+	}
+	if !gs.SubmittedAt.Equal(epoch) || !gs.StartedAt.Equal(epoch.Add(time.Second)) || !gs.CompletedAt.Equal(epoch.Add(7*time.Second)) {
+		t.Errorf("unexpected timings %v %v %v", gs.SubmittedAt, gs.StartedAt, gs.CompletedAt)
+	}
+	if gs.Inputs["url"] != "u" {
+		t.Errorf("expected the submitted inputs, got %v", gs.Inputs)
+	}
+
+	fetch := gs.NodeStates["fetch"]
+	if fetch.Status != domain.ExecutionStatusCompleted || fetch.Output.(map[string]interface{})["body"] != "ok" ||
+		fetch.Metadata["attempts"] != 2 || fetch.Metadata["node_type"] != "executor" {
+		t.Errorf("unexpected fetch state %+v", fetch)
+	}
+	if !fetch.StartedAt.Equal(epoch.Add(2*time.Second)) || !fetch.CompletedAt.Equal(epoch.Add(3*time.Second)) {
+		t.Errorf("unexpected fetch timings %v %v", fetch.StartedAt, fetch.CompletedAt)
+	}
+	draft := gs.NodeStates["draft"]
+	if draft.Status != domain.ExecutionStatusFailed || draft.Error != "timeout" || draft.Metadata["handled_by"] != "fallback" {
+		t.Errorf("unexpected draft state %+v", draft)
+	}
+}
+
+func TestApply_WaitingAndResumed(t *testing.T) {
+	gs := NewGraphState()
+	for _, e := range []ports.Event{
+		event("1", 0, "", events.GraphStartedPayload{GraphID: "g"}),
+		event("2", 1, "approve", events.NodeStartedPayload{}),
+		event("3", 2, "approve", events.NodeWaitingForInputPayload{RequestID: "req-1"}),
+	} {
+		if err := Apply(gs, e); err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+	}
+	if gs.Status != domain.ExecutionStatusWaitingForInput || gs.NodeStates["approve"].Metadata["request_id"] != "req-1" {
+		t.Errorf("expected the execution waiting for req-1, got %s %+v", gs.Status, gs.NodeStates["approve"])
+	}
+
+	if err := Apply(gs, event("4", 3, "", events.GraphResumedPayload{GraphID: "g"})); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if gs.Status != domain.ExecutionStatusRunning {
+		t.Errorf("expected running after resume, got %s", gs.Status)
+	}
+}
+
+func TestApply_UnsupportedVersion(t *testing.T) {
+	e := event("1", 0, "", events.GraphStartedPayload{GraphID: "g"})
+	e.SchemaVersion = "2.0"
+	if err := Apply(NewGraphState(), e); err == nil {
+		t.Error("expected an error for a newer major schema version")
+	}
+}
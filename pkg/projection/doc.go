@@ -0,0 +1,14 @@
+// Package projection rebuilds the domain.GraphState of executions from their
+// events, making the ports.EventStore the source of truth for execution
+// progress.
+//
+// Apply folds one event into a GraphState: graph events set the execution
+// status, timings and error, and node events set node statuses, outputs,
+// timings and attempts. Projector.Rebuild folds every event of an execution,
+// in timestamp order, read with EventStore.GetByExecutionID.
+//
+// Long executions produce many events. With a ports.SnapshotStore, the
+// projector saves a ports.Snapshot every few events and later rebuilds start
+// from the latest snapshot, reading only the events since it with
+// EventStore.Query.
+package projection
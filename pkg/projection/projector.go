@@ -0,0 +1,187 @@
+package projection
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// DefaultSnapshotEvery is the default number of events folded between two
+// snapshots.
+const DefaultSnapshotEvery = 100
+
+// Projector rebuilds the domain.GraphState of executions from the
+// ports.EventStore.
+type Projector struct {
+	events        ports.EventStore
+	snapshots     ports.SnapshotStore
+	snapshotEvery int
+	now           func() time.Time
+}
+
+// NewProjector creates a projector reading events from store.
+func NewProjector(store ports.EventStore) *Projector {
+	return &Projector{events: store, snapshotEvery: DefaultSnapshotEvery, now: time.Now}
+}
+
+// WithSnapshots makes Rebuild start from the latest snapshot in store, and
+// save a new snapshot once every events have been folded since the last one.
+// A non-positive every means DefaultSnapshotEvery.
+func (p *Projector) WithSnapshots(store ports.SnapshotStore, every int) *Projector {
+	if every <= 0 {
+		every = DefaultSnapshotEvery
+	}
+	p.snapshots = store
+	p.snapshotEvery = every
+	return p
+}
+
+// WithClock sets the clock used to timestamp snapshots.
+func (p *Projector) WithClock(now func() time.Time) *Projector {
+	p.now = now
+	return p
+}
+
+// Rebuild returns the state of an execution folded from its events, in
+// timestamp order. With snapshots, only the events after the latest snapshot
+// are read; a snapshot whose last event is not found is ignored and the
+// state is folded from the first event.
+func (p *Projector) Rebuild(ctx context.Context, executionID string) (*domain.GraphState, error) {
+	snapshot, err := p.loadSnapshot(ctx, executionID)
+	if err != nil {
+		return nil, err
+	}
+
+	var gs *domain.GraphState
+	var pending []ports.Event
+	version := 0
+	if snapshot != nil {
+		after, ok, err := p.eventsAfter(ctx, snapshot)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			gs, pending, version = snapshot.State, after, snapshot.Version
+		}
+	}
+	if gs == nil {
+		all, err := p.events.GetByExecutionID(ctx, executionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load events of execution '%s': %w", executionID, err)
+		}
+		sortEvents(all)
+		gs, pending, snapshot = NewGraphState(), all, nil
+	}
+
+	for _, event := range pending {
+		if err := Apply(gs, event); err != nil {
+			return nil, fmt.Errorf("execution '%s': %w", executionID, err)
+		}
+		version++
+		if p.snapshots != nil && p.due(snapshot, version) {
+			next := ports.Snapshot{
+				ExecutionID:   executionID,
+				State:         copyState(gs),
+				Version:       version,
+				LastEventID:   event.ID,
+				LastEventTime: event.Timestamp,
+				CreatedAt:     p.now(),
+			}
+			if err := p.snapshots.SaveSnapshot(ctx, next); err != nil {
+				return nil, fmt.Errorf("failed to save snapshot of execution '%s': %w", executionID, err)
+			}
+			snapshot = &next
+		}
+	}
+	return gs, nil
+}
+
+// Project folds a sequence of events, in the given order, into a new state.
+func Project(events []ports.Event) (*domain.GraphState, error) {
+	gs := NewGraphState()
+	for _, event := range events {
+		if err := Apply(gs, event); err != nil {
+			return nil, err
+		}
+	}
+	return gs, nil
+}
+
+func (p *Projector) loadSnapshot(ctx context.Context, executionID string) (*ports.Snapshot, error) {
+	if p.snapshots == nil {
+		return nil, nil
+	}
+	snapshot, err := p.snapshots.LoadSnapshot(ctx, executionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshot of execution '%s': %w", executionID, err)
+	}
+	if snapshot == nil || snapshot.State == nil {
+		return nil, nil
+	}
+	copied := *snapshot
+	copied.State = copyState(snapshot.State)
+	return &copied, nil
+}
+
+// eventsAfter returns the events following the last event of a snapshot. It
+// reports false if that event is not among the events since its timestamp.
+func (p *Projector) eventsAfter(ctx context.Context, snapshot *ports.Snapshot) ([]ports.Event, bool, error) {
+	since, err := p.events.Query(ctx, ports.EventFilter{ExecutionID: snapshot.ExecutionID, Since: snapshot.LastEventTime})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load events of execution '%s': %w", snapshot.ExecutionID, err)
+	}
+	sortEvents(since)
+	for i, event := range since {
+		if event.ID == snapshot.LastEventID {
+			return since[i+1:], true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// due reports whether a snapshot is taken after folding event number version.
+func (p *Projector) due(last *ports.Snapshot, version int) bool {
+	if last == nil {
+		return version >= p.snapshotEvery
+	}
+	return version-last.Version >= p.snapshotEvery
+}
+
+// sortEvents sorts events by timestamp, keeping the store order of events
+// with the same timestamp.
+func sortEvents(events []ports.Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.Before(events[j].Timestamp)
+	})
+}
+
+// copyState deep copies a state, so that folding events never modifies a
+// stored snapshot. The graph definition is shared.
+func copyState(gs *domain.GraphState) *domain.GraphState {
+	copied := *gs
+	copied.Inputs, _ = state.DeepCopyValue(gs.Inputs).(map[string]interface{})
+	copied.StartedAt = copyTime(gs.StartedAt)
+	copied.CompletedAt = copyTime(gs.CompletedAt)
+	copied.NodeStates = make(map[string]*domain.NodeState, len(gs.NodeStates))
+	for id, ns := range gs.NodeStates {
+		n := *ns
+		n.Output = state.DeepCopyValue(ns.Output)
+		n.Metadata, _ = state.DeepCopyValue(ns.Metadata).(map[string]interface{})
+		n.StartedAt = copyTime(ns.StartedAt)
+		n.CompletedAt = copyTime(ns.CompletedAt)
+		copied.NodeStates[id] = &n
+	}
+	return &copied
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	return timePtr(*t)
+}
@@ -0,0 +1,208 @@
+package projection
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-libs/pkg/engine"
+	"github.com/aescanero/dago-libs/pkg/events"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// memoryEvents is a minimal ports.EventStore counting its reads.
+type memoryEvents struct {
+	mu      sync.Mutex
+	events  []ports.Event
+	queries int
+	full    int
+}
+
+func (s *memoryEvents) Store(ctx context.Context, event ports.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *memoryEvents) Query(ctx context.Context, filter ports.EventFilter) ([]ports.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries++
+	var out []ports.Event
+	for _, e := range s.events {
+		if events.Matches(&filter, e) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (s *memoryEvents) GetByID(ctx context.Context, id string) (*ports.Event, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *memoryEvents) GetByExecutionID(ctx context.Context, executionID string) ([]ports.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.full++
+	var out []ports.Event
+	for _, e := range s.events {
+		if e.ExecutionID == executionID {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// memorySnapshots is a minimal ports.SnapshotStore.
+type memorySnapshots map[string]ports.Snapshot
+
+func (m memorySnapshots) SaveSnapshot(ctx context.Context, snapshot ports.Snapshot) error {
+	m[snapshot.ExecutionID] = snapshot
+	return nil
+}
+
+func (m memorySnapshots) LoadSnapshot(ctx context.Context, executionID string) (*ports.Snapshot, error) {
+	snapshot, ok := m[executionID]
+	if !ok {
+		return nil, nil
+	}
+	return &snapshot, nil
+}
+
+// storingBus is a ports.EventBus storing published events in an EventStore.
+type storingBus struct {
+	ports.EventBus
+	store ports.EventStore
+}
+
+func (b storingBus) Publish(ctx context.Context, topic string, event ports.Event) error {
+	return b.store.Store(ctx, event)
+}
+
+func TestProjector_Rebuild(t *testing.T) {
+	store := &memoryEvents{}
+	for _, e := range []ports.Event{
+		event("3", 2, "fetch", events.NodeCompletedPayload{Attempts: 1}),
+		event("1", 0, "", events.GraphStartedPayload{GraphID: "g"}),
+		event("2", 1, "fetch", events.NodeStartedPayload{}),
+	} {
+		store.Store(context.Background(), e)
+	}
+
+	gs, err := NewProjector(store).Rebuild(context.Background(), "exec-1")
+	if err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+	if gs.Status != domain.ExecutionStatusRunning || gs.NodeStates["fetch"].Status != domain.ExecutionStatusCompleted {
+		t.Errorf("expected events folded in timestamp order, got %s %+v", gs.Status, gs.NodeStates["fetch"])
+	}
+}
+
+func TestProjector_Snapshots(t *testing.T) {
+	store := &memoryEvents{}
+	snapshots := memorySnapshots{}
+	projector := NewProjector(store).WithSnapshots(snapshots, 3).WithClock(func() time.Time { return epoch })
+	publish := func(e ports.Event) { store.Store(context.Background(), e) }
+
+	publish(event("1", 0, "", events.GraphStartedPayload{GraphID: "g"}))
+	publish(event("2", 1, "a", events.NodeStartedPayload{}))
+	publish(event("3", 2, "a", events.NodeCompletedPayload{Output: map[string]interface{}{"n": 1}}))
+	publish(event("4", 2, "b", events.NodeStartedPayload{}))
+
+	if _, err := projector.Rebuild(context.Background(), "exec-1"); err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+	snapshot := snapshots["exec-1"]
+	if snapshot.Version != 3 || snapshot.LastEventID != "3" || snapshot.State.NodeStates["b"] != nil {
+		t.Fatalf("expected a snapshot after event 3, got %+v", snapshot)
+	}
+
+	publish(event("5", 3, "b", events.NodeCompletedPayload{}))
+	publish(event("6", 4, "", events.GraphCompletedPayload{GraphID: "g"}))
+	gs, err := projector.Rebuild(context.Background(), "exec-1")
+	if err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+	if store.full != 1 || store.queries != 1 {
+		t.Errorf("expected the second rebuild to query from the snapshot, got %d full reads and %d queries", store.full, store.queries)
+	}
+	if snapshots["exec-1"].Version != 6 {
+		t.Errorf("expected a new snapshot after 3 more events, got version %d", snapshots["exec-1"].Version)
+	}
+	if snapshot.State.Status != domain.ExecutionStatusRunning {
+		t.Error("expected the previous snapshot to be left unmodified")
+	}
+
+	full, err := Project(store.events)
+	if err != nil {
+		t.Fatalf("Project failed: %v", err)
+	}
+	if !reflect.DeepEqual(gs, full) {
+		t.Errorf("expected the snapshot rebuild to match a full rebuild:\n%+v\n%+v", gs, full)
+	}
+}
+
+func TestProjector_StaleSnapshot(t *testing.T) {
+	store := &memoryEvents{}
+	store.Store(context.Background(), event("1", 0, "", events.GraphStartedPayload{GraphID: "g"}))
+	snapshots := memorySnapshots{"exec-1": {
+		ExecutionID: "exec-1",
+		State:       &domain.GraphState{Status: domain.ExecutionStatusCompleted},
+		Version:     10,
+		LastEventID: "missing",
+	}}
+
+	gs, err := NewProjector(store).WithSnapshots(snapshots, 100).Rebuild(context.Background(), "exec-1")
+	if err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+	if gs.Status != domain.ExecutionStatusRunning || store.full != 1 {
+		t.Errorf("expected a full rebuild ignoring the stale snapshot, got %s", gs.Status)
+	}
+}
+
+func TestProjector_EngineEvents(t *testing.T) {
+	g, err := graph.Build("pipeline").ID("pipeline").
+		Start().
+		Tool("fetch", "http_get", nil).
+		Tool("store", "db_write", nil).
+		End()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	store := &memoryEvents{}
+	eng := engine.NewEngine().
+		Register(graph.ExecutorTypeTool, engine.ExecutorFunc(func(ctx context.Context, node *graph.ExecutorNode, s state.State) (state.State, error) {
+			s.Set(node.ID, "done")
+			return s, nil
+		})).
+		WithEvents(storingBus{store: store}, "executions")
+
+	result, err := eng.Run(context.Background(), g, state.State{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	gs, err := NewProjector(store).Rebuild(context.Background(), result.ExecutionID)
+	if err != nil {
+		t.Fatalf("Rebuild failed: %v", err)
+	}
+	if gs.GraphID != "pipeline" || gs.Status != domain.ExecutionStatusCompleted {
+		t.Errorf("unexpected execution %s %s", gs.GraphID, gs.Status)
+	}
+	for id, ns := range result.NodeStates {
+		if projected := gs.NodeStates[id]; projected == nil || projected.Status != ns.Status {
+			t.Errorf("node %s: expected %s, got %+v", id, ns.Status, projected)
+		}
+	}
+	if output, _ := gs.NodeStates["store"].Output.(map[string]interface{}); output["store"] != "done" {
+		t.Errorf("expected the node output, got %v", gs.NodeStates["store"].Output)
+	}
+}
@@ -0,0 +1,19 @@
+// Package render exports graphs to visual formats for documentation and review.
+//
+// Three formats are supported:
+//
+//   - DOT, for Graphviz (dot -Tsvg graph.dot > graph.svg)
+//   - Mermaid flowcharts, which render inline in GitHub and GitLab markdown
+//   - a self-contained HTML page with an SVG drawing of the graph that can be
+//     panned, zoomed and clicked to inspect node configuration
+//
+// Node types are drawn with distinct shapes (start and end nodes as circles,
+// executors as boxes, routers as diamonds, subgraphs as double-bordered boxes,
+// approvals as hexagons in DOT and Mermaid), the entry node is highlighted,
+// and edges are labeled with their label and condition. Router routes and default routes that have no matching edge, and
+// the error edges of node policies, are drawn as dashed edges.
+//
+// When Options.State is set, nodes are colored by the status of their
+// NodeState in the given domain.GraphState, e.g. to show the progress of an
+// execution.
+package render
@@ -0,0 +1,84 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+)
+
+// dotShapes maps node types to Graphviz shapes.
+var dotShapes = map[graph.NodeType]string{
+	graph.NodeTypeStart:    "circle",
+	graph.NodeTypeEnd:      "doublecircle",
+	graph.NodeTypeExecutor: "box",
+	graph.NodeTypeRouter:   "diamond",
+	graph.NodeTypeSubgraph: "box3d",
+	graph.NodeTypeApproval: "hexagon",
+}
+
+// DOT exports g as a Graphviz digraph.
+func DOT(g *graph.Graph, opts Options) (string, error) {
+	if g == nil {
+		return "", fmt.Errorf("cannot render nil graph")
+	}
+	v := newView(g, opts)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(v.title))
+	fmt.Fprintf(&b, "  label=%s;\n", dotQuote(v.title))
+	fmt.Fprintf(&b, "  labelloc=t;\n")
+	fmt.Fprintf(&b, "  rankdir=%s;\n", v.direction)
+	fmt.Fprintf(&b, "  node [fontname=\"Helvetica\", style=\"rounded,filled\", fillcolor=\"#ffffff\"];\n")
+	fmt.Fprintf(&b, "  edge [fontname=\"Helvetica\", fontsize=10];\n\n")
+
+	for _, n := range v.nodes {
+		shape, ok := dotShapes[n.kind]
+		if !ok {
+			shape = "ellipse"
+		}
+		attrs := []string{
+			"label=" + dotQuote(strings.Join(n.text(), "\n")),
+			"shape=" + shape,
+		}
+		if color, ok := statusColors[n.status]; ok {
+			attrs = append(attrs, "fillcolor="+dotQuote(color))
+		}
+		if n.entry {
+			attrs = append(attrs, "penwidth=3")
+		}
+		if n.error != "" {
+			attrs = append(attrs, "tooltip="+dotQuote(n.error))
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", dotQuote(n.id), strings.Join(attrs, ", "))
+	}
+	if len(v.edges) > 0 {
+		b.WriteString("\n")
+	}
+
+	for _, e := range v.edges {
+		var attrs []string
+		if e.label != "" {
+			attrs = append(attrs, "label="+dotQuote(e.label))
+		}
+		if e.route {
+			attrs = append(attrs, "style=dashed")
+		}
+		if len(attrs) > 0 {
+			fmt.Fprintf(&b, "  %s -> %s [%s];\n", dotQuote(e.from), dotQuote(e.to), strings.Join(attrs, ", "))
+		} else {
+			fmt.Fprintf(&b, "  %s -> %s;\n", dotQuote(e.from), dotQuote(e.to))
+		}
+	}
+
+	b.WriteString("}\n")
+	return b.String(), nil
+}
+
+// dotQuote quotes s as a DOT string. Newlines become centered line breaks.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,53 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDOT(t *testing.T) {
+	out, err := DOT(reviewGraph(t), Options{Direction: LeftToRight})
+	if err != nil {
+		t.Fatalf("DOT failed: %v", err)
+	}
+
+	for _, want := range []string{
+		`digraph "Review \"pipeline\"" {`,
+		`rankdir=LR;`,
+		`"start" [label="start", shape=circle, penwidth=3];`,
+		`"draft" [label="draft\nllm", shape=box];`,
+		`"check" [label="check", shape=diamond];`,
+		`"end" [label="end", shape=doublecircle];`,
+		`"check" -> "publish" [label="state.approved == true"];`,
+		`"check" -> "draft" [label="default"];`,
+		`"start" -> "draft";`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+	if !strings.HasSuffix(out, "}\n") {
+		t.Error("expected closing brace")
+	}
+}
+
+func TestDOT_StateOverlay(t *testing.T) {
+	out, err := DOT(reviewGraph(t), Options{State: executionState()})
+	if err != nil {
+		t.Fatalf("DOT failed: %v", err)
+	}
+	for _, want := range []string{
+		`"draft" [label="draft\nllm\n[failed]", shape=box, fillcolor="#ef9a9a", tooltip="rate limited"];`,
+		`"start" [label="start\n[completed]", shape=circle, fillcolor="#a5d6a7", penwidth=3];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+}
+
+func TestDOT_NilGraph(t *testing.T) {
+	if _, err := DOT(nil, Options{}); err == nil {
+		t.Error("expected error for nil graph")
+	}
+}
@@ -0,0 +1,333 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"math"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+)
+
+// Layout dimensions of the HTML drawing, in SVG units.
+const (
+	nodeWidth  = 170
+	nodeHeight = 60
+	colGap     = 210
+	rowGap     = 120
+	margin     = 60
+)
+
+// HTML exports g as a self-contained HTML page.
+//
+// The page embeds an SVG drawing laid out by rank (distance from the entry
+// node) and a small script for panning, zooming and showing the definition
+// and execution state of a node when it is clicked. It loads no external
+// resources.
+func HTML(g *graph.Graph, opts Options) (string, error) {
+	if g == nil {
+		return "", fmt.Errorf("cannot render nil graph")
+	}
+	v := newView(g, opts)
+	page := layout(v)
+
+	details := make(map[string]interface{}, len(v.nodes))
+	for _, n := range v.nodes {
+		entry := map[string]interface{}{"node": n.node}
+		if opts.State != nil {
+			if ns, ok := opts.State.NodeStates[n.id]; ok && ns != nil {
+				entry["state"] = ns
+			}
+		}
+		details[n.id] = entry
+	}
+	data, err := json.Marshal(details)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode node details: %w", err)
+	}
+	page.Details = template.JS(data)
+
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, page); err != nil {
+		return "", fmt.Errorf("failed to render HTML: %w", err)
+	}
+	return buf.String(), nil
+}
+
+type htmlPage struct {
+	Title   string
+	Status  string
+	Width   int
+	Height  int
+	Nodes   []htmlNode
+	Edges   []htmlEdge
+	Details template.JS
+}
+
+type htmlNode struct {
+	ID    string
+	Kind  string
+	Shape string
+	X, Y  int
+	// Left and Top are the corner of the node's bounding box.
+	Left, Top int
+	W, H      int
+	Points    string
+	RX, RY    int
+	Lines     []htmlLine
+	Fill      string
+	Entry     bool
+	Status    string
+}
+
+type htmlLine struct {
+	Text string
+	Y    int
+}
+
+type htmlEdge struct {
+	Path   string
+	Label  string
+	LabelX int
+	LabelY int
+	Dashed bool
+}
+
+// layout positions nodes on a grid: one row (or column, left to right) per
+// rank, centered, and routes edges as curves between node borders.
+func layout(v *view) *htmlPage {
+	byRank := make(map[int][]int)
+	maxRank, maxWidth := 0, 1
+	for i, n := range v.nodes {
+		byRank[n.rank] = append(byRank[n.rank], i)
+		if n.rank > maxRank {
+			maxRank = n.rank
+		}
+		if len(byRank[n.rank]) > maxWidth {
+			maxWidth = len(byRank[n.rank])
+		}
+	}
+
+	lr := v.direction == LeftToRight
+	page := &htmlPage{Title: v.title, Status: string(v.status)}
+	span := (maxWidth-1)*colGap + nodeWidth + 2*margin
+	depth := maxRank*rowGap + nodeHeight + 2*margin
+	if lr {
+		span = (maxWidth-1)*rowGap + nodeHeight + 2*margin
+		depth = maxRank*colGap + nodeWidth + 2*margin
+		page.Width, page.Height = depth, span
+	} else {
+		page.Width, page.Height = span, depth
+	}
+
+	centers := make(map[string][2]int, len(v.nodes))
+	for rank := 0; rank <= maxRank; rank++ {
+		row := byRank[rank]
+		for i, idx := range row {
+			offset := float64(i) - float64(len(row)-1)/2
+			var x, y int
+			if lr {
+				x = margin + nodeWidth/2 + rank*colGap
+				y = span/2 + int(offset*rowGap)
+			} else {
+				x = span/2 + int(offset*colGap)
+				y = margin + nodeHeight/2 + rank*rowGap
+			}
+			centers[v.nodes[idx].id] = [2]int{x, y}
+		}
+	}
+
+	for _, n := range v.nodes {
+		c := centers[n.id]
+		hn := htmlNode{
+			ID:     n.id,
+			Kind:   string(n.kind),
+			X:      c[0],
+			Y:      c[1],
+			Left:   c[0] - nodeWidth/2,
+			Top:    c[1] - nodeHeight/2,
+			RX:     nodeWidth / 2,
+			RY:     nodeHeight / 2,
+			W:      nodeWidth,
+			H:      nodeHeight,
+			Fill:   "#ffffff",
+			Entry:  n.entry,
+			Status: string(n.status),
+		}
+		if color, ok := statusColors[n.status]; ok {
+			hn.Fill = color
+		}
+		switch n.kind {
+		case graph.NodeTypeStart:
+			hn.Shape = "circle"
+		case graph.NodeTypeEnd:
+			hn.Shape = "doublecircle"
+		case graph.NodeTypeRouter:
+			hn.Shape = "diamond"
+			hn.Points = fmt.Sprintf("%d,%d %d,%d %d,%d %d,%d",
+				c[0], c[1]-nodeHeight/2-8, c[0]+nodeWidth/2, c[1], c[0], c[1]+nodeHeight/2+8, c[0]-nodeWidth/2, c[1])
+		case graph.NodeTypeSubgraph:
+			hn.Shape = "subgraph"
+		case graph.NodeTypeExecutor, graph.NodeTypeApproval:
+			hn.Shape = "box"
+		default:
+			hn.Shape = "ellipse"
+		}
+		lines := n.text()
+		top := c[1] - (len(lines)-1)*8
+		for i, text := range lines {
+			hn.Lines = append(hn.Lines, htmlLine{Text: text, Y: top + i*16})
+		}
+		page.Nodes = append(page.Nodes, hn)
+	}
+
+	ranks := make(map[string]int, len(v.nodes))
+	for _, n := range v.nodes {
+		ranks[n.id] = n.rank
+	}
+	for _, e := range v.edges {
+		from, okFrom := centers[e.from]
+		to, okTo := centers[e.to]
+		if !okFrom || !okTo {
+			continue
+		}
+		page.Edges = append(page.Edges, routeEdge(from, to, ranks[e.to] <= ranks[e.from], lr, e))
+	}
+	return page
+}
+
+// routeEdge draws an edge from the far side of the source to the near side of
+// the target. Edges that go back up (loops) leave and enter on the side.
+func routeEdge(from, to [2]int, back, lr bool, e viewEdge) htmlEdge {
+	// Work in (along, across) coordinates, where "along" follows the ranks.
+	a := func(p [2]int) (float64, float64) {
+		if lr {
+			return float64(p[0]), float64(p[1])
+		}
+		return float64(p[1]), float64(p[0])
+	}
+	xy := func(along, across float64) (float64, float64) {
+		if lr {
+			return along, across
+		}
+		return across, along
+	}
+	pt := func(along, across float64) string {
+		x, y := xy(along, across)
+		return fmt.Sprintf("%.0f,%.0f", x, y)
+	}
+	half := float64(nodeHeight) / 2
+	sideHalf := float64(nodeWidth) / 2
+	if lr {
+		half, sideHalf = float64(nodeWidth)/2, float64(nodeHeight)/2
+	}
+
+	fa, fc := a(from)
+	ta, tc := a(to)
+	var path string
+	var la, lc float64
+	if back {
+		bulge := sideHalf + 50 + math.Abs(fa-ta)/4
+		path = fmt.Sprintf("M%s C%s %s %s", pt(fa, fc+sideHalf), pt(fa, fc+bulge), pt(ta, tc+bulge), pt(ta, tc+sideHalf))
+		la, lc = (fa+ta)/2, math.Max(fc, tc)+bulge*0.75
+	} else {
+		sa, ea := fa+half, ta-half
+		mid := (sa + ea) / 2
+		path = fmt.Sprintf("M%s C%s %s %s", pt(sa, fc), pt(mid, fc), pt(mid, tc), pt(ea, tc))
+		la, lc = mid, (fc+tc)/2
+	}
+
+	lx, ly := xy(la, lc)
+	return htmlEdge{Path: path, Label: e.label, LabelX: int(lx), LabelY: int(ly), Dashed: e.route}
+}
+
+var htmlTemplate = template.Must(template.New("graph").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+  body { margin: 0; font-family: Helvetica, Arial, sans-serif; display: flex; height: 100vh; }
+  header { position: absolute; top: 0; left: 0; padding: 8px 16px; background: rgba(255,255,255,.9); }
+  h1 { font-size: 18px; margin: 0; }
+  #canvas { flex: 1; cursor: grab; }
+  #canvas.dragging { cursor: grabbing; }
+  aside { width: 360px; border-left: 1px solid #ccc; padding: 12px; overflow: auto; background: #fafafa; }
+  aside pre { font-size: 12px; white-space: pre-wrap; word-break: break-all; }
+  .node { cursor: pointer; }
+  .node .shape { stroke: #333; stroke-width: 1.5; }
+  .node.entry .shape { stroke-width: 4; }
+  .node.selected .shape { stroke: #1565c0; }
+  .node text { font-size: 13px; text-anchor: middle; dominant-baseline: middle; pointer-events: none; }
+  .edge path { fill: none; stroke: #555; stroke-width: 1.5; marker-end: url(#arrow); }
+  .edge.route path { stroke-dasharray: 6 4; }
+  .edge text { font-size: 11px; text-anchor: middle; fill: #333; paint-order: stroke; stroke: #fff; stroke-width: 4px; }
+</style>
+</head>
+<body>
+<header><h1>{{.Title}}</h1>{{if .Status}}<div>Status: {{.Status}}</div>{{end}}</header>
+<svg id="canvas" viewBox="0 0 {{.Width}} {{.Height}}" xmlns="http://www.w3.org/2000/svg">
+  <defs>
+    <marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" orient="auto-start-reverse">
+      <path d="M0,0 L10,5 L0,10 z" fill="#555"/>
+    </marker>
+  </defs>
+  {{range .Edges}}<g class="edge{{if .Dashed}} route{{end}}"><path d="{{.Path}}"/>{{if .Label}}<text x="{{.LabelX}}" y="{{.LabelY}}">{{.Label}}</text>{{end}}</g>
+  {{end}}
+  {{range .Nodes}}<g class="node{{if .Entry}} entry{{end}}" data-id="{{.ID}}" data-type="{{.Kind}}"{{if .Status}} data-status="{{.Status}}"{{end}}>
+    {{if eq .Shape "circle"}}<circle class="shape" cx="{{.X}}" cy="{{.Y}}" r="30" fill="{{.Fill}}"/>
+    {{else if eq .Shape "doublecircle"}}<circle class="shape" cx="{{.X}}" cy="{{.Y}}" r="30" fill="{{.Fill}}"/><circle class="shape" cx="{{.X}}" cy="{{.Y}}" r="24" fill="{{.Fill}}"/>
+    {{else if eq .Shape "diamond"}}<polygon class="shape" points="{{.Points}}" fill="{{.Fill}}"/>
+    {{else if eq .Shape "subgraph"}}<rect class="shape" x="{{.Left}}" y="{{.Top}}" width="{{.W}}" height="{{.H}}" rx="6" fill="{{.Fill}}" transform="translate(5,5)"/><rect class="shape" x="{{.Left}}" y="{{.Top}}" width="{{.W}}" height="{{.H}}" rx="6" fill="{{.Fill}}"/>
+    {{else if eq .Shape "box"}}<rect class="shape" x="{{.Left}}" y="{{.Top}}" width="{{.W}}" height="{{.H}}" rx="6" fill="{{.Fill}}"/>
+    {{else}}<ellipse class="shape" cx="{{.X}}" cy="{{.Y}}" rx="{{.RX}}" ry="{{.RY}}" fill="{{.Fill}}"/>
+    {{end}}{{$x := .X}}{{range .Lines}}<text x="{{$x}}" y="{{.Y}}">{{.Text}}</text>{{end}}
+  </g>
+  {{end}}
+</svg>
+<aside><h2 id="title">Select a node</h2><pre id="details"></pre></aside>
+<script>
+(function () {
+  const details = {{.Details}};
+  const svg = document.getElementById("canvas");
+  let box = svg.viewBox.baseVal;
+  let view = { x: box.x, y: box.y, w: box.width, h: box.height };
+  const apply = () => svg.setAttribute("viewBox", view.x + " " + view.y + " " + view.w + " " + view.h);
+
+  svg.addEventListener("wheel", (e) => {
+    e.preventDefault();
+    const k = e.deltaY < 0 ? 0.9 : 1.1;
+    const r = svg.getBoundingClientRect();
+    const px = view.x + (e.clientX - r.left) / r.width * view.w;
+    const py = view.y + (e.clientY - r.top) / r.height * view.h;
+    view = { x: px - (px - view.x) * k, y: py - (py - view.y) * k, w: view.w * k, h: view.h * k };
+    apply();
+  }, { passive: false });
+
+  let drag = null;
+  svg.addEventListener("mousedown", (e) => { drag = { x: e.clientX, y: e.clientY }; svg.classList.add("dragging"); });
+  window.addEventListener("mouseup", () => { drag = null; svg.classList.remove("dragging"); });
+  window.addEventListener("mousemove", (e) => {
+    if (!drag) return;
+    const r = svg.getBoundingClientRect();
+    view.x -= (e.clientX - drag.x) / r.width * view.w;
+    view.y -= (e.clientY - drag.y) / r.height * view.h;
+    drag = { x: e.clientX, y: e.clientY };
+    apply();
+  });
+
+  document.querySelectorAll(".node").forEach((el) => {
+    el.addEventListener("click", () => {
+      document.querySelectorAll(".node.selected").forEach((n) => n.classList.remove("selected"));
+      el.classList.add("selected");
+      const id = el.dataset.id;
+      document.getElementById("title").textContent = id;
+      document.getElementById("details").textContent = JSON.stringify(details[id], null, 2);
+    });
+  });
+})();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,69 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+)
+
+func TestHTML(t *testing.T) {
+	g := reviewGraph(t)
+	g.GetNode("draft").(*graph.ExecutorNode).Config["prompt"] = "</script><script>alert(1)</script>"
+
+	out, err := HTML(g, Options{State: executionState()})
+	if err != nil {
+		t.Fatalf("HTML failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"<title>Review &#34;pipeline&#34;</title>",
+		"Status: running",
+		`<g class="node entry" data-id="start" data-type="start" data-status="completed">`,
+		`data-id="check" data-type="router"`,
+		`<polygon class="shape"`,
+		`fill="#ef9a9a"`,
+		`<text x=`,
+		`>state.approved == true</text>`,
+		`"rate limited"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output", want)
+		}
+	}
+
+	// Node details are embedded as data, not markup.
+	if strings.Contains(out, "<script>alert(1)</script>") {
+		t.Error("node configuration was not escaped")
+	}
+	// The page is self-contained.
+	for _, external := range []string{"src=\"http", "href=\"http", "@import"} {
+		if strings.Contains(out, external) {
+			t.Errorf("unexpected external resource %q", external)
+		}
+	}
+}
+
+func TestLayout(t *testing.T) {
+	v := newView(reviewGraph(t), Options{})
+	page := layout(v)
+
+	if len(page.Nodes) != 5 {
+		t.Fatalf("expected 5 nodes, got %d", len(page.Nodes))
+	}
+	y := make(map[string]int)
+	for _, n := range page.Nodes {
+		y[n.ID] = n.Y
+		if n.X < 0 || n.X > page.Width || n.Y < 0 || n.Y > page.Height {
+			t.Errorf("node %s at (%d,%d) outside %dx%d", n.ID, n.X, n.Y, page.Width, page.Height)
+		}
+	}
+	if !(y["start"] < y["draft"] && y["draft"] < y["check"] && y["check"] < y["publish"]) {
+		t.Errorf("expected ranks to flow downwards, got %v", y)
+	}
+
+	lr := layout(newView(reviewGraph(t), Options{Direction: LeftToRight}))
+	if lr.Width <= lr.Height {
+		t.Errorf("expected a wide drawing for left-to-right layout, got %dx%d", lr.Width, lr.Height)
+	}
+}
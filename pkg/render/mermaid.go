@@ -0,0 +1,124 @@
+package render
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+)
+
+// mermaidShapes maps node types to Mermaid shape delimiters.
+var mermaidShapes = map[graph.NodeType][2]string{
+	graph.NodeTypeStart:    {"((", "))"},
+	graph.NodeTypeEnd:      {"(((", ")))"},
+	graph.NodeTypeExecutor: {"[", "]"},
+	graph.NodeTypeRouter:   {"{", "}"},
+	graph.NodeTypeSubgraph: {"[[", "]]"},
+	graph.NodeTypeApproval: {"{{", "}}"},
+}
+
+var mermaidUnsafe = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// Mermaid exports g as a Mermaid flowchart.
+//
+// Node IDs are rewritten to valid Mermaid identifiers (prefixed with "n_", as
+// "end" is a reserved word); labels keep the original IDs.
+func Mermaid(g *graph.Graph, opts Options) (string, error) {
+	if g == nil {
+		return "", fmt.Errorf("cannot render nil graph")
+	}
+	v := newView(g, opts)
+	ids := mermaidIDs(v.nodes)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "---\ntitle: %s\n---\n", mermaidText(v.title))
+	fmt.Fprintf(&b, "flowchart %s\n", v.direction)
+
+	statuses := make(map[domain.ExecutionStatus][]string)
+	var entry string
+	for _, n := range v.nodes {
+		shape, ok := mermaidShapes[n.kind]
+		if !ok {
+			shape = [2]string{"(", ")"}
+		}
+		lines := n.text()
+		for i := range lines {
+			lines[i] = mermaidText(lines[i])
+		}
+		fmt.Fprintf(&b, "    %s%s\"%s\"%s\n", ids[n.id], shape[0], strings.Join(lines, "<br/>"), shape[1])
+		if n.status != "" {
+			statuses[n.status] = append(statuses[n.status], ids[n.id])
+		}
+		if n.entry {
+			entry = ids[n.id]
+		}
+	}
+
+	for _, e := range v.edges {
+		from, okFrom := ids[e.from]
+		to, okTo := ids[e.to]
+		if !okFrom || !okTo {
+			// Dangling edges are reported by Graph.Validate; skip them here.
+			continue
+		}
+		arrow := "-->"
+		if e.route {
+			arrow = "-.->"
+		}
+		if e.label != "" {
+			fmt.Fprintf(&b, "    %s %s|\"%s\"| %s\n", from, arrow, mermaidText(e.label), to)
+		} else {
+			fmt.Fprintf(&b, "    %s %s %s\n", from, arrow, to)
+		}
+	}
+
+	if entry != "" {
+		fmt.Fprintf(&b, "    classDef entry stroke-width:3px\n")
+		fmt.Fprintf(&b, "    class %s entry\n", entry)
+	}
+	names := make([]domain.ExecutionStatus, 0, len(statuses))
+	for status := range statuses {
+		names = append(names, status)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	for _, status := range names {
+		color := statusColors[status]
+		if color == "" {
+			color = "#ffffff"
+		}
+		class := "status_" + mermaidUnsafe.ReplaceAllString(string(status), "_")
+		fmt.Fprintf(&b, "    classDef %s fill:%s\n", class, color)
+		fmt.Fprintf(&b, "    class %s %s\n", strings.Join(statuses[status], ","), class)
+	}
+
+	return b.String(), nil
+}
+
+// mermaidIDs assigns a unique Mermaid identifier to each node.
+func mermaidIDs(nodes []viewNode) map[string]string {
+	ids := make(map[string]string, len(nodes))
+	used := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		base := "n_" + mermaidUnsafe.ReplaceAllString(n.id, "_")
+		id := base
+		for i := 2; used[id]; i++ {
+			id = fmt.Sprintf("%s_%d", base, i)
+		}
+		used[id] = true
+		ids[n.id] = id
+	}
+	return ids
+}
+
+// mermaidText escapes text for use inside a quoted Mermaid label.
+func mermaidText(s string) string {
+	return strings.NewReplacer(
+		`"`, "#quot;",
+		"<", "#lt;",
+		">", "#gt;",
+		"\n", " ",
+	).Replace(s)
+}
@@ -0,0 +1,54 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+)
+
+func TestMermaid(t *testing.T) {
+	out, err := Mermaid(reviewGraph(t), Options{State: executionState()})
+	if err != nil {
+		t.Fatalf("Mermaid failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"title: Review #quot;pipeline#quot;",
+		"flowchart TB",
+		`n_start(("start<br/>[completed]"))`,
+		`n_draft["draft<br/>llm<br/>[failed]"]`,
+		`n_check{"check"}`,
+		`n_end((("end")))`,
+		`n_check -->|"state.approved == true"| n_publish`,
+		`n_check -->|"default"| n_draft`,
+		"n_start --> n_draft",
+		"class n_start entry",
+		"classDef status_failed fill:#ef9a9a",
+		"class n_draft status_failed",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+}
+
+func TestMermaid_IDs(t *testing.T) {
+	g := graph.NewGraph("ids")
+	for _, id := range []string{"a.b", "a-b", "a_b"} {
+		if err := g.AddNode(&graph.ExecutorNode{BaseNode: graph.BaseNode{ID: id, Type: graph.NodeTypeExecutor}, ExecutorType: "llm", Config: map[string]interface{}{"model": "gpt-4"}}); err != nil {
+			t.Fatalf("AddNode failed: %v", err)
+		}
+	}
+	g.EntryNode = "a.b"
+
+	out, err := Mermaid(g, Options{})
+	if err != nil {
+		t.Fatalf("Mermaid failed: %v", err)
+	}
+	for _, want := range []string{`n_a_b["a.b`, `n_a_b_2["a-b`, `n_a_b_3["a_b`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+}
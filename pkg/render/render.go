@@ -0,0 +1,266 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+)
+
+// Format is an export format.
+type Format string
+
+const (
+	FormatDOT     Format = "dot"
+	FormatMermaid Format = "mermaid"
+	FormatHTML    Format = "html"
+)
+
+// Formats lists the supported export formats.
+var Formats = []Format{FormatDOT, FormatMermaid, FormatHTML}
+
+// Direction is the layout direction of the rendered graph.
+type Direction string
+
+const (
+	// TopToBottom lays out the graph from top to bottom (default).
+	TopToBottom Direction = "TB"
+
+	// LeftToRight lays out the graph from left to right.
+	LeftToRight Direction = "LR"
+)
+
+// Options configures rendering.
+type Options struct {
+	// Title overrides the graph name as the title of the drawing.
+	Title string
+
+	// Direction sets the layout direction. Defaults to TopToBottom.
+	Direction Direction
+
+	// State overlays the execution status of each node.
+	State *domain.GraphState
+}
+
+// Render exports g in the given format.
+func Render(g *graph.Graph, format Format, opts Options) (string, error) {
+	switch format {
+	case FormatDOT:
+		return DOT(g, opts)
+	case FormatMermaid:
+		return Mermaid(g, opts)
+	case FormatHTML:
+		return HTML(g, opts)
+	default:
+		return "", fmt.Errorf("unsupported render format '%s'", format)
+	}
+}
+
+// ParseFormat parses a format name, also accepting common file extensions
+// ("gv", "mmd", "htm").
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(name, ".")) {
+	case "dot", "gv":
+		return FormatDOT, nil
+	case "mermaid", "mmd":
+		return FormatMermaid, nil
+	case "html", "htm":
+		return FormatHTML, nil
+	default:
+		return "", fmt.Errorf("unknown render format '%s'", name)
+	}
+}
+
+// statusColors are the fill colors used for node statuses.
+var statusColors = map[domain.ExecutionStatus]string{
+	domain.ExecutionStatusPending:         "#eeeeee",
+	domain.ExecutionStatusSubmitted:       "#eeeeee",
+	domain.ExecutionStatusRunning:         "#90caf9",
+	domain.ExecutionStatusCompleted:       "#a5d6a7",
+	domain.ExecutionStatusFailed:          "#ef9a9a",
+	domain.ExecutionStatusCancelled:       "#ffcc80",
+	domain.ExecutionStatusWaitingForInput: "#fff59d",
+}
+
+// view is the format-independent description of a drawing.
+type view struct {
+	title     string
+	direction Direction
+	status    domain.ExecutionStatus
+	nodes     []viewNode
+	edges     []viewEdge
+}
+
+type viewNode struct {
+	id     string
+	label  string
+	detail string
+	kind   graph.NodeType
+	entry  bool
+	rank   int
+	status domain.ExecutionStatus
+	error  string
+	node   graph.Node
+}
+
+type viewEdge struct {
+	from, to string
+	label    string
+	// route marks transitions derived from router routes rather than edges.
+	route bool
+}
+
+// newView collects the nodes and edges to draw. Nodes are ordered by their
+// distance from the entry node, then by ID; unreachable nodes come last.
+func newView(g *graph.Graph, opts Options) *view {
+	v := &view{title: opts.Title, direction: opts.Direction}
+	if v.title == "" {
+		v.title = g.Name
+	}
+	if v.title == "" {
+		v.title = g.ID
+	}
+	if v.direction == "" {
+		v.direction = TopToBottom
+	}
+	if opts.State != nil {
+		v.status = opts.State.Status
+	}
+
+	edges := make(map[[2]string]bool, len(g.Edges))
+	for _, e := range g.Edges {
+		edges[[2]string{e.From, e.To}] = true
+		v.edges = append(v.edges, viewEdge{from: e.From, to: e.To, label: edgeLabel(e.Label, e.Condition)})
+	}
+
+	ids := make([]string, 0, len(g.Nodes))
+	for id := range g.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		router, ok := g.Nodes[id].(*graph.RouterNode)
+		if !ok {
+			continue
+		}
+		for _, r := range router.Routes {
+			if !edges[[2]string{id, r.Target}] {
+				edges[[2]string{id, r.Target}] = true
+				v.edges = append(v.edges, viewEdge{from: id, to: r.Target, label: edgeLabel(r.Description, r.Condition), route: true})
+			}
+		}
+		if router.DefaultRoute != "" && !edges[[2]string{id, router.DefaultRoute}] {
+			v.edges = append(v.edges, viewEdge{from: id, to: router.DefaultRoute, label: "default", route: true})
+		}
+	}
+
+	for _, id := range ids {
+		based, ok := g.Nodes[id].(interface{ Base() *graph.BaseNode })
+		if !ok || based.Base().Policy == nil {
+			continue
+		}
+		for _, h := range based.Base().Policy.OnError {
+			label := "on error"
+			if len(h.Errors) > 0 {
+				label += ": " + strings.Join(h.Errors, ", ")
+			}
+			v.edges = append(v.edges, viewEdge{from: id, to: h.Target, label: label, route: true})
+		}
+	}
+
+	ranks := rankNodes(g.EntryNode, ids, v.edges)
+	for _, id := range ids {
+		node := g.Nodes[id]
+		vn := viewNode{
+			id:    id,
+			label: id,
+			kind:  node.GetType(),
+			entry: id == g.EntryNode,
+			rank:  ranks[id],
+			node:  node,
+		}
+		if based, ok := node.(interface{ Base() *graph.BaseNode }); ok && based.Base().Name != "" {
+			vn.label = based.Base().Name
+		}
+		switch n := node.(type) {
+		case *graph.ExecutorNode:
+			vn.detail = n.ExecutorType
+		case *graph.SubgraphNode:
+			if n.Ref != nil {
+				vn.detail = n.Ref.Key()
+			} else if n.Graph != nil {
+				vn.detail = "inline: " + n.Graph.ID
+			}
+		}
+		if opts.State != nil {
+			if ns, ok := opts.State.NodeStates[id]; ok && ns != nil {
+				vn.status = ns.Status
+				vn.error = ns.Error
+			}
+		}
+		v.nodes = append(v.nodes, vn)
+	}
+	sort.SliceStable(v.nodes, func(i, j int) bool { return v.nodes[i].rank < v.nodes[j].rank })
+	return v
+}
+
+// rankNodes returns the breadth-first distance of each node from the entry
+// node. Unreachable nodes are ranked after the deepest reachable node.
+func rankNodes(entry string, ids []string, edges []viewEdge) map[string]int {
+	next := make(map[string][]string)
+	for _, e := range edges {
+		next[e.from] = append(next[e.from], e.to)
+	}
+
+	ranks := make(map[string]int, len(ids))
+	maxRank := 0
+	if entry != "" {
+		ranks[entry] = 0
+		queue := []string{entry}
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+			for _, to := range next[id] {
+				if _, seen := ranks[to]; !seen {
+					ranks[to] = ranks[id] + 1
+					if ranks[to] > maxRank {
+						maxRank = ranks[to]
+					}
+					queue = append(queue, to)
+				}
+			}
+		}
+	}
+	for _, id := range ids {
+		if _, ok := ranks[id]; !ok {
+			ranks[id] = maxRank + 1
+		}
+	}
+	return ranks
+}
+
+func edgeLabel(label, condition string) string {
+	switch {
+	case label != "" && condition != "":
+		return label + ": " + condition
+	case condition != "":
+		return condition
+	default:
+		return label
+	}
+}
+
+// text returns the lines drawn inside a node.
+func (n viewNode) text() []string {
+	lines := []string{n.label}
+	if n.detail != "" {
+		lines = append(lines, n.detail)
+	}
+	if n.status != "" {
+		lines = append(lines, "["+string(n.status)+"]")
+	}
+	return lines
+}
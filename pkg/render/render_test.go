@@ -0,0 +1,146 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+)
+
+func reviewGraph(t *testing.T) *graph.Graph {
+	t.Helper()
+	g, err := graph.Build("Review \"pipeline\"").
+		ID("review").
+		Start().
+		LLM("draft", map[string]interface{}{"model": "gpt-4"}).
+		Router("check", graph.When("state.approved == true", "publish")).
+		Default("draft").
+		Tool("publish", "http_post", nil).
+		End()
+	if err != nil {
+		t.Fatalf("failed to build graph: %v", err)
+	}
+	return g
+}
+
+func executionState() *domain.GraphState {
+	return &domain.GraphState{
+		GraphID: "review",
+		Status:  domain.ExecutionStatusRunning,
+		NodeStates: map[string]*domain.NodeState{
+			"start": {NodeID: "start", Status: domain.ExecutionStatusCompleted},
+			"draft": {NodeID: "draft", Status: domain.ExecutionStatusFailed, Error: "rate limited"},
+		},
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		expected    Format
+		expectError bool
+	}{
+		{name: "dot", expected: FormatDOT},
+		{name: ".gv", expected: FormatDOT},
+		{name: "Mermaid", expected: FormatMermaid},
+		{name: "mmd", expected: FormatMermaid},
+		{name: "html", expected: FormatHTML},
+		{name: "png", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseFormat(tt.name)
+			if tt.expectError {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil || f != tt.expected {
+				t.Errorf("expected %s, got %s (%v)", tt.expected, f, err)
+			}
+		})
+	}
+}
+
+func TestRender_AllFormats(t *testing.T) {
+	g := reviewGraph(t)
+	for _, f := range Formats {
+		out, err := Render(g, f, Options{})
+		if err != nil {
+			t.Errorf("%s: render failed: %v", f, err)
+		}
+		if out == "" {
+			t.Errorf("%s: empty output", f)
+		}
+	}
+	if _, err := Render(g, Format("png"), Options{}); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
+
+func TestNewView(t *testing.T) {
+	g := reviewGraph(t)
+	// A route without a matching edge is drawn as a route edge.
+	router := g.GetNode("check").(*graph.RouterNode)
+	router.Routes = append(router.Routes, graph.When("state.escalate == true", "end"))
+
+	v := newView(g, Options{State: executionState()})
+
+	if v.title != "Review \"pipeline\"" || v.direction != TopToBottom {
+		t.Errorf("unexpected view header: %q %q", v.title, v.direction)
+	}
+
+	order := make([]string, 0, len(v.nodes))
+	for _, n := range v.nodes {
+		order = append(order, n.id)
+	}
+	expected := []string{"start", "draft", "check", "end", "publish"}
+	for i := range expected {
+		if i >= len(order) || order[i] != expected[i] {
+			t.Fatalf("expected node order %v, got %v", expected, order)
+		}
+	}
+
+	var routes int
+	for _, e := range v.edges {
+		if e.route {
+			routes++
+			if e.from != "check" || e.to != "end" || e.label != "state.escalate == true" {
+				t.Errorf("unexpected route edge: %+v", e)
+			}
+		}
+	}
+	if routes != 1 {
+		t.Errorf("expected 1 route edge, got %d", routes)
+	}
+
+	if v.nodes[1].status != domain.ExecutionStatusFailed || v.nodes[1].error != "rate limited" {
+		t.Errorf("expected draft status overlay, got %+v", v.nodes[1])
+	}
+	if !v.nodes[0].entry {
+		t.Error("expected start to be the entry node")
+	}
+}
+
+func TestNewView_ErrorEdges(t *testing.T) {
+	g := reviewGraph(t)
+	g.GetNode("publish").(*graph.ExecutorNode).Policy = &graph.Policy{
+		OnError: []graph.ErrorHandler{{Errors: []string{graph.ErrorKindTimeout}, Target: "draft"}},
+	}
+
+	v := newView(g, Options{})
+	found := false
+	for _, e := range v.edges {
+		if e.from == "publish" && e.to == "draft" {
+			found = true
+			if !e.route || e.label != "on error: timeout" {
+				t.Errorf("unexpected error edge: %+v", e)
+			}
+		}
+	}
+	if !found {
+		t.Error("expected an error edge from publish to draft")
+	}
+}
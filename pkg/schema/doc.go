@@ -7,6 +7,16 @@
 //
 // The Validator type provides methods to validate JSON data against these schemas,
 // ensuring that graph definitions and node configurations conform to the expected structure.
+// YAML graph definitions are validated against the same schema, and violations are
+// reported with their line and column in the YAML source (see LocatedError).
+//
+// ReportGraph combines schema validation with the structural checks of
+// graph.Graph.ValidateAll and returns a ValidationReport listing every
+// violation with its JSON Pointer, schema keyword, severity and message.
+//
+// The graph schema mirrors the Go types of the graph package; tests fail when
+// they drift apart. Downstream node types and executor types are added with
+// Validator.RegisterNodeSchema and Validator.RegisterExecutorSchema.
 //
 // Schemas are embedded in the binary using go:embed, so they are always available
 // at runtime without requiring external files.
@@ -0,0 +1,327 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+)
+
+// goField describes a JSON field of a Go struct.
+type goField struct {
+	typ       reflect.Type
+	omitempty bool
+}
+
+// jsonFields returns the JSON fields of a struct type, including the fields of
+// embedded structs.
+func jsonFields(t reflect.Type) map[string]goField {
+	fields := make(map[string]goField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if f.Anonymous && tag == "" {
+			for name, field := range jsonFields(f.Type) {
+				fields[name] = field
+			}
+			continue
+		}
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = goField{typ: f.Type, omitempty: strings.Contains(options, "omitempty")}
+	}
+	return fields
+}
+
+// schemaTypes returns the JSON types a Go type encodes to.
+func schemaTypes(f goField) []string {
+	typ := f.typ
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	var types []string
+	switch typ.Kind() {
+	case reflect.String:
+		types = []string{"string"}
+	case reflect.Bool:
+		types = []string{"boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		types = []string{"integer"}
+	case reflect.Float32, reflect.Float64:
+		types = []string{"number"}
+	case reflect.Map, reflect.Struct:
+		types = []string{"object"}
+	case reflect.Slice:
+		types = []string{"array"}
+	case reflect.Interface:
+		return nil
+	}
+	// nil maps, slices and pointers are encoded as null unless omitted
+	switch f.typ.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Ptr:
+		if !f.omitempty {
+			types = append(types, "null")
+		}
+	}
+	return types
+}
+
+// declaredTypes returns the "type" of a property schema as a sorted list.
+func declaredTypes(property map[string]interface{}) []string {
+	switch t := property["type"].(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, len(t))
+		for i, v := range t {
+			types[i] = v.(string)
+		}
+		sort.Strings(types)
+		return types
+	}
+	return nil
+}
+
+// checkDefinition compares a schema object definition with a Go struct type.
+func checkDefinition(t *testing.T, name string, definition map[string]interface{}, typ reflect.Type) {
+	t.Helper()
+	properties, _ := definition["properties"].(map[string]interface{})
+	fields := jsonFields(typ)
+	required := make(map[string]bool)
+	if list, ok := definition["required"].([]interface{}); ok {
+		for _, r := range list {
+			required[r.(string)] = true
+		}
+	}
+
+	for field, f := range fields {
+		raw, ok := properties[field]
+		if !ok {
+			t.Errorf("%s: Go field %s.%s is missing from the schema", name, typ.Name(), field)
+			continue
+		}
+		property := raw.(map[string]interface{})
+		if _, isRef := property["$ref"]; isRef {
+			continue
+		}
+		want := schemaTypes(f)
+		if required[field] {
+			// Required properties deliberately reject null.
+			want = without(want, "null")
+		}
+		sort.Strings(want)
+		if got := declaredTypes(property); want != nil && !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: property %s has type %v, but %s encodes it as %v", name, field, got, typ.Name(), want)
+		}
+	}
+	for property := range properties {
+		if _, ok := fields[property]; !ok {
+			t.Errorf("%s: schema property %s has no field in %s", name, property, typ.Name())
+		}
+	}
+
+	for r := range required {
+		if f, ok := fields[r]; ok && f.omitempty {
+			t.Errorf("%s: required property %s is omitted when empty by %s", name, r, typ.Name())
+		}
+	}
+}
+
+func without(values []string, value string) []string {
+	var out []string
+	for _, v := range values {
+		if v != value {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func TestGraphSchema_MatchesGoTypes(t *testing.T) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(graphSchemaJSON), &doc); err != nil {
+		t.Fatalf("invalid graph schema: %v", err)
+	}
+	definitions := doc["definitions"].(map[string]interface{})
+	definition := func(name string) map[string]interface{} {
+		def, ok := definitions[name].(map[string]interface{})
+		if !ok {
+			t.Fatalf("graph schema has no definition %s", name)
+		}
+		return def
+	}
+
+	checkDefinition(t, "graph", doc, reflect.TypeOf(graph.Graph{}))
+	checkDefinition(t, "edge", definition("edge"), reflect.TypeOf(graph.Edge{}))
+	checkDefinition(t, "route", definition("route"), reflect.TypeOf(graph.Route{}))
+	checkDefinition(t, "policy", definition("policy"), reflect.TypeOf(graph.Policy{}))
+	checkDefinition(t, "retryPolicy", definition("retryPolicy"), reflect.TypeOf(graph.RetryPolicy{}))
+	checkDefinition(t, "errorHandler", definition("errorHandler"), reflect.TypeOf(graph.ErrorHandler{}))
+
+	nodeTypes := map[string]reflect.Type{
+		"executorNode": reflect.TypeOf(graph.ExecutorNode{}),
+		"routerNode":   reflect.TypeOf(graph.RouterNode{}),
+		"subgraphNode": reflect.TypeOf(graph.SubgraphNode{}),
+		"startNode":    reflect.TypeOf(graph.StartNode{}),
+		"endNode":      reflect.TypeOf(graph.EndNode{}),
+		"approvalNode": reflect.TypeOf(graph.ApprovalNode{}),
+	}
+	for name, typ := range nodeTypes {
+		checkDefinition(t, name, definition(name), typ)
+	}
+
+	subgraphProperties := definition("subgraphNode")["properties"].(map[string]interface{})
+	checkDefinition(t, "subgraphNode.graph_ref", subgraphProperties["graph_ref"].(map[string]interface{}), reflect.TypeOf(graph.GraphRef{}))
+}
+
+func TestGraphSchema_NodeTypes(t *testing.T) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(graphSchemaJSON), &doc); err != nil {
+		t.Fatalf("invalid graph schema: %v", err)
+	}
+	definitions := doc["definitions"].(map[string]interface{})
+
+	// Every node type the graph package decodes has a definition whose
+	// "type" const names it, and is listed in the node oneOf.
+	refs := make(map[string]bool)
+	for _, branch := range definitions["node"].(map[string]interface{})["oneOf"].([]interface{}) {
+		refs[branch.(map[string]interface{})["$ref"].(string)] = true
+	}
+	for _, nodeType := range graph.RegisteredNodeTypes() {
+		name, ok := builtinNodeDefinitions[nodeType]
+		if !ok {
+			t.Errorf("node type %s has no schema definition", nodeType)
+			continue
+		}
+		def := definitions[name].(map[string]interface{})
+		typeProperty := def["properties"].(map[string]interface{})["type"].(map[string]interface{})
+		if typeProperty["const"] != string(nodeType) {
+			t.Errorf("definition %s: expected type const %q, got %v", name, nodeType, typeProperty["const"])
+		}
+		if !refs["#/definitions/"+name] {
+			t.Errorf("definition %s is not listed in the node oneOf", name)
+		}
+	}
+	if len(refs) != len(graph.RegisteredNodeTypes()) {
+		t.Errorf("node oneOf has %d branches for %d node types", len(refs), len(graph.RegisteredNodeTypes()))
+	}
+}
+
+func TestExecutorSchema_MatchesConfigTypes(t *testing.T) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(executorNodeSchemaJSON), &doc); err != nil {
+		t.Fatalf("invalid executor node schema: %v", err)
+	}
+	definitions := doc["definitions"].(map[string]interface{})
+
+	configTypes := map[string]reflect.Type{
+		"llmConfig":    reflect.TypeOf(graph.LLMConfig{}),
+		"toolConfig":   reflect.TypeOf(graph.ToolConfig{}),
+		"pythonConfig": reflect.TypeOf(graph.PythonConfig{}),
+		"bashConfig":   reflect.TypeOf(graph.BashConfig{}),
+		"httpConfig":   reflect.TypeOf(graph.HTTPConfig{}),
+	}
+	for name, typ := range configTypes {
+		def, ok := definitions[name].(map[string]interface{})
+		if !ok {
+			t.Errorf("executor node schema has no definition %s", name)
+			continue
+		}
+		checkDefinition(t, name, def, typ)
+	}
+
+	methods := definitions["httpConfig"].(map[string]interface{})["properties"].(map[string]interface{})["method"].(map[string]interface{})["enum"]
+	want := make([]interface{}, len(graph.HTTPMethods))
+	for i, m := range graph.HTTPMethods {
+		want[i] = m
+	}
+	if !reflect.DeepEqual(methods, want) {
+		t.Errorf("expected HTTP methods %v, got %v", want, methods)
+	}
+
+	// Every built-in executor type with a config schema has a Go config decoder.
+	decoders := make(map[string]bool)
+	for _, executorType := range graph.RegisteredExecutorConfigs() {
+		decoders[executorType] = true
+	}
+	for _, rule := range doc["allOf"].([]interface{}) {
+		condition := rule.(map[string]interface{})["if"].(map[string]interface{})
+		executorType := condition["properties"].(map[string]interface{})["executor_type"].(map[string]interface{})["const"].(string)
+		if !decoders[executorType] {
+			t.Errorf("executor type %q has a config schema but no Go config decoder", executorType)
+		}
+	}
+}
+
+func TestExecutorSchema_ExecutorTypes(t *testing.T) {
+	validator, err := NewValidator()
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+	if got := validator.ExecutorTypes(); !reflect.DeepEqual(got, graph.BuiltinExecutorTypes()) {
+		t.Errorf("expected executor types %v, got %v", graph.BuiltinExecutorTypes(), got)
+	}
+
+	// Config schemas in executor-node.schema.json only exist for built-in types.
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(executorNodeSchemaJSON), &doc); err != nil {
+		t.Fatalf("invalid executor node schema: %v", err)
+	}
+	builtin := make(map[string]bool)
+	for _, executorType := range graph.BuiltinExecutorTypes() {
+		builtin[executorType] = true
+	}
+	for _, rule := range doc["allOf"].([]interface{}) {
+		condition := rule.(map[string]interface{})["if"].(map[string]interface{})
+		executorType := condition["properties"].(map[string]interface{})["executor_type"].(map[string]interface{})["const"].(string)
+		if !builtin[executorType] {
+			t.Errorf("config schema for unknown executor type %q", executorType)
+		}
+	}
+}
+
+func TestValidateGraph_GoGraphs(t *testing.T) {
+	validator, err := NewValidator()
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+
+	built, err := graph.Build("review").
+		DefaultPolicy(&graph.Policy{Timeout: graph.Duration(30 * time.Second)}).
+		Start().
+		LLM("draft", map[string]interface{}{"model": "gpt-4"}).
+		Policy(&graph.Policy{Retry: &graph.RetryPolicy{MaxAttempts: 3, InitialInterval: graph.Duration(500 * time.Millisecond)}}).
+		OnError("end", graph.ErrorKindTimeout).
+		Router("check", graph.When("state.approved", "publish")).Default("draft").
+		Tool("publish", "http_post", nil).
+		End()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	// Fields left nil by Go code are encoded as null.
+	sparse := graph.NewGraph("sparse")
+	sparse.Nodes["run"] = &graph.ExecutorNode{BaseNode: graph.BaseNode{ID: "run", Type: graph.NodeTypeExecutor}, ExecutorType: graph.ExecutorTypeBash}
+	sparse.Nodes["route"] = &graph.RouterNode{BaseNode: graph.BaseNode{ID: "route", Type: graph.NodeTypeRouter}, DefaultRoute: "run"}
+	sparse.Edges = nil
+	sparse.EntryNode = "route"
+
+	for _, g := range []*graph.Graph{built, sparse} {
+		data, err := g.ToJSON()
+		if err != nil {
+			t.Fatalf("ToJSON failed: %v", err)
+		}
+		if err := validator.ValidateGraph([]byte(data)); err != nil {
+			t.Errorf("graph %s produced by Go code fails schema validation: %v\n%s", g.Name, err, data)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+)
+
+// schemaBaseURL is the base URL of the embedded schemas (their "$id").
+// Registered schemas are added below it, so that the embedded schemas can
+// reference them with relative URLs.
+const schemaBaseURL = "https://disasterproject.com/schemas/"
+
+// builtinNodeDefinitions maps the node types defined in graph.schema.json to
+// their definitions.
+var builtinNodeDefinitions = map[graph.NodeType]string{
+	graph.NodeTypeExecutor: "executorNode",
+	graph.NodeTypeRouter:   "routerNode",
+	graph.NodeTypeSubgraph: "subgraphNode",
+	graph.NodeTypeStart:    "startNode",
+	graph.NodeTypeEnd:      "endNode",
+	graph.NodeTypeApproval: "approvalNode",
+}
+
+// RegisterNodeSchema registers the JSON schema of a node type that is not
+// built in, typically one registered with graph.RegisterNodeType. Nodes of
+// that type are then accepted by graph validation and validated against the
+// schema; "id" and "type" are checked by the Validator and need not be
+// declared. Registering a type again replaces its schema.
+func (v *Validator) RegisterNodeSchema(nodeType graph.NodeType, schemaJSON []byte) error {
+	if nodeType == "" {
+		return fmt.Errorf("node type cannot be empty")
+	}
+	if _, ok := builtinNodeDefinitions[nodeType]; ok {
+		return fmt.Errorf("node type '%s' is built in", nodeType)
+	}
+	if !json.Valid(schemaJSON) {
+		return fmt.Errorf("invalid schema for node type '%s': not JSON", nodeType)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	nodeSchemas := make(map[graph.NodeType]string, len(v.nodeSchemas)+1)
+	for t, s := range v.nodeSchemas {
+		nodeSchemas[t] = s
+	}
+	nodeSchemas[nodeType] = string(schemaJSON)
+	return v.compile(nodeSchemas, v.executorSchemas, v.executorTypes)
+}
+
+// RegisterExecutorSchema registers an executor type and the JSON schema of
+// the config of executor nodes of that type. Unknown executor types are
+// rejected by validation, so downstream executors must be registered. A nil
+// schema registers the type without constraining its config. Registering a
+// built-in type adds the schema to its built-in constraints; registering a
+// type again replaces its schema.
+func (v *Validator) RegisterExecutorSchema(executorType string, configSchemaJSON []byte) error {
+	if executorType == "" {
+		return fmt.Errorf("executor type cannot be empty")
+	}
+	if configSchemaJSON != nil && !json.Valid(configSchemaJSON) {
+		return fmt.Errorf("invalid schema for executor type '%s': not JSON", executorType)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	executorSchemas := make(map[string]string, len(v.executorSchemas)+1)
+	for t, s := range v.executorSchemas {
+		executorSchemas[t] = s
+	}
+	executorTypes := v.executorTypes
+	if _, ok := executorSchemas[executorType]; !ok {
+		executorTypes = append(append([]string(nil), executorTypes...), executorType)
+	}
+	executorSchemas[executorType] = string(configSchemaJSON)
+	return v.compile(v.nodeSchemas, executorSchemas, executorTypes)
+}
+
+// ExecutorTypes returns the executor types accepted by the validator: the
+// built-in types followed by the registered ones, in registration order.
+func (v *Validator) ExecutorTypes() []string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return append([]string(nil), v.executorTypes...)
+}
+
+// NodeTypes returns the node types accepted by the validator, sorted.
+func (v *Validator) NodeTypes() []graph.NodeType {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	types := make([]graph.NodeType, 0, len(builtinNodeDefinitions)+len(v.nodeSchemas))
+	for t := range builtinNodeDefinitions {
+		types = append(types, t)
+	}
+	for t := range v.nodeSchemas {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+func nodeSchemaURL(nodeType graph.NodeType) string {
+	return schemaBaseURL + "nodes/" + url.PathEscape(string(nodeType)) + ".schema.json"
+}
+
+func executorSchemaURL(executorType string) string {
+	return schemaBaseURL + "executors/" + url.PathEscape(executorType) + ".schema.json"
+}
+
+// extendGraphSchema returns graph.schema.json with the registered node types
+// added to the node definition and the executor types and config schemas
+// added to the executor node definition.
+func extendGraphSchema(nodeSchemas map[graph.NodeType]string, executorSchemas map[string]string, executorTypes []string) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(graphSchemaJSON), &doc); err != nil {
+		return nil, err
+	}
+	definitions, err := object(doc, "definitions")
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := object(definitions, "node")
+	if err != nil {
+		return nil, err
+	}
+	branches, _ := node["oneOf"].([]interface{})
+	nodeTypes := make([]string, 0, len(nodeSchemas))
+	for t := range nodeSchemas {
+		nodeTypes = append(nodeTypes, string(t))
+	}
+	sort.Strings(nodeTypes)
+	for _, t := range nodeTypes {
+		// The "type" const lets validation reports skip this branch for
+		// nodes of other types.
+		branches = append(branches, map[string]interface{}{
+			"allOf": []interface{}{
+				map[string]interface{}{
+					"type":     "object",
+					"required": []interface{}{"id", "type"},
+					"properties": map[string]interface{}{
+						"id":   map[string]interface{}{"type": "string", "minLength": 1},
+						"type": map[string]interface{}{"type": "string", "const": t},
+					},
+				},
+				map[string]interface{}{"$ref": nodeSchemaURL(graph.NodeType(t))},
+			},
+		})
+	}
+	node["oneOf"] = branches
+
+	executorNode, err := object(definitions, "executorNode")
+	if err != nil {
+		return nil, err
+	}
+	if err := addExecutorTypes(executorNode, executorSchemas, executorTypes); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// extendExecutorSchema returns executor-node.schema.json with the registered
+// executor types and config schemas added.
+func extendExecutorSchema(executorSchemas map[string]string, executorTypes []string) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(executorNodeSchemaJSON), &doc); err != nil {
+		return nil, err
+	}
+	if err := addExecutorTypes(doc, executorSchemas, executorTypes); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// addExecutorTypes restricts the executor_type of an executor node schema to
+// executorTypes and validates the config of each type with a registered
+// schema.
+func addExecutorTypes(schema map[string]interface{}, executorSchemas map[string]string, executorTypes []string) error {
+	properties, err := object(schema, "properties")
+	if err != nil {
+		return err
+	}
+	executorType, err := object(properties, "executor_type")
+	if err != nil {
+		return err
+	}
+	enum := make([]interface{}, len(executorTypes))
+	for i, t := range executorTypes {
+		enum[i] = t
+	}
+	executorType["enum"] = enum
+
+	allOf, _ := schema["allOf"].([]interface{})
+	for _, t := range executorTypes {
+		if executorSchemas[t] == "" {
+			continue
+		}
+		allOf = append(allOf, map[string]interface{}{
+			"if": map[string]interface{}{
+				"required":   []interface{}{"executor_type"},
+				"properties": map[string]interface{}{"executor_type": map[string]interface{}{"const": t}},
+			},
+			"then": map[string]interface{}{
+				"properties": map[string]interface{}{"config": map[string]interface{}{"$ref": executorSchemaURL(t)}},
+			},
+		})
+	}
+	if len(allOf) > 0 {
+		schema["allOf"] = allOf
+	}
+	return nil
+}
+
+// object returns the JSON object stored under key in parent.
+func object(parent map[string]interface{}, key string) (map[string]interface{}, error) {
+	obj, ok := parent[key].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("schema has no object '%s'", key)
+	}
+	return obj, nil
+}
@@ -0,0 +1,205 @@
+package schema
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+)
+
+const sqlConfigSchema = `{
+	"type": "object",
+	"required": ["query"],
+	"properties": {
+		"query": {"type": "string", "minLength": 1},
+		"database": {"$ref": "#/definitions/database"}
+	},
+	"definitions": {
+		"database": {"type": "string", "enum": ["primary", "replica"]}
+	}
+}`
+
+func sqlGraph(config string) []byte {
+	return []byte(`{"id": "g", "entry_node": "q", "nodes": {"q": {"id": "q", "type": "executor", "executor_type": "sql", "config": ` + config + `}}}`)
+}
+
+func TestRegisterExecutorSchema(t *testing.T) {
+	validator, err := NewValidator()
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+
+	if err := validator.ValidateGraph(sqlGraph(`{"query": "SELECT 1"}`)); err == nil {
+		t.Fatal("expected unregistered executor type to be rejected")
+	}
+
+	if err := validator.RegisterExecutorSchema("sql", []byte(sqlConfigSchema)); err != nil {
+		t.Fatalf("RegisterExecutorSchema failed: %v", err)
+	}
+	if got := validator.ExecutorTypes(); got[len(got)-1] != "sql" {
+		t.Errorf("expected sql to be listed last, got %v", got)
+	}
+
+	tests := []struct {
+		name        string
+		config      string
+		pointer     string
+		expectError bool
+	}{
+		{name: "valid config", config: `{"query": "SELECT 1", "database": "replica"}`},
+		{name: "missing query", config: `{}`, pointer: "/nodes/q/config", expectError: true},
+		{name: "invalid nested definition", config: `{"query": "SELECT 1", "database": "backup"}`, pointer: "/nodes/q/config/database", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.ValidateGraph(sqlGraph(tt.config))
+			if !tt.expectError {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("expected *ValidationError, got %v", err)
+			}
+			if len(ve.Violations) != 1 || ve.Violations[0].Pointer != tt.pointer {
+				t.Errorf("expected one violation at %s, got %+v", tt.pointer, ve.Violations)
+			}
+		})
+	}
+
+	if err := validator.ValidateExecutorNode([]byte(`{"executor_type": "sql", "config": {}}`)); err == nil {
+		t.Error("expected executor node schema to apply the registered config schema")
+	}
+	if err := validator.ValidateExecutorNode([]byte(`{"executor_type": "sql", "config": {"query": "SELECT 1"}}`)); err != nil {
+		t.Errorf("expected valid sql executor node, got %v", err)
+	}
+}
+
+func TestRegisterExecutorSchema_WithoutSchema(t *testing.T) {
+	validator, err := NewValidator()
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+
+	if err := validator.RegisterExecutorSchema("sql", nil); err != nil {
+		t.Fatalf("RegisterExecutorSchema failed: %v", err)
+	}
+	if err := validator.ValidateGraph(sqlGraph(`{"anything": true}`)); err != nil {
+		t.Errorf("expected any config to be accepted, got %v", err)
+	}
+}
+
+func TestRegisterExecutorSchema_Invalid(t *testing.T) {
+	validator, err := NewValidator()
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		executorType string
+		schema       string
+	}{
+		{name: "empty type", executorType: "", schema: `{}`},
+		{name: "not JSON", executorType: "sql", schema: `{`},
+		{name: "invalid schema", executorType: "sql", schema: `{"type": 5}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validator.RegisterExecutorSchema(tt.executorType, []byte(tt.schema)); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+
+	// Failed registrations leave the validator unchanged.
+	if got := validator.ExecutorTypes(); !reflect.DeepEqual(got, graph.BuiltinExecutorTypes()) {
+		t.Errorf("expected built-in executor types only, got %v", got)
+	}
+	if err := validator.ValidateGraph(sqlGraph(`{}`)); err == nil {
+		t.Error("expected sql executor type to stay unknown")
+	}
+}
+
+func TestRegisterNodeSchema(t *testing.T) {
+	validator, err := NewValidator()
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+
+	delayGraph := func(node string) []byte {
+		return []byte(`{"id": "g", "entry_node": "wait", "nodes": {"wait": ` + node + `}}`)
+	}
+	valid := `{"id": "wait", "type": "delay", "seconds": 30}`
+
+	err = validator.ValidateGraph(delayGraph(valid))
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected unknown node type to be rejected, got %v", err)
+	}
+	if len(ve.Violations) != 1 || !strings.Contains(ve.Violations[0].Message, `one of "executor", "router", "subgraph", "start", "end", "approval"`) {
+		t.Errorf("expected a single violation listing the node types, got %+v", ve.Violations)
+	}
+
+	schema := []byte(`{"type": "object", "required": ["seconds"], "properties": {"seconds": {"type": "integer", "minimum": 1}}}`)
+	if err := validator.RegisterNodeSchema("delay", schema); err != nil {
+		t.Fatalf("RegisterNodeSchema failed: %v", err)
+	}
+
+	if err := validator.ValidateGraph(delayGraph(valid)); err != nil {
+		t.Errorf("expected registered node type to be accepted, got %v", err)
+	}
+
+	err = validator.ValidateGraph(delayGraph(`{"id": "wait", "type": "delay", "seconds": 0}`))
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected invalid delay node to be rejected, got %v", err)
+	}
+	if len(ve.Violations) != 1 || ve.Violations[0].Pointer != "/nodes/wait/seconds" {
+		t.Errorf("expected only the seconds violation, got %+v", ve.Violations)
+	}
+
+	found := false
+	for _, nodeType := range validator.NodeTypes() {
+		if nodeType == "delay" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected delay in node types, got %v", validator.NodeTypes())
+	}
+}
+
+func TestRegisterNodeSchema_Invalid(t *testing.T) {
+	validator, err := NewValidator()
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		nodeType graph.NodeType
+		schema   string
+	}{
+		{name: "empty type", nodeType: "", schema: `{}`},
+		{name: "built-in type", nodeType: graph.NodeTypeRouter, schema: `{}`},
+		{name: "not JSON", nodeType: "delay", schema: `nope`},
+		{name: "unresolvable reference", nodeType: "delay", schema: `{"$ref": "#/definitions/missing"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validator.RegisterNodeSchema(tt.nodeType, []byte(tt.schema)); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+	if len(validator.NodeTypes()) != len(builtinNodeDefinitions) {
+		t.Errorf("expected built-in node types only, got %v", validator.NodeTypes())
+	}
+}
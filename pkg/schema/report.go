@@ -0,0 +1,206 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+)
+
+// Severity ranks a violation in a ValidationReport.
+type Severity string
+
+const (
+	// SeverityError marks a violation that makes the graph unusable.
+	SeverityError Severity = "error"
+
+	// SeverityWarning marks a violation that does not prevent execution.
+	SeverityWarning Severity = "warning"
+)
+
+// Violation sources.
+const (
+	// SourceSchema marks violations of the JSON schema.
+	SourceSchema = "schema"
+
+	// SourceStructure marks violations found by graph.Graph.ValidateAll.
+	SourceStructure = "structure"
+)
+
+// Violation is a single problem found while validating a graph definition.
+type Violation struct {
+	// Pointer is the JSON Pointer of the offending value ("" for the whole document).
+	Pointer string `json:"pointer"`
+
+	// Keyword is the schema keyword that failed (e.g. "required", "enum").
+	// It is empty for structural violations.
+	Keyword string `json:"keyword,omitempty"`
+
+	// Severity ranks the violation.
+	Severity Severity `json:"severity"`
+
+	// Source is SourceSchema or SourceStructure.
+	Source string `json:"source"`
+
+	// Message describes the violation.
+	Message string `json:"message"`
+
+	// Position is the source position of the offending value, for YAML documents.
+	Position *graph.Position `json:"position,omitempty"`
+}
+
+// String formats the violation as "pointer: message".
+func (v Violation) String() string {
+	pointer := v.Pointer
+	if pointer == "" {
+		pointer = "/"
+	}
+	return pointer + ": " + v.Message
+}
+
+// ValidationReport collects every violation found in a graph definition by
+// schema and structural validation, ordered by pointer.
+type ValidationReport struct {
+	Violations []Violation `json:"violations"`
+}
+
+// Valid reports whether the report has no error-level violations.
+func (r *ValidationReport) Valid() bool {
+	for _, v := range r.Violations {
+		if v.Severity == SeverityError {
+			return false
+		}
+	}
+	return true
+}
+
+// Err returns the report as a *ValidationError, or nil if it is valid.
+func (r *ValidationReport) Err() error {
+	if r.Valid() {
+		return nil
+	}
+	return newValidationError("graph", r.Violations)
+}
+
+// ReportGraph validates a JSON graph definition against the graph schema and,
+// if it decodes, the structural rules of graph.Graph.ValidateAll, and returns
+// every violation found. An error is returned only if graphJSON is not JSON.
+func (v *Validator) ReportGraph(graphJSON []byte) (*ValidationReport, error) {
+	var instance interface{}
+	if err := json.Unmarshal(graphJSON, &instance); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return v.report(instance, graphJSON, nil), nil
+}
+
+// ReportGraphYAMLDocument is like ReportGraph for a parsed YAML graph
+// definition. Violations carry the source position of the offending value.
+func (v *Validator) ReportGraphYAMLDocument(doc *graph.YAMLDocument) (*ValidationReport, error) {
+	data, err := doc.JSON()
+	if err != nil {
+		return nil, fmt.Errorf("invalid YAML document: %w", err)
+	}
+	var instance interface{}
+	if err := json.Unmarshal(data, &instance); err != nil {
+		return nil, fmt.Errorf("invalid YAML document: %w", err)
+	}
+	return v.report(instance, data, doc), nil
+}
+
+// report merges the schema and structural violations of a graph definition.
+func (v *Validator) report(instance interface{}, data []byte, doc *graph.YAMLDocument) *ValidationReport {
+	graphSchema, _, _ := v.schemas()
+	violations := schemaViolations(graphSchema, instance)
+
+	var g graph.Graph
+	if err := json.Unmarshal(data, &g); err != nil {
+		// Documents the schema rejects often cannot be decoded either; the
+		// schema violations already explain why.
+		if len(violations) == 0 {
+			violations = append(violations, Violation{
+				Severity: SeverityError,
+				Source:   SourceStructure,
+				Message:  err.Error(),
+			})
+		}
+	} else {
+		for _, err := range g.ValidateAll() {
+			violations = append(violations, Violation{
+				Pointer:  err.Pointer,
+				Severity: SeverityError,
+				Source:   SourceStructure,
+				Message:  err.Message,
+			})
+		}
+	}
+
+	if doc != nil {
+		for i := range violations {
+			if pos, ok := doc.PositionOf(violations[i].Pointer); ok {
+				violations[i].Position = &pos
+			}
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Pointer < violations[j].Pointer
+	})
+	return &ValidationReport{Violations: violations}
+}
+
+// schemaViolations validates instance against schema and returns the most
+// specific causes of failure.
+func schemaViolations(schema *jsonschema.Schema, instance interface{}) []Violation {
+	err := schema.Validate(instance)
+	if err == nil {
+		return nil
+	}
+	ve, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return []Violation{{Severity: SeverityError, Source: SourceSchema, Message: err.Error()}}
+	}
+
+	leaves := leafErrors(ve)
+	violations := make([]Violation, len(leaves))
+	for i, leaf := range leaves {
+		violations[i] = Violation{
+			Pointer:  leaf.InstanceLocation,
+			Keyword:  keyword(leaf.KeywordLocation),
+			Severity: SeverityError,
+			Source:   SourceSchema,
+			Message:  leaf.Message,
+		}
+	}
+	return violations
+}
+
+// keyword returns the schema keyword at the end of a keyword location such as
+// "/properties/nodes/minProperties", skipping array indexes.
+func keyword(location string) string {
+	segments := strings.Split(location, "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		if _, err := strconv.Atoi(segments[i]); err != nil && segments[i] != "" {
+			return segments[i]
+		}
+	}
+	return ""
+}
+
+// newValidationError summarizes violations in a ValidationError.
+func newValidationError(schemaType string, violations []Violation) *ValidationError {
+	messages := make([]string, 0, len(violations))
+	for _, v := range violations {
+		if v.Severity == SeverityError {
+			messages = append(messages, v.String())
+		}
+	}
+	return &ValidationError{
+		SchemaType: schemaType,
+		Message:    strings.Join(messages, "; "),
+		Violations: violations,
+	}
+}
@@ -0,0 +1,175 @@
+package schema
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+)
+
+func TestReportGraph(t *testing.T) {
+	validator, err := NewValidator()
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		graph    string
+		expected []Violation
+	}{
+		{
+			name:     "valid graph",
+			graph:    `{"id": "g", "entry_node": "a", "nodes": {"a": {"id": "a", "type": "executor", "executor_type": "llm", "config": {"model": "gpt-4"}}}}`,
+			expected: nil,
+		},
+		{
+			name: "schema and structural violations",
+			graph: `{
+				"id": "g",
+				"entry_node": "missing",
+				"nodes": {
+					"a": {"id": "a", "type": "executor", "executor_type": "teleport"},
+					"r": {"id": "r", "type": "router", "routes": [{"target": "a"}]}
+				},
+				"edges": [{"from": "a", "to": "ghost"}]
+			}`,
+			expected: []Violation{
+				{Pointer: "/edges/0/to", Source: SourceStructure},
+				{Pointer: "/entry_node", Source: SourceStructure},
+				{Pointer: "/nodes/a/executor_type", Keyword: "enum", Source: SourceSchema},
+			},
+		},
+		{
+			name:  "missing required fields",
+			graph: `{"nodes": {"a": {"id": "a", "type": "executor", "executor_type": "llm", "config": {"model": "gpt-4"}}}}`,
+			expected: []Violation{
+				{Pointer: "", Keyword: "required", Source: SourceSchema},
+				{Pointer: "/entry_node", Source: SourceStructure},
+				{Pointer: "/id", Source: SourceStructure},
+			},
+		},
+		{
+			name:  "undecodable node",
+			graph: `{"id": "g", "entry_node": "a", "nodes": {"a": {"id": "a", "type": "teleporter"}}}`,
+			expected: []Violation{
+				{Pointer: "/nodes/a/type", Keyword: "const", Source: SourceSchema},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := validator.ReportGraph([]byte(tt.graph))
+			if err != nil {
+				t.Fatalf("ReportGraph failed: %v", err)
+			}
+			if report.Valid() != (len(tt.expected) == 0) {
+				t.Errorf("expected valid=%v, got %v", len(tt.expected) == 0, report.Valid())
+			}
+			if len(report.Violations) != len(tt.expected) {
+				t.Fatalf("expected %d violations, got %d: %+v", len(tt.expected), len(report.Violations), report.Violations)
+			}
+			for i, want := range tt.expected {
+				got := report.Violations[i]
+				if got.Pointer != want.Pointer || got.Keyword != want.Keyword || got.Source != want.Source {
+					t.Errorf("violation %d: expected %s %q (%s), got %s %q (%s): %s",
+						i, want.Source, want.Pointer, want.Keyword, got.Source, got.Pointer, got.Keyword, got.Message)
+				}
+				if got.Severity != SeverityError || got.Message == "" {
+					t.Errorf("violation %d: expected an error with a message, got %+v", i, got)
+				}
+			}
+		})
+	}
+}
+
+func TestReportGraph_InvalidJSON(t *testing.T) {
+	validator, err := NewValidator()
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+
+	if _, err := validator.ReportGraph([]byte(`{invalid`)); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
+
+func TestReportGraphYAMLDocument_Positions(t *testing.T) {
+	validator, err := NewValidator()
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+
+	doc, err := graph.ParseYAML([]byte(`id: g
+entry_node: a
+nodes:
+  a:
+    id: a
+    type: executor
+    executor_type: llm
+    config:
+      model: gpt-4
+edges:
+  - from: a
+    to: ghost
+`), "graph.yaml", nil)
+	if err != nil {
+		t.Fatalf("ParseYAML failed: %v", err)
+	}
+
+	report, err := validator.ReportGraphYAMLDocument(doc)
+	if err != nil {
+		t.Fatalf("ReportGraphYAMLDocument failed: %v", err)
+	}
+	if len(report.Violations) != 1 {
+		t.Fatalf("expected 1 violation, got %+v", report.Violations)
+	}
+	v := report.Violations[0]
+	if v.Pointer != "/edges/0/to" || v.Position == nil || v.Position.String() != "graph.yaml:12:5" {
+		t.Errorf("expected /edges/0/to at graph.yaml:12:5, got %s at %v", v.Pointer, v.Position)
+	}
+}
+
+func TestValidationReport_Err(t *testing.T) {
+	report := &ValidationReport{Violations: []Violation{
+		{Pointer: "/nodes/a", Severity: SeverityWarning, Message: "unused"},
+	}}
+	if err := report.Err(); err != nil {
+		t.Errorf("expected warnings only to be valid, got %v", err)
+	}
+
+	report.Violations = append(report.Violations, Violation{Pointer: "/id", Severity: SeverityError, Message: "missing"})
+	err := report.Err()
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected *ValidationError, got %T", err)
+	}
+	if ve.Message != "/id: missing" || len(ve.Violations) != 2 {
+		t.Errorf("unexpected error: %q with %d violations", ve.Message, len(ve.Violations))
+	}
+}
+
+func TestValidateGraph_ReturnsValidationError(t *testing.T) {
+	validator, err := NewValidator()
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+
+	err = validator.ValidateGraph([]byte(`{"id": "g", "entry_node": "a", "nodes": {"a": {"id": "a", "type": "executor", "executor_type": "teleport"}}}`))
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected *ValidationError, got %T: %v", err, err)
+	}
+	if ve.SchemaType != "graph" {
+		t.Errorf("expected schema type graph, got %q", ve.SchemaType)
+	}
+	// The router and subgraph branches of the node oneOf are not reported.
+	if len(ve.Violations) != 1 || ve.Violations[0].Pointer != "/nodes/a/executor_type" {
+		t.Errorf("expected only the executor_type violation, got %+v", ve.Violations)
+	}
+	if !strings.Contains(err.Error(), "/nodes/a/executor_type: value must be one of") {
+		t.Errorf("unexpected message: %v", err)
+	}
+}
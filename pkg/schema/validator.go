@@ -2,12 +2,17 @@
 package schema
 
 import (
+	"bytes"
 	_ "embed"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/santhosh-tekuri/jsonschema/v5"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
 )
 
 //go:embed graph.schema.json
@@ -20,62 +25,243 @@ var executorNodeSchemaJSON string
 var routerNodeSchemaJSON string
 
 // Validator provides JSON schema validation for DA Orchestrator entities.
+// Node types and executor types beyond the built-in ones can be added with
+// RegisterNodeSchema and RegisterExecutorSchema.
 type Validator struct {
-	compiler       *jsonschema.Compiler
+	mu             sync.RWMutex
 	graphSchema    *jsonschema.Schema
 	executorSchema *jsonschema.Schema
 	routerSchema   *jsonschema.Schema
+
+	// nodeSchemas and executorSchemas hold the registered extension schemas.
+	// Built-in executor types are present with an empty schema.
+	nodeSchemas     map[graph.NodeType]string
+	executorSchemas map[string]string
+	executorTypes   []string
 }
 
 // NewValidator creates a new validator with all schemas loaded.
 func NewValidator() (*Validator, error) {
+	v := &Validator{
+		nodeSchemas:     make(map[graph.NodeType]string),
+		executorSchemas: make(map[string]string),
+	}
+	for _, executorType := range graph.BuiltinExecutorTypes() {
+		v.executorSchemas[executorType] = ""
+		v.executorTypes = append(v.executorTypes, executorType)
+	}
+	if err := v.compile(v.nodeSchemas, v.executorSchemas, v.executorTypes); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// compile compiles the embedded schemas extended with the given node and
+// executor schemas and, on success, installs them in v. The caller must hold
+// v.mu for writing, or own v exclusively.
+func (v *Validator) compile(nodeSchemas map[graph.NodeType]string, executorSchemas map[string]string, executorTypes []string) error {
 	compiler := jsonschema.NewCompiler()
 	compiler.Draft = jsonschema.Draft7
 
+	graphDoc, err := extendGraphSchema(nodeSchemas, executorSchemas, executorTypes)
+	if err != nil {
+		return fmt.Errorf("failed to extend graph schema: %w", err)
+	}
+	executorDoc, err := extendExecutorSchema(executorSchemas, executorTypes)
+	if err != nil {
+		return fmt.Errorf("failed to extend executor node schema: %w", err)
+	}
+
 	// Add schemas to the compiler
-	if err := compiler.AddResource("graph.schema.json", strings.NewReader(graphSchemaJSON)); err != nil {
-		return nil, fmt.Errorf("failed to add graph schema: %w", err)
+	if err := compiler.AddResource("graph.schema.json", bytes.NewReader(graphDoc)); err != nil {
+		return fmt.Errorf("failed to add graph schema: %w", err)
 	}
-	if err := compiler.AddResource("executor-node.schema.json", strings.NewReader(executorNodeSchemaJSON)); err != nil {
-		return nil, fmt.Errorf("failed to add executor node schema: %w", err)
+	if err := compiler.AddResource("executor-node.schema.json", bytes.NewReader(executorDoc)); err != nil {
+		return fmt.Errorf("failed to add executor node schema: %w", err)
 	}
 	if err := compiler.AddResource("router-node.schema.json", strings.NewReader(routerNodeSchemaJSON)); err != nil {
-		return nil, fmt.Errorf("failed to add router node schema: %w", err)
+		return fmt.Errorf("failed to add router node schema: %w", err)
+	}
+	for nodeType, schema := range nodeSchemas {
+		if err := compiler.AddResource(nodeSchemaURL(nodeType), strings.NewReader(schema)); err != nil {
+			return fmt.Errorf("failed to add schema of node type '%s': %w", nodeType, err)
+		}
+	}
+	for executorType, schema := range executorSchemas {
+		if schema == "" {
+			continue
+		}
+		if err := compiler.AddResource(executorSchemaURL(executorType), strings.NewReader(schema)); err != nil {
+			return fmt.Errorf("failed to add config schema of executor type '%s': %w", executorType, err)
+		}
 	}
 
 	// Compile the schemas
 	graphSchema, err := compiler.Compile("graph.schema.json")
 	if err != nil {
-		return nil, fmt.Errorf("failed to compile graph schema: %w", err)
+		return fmt.Errorf("failed to compile graph schema: %w", err)
 	}
 	executorSchema, err := compiler.Compile("executor-node.schema.json")
 	if err != nil {
-		return nil, fmt.Errorf("failed to compile executor node schema: %w", err)
+		return fmt.Errorf("failed to compile executor node schema: %w", err)
 	}
 	routerSchema, err := compiler.Compile("router-node.schema.json")
 	if err != nil {
-		return nil, fmt.Errorf("failed to compile router node schema: %w", err)
+		return fmt.Errorf("failed to compile router node schema: %w", err)
 	}
 
-	return &Validator{
-		compiler:       compiler,
-		graphSchema:    graphSchema,
-		executorSchema: executorSchema,
-		routerSchema:   routerSchema,
-	}, nil
+	v.graphSchema = graphSchema
+	v.executorSchema = executorSchema
+	v.routerSchema = routerSchema
+	v.nodeSchemas = nodeSchemas
+	v.executorSchemas = executorSchemas
+	v.executorTypes = executorTypes
+	return nil
+}
+
+// schemas returns the compiled graph, executor node and router node schemas.
+func (v *Validator) schemas() (graphSchema, executorSchema, routerSchema *jsonschema.Schema) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.graphSchema, v.executorSchema, v.routerSchema
 }
 
 // ValidateGraph validates a graph definition against the graph schema.
+// Violations are returned as a *ValidationError; use ReportGraph to also run
+// the structural checks of graph.Graph.ValidateAll.
 func (v *Validator) ValidateGraph(graphJSON []byte) error {
 	var data interface{}
 	if err := json.Unmarshal(graphJSON, &data); err != nil {
 		return fmt.Errorf("invalid JSON: %w", err)
 	}
 
-	if err := v.graphSchema.Validate(data); err != nil {
+	graphSchema, _, _ := v.schemas()
+	if violations := schemaViolations(graphSchema, data); len(violations) > 0 {
+		return newValidationError("graph", violations)
+	}
+
+	return nil
+}
+
+// ValidateGraphYAML validates a YAML graph definition against the graph schema.
+// Anchors and merge keys are resolved before validation; $include is not
+// supported (see ValidateGraphYAMLDocument). Violations are returned as
+// LocatedErrors carrying the line and column of each offending value.
+func (v *Validator) ValidateGraphYAML(graphYAML []byte) error {
+	doc, err := graph.ParseYAML(graphYAML, "", nil)
+	if err != nil {
+		return fmt.Errorf("invalid YAML: %w", err)
+	}
+	return v.ValidateGraphYAMLDocument(doc)
+}
+
+// ValidateGraphYAMLDocument validates a parsed YAML graph definition, such as
+// one loaded with graph.ParseYAML from a file system with includes.
+func (v *Validator) ValidateGraphYAMLDocument(doc *graph.YAMLDocument) error {
+	data, err := doc.JSON()
+	if err != nil {
+		return fmt.Errorf("invalid YAML document: %w", err)
+	}
+
+	var instance interface{}
+	if err := json.Unmarshal(data, &instance); err != nil {
+		return fmt.Errorf("invalid YAML document: %w", err)
+	}
+
+	graphSchema, _, _ := v.schemas()
+	err = graphSchema.Validate(instance)
+	if err == nil {
+		return nil
+	}
+	ve, ok := err.(*jsonschema.ValidationError)
+	if !ok {
 		return fmt.Errorf("graph validation failed: %w", err)
 	}
 
+	var located LocatedErrors
+	for _, leaf := range leafErrors(ve) {
+		pos, _ := doc.PositionOf(leaf.InstanceLocation)
+		located = append(located, LocatedError{
+			Pointer:  leaf.InstanceLocation,
+			Position: pos,
+			Message:  leaf.Message,
+		})
+	}
+	sort.SliceStable(located, func(i, j int) bool {
+		a, b := located[i].Position, located[j].Position
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return located
+}
+
+// leafErrors flattens a jsonschema error tree into its most specific causes.
+func leafErrors(ve *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(ve.Causes) == 0 {
+		return []*jsonschema.ValidationError{ve}
+	}
+	causes := ve.Causes
+	if strings.HasSuffix(ve.KeywordLocation, "/oneOf") {
+		causes = matchingBranches(ve)
+	}
+	var leaves []*jsonschema.ValidationError
+	for _, cause := range causes {
+		leaves = append(leaves, leafErrors(cause)...)
+	}
+	return leaves
+}
+
+// matchingBranches drops the failed branches of a oneOf whose "type"
+// discriminator does not match the instance (e.g. the router branch for an
+// executor node), so that only the errors of the intended branch are
+// reported. If no branch matches, the discriminator errors are merged into one.
+func matchingBranches(ve *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	discriminator := ve.InstanceLocation + "/type"
+	var matching, rejections []*jsonschema.ValidationError
+	for _, branch := range ve.Causes {
+		if rejection := findRejection(branch, discriminator); rejection != nil {
+			rejections = append(rejections, rejection)
+		} else {
+			matching = append(matching, branch)
+		}
+	}
+	if len(matching) > 0 {
+		return matching
+	}
+	return []*jsonschema.ValidationError{mergeRejections(rejections)}
+}
+
+// mergeRejections merges the `value must be "x"` errors of several branches
+// into a single `value must be one of "x", "y"` error.
+func mergeRejections(rejections []*jsonschema.ValidationError) *jsonschema.ValidationError {
+	const prefix = "value must be "
+	values := make([]string, len(rejections))
+	for i, r := range rejections {
+		if !strings.HasPrefix(r.Message, prefix) {
+			return rejections[0]
+		}
+		values[i] = strings.TrimPrefix(r.Message, prefix)
+	}
+	merged := *rejections[0]
+	merged.Message = prefix + "one of " + strings.Join(values, ", ")
+	return &merged
+}
+
+// findRejection returns the "const" failure at pointer in ve, if any.
+func findRejection(ve *jsonschema.ValidationError, pointer string) *jsonschema.ValidationError {
+	if ve.InstanceLocation == pointer && strings.HasSuffix(ve.KeywordLocation, "/const") {
+		return ve
+	}
+	for _, cause := range ve.Causes {
+		if rejection := findRejection(cause, pointer); rejection != nil {
+			return rejection
+		}
+	}
 	return nil
 }
 
@@ -86,8 +272,9 @@ func (v *Validator) ValidateExecutorNode(nodeJSON []byte) error {
 		return fmt.Errorf("invalid JSON: %w", err)
 	}
 
-	if err := v.executorSchema.Validate(data); err != nil {
-		return fmt.Errorf("executor node validation failed: %w", err)
+	_, executorSchema, _ := v.schemas()
+	if violations := schemaViolations(executorSchema, data); len(violations) > 0 {
+		return newValidationError("executor node", violations)
 	}
 
 	return nil
@@ -100,18 +287,21 @@ func (v *Validator) ValidateRouterNode(nodeJSON []byte) error {
 		return fmt.Errorf("invalid JSON: %w", err)
 	}
 
-	if err := v.routerSchema.Validate(data); err != nil {
-		return fmt.Errorf("router node validation failed: %w", err)
+	_, _, routerSchema := v.schemas()
+	if violations := schemaViolations(routerSchema, data); len(violations) > 0 {
+		return newValidationError("router node", violations)
 	}
 
 	return nil
 }
 
 // ValidationError wraps validation errors with additional context.
+// Schema violations are listed in Violations.
 type ValidationError struct {
 	SchemaType string
 	Message    string
 	Cause      error
+	Violations []Violation
 }
 
 // Error implements the error interface.
@@ -126,3 +316,36 @@ func (e *ValidationError) Error() string {
 func (e *ValidationError) Unwrap() error {
 	return e.Cause
 }
+
+// LocatedError is a schema violation located in a source document.
+type LocatedError struct {
+	// Pointer is the JSON Pointer of the offending value.
+	Pointer string
+
+	// Position is the source position of the offending value.
+	Position graph.Position
+
+	// Message describes the violation.
+	Message string
+}
+
+// Error implements the error interface.
+func (e LocatedError) Error() string {
+	pointer := e.Pointer
+	if pointer == "" {
+		pointer = "/"
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Position, pointer, e.Message)
+}
+
+// LocatedErrors is a list of located schema violations.
+type LocatedErrors []LocatedError
+
+// Error implements the error interface.
+func (e LocatedErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return "graph validation failed:\n" + strings.Join(lines, "\n")
+}
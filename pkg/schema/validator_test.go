@@ -360,3 +360,158 @@ func TestValidationError_Unwrap(t *testing.T) {
 		t.Errorf("expected unwrapped error to be %v, got %v", cause, unwrapped)
 	}
 }
+
+func TestValidateGraphYAML_Valid(t *testing.T) {
+	validator, err := NewValidator()
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+
+	graphYAML := []byte(`
+id: graph-1
+defaults: &model
+  model: gpt-4
+nodes:
+  start:
+    id: start
+    type: executor
+    executor_type: llm
+    config: *model
+entry_node: start
+`)
+
+	if err := validator.ValidateGraphYAML(graphYAML); err != nil {
+		t.Errorf("validation failed for valid YAML graph: %v", err)
+	}
+}
+
+func TestValidateGraphYAML_Positions(t *testing.T) {
+	validator, err := NewValidator()
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+
+	graphYAML := []byte(`id: graph-1
+nodes:
+  start:
+    id: start
+    type: executor
+    executor_type: llm
+edges:
+  - from: start
+    to: ""
+entry_node: start
+`)
+
+	err = validator.ValidateGraphYAML(graphYAML)
+	var located LocatedErrors
+	if !errors.As(err, &located) {
+		t.Fatalf("expected LocatedErrors, got %T: %v", err, err)
+	}
+
+	found := false
+	for _, le := range located {
+		if le.Pointer == "/edges/0/to" {
+			found = true
+			if le.Position.Line != 9 || le.Position.Column != 5 {
+				t.Errorf("expected position 9:5 for /edges/0/to, got %v", le.Position)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("expected a violation at /edges/0/to, got %v", located)
+	}
+}
+
+func TestValidateGraph_SubgraphNode(t *testing.T) {
+	validator, err := NewValidator()
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		node        string
+		expectError bool
+	}{
+		{
+			name:        "reference",
+			node:        `{"id": "retrieve", "type": "subgraph", "graph_ref": {"id": "retrieval", "version": "2"}, "input_mapping": {"query": "question"}}`,
+			expectError: false,
+		},
+		{
+			name:        "inline graph",
+			node:        `{"id": "retrieve", "type": "subgraph", "graph": {"id": "retrieval", "entry_node": "search", "nodes": {"search": {"id": "search", "type": "executor", "executor_type": "tool"}}}}`,
+			expectError: false,
+		},
+		{
+			name:        "invalid inline graph",
+			node:        `{"id": "retrieve", "type": "subgraph", "graph": {"id": "retrieval", "nodes": {}}}`,
+			expectError: true,
+		},
+		{
+			name:        "missing graph",
+			node:        `{"id": "retrieve", "type": "subgraph"}`,
+			expectError: true,
+		},
+		{
+			name:        "both reference and graph",
+			node:        `{"id": "retrieve", "type": "subgraph", "graph_ref": {"id": "retrieval"}, "graph": {"id": "retrieval", "entry_node": "search", "nodes": {"search": {"id": "search", "type": "executor", "executor_type": "tool"}}}}`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph := []byte(`{"id": "qa", "entry_node": "retrieve", "nodes": {"retrieve": ` + tt.node + `}}`)
+			err := validator.ValidateGraph(graph)
+			if tt.expectError && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateGraph_Policy(t *testing.T) {
+	validator, err := NewValidator()
+	if err != nil {
+		t.Fatalf("NewValidator failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		policy  string
+		pointer string
+	}{
+		{name: "full policy", policy: `{"timeout": "30s", "retry": {"max_attempts": 3, "backoff": "exponential", "initial_interval": 1, "max_interval": "1m", "multiplier": 2, "jitter": 0.1, "retry_on": ["timeout"]}, "idempotent": true, "on_error": [{"errors": ["timeout"], "target": "run"}]}`},
+		{name: "invalid timeout", policy: `{"timeout": "soon"}`, pointer: "/nodes/run/policy/timeout"},
+		{name: "negative timeout", policy: `{"timeout": -5}`, pointer: "/nodes/run/policy/timeout"},
+		{name: "retry without attempts", policy: `{"retry": {"backoff": "fixed"}}`, pointer: "/nodes/run/policy/retry"},
+		{name: "unknown backoff", policy: `{"retry": {"max_attempts": 2, "backoff": "random"}}`, pointer: "/nodes/run/policy/retry/backoff"},
+		{name: "jitter out of range", policy: `{"retry": {"max_attempts": 2, "jitter": 2}}`, pointer: "/nodes/run/policy/retry/jitter"},
+		{name: "error handler without target", policy: `{"on_error": [{"errors": ["timeout"]}]}`, pointer: "/nodes/run/policy/on_error/0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph := []byte(`{"id": "g", "entry_node": "run", "policy": {"timeout": "1m"}, "nodes": {"run": {"id": "run", "type": "executor", "executor_type": "bash", "config": {"command": "true"}, "policy": ` + tt.policy + `}}}`)
+			err := validator.ValidateGraph(graph)
+			if tt.pointer == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("expected *ValidationError, got %v", err)
+			}
+			if len(ve.Violations) != 1 || ve.Violations[0].Pointer != tt.pointer {
+				t.Errorf("expected one violation at %s, got %+v", tt.pointer, ve.Violations)
+			}
+		})
+	}
+}
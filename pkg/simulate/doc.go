@@ -0,0 +1,14 @@
+// Package simulate runs graphs in dry-run mode to find the paths they can
+// take and estimate what those paths cost before a graph is deployed.
+//
+// A Simulator walks a graph in the steps of engine.Engine without running
+// anything: executor nodes are replaced by stubs, whose outputs are fixed or
+// generated from an output JSON schema (see Generate), and approval nodes are
+// approved. Run follows the routes whose conditions hold for each initial
+// state variation; Explore takes every route of every router, forks a path
+// for each conditional edge, and follows loops a bounded number of times.
+//
+// Each simulated Path reports its steps, the router decisions taken, the
+// number of LLM calls, estimated input and output tokens, and the worst-case
+// latency, where each node takes its policy timeout on every retry.
+package simulate
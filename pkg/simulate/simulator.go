@@ -0,0 +1,687 @@
+package simulate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/checkpoint"
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+	"github.com/aescanero/dago-libs/pkg/engine"
+)
+
+// Defaults of the simulation limits and estimates.
+const (
+	// DefaultMaxSteps bounds the steps of a path.
+	DefaultMaxSteps = 100
+
+	// DefaultMaxVisits bounds how many times Explore takes a branch into the
+	// same node, so that loops are explored a bounded number of times.
+	DefaultMaxVisits = 2
+
+	// DefaultMaxPaths bounds the number of paths Explore enumerates.
+	DefaultMaxPaths = 1000
+
+	// DefaultMaxTokens is the output token estimate of LLM nodes without
+	// max_tokens, the LLMConfig default.
+	DefaultMaxTokens = 2000
+
+	// charsPerToken is the ratio used to estimate input tokens from text.
+	charsPerToken = 4
+)
+
+// ErrSimulatedFailure is wrapped by the errors of failed simulated paths.
+var ErrSimulatedFailure = errors.New("simulated failure")
+
+// Outcome is how a simulated path ended.
+type Outcome string
+
+const (
+	// OutcomeCompleted means the path ran out of pending nodes.
+	OutcomeCompleted Outcome = "completed"
+
+	// OutcomeFailed means a node failed without an error edge, or a router
+	// had no matching route.
+	OutcomeFailed Outcome = "failed"
+
+	// OutcomeTruncated means the path hit the step or visit limit.
+	OutcomeTruncated Outcome = "truncated"
+)
+
+// Decision records a branch taken by a path.
+type Decision struct {
+	// NodeID is the router, the source of a conditional edge when exploring,
+	// or the failed node for error edges.
+	NodeID string `json:"node_id"`
+
+	// Target is the node the path continued at.
+	Target string `json:"target"`
+
+	// Condition is the condition of the route taken, if any.
+	Condition string `json:"condition,omitempty"`
+
+	// Error is the error kind for error edges.
+	Error string `json:"error,omitempty"`
+}
+
+// Path is one simulated execution.
+type Path struct {
+	// Variation is the index of the initial state the path started from.
+	Variation int `json:"variation"`
+
+	// Steps lists the nodes run in each step; nodes of a step run concurrently.
+	Steps [][]string `json:"steps"`
+
+	// Decisions lists the branches taken, in order.
+	Decisions []Decision `json:"decisions,omitempty"`
+
+	// Outcome is how the path ended.
+	Outcome Outcome `json:"outcome"`
+
+	// Error describes why a path failed or was truncated.
+	Error string `json:"error,omitempty"`
+
+	// LLMCalls is the number of LLM executor nodes run.
+	LLMCalls int `json:"llm_calls"`
+
+	// InputTokens and OutputTokens estimate the token usage of the LLM calls.
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+
+	// WorstCaseLatency sums, over the steps, the slowest node of the step,
+	// with every retry of its policy exhausted.
+	WorstCaseLatency time.Duration `json:"worst_case_latency"`
+
+	// State is the final simulated state.
+	State state.State `json:"state,omitempty"`
+}
+
+// Nodes returns the nodes of the path in execution order.
+func (p *Path) Nodes() []string {
+	var nodes []string
+	for _, step := range p.Steps {
+		nodes = append(nodes, step...)
+	}
+	return nodes
+}
+
+// Report holds the simulated paths of a graph.
+type Report struct {
+	// GraphID is the simulated graph.
+	GraphID string `json:"graph_id"`
+
+	// Paths lists the simulated paths.
+	Paths []*Path `json:"paths"`
+
+	// Truncated reports that Explore stopped at the path limit.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// MaxLLMCalls returns the most LLM calls of any path.
+func (r *Report) MaxLLMCalls() int {
+	max := 0
+	for _, p := range r.Paths {
+		if p.LLMCalls > max {
+			max = p.LLMCalls
+		}
+	}
+	return max
+}
+
+// MaxTokens returns the highest estimated token usage of any path.
+func (r *Report) MaxTokens() int {
+	max := 0
+	for _, p := range r.Paths {
+		if t := p.InputTokens + p.OutputTokens; t > max {
+			max = t
+		}
+	}
+	return max
+}
+
+// MaxLatency returns the highest worst-case latency of any path.
+func (r *Report) MaxLatency() time.Duration {
+	var max time.Duration
+	for _, p := range r.Paths {
+		if p.WorstCaseLatency > max {
+			max = p.WorstCaseLatency
+		}
+	}
+	return max
+}
+
+// Simulator runs graphs without side effects. Executor nodes are replaced by
+// stubs, approval nodes are approved, and the graph is walked in the steps of
+// engine.Engine, so that the simulated paths are the ones the engine takes.
+type Simulator struct {
+	stubs      map[string]Stub
+	latencies  map[string]time.Duration
+	conditions engine.ConditionEvaluator
+	resolver   graph.GraphResolver
+	maxSteps   int
+	maxVisits  int
+	maxPaths   int
+}
+
+// NewSimulator creates a Simulator with default limits.
+func NewSimulator() *Simulator {
+	return &Simulator{
+		stubs:      make(map[string]Stub),
+		latencies:  make(map[string]time.Duration),
+		conditions: engine.ConditionFunc(engine.EvaluateCondition),
+		maxSteps:   DefaultMaxSteps,
+		maxVisits:  DefaultMaxVisits,
+		maxPaths:   DefaultMaxPaths,
+	}
+}
+
+// WithStub sets the stub of a node.
+func (s *Simulator) WithStub(nodeID string, stub Stub) *Simulator {
+	s.stubs[nodeID] = stub
+	return s
+}
+
+// WithLatency sets the expected latency of an attempt of the executor nodes
+// of an executor type without a stub latency.
+func (s *Simulator) WithLatency(executorType string, d time.Duration) *Simulator {
+	s.latencies[executorType] = d
+	return s
+}
+
+// WithConditions sets the condition evaluator, as engine.Engine.WithConditions.
+func (s *Simulator) WithConditions(c engine.ConditionEvaluator) *Simulator {
+	s.conditions = c
+	return s
+}
+
+// WithResolver sets the resolver of subgraph references.
+func (s *Simulator) WithResolver(r graph.GraphResolver) *Simulator {
+	s.resolver = r
+	return s
+}
+
+// WithLimits sets the step limit of a path, the visit limit of Explore and
+// its path limit. Zero keeps a limit unchanged.
+func (s *Simulator) WithLimits(maxSteps, maxVisits, maxPaths int) *Simulator {
+	if maxSteps > 0 {
+		s.maxSteps = maxSteps
+	}
+	if maxVisits > 0 {
+		s.maxVisits = maxVisits
+	}
+	if maxPaths > 0 {
+		s.maxPaths = maxPaths
+	}
+	return s
+}
+
+// Run simulates g once per initial state variation, taking the routes whose
+// conditions hold. Without variations it runs from an empty state.
+func (s *Simulator) Run(ctx context.Context, g *graph.Graph, variations ...state.State) (*Report, error) {
+	return s.simulate(ctx, g, false, variations)
+}
+
+// Explore enumerates the paths through g from each initial state variation,
+// taking every route of every router and every conditional edge regardless of
+// its condition, as well as the rejection of approval nodes with a rejection
+// error edge. Conditional edges leaving the same node are taken on separate
+// paths. Loops are followed up to the visit limit.
+func (s *Simulator) Explore(ctx context.Context, g *graph.Graph, variations ...state.State) (*Report, error) {
+	return s.simulate(ctx, g, true, variations)
+}
+
+func (s *Simulator) simulate(ctx context.Context, g *graph.Graph, explore bool, variations []state.State) (*Report, error) {
+	if err := g.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid graph '%s': %w", g.ID, err)
+	}
+	flat, err := graph.Flatten(ctx, g, s.resolver)
+	if err != nil {
+		return nil, fmt.Errorf("failed to flatten graph '%s': %w", g.ID, err)
+	}
+	if len(variations) == 0 {
+		variations = []state.State{nil}
+	}
+
+	w := &walker{sim: s, graph: flat, explore: explore, report: &Report{GraphID: g.ID}}
+	for i, initial := range variations {
+		st := initial.DeepCopy()
+		if st == nil {
+			st = state.NewState()
+		}
+		r := &run{
+			state:  st,
+			cp:     &checkpoint.Checkpoint{Pending: []string{flat.EntryNode}},
+			path:   &Path{Variation: i},
+			visits: map[string]int{flat.EntryNode: 1},
+		}
+		if err := w.walk(ctx, r); err != nil {
+			return nil, err
+		}
+		if w.report.Truncated {
+			break
+		}
+	}
+	return w.report, nil
+}
+
+// run is a simulated execution in progress.
+type run struct {
+	state  state.State
+	cp     *checkpoint.Checkpoint
+	path   *Path
+	visits map[string]int
+}
+
+func (r *run) clone() *run {
+	path := *r.path
+	path.Steps = append([][]string(nil), r.path.Steps...)
+	path.Decisions = append([]Decision(nil), r.path.Decisions...)
+	visits := make(map[string]int, len(r.visits))
+	for k, v := range r.visits {
+		visits[k] = v
+	}
+	cp := *r.cp
+	cp.Pending = append([]string(nil), r.cp.Pending...)
+	cp.Completed = nil
+	return &run{state: r.state.DeepCopy(), cp: &cp, path: &path, visits: visits}
+}
+
+// alternative is one possible outcome of a node in a step.
+type alternative struct {
+	output   state.State
+	next     []string
+	decision *Decision
+	err      error
+}
+
+type walker struct {
+	sim     *Simulator
+	graph   *graph.Graph
+	explore bool
+	report  *Report
+}
+
+// walk advances r step by step, forking it when a step has several possible
+// outcomes.
+func (w *walker) walk(ctx context.Context, r *run) error {
+	for steps := 0; len(r.cp.Pending) > 0; steps++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if steps >= w.sim.maxSteps {
+			return w.finish(r, OutcomeTruncated, fmt.Sprintf("step limit %d reached", w.sim.maxSteps))
+		}
+
+		step := append([]string(nil), r.cp.Pending...)
+		options := make([][]alternative, len(step))
+		for i, id := range step {
+			options[i] = w.alternatives(r, id)
+			if len(options[i]) == 0 {
+				return w.finish(r, OutcomeTruncated, fmt.Sprintf("visit limit %d reached at '%s'", w.sim.maxVisits, id))
+			}
+		}
+		w.account(r, step)
+
+		combinations := product(options)
+		if len(combinations) == 1 {
+			if done, err := w.apply(r, step, combinations[0]); done || err != nil {
+				return err
+			}
+			continue
+		}
+		for _, combination := range combinations {
+			fork := r.clone()
+			done, err := w.apply(fork, step, combination)
+			if err != nil {
+				return err
+			}
+			if !done {
+				if err := w.walk(ctx, fork); err != nil {
+					return err
+				}
+			}
+			if w.report.Truncated {
+				return nil
+			}
+		}
+		return nil
+	}
+	return w.finish(r, OutcomeCompleted, "")
+}
+
+// apply applies the outcomes of a step to r. It reports whether the path
+// ended.
+func (w *walker) apply(r *run, step []string, combination []alternative) (bool, error) {
+	for i, alt := range combination {
+		if alt.err != nil {
+			return true, w.finish(r, OutcomeFailed, fmt.Sprintf("node '%s': %v", step[i], alt.err))
+		}
+		for key, value := range alt.output {
+			r.state.Set(key, value)
+		}
+		if alt.decision != nil {
+			r.path.Decisions = append(r.path.Decisions, *alt.decision)
+		}
+		for _, next := range alt.next {
+			r.visits[next]++
+		}
+		r.cp.Record(checkpoint.Completion{NodeID: step[i], Output: alt.output, Next: alt.next}, time.Time{})
+	}
+	return false, nil
+}
+
+// alternatives returns the possible outcomes of a node. Branches into nodes
+// visited too often are dropped when exploring.
+func (w *walker) alternatives(r *run, id string) []alternative {
+	node := w.graph.GetNode(id)
+	if node == nil {
+		return []alternative{{err: fmt.Errorf("node not found")}}
+	}
+	policy := w.graph.EffectivePolicy(id)
+
+	var alts []alternative
+	switch n := node.(type) {
+	case *graph.RouterNode:
+		alts = w.routes(r, n)
+	case *graph.ApprovalNode:
+		approved := &graph.InputResponse{RequestID: "simulated", Approved: true, Responder: "simulator"}
+		out := state.State{n.ResponseKey(): approved.ToMap()}
+		alts = append(alts, w.branches(id, merged(r.state, out), out)...)
+		if target, ok := policy.ErrorTarget(kindError(graph.ErrorKindRejected)); ok && w.explore && !n.InputOnly {
+			rejected := &graph.InputResponse{RequestID: "simulated", Responder: "simulator"}
+			alts = append(alts, alternative{
+				output:   state.State{n.ResponseKey(): rejected.ToMap()},
+				next:     []string{target},
+				decision: &Decision{NodeID: id, Target: target, Error: graph.ErrorKindRejected},
+			})
+		}
+	default:
+		stub := w.sim.stubs[id]
+		if stub.Err != nil {
+			err := fmt.Errorf("%w: %w", ErrSimulatedFailure, stub.Err)
+			if target, ok := policy.ErrorTarget(stub.Err); ok {
+				alts = append(alts, alternative{
+					next:     []string{target},
+					decision: &Decision{NodeID: id, Target: target, Error: graph.ErrorKind(stub.Err)},
+				})
+			} else {
+				alts = append(alts, alternative{err: err})
+			}
+			break
+		}
+		var out state.State
+		switch node.(type) {
+		case *graph.ExecutorNode:
+			out = stub.output(node)
+		case *graph.StartNode, *graph.EndNode:
+			if stub.Output != nil {
+				out = stub.Output.DeepCopy()
+				break
+			}
+			// Boundary nodes of flattened subgraphs apply their mappings.
+			mapped, _ := node.Execute(context.Background(), r.state)
+			out = changed(r.state, mapped)
+		default:
+			if stub.Output != nil {
+				out = stub.Output.DeepCopy()
+			}
+		}
+		alts = append(alts, w.branches(id, merged(r.state, out), out)...)
+	}
+
+	if !w.explore {
+		return alts[:1]
+	}
+	var kept []alternative
+	for _, alt := range alts {
+		if alt.err != nil || w.withinVisits(r, alt.next) {
+			kept = append(kept, alt)
+		}
+	}
+	return kept
+}
+
+// routes returns the branches of a router: the matching one, or every
+// distinct target when exploring.
+func (w *walker) routes(r *run, n *graph.RouterNode) []alternative {
+	if !w.explore {
+		for _, route := range n.Routes {
+			ok, err := w.sim.conditions.Evaluate(route.Condition, r.state)
+			if err != nil {
+				return []alternative{{err: err}}
+			}
+			if ok {
+				return []alternative{{next: []string{route.Target}, decision: &Decision{NodeID: n.ID, Target: route.Target, Condition: route.Condition}}}
+			}
+		}
+		if n.DefaultRoute != "" {
+			return []alternative{{next: []string{n.DefaultRoute}, decision: &Decision{NodeID: n.ID, Target: n.DefaultRoute}}}
+		}
+		return []alternative{{err: fmt.Errorf("router '%s': %w", n.ID, engine.ErrNoRoute)}}
+	}
+
+	var alts []alternative
+	seen := make(map[string]bool)
+	for _, route := range n.Routes {
+		if seen[route.Target] {
+			continue
+		}
+		seen[route.Target] = true
+		alts = append(alts, alternative{next: []string{route.Target}, decision: &Decision{NodeID: n.ID, Target: route.Target, Condition: route.Condition}})
+	}
+	if n.DefaultRoute != "" && !seen[n.DefaultRoute] {
+		alts = append(alts, alternative{next: []string{n.DefaultRoute}, decision: &Decision{NodeID: n.ID, Target: n.DefaultRoute}})
+	}
+	return alts
+}
+
+// branches returns the alternatives of a node that output out, leaving it in
+// state s: the outgoing edges whose condition holds on s or, when exploring,
+// one alternative per conditional edge, each also following the
+// unconditional edges. Conditional edges may exclude each other, so they are
+// not followed together.
+func (w *walker) branches(id string, s, out state.State) []alternative {
+	if !w.explore {
+		alt := alternative{output: out}
+		alt.next, alt.err = w.follow(id, s)
+		return []alternative{alt}
+	}
+
+	var always []string
+	var conditional []*graph.Edge
+	seen := make(map[string]bool)
+	for _, edge := range w.graph.GetOutgoingEdges(id) {
+		if edge.Condition == "" {
+			always = append(always, edge.To)
+			seen[edge.To] = true
+		} else {
+			conditional = append(conditional, edge)
+		}
+	}
+	var alts []alternative
+	for _, edge := range conditional {
+		if seen[edge.To] {
+			continue
+		}
+		seen[edge.To] = true
+		alts = append(alts, alternative{
+			output:   out,
+			next:     append(append([]string(nil), always...), edge.To),
+			decision: &Decision{NodeID: id, Target: edge.To, Condition: edge.Condition},
+		})
+	}
+	if len(alts) == 0 {
+		alts = append(alts, alternative{output: out, next: always})
+	}
+	return alts
+}
+
+// follow returns the targets of the outgoing edges of a node whose condition
+// holds on s.
+func (w *walker) follow(id string, s state.State) ([]string, error) {
+	var next []string
+	for _, edge := range w.graph.GetOutgoingEdges(id) {
+		ok, err := w.sim.conditions.Evaluate(edge.Condition, s)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			next = append(next, edge.To)
+		}
+	}
+	return next, nil
+}
+
+func (w *walker) withinVisits(r *run, next []string) bool {
+	for _, id := range next {
+		if r.visits[id] >= w.sim.maxVisits {
+			return false
+		}
+	}
+	return true
+}
+
+// account adds the cost of a step to the path.
+func (w *walker) account(r *run, step []string) {
+	r.path.Steps = append(r.path.Steps, step)
+	var slowest time.Duration
+	for _, id := range step {
+		node := w.graph.GetNode(id)
+		if node == nil {
+			continue
+		}
+		if latency := w.worstCase(node); latency > slowest {
+			slowest = latency
+		}
+		exec, ok := node.(*graph.ExecutorNode)
+		if !ok || exec.ExecutorType != graph.ExecutorTypeLLM {
+			continue
+		}
+		in, out := w.tokens(exec, r.state)
+		r.path.LLMCalls++
+		r.path.InputTokens += in
+		r.path.OutputTokens += out
+	}
+	r.path.WorstCaseLatency += slowest
+}
+
+// worstCase returns the worst-case latency of a node: every attempt allowed by
+// its policy taking the policy timeout, or the expected latency without one,
+// plus the backoff delays in between.
+func (w *walker) worstCase(node graph.Node) time.Duration {
+	latency := w.sim.stubs[node.GetID()].Latency
+	if exec, ok := node.(*graph.ExecutorNode); ok && latency == 0 {
+		latency = w.sim.latencies[exec.ExecutorType]
+	}
+	if _, ok := node.(*graph.ExecutorNode); !ok && latency == 0 {
+		return 0
+	}
+	policy := w.graph.EffectivePolicy(node.GetID())
+	if policy != nil && policy.Timeout > 0 {
+		latency = time.Duration(policy.Timeout)
+	}
+	if policy == nil || policy.Retry == nil {
+		return latency
+	}
+	retry := policy.Retry
+	total := time.Duration(retry.MaxAttempts) * latency
+	for attempt := 1; attempt < retry.MaxAttempts; attempt++ {
+		total += time.Duration(float64(retry.Delay(attempt)) * (1 + retry.Jitter))
+	}
+	return total
+}
+
+// tokens estimates the token usage of an LLM node.
+func (w *walker) tokens(node *graph.ExecutorNode, s state.State) (int, int) {
+	stub := w.sim.stubs[node.ID]
+	in, out := stub.InputTokens, stub.OutputTokens
+	config, _ := node.TypedConfig()
+	llm, _ := config.(*graph.LLMConfig)
+	if in == 0 {
+		chars := 0
+		if data, err := json.Marshal(s); err == nil {
+			chars = len(data)
+		}
+		if llm != nil {
+			chars += len(llm.SystemPrompt)
+		}
+		in = (chars + charsPerToken - 1) / charsPerToken
+	}
+	if out == 0 {
+		out = DefaultMaxTokens
+		if llm != nil && llm.MaxTokens != nil {
+			out = *llm.MaxTokens
+		}
+	}
+	return in, out
+}
+
+// finish records a path.
+func (w *walker) finish(r *run, outcome Outcome, reason string) error {
+	r.path.Outcome = outcome
+	r.path.Error = reason
+	r.path.State = r.state
+	w.report.Paths = append(w.report.Paths, r.path)
+	if len(w.report.Paths) >= w.sim.maxPaths {
+		w.report.Truncated = true
+	}
+	return nil
+}
+
+// kindError is an error of a given kind, for matching error handlers.
+type kindError string
+
+func (e kindError) Error() string { return string(e) }
+
+func (e kindError) ErrorKind() string { return string(e) }
+
+// merged returns s with out applied, for evaluating edge conditions.
+func merged(s, out state.State) state.State {
+	if len(out) == 0 {
+		return s
+	}
+	m := make(state.State, len(s)+len(out))
+	for k, v := range s {
+		m[k] = v
+	}
+	for k, v := range out {
+		m[k] = v
+	}
+	return m
+}
+
+// changed returns the values of after that are missing from or differ from
+// before.
+func changed(before, after state.State) state.State {
+	var out state.State
+	for k, v := range after {
+		if old, ok := before[k]; ok && reflect.DeepEqual(old, v) {
+			continue
+		}
+		if out == nil {
+			out = make(state.State)
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// product returns every combination of one alternative per node.
+func product(options [][]alternative) [][]alternative {
+	combinations := [][]alternative{nil}
+	for _, alts := range options {
+		var next [][]alternative
+		for _, combination := range combinations {
+			for _, alt := range alts {
+				c := append(append([]alternative(nil), combination...), alt)
+				next = append(next, c)
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
@@ -0,0 +1,189 @@
+package simulate
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+)
+
+func reviewGraph(t *testing.T) *graph.Graph {
+	t.Helper()
+	g, err := graph.Build("review").ID("review").
+		Start().
+		LLM("draft", map[string]interface{}{"model": "gpt-4", "max_tokens": 500}).
+		Policy(&graph.Policy{
+			Timeout: graph.Duration(10 * time.Second),
+			Retry:   &graph.RetryPolicy{MaxAttempts: 2, InitialInterval: graph.Duration(time.Second)},
+		}).
+		Router("check", graph.When("state.score >= 0.8", "publish")).Default("draft").
+		At().Tool("publish", "http_post", nil).
+		End()
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+	return g
+}
+
+func TestSimulator_Run(t *testing.T) {
+	sim := NewSimulator().
+		WithStub("draft", Stub{Output: state.State{"score": 0.9}, InputTokens: 100}).
+		WithLatency(graph.ExecutorTypeTool, 2*time.Second)
+
+	report, err := sim.Run(context.Background(), reviewGraph(t))
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(report.Paths) != 1 {
+		t.Fatalf("expected one path, got %d", len(report.Paths))
+	}
+	path := report.Paths[0]
+	if want := []string{"start", "draft", "check", "publish", "end"}; !reflect.DeepEqual(path.Nodes(), want) {
+		t.Errorf("expected %v, got %v", want, path.Nodes())
+	}
+	if path.Outcome != OutcomeCompleted || path.State.Get("score") != 0.9 {
+		t.Errorf("unexpected path %+v", path)
+	}
+	if len(path.Decisions) != 1 || path.Decisions[0] != (Decision{NodeID: "check", Target: "publish", Condition: "state.score >= 0.8"}) {
+		t.Errorf("unexpected decisions %v", path.Decisions)
+	}
+	if path.LLMCalls != 1 || path.InputTokens != 100 || path.OutputTokens != 500 {
+		t.Errorf("unexpected usage: %d calls, %d/%d tokens", path.LLMCalls, path.InputTokens, path.OutputTokens)
+	}
+	// Two 10s attempts with a 1s backoff, then the 2s tool call.
+	if path.WorstCaseLatency != 23*time.Second {
+		t.Errorf("expected a worst case of 23s, got %v", path.WorstCaseLatency)
+	}
+}
+
+func TestSimulator_RunVariations(t *testing.T) {
+	sim := NewSimulator().WithLimits(20, 0, 0)
+
+	report, err := sim.Run(context.Background(), reviewGraph(t), state.State{"score": 0.9}, state.State{"score": 0.1})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(report.Paths) != 2 {
+		t.Fatalf("expected a path per variation, got %d", len(report.Paths))
+	}
+	if p := report.Paths[0]; p.Variation != 0 || p.Outcome != OutcomeCompleted || p.State.Get("draft") != "<simulated draft>" {
+		t.Errorf("unexpected first path %+v", p)
+	}
+	if p := report.Paths[1]; p.Variation != 1 || p.Outcome != OutcomeTruncated || p.LLMCalls < 2 {
+		t.Errorf("expected the low score to loop until the step limit, got %+v", p)
+	}
+}
+
+func TestSimulator_Explore(t *testing.T) {
+	report, err := NewSimulator().Explore(context.Background(), reviewGraph(t))
+	if err != nil {
+		t.Fatalf("Explore failed: %v", err)
+	}
+	var got [][]string
+	for _, p := range report.Paths {
+		if p.Outcome != OutcomeCompleted {
+			t.Errorf("unexpected outcome %s: %s", p.Outcome, p.Error)
+		}
+		got = append(got, p.Nodes())
+	}
+	want := [][]string{
+		{"start", "draft", "check", "publish", "end"},
+		{"start", "draft", "check", "draft", "check", "publish", "end"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected paths %v, got %v", want, got)
+	}
+	if report.MaxLLMCalls() != 2 || report.MaxTokens() < 1000 || report.MaxLatency() != 42*time.Second {
+		t.Errorf("unexpected maxima: %d calls, %d tokens, %v", report.MaxLLMCalls(), report.MaxTokens(), report.MaxLatency())
+	}
+
+	limited, err := NewSimulator().WithLimits(0, 0, 1).Explore(context.Background(), reviewGraph(t))
+	if err != nil || len(limited.Paths) != 1 || !limited.Truncated {
+		t.Errorf("expected the path limit to truncate the report, got %+v, %v", limited, err)
+	}
+}
+
+func TestSimulator_ExploreConditionalEdges(t *testing.T) {
+	cfg := map[string]interface{}{"model": "gpt-4", "max_tokens": 100}
+	g, err := graph.Build("classify").ID("classify").
+		Start().
+		LLM("classify", cfg).
+		Edge(graph.NewEdge("classify", "a").WithCondition("state.x == 1")).
+		Edge(graph.NewEdge("classify", "b").WithCondition("state.x != 1")).
+		Edge(graph.NewEdge("classify", "audit")).
+		At().LLM("a", cfg).
+		At().LLM("b", cfg).
+		At().Tool("audit", "log", nil).
+		Graph()
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+
+	report, err := NewSimulator().Explore(context.Background(), g)
+	if err != nil {
+		t.Fatalf("Explore failed: %v", err)
+	}
+	var got [][][]string
+	for _, p := range report.Paths {
+		got = append(got, p.Steps)
+		if p.LLMCalls != 2 {
+			t.Errorf("expected 2 LLM calls on %v, got %d", p.Steps, p.LLMCalls)
+		}
+	}
+	want := [][][]string{
+		{{"start"}, {"classify"}, {"audit", "a"}},
+		{{"start"}, {"classify"}, {"audit", "b"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected a path per conditional edge %v, got %v", want, got)
+	}
+	if d := report.Paths[1].Decisions; len(d) != 1 || d[0] != (Decision{NodeID: "classify", Target: "b", Condition: "state.x != 1"}) {
+		t.Errorf("unexpected decisions %v", d)
+	}
+}
+
+func TestSimulator_Failures(t *testing.T) {
+	g, err := graph.Build("publish").ID("publish").
+		Start().
+		Approval("review", "Publish?").
+		OnError("fix", graph.ErrorKindRejected).
+		Tool("upload", "s3", nil).
+		OnError("alert").
+		Node(&graph.EndNode{BaseNode: graph.BaseNode{ID: graph.EndNodeID, Type: graph.NodeTypeEnd}}).
+		At().Tool("fix", "editor", nil).
+		At().Tool("alert", "pager", nil).
+		Graph()
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+	sim := NewSimulator().WithStub("upload", Stub{Err: errors.New("bucket full")})
+
+	report, err := sim.Explore(context.Background(), g)
+	if err != nil {
+		t.Fatalf("Explore failed: %v", err)
+	}
+	var got [][]string
+	for _, p := range report.Paths {
+		got = append(got, p.Nodes())
+	}
+	want := [][]string{{"start", "review", "upload", "alert"}, {"start", "review", "fix"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected paths %v, got %v", want, got)
+	}
+	if d := report.Paths[1].Decisions; len(d) != 1 || d[0].Error != graph.ErrorKindRejected {
+		t.Errorf("expected the rejection decision, got %v", d)
+	}
+
+	g.Nodes["upload"].(*graph.ExecutorNode).Policy = nil
+	report, err = sim.Run(context.Background(), g)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if p := report.Paths[0]; p.Outcome != OutcomeFailed || p.Error == "" {
+		t.Errorf("expected an unhandled failure, got %+v", p)
+	}
+}
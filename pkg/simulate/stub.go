@@ -0,0 +1,150 @@
+package simulate
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+)
+
+// OutputSchemaKey is the node metadata key holding the JSON schema of the
+// node's output, used to generate stub outputs.
+const OutputSchemaKey = "output_schema"
+
+// Stub replaces the execution of a node during a simulation.
+type Stub struct {
+	// Output holds the state keys the node sets. If nil, the output is
+	// generated from Schema, or from the node's output schema metadata, or is
+	// a placeholder under the node ID.
+	Output state.State
+
+	// Schema is the JSON schema of the node output. The properties of an
+	// object schema are set as state keys; any other schema sets the node ID.
+	Schema map[string]interface{}
+
+	// Err makes the node fail, e.g. to simulate error edges.
+	Err error
+
+	// Latency is the expected duration of an attempt. Zero uses the latency
+	// of the executor type (see Simulator.WithLatency).
+	Latency time.Duration
+
+	// InputTokens and OutputTokens are the expected token usage of an LLM
+	// node. Zero estimates them from the state and the node config.
+	InputTokens  int
+	OutputTokens int
+}
+
+// output returns the state keys a stubbed node sets.
+func (s Stub) output(node graph.Node) state.State {
+	if s.Output != nil {
+		return s.Output.DeepCopy()
+	}
+	schema := s.Schema
+	if schema == nil {
+		if base, ok := node.(interface{ Base() *graph.BaseNode }); ok {
+			schema, _ = base.Base().Metadata[OutputSchemaKey].(map[string]interface{})
+		}
+	}
+	if schema == nil {
+		return state.State{node.GetID(): fmt.Sprintf("<simulated %s>", node.GetID())}
+	}
+	value := Generate(schema)
+	if m, ok := value.(map[string]interface{}); ok && schemaType(schema) == "object" {
+		return state.State(m)
+	}
+	return state.State{node.GetID(): value}
+}
+
+// Generate returns an example value matching a JSON schema. It uses const,
+// default, examples and enum values when present, the minimum of numbers,
+// and recurses into object properties and array items.
+func Generate(schema map[string]interface{}) interface{} {
+	if v, ok := schema["const"]; ok {
+		return v
+	}
+	if v, ok := schema["default"]; ok {
+		return v
+	}
+	if examples, ok := schema["examples"].([]interface{}); ok && len(examples) > 0 {
+		return examples[0]
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	for _, key := range []string{"oneOf", "anyOf", "allOf"} {
+		if options, ok := schema[key].([]interface{}); ok && len(options) > 0 {
+			if option, ok := options[0].(map[string]interface{}); ok {
+				return Generate(option)
+			}
+		}
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		out := make(map[string]interface{})
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, property := range properties {
+			if p, ok := property.(map[string]interface{}); ok {
+				out[name] = Generate(p)
+			}
+		}
+		return out
+	case "array":
+		items, _ := schema["items"].(map[string]interface{})
+		if items == nil {
+			return []interface{}{}
+		}
+		return []interface{}{Generate(items)}
+	case "string":
+		return "example"
+	case "integer":
+		if min, ok := number(schema["minimum"]); ok {
+			return int(min)
+		}
+		return 0
+	case "number":
+		if min, ok := number(schema["minimum"]); ok {
+			return min
+		}
+		return 0.0
+	case "boolean":
+		return true
+	default:
+		return nil
+	}
+}
+
+// schemaType returns the type of a schema, inferring object from properties.
+func schemaType(schema map[string]interface{}) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []interface{}:
+		for _, v := range t {
+			if s, ok := v.(string); ok && s != "null" {
+				return s
+			}
+		}
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	return ""
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,58 @@
+package simulate
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+	"github.com/aescanero/dago-libs/pkg/domain/state"
+)
+
+func TestGenerate(t *testing.T) {
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"title":    map[string]interface{}{"type": "string"},
+			"score":    map[string]interface{}{"type": "number", "minimum": 0.5},
+			"count":    map[string]interface{}{"type": "integer"},
+			"approved": map[string]interface{}{"type": "boolean", "default": false},
+			"status":   map[string]interface{}{"enum": []interface{}{"draft", "final"}},
+			"tags":     map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			"author":   map[string]interface{}{"properties": map[string]interface{}{"name": map[string]interface{}{"const": "ann"}}},
+			"note":     map[string]interface{}{"type": []interface{}{"null", "string"}, "examples": []interface{}{"hi"}},
+		},
+	}
+	want := map[string]interface{}{
+		"title":    "example",
+		"score":    0.5,
+		"count":    0,
+		"approved": false,
+		"status":   "draft",
+		"tags":     []interface{}{"example"},
+		"author":   map[string]interface{}{"name": "ann"},
+		"note":     "hi",
+	}
+	if got := Generate(schema); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestStub_Output(t *testing.T) {
+	node := &graph.ExecutorNode{BaseNode: graph.BaseNode{ID: "classify", Metadata: map[string]interface{}{
+		OutputSchemaKey: map[string]interface{}{"type": "object", "properties": map[string]interface{}{"label": map[string]interface{}{"const": "spam"}}},
+	}}}
+
+	if got := (Stub{}).output(node); !reflect.DeepEqual(got, state.State{"label": "spam"}) {
+		t.Errorf("expected the output generated from metadata, got %v", got)
+	}
+	if got := (Stub{Schema: map[string]interface{}{"type": "integer", "minimum": 3}}).output(node); !reflect.DeepEqual(got, state.State{"classify": 3}) {
+		t.Errorf("expected a scalar under the node ID, got %v", got)
+	}
+	if got := (Stub{Output: state.State{"label": "ham"}}).output(node); !reflect.DeepEqual(got, state.State{"label": "ham"}) {
+		t.Errorf("expected the fixed output, got %v", got)
+	}
+	node.Metadata = nil
+	if got := (Stub{}).output(node); got.Get("classify") != "<simulated classify>" {
+		t.Errorf("expected a placeholder, got %v", got)
+	}
+}
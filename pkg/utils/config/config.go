@@ -6,6 +6,8 @@ import (
 	"os"
 	"strconv"
 	"time"
+
+	"github.com/aescanero/dago-libs/pkg/codec"
 )
 
 // GetEnv retrieves an environment variable with a default fallback.
@@ -84,6 +86,10 @@ type Config struct {
 	DefaultTimeout time.Duration
 	LLMTimeout     time.Duration
 	ToolTimeout    time.Duration
+
+	// Serialization formats used by storage adapters (see codec.Parse)
+	StateCodec string
+	GraphCodec string
 }
 
 // LoadFromEnv loads configuration from environment variables.
@@ -110,6 +116,10 @@ func LoadFromEnv() Config {
 		DefaultTimeout: GetEnvDuration("DEFAULT_TIMEOUT", 5*time.Minute),
 		LLMTimeout:     GetEnvDuration("LLM_TIMEOUT", 2*time.Minute),
 		ToolTimeout:    GetEnvDuration("TOOL_TIMEOUT", 5*time.Minute),
+
+		// Serialization
+		StateCodec: GetEnv("STATE_CODEC", "json"),
+		GraphCodec: GetEnv("GRAPH_CODEC", "json"),
 	}
 }
 
@@ -124,5 +134,11 @@ func (c *Config) Validate() error {
 	if c.MetricsPort <= 0 || c.MetricsPort > 65535 {
 		return fmt.Errorf("metrics port must be between 1 and 65535")
 	}
+	if _, err := codec.Parse(c.StateCodec); err != nil {
+		return fmt.Errorf("invalid state codec: %w", err)
+	}
+	if _, err := codec.Parse(c.GraphCodec); err != nil {
+		return fmt.Errorf("invalid graph codec: %w", err)
+	}
 	return nil
 }
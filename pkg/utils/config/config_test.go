@@ -237,6 +237,7 @@ func TestLoadFromEnv_Defaults(t *testing.T) {
 		"METRICS_ENABLED", "METRICS_PORT",
 		"SERVICE_NAME", "SERVICE_PORT",
 		"DEFAULT_TIMEOUT", "LLM_TIMEOUT", "TOOL_TIMEOUT",
+		"STATE_CODEC", "GRAPH_CODEC",
 	}
 	for _, v := range vars {
 		_ = os.Unsetenv(v)
@@ -269,6 +270,9 @@ func TestLoadFromEnv_Defaults(t *testing.T) {
 	if cfg.ServicePort != 8080 {
 		t.Errorf("expected default ServicePort 8080, got %d", cfg.ServicePort)
 	}
+	if cfg.StateCodec != "json" || cfg.GraphCodec != "json" {
+		t.Errorf("expected default codecs 'json', got %q and %q", cfg.StateCodec, cfg.GraphCodec)
+	}
 }
 
 func TestConfig_Validate(t *testing.T) {
@@ -331,6 +335,27 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "compressed binary codecs",
+			config: Config{
+				RedisAddr:   "localhost:6379",
+				ServicePort: 8080,
+				MetricsPort: 9090,
+				StateCodec:  "msgpack+zstd",
+				GraphCodec:  "cbor",
+			},
+			expectError: false,
+		},
+		{
+			name: "unknown state codec",
+			config: Config{
+				RedisAddr:   "localhost:6379",
+				ServicePort: 8080,
+				MetricsPort: 9090,
+				StateCodec:  "xml",
+			},
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,17 @@
+// Package watch serves the live progress of executions over HTTP.
+//
+// Handler subscribes to the ports.EventBus for the events of one execution
+// and streams them to the client as Server-Sent Events or, when the request
+// asks for a WebSocket upgrade, as WebSocket messages. A reconnecting client
+// sends the ID of the last event it received (the Last-Event-ID header, set
+// automatically by EventSource, or the last_event_id query parameter) and
+// first receives the events stored since then in the ports.EventStore; a new
+// client first receives every stored event of the execution.
+//
+// WebSocket upgrades are accepted from the same origin only, unless
+// Handler.WithCheckOrigin allows others.
+//
+// Heartbeats keep idle connections open. A client that falls behind by more
+// than the buffer size is disconnected rather than slowing down the bus; it
+// resumes from its last event when it reconnects.
+package watch
@@ -0,0 +1,309 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain"
+	"github.com/aescanero/dago-libs/pkg/ports"
+	"github.com/aescanero/dago-libs/pkg/utils/logging"
+)
+
+// Defaults of Handler settings.
+const (
+	DefaultHeartbeat  = 15 * time.Second
+	DefaultBufferSize = 256
+)
+
+// Request parameters read by Handler.
+const (
+	// ParamExecutionID is the path wildcard or query parameter naming the
+	// execution to watch.
+	ParamExecutionID = "execution_id"
+
+	// ParamTypes is the query parameter restricting the event types, as a
+	// comma-separated list of types or patterns such as "node.*".
+	ParamTypes = "types"
+
+	// ParamLastEventID is the query parameter of the last event a client
+	// received, for clients that cannot set the Last-Event-ID header.
+	ParamLastEventID = "last_event_id"
+
+	// HeaderLastEventID is the header set by reconnecting SSE clients.
+	HeaderLastEventID = "Last-Event-ID"
+)
+
+// ErrSlowConsumer ends a stream whose client does not keep up with the
+// events of its execution. The client reconnects with the ID of the last
+// event it received to resume.
+var ErrSlowConsumer = errors.New("watch client too slow")
+
+// Handler is an http.Handler streaming the events of an execution, as
+// Server-Sent Events or, for WebSocket upgrade requests, as WebSocket text
+// messages holding one JSON ports.Event each.
+//
+// With a ports.EventStore, a client first gets the stored events of the
+// execution, or those since the last event it received when it resumes with
+// its ID, then the live events of the ports.EventBus. The stream ends after
+// the execution completes or fails, including when it had already ended.
+type Handler struct {
+	bus         ports.EventBus
+	topic       string
+	store       ports.EventStore
+	heartbeat   time.Duration
+	buffer      int
+	checkOrigin func(r *http.Request) bool
+	logger      *logging.Logger
+}
+
+// NewHandler creates a handler streaming the events published to topic, a
+// topic or topic pattern, on bus.
+func NewHandler(bus ports.EventBus, topic string) *Handler {
+	return &Handler{bus: bus, topic: topic, heartbeat: DefaultHeartbeat, buffer: DefaultBufferSize, checkOrigin: SameOrigin}
+}
+
+// WithEventStore enables resuming streams from the events in store.
+func (h *Handler) WithEventStore(store ports.EventStore) *Handler {
+	h.store = store
+	return h
+}
+
+// WithHeartbeat sets the interval of heartbeats, sent to keep idle
+// connections open through proxies. Zero disables heartbeats.
+func (h *Handler) WithHeartbeat(interval time.Duration) *Handler {
+	h.heartbeat = interval
+	return h
+}
+
+// WithBufferSize sets the number of events buffered for a client. A client
+// falling further behind is disconnected with ErrSlowConsumer instead of
+// slowing down the bus.
+func (h *Handler) WithBufferSize(size int) *Handler {
+	if size < 1 {
+		size = 1
+	}
+	h.buffer = size
+	return h
+}
+
+// WithCheckOrigin sets the check of the origin of WebSocket upgrade
+// requests; requests it rejects get 403 Forbidden. The default, SameOrigin,
+// refuses pages of other sites, which browsers let open WebSockets with the
+// user's cookies.
+func (h *Handler) WithCheckOrigin(check func(r *http.Request) bool) *Handler {
+	if check == nil {
+		check = SameOrigin
+	}
+	h.checkOrigin = check
+	return h
+}
+
+// WithLogger sets the logger of stream errors.
+func (h *Handler) WithLogger(logger *logging.Logger) *Handler {
+	h.logger = logger
+	return h
+}
+
+// ServeHTTP streams the events of the execution named by the request.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	executionID := r.PathValue(ParamExecutionID)
+	if executionID == "" {
+		executionID = r.URL.Query().Get(ParamExecutionID)
+	}
+	if executionID == "" {
+		http.Error(w, "missing execution_id", http.StatusBadRequest)
+		return
+	}
+	filter := ports.EventFilter{ExecutionID: executionID}
+	if types := r.URL.Query().Get(ParamTypes); types != "" {
+		for _, t := range strings.Split(types, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				filter.Types = append(filter.Types, ports.EventType(t))
+			}
+		}
+	}
+	lastEventID := r.Header.Get(HeaderLastEventID)
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get(ParamLastEventID)
+	}
+
+	var conn conn
+	var err error
+	if isWebSocket(r) {
+		if !h.checkOrigin(r) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		conn, err = upgrade(w, r)
+	} else {
+		conn, err = newSSE(w)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer conn.Close()
+
+	if err := h.stream(r.Context(), conn, filter, lastEventID); err != nil && !errors.Is(err, context.Canceled) && h.logger != nil {
+		h.logger.WithExecutionID(executionID).Warn("watch stream ended", "error", err)
+	}
+}
+
+// stream sends the stored events after lastEventID, then the live events,
+// until the execution ends, the client leaves or falls behind.
+func (h *Handler) stream(ctx context.Context, c conn, filter ports.EventFilter, lastEventID string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		// A closed connection ends the stream.
+		<-c.Done()
+		cancel()
+	}()
+
+	// Subscribe before replaying so that no event falls in between; events
+	// received both ways are sent once.
+	live := make(chan ports.Event, h.buffer)
+	overflow := make(chan struct{})
+	var once sync.Once
+	sub, err := h.bus.Subscribe(ctx, h.topic, func(_ context.Context, event ports.Event) error {
+		select {
+		case live <- event:
+		default:
+			once.Do(func() { close(overflow) })
+		}
+		return nil
+	}, ports.SubscribeOptions{Filter: &filter})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to '%s': %w", h.topic, err)
+	}
+	defer sub.Unsubscribe(context.WithoutCancel(ctx))
+
+	sent := make(map[string]bool)
+	replay, err := h.replay(ctx, filter, lastEventID)
+	if err != nil {
+		return err
+	}
+	for _, event := range replay {
+		if err := c.Send(event); err != nil {
+			return err
+		}
+		sent[event.ID] = true
+		if isTerminal(event) {
+			return nil
+		}
+	}
+
+	var heartbeat <-chan time.Time
+	if h.heartbeat > 0 {
+		ticker := time.NewTicker(h.heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-overflow:
+			c.Fail(ErrSlowConsumer)
+			return ErrSlowConsumer
+		case <-heartbeat:
+			if err := c.Heartbeat(); err != nil {
+				return err
+			}
+		case event := <-live:
+			if sent[event.ID] {
+				continue
+			}
+			if err := c.Send(event); err != nil {
+				return err
+			}
+			if isTerminal(event) {
+				return nil
+			}
+		}
+	}
+}
+
+// replay returns the stored events after lastEventID, in timestamp order
+// with ties broken by ID, the order of paged event queries. An empty or
+// unknown lastEventID replays every stored event of the execution, so that
+// clients connecting after the execution ended still get its terminal event.
+func (h *Handler) replay(ctx context.Context, filter ports.EventFilter, lastEventID string) ([]ports.Event, error) {
+	if h.store == nil {
+		return nil, nil
+	}
+	var last *ports.Event
+	if lastEventID != "" {
+		if event, err := h.store.GetByID(ctx, lastEventID); err == nil && event != nil {
+			last = event
+			filter.Since = last.Timestamp
+		}
+	}
+	stored, err := h.store.Query(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load events of execution '%s': %w", filter.ExecutionID, err)
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		return before(stored[i], stored[j])
+	})
+	if last != nil {
+		i := sort.Search(len(stored), func(i int) bool { return before(*last, stored[i]) })
+		return stored[i:], nil
+	}
+	return stored, nil
+}
+
+// before reports whether a comes before b in replay order.
+func before(a, b ports.Event) bool {
+	if !a.Timestamp.Equal(b.Timestamp) {
+		return a.Timestamp.Before(b.Timestamp)
+	}
+	return a.ID < b.ID
+}
+
+// isTerminal reports whether an event ends the execution.
+func isTerminal(event ports.Event) bool {
+	switch event.Type {
+	case ports.EventTypeGraphCompleted, ports.EventTypeGraphFailed, ports.EventType(domain.EventTypeGraphCancelled):
+		return true
+	}
+	return false
+}
+
+// conn is a client connection events are streamed to.
+type conn interface {
+	// Send sends an event.
+	Send(event ports.Event) error
+
+	// Heartbeat keeps the connection alive.
+	Heartbeat() error
+
+	// Fail tells the client why the stream ends, if the protocol allows it.
+	Fail(err error)
+
+	// Done is closed when the client closes the connection.
+	Done() <-chan struct{}
+
+	// Close ends the stream.
+	Close() error
+}
+
+func marshal(event ports.Event) ([]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event '%s': %w", event.ID, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,370 @@
+package watch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/events"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+var epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// syncBus is a ports.EventBus calling subscribed handlers synchronously from
+// Publish, and signalling each subscription.
+type syncBus struct {
+	mu         sync.Mutex
+	handlers   map[int]subscribed
+	next       int
+	subscribed chan struct{}
+}
+
+type subscribed struct {
+	handler ports.EventHandler
+	filter  *ports.EventFilter
+}
+
+func newSyncBus() *syncBus {
+	return &syncBus{handlers: make(map[int]subscribed), subscribed: make(chan struct{}, 8)}
+}
+
+func (b *syncBus) Publish(ctx context.Context, topic string, event ports.Event) error {
+	b.mu.Lock()
+	var handlers []subscribed
+	for _, s := range b.handlers {
+		handlers = append(handlers, s)
+	}
+	b.mu.Unlock()
+	for _, s := range handlers {
+		if events.Matches(s.filter, event) {
+			s.handler(ctx, event)
+		}
+	}
+	return nil
+}
+
+func (b *syncBus) Subscribe(ctx context.Context, topic string, handler ports.EventHandler, opts ports.SubscribeOptions) (ports.Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.next++
+	b.handlers[b.next] = subscribed{handler: handler, filter: opts.Filter}
+	b.subscribed <- struct{}{}
+	return &syncSubscription{bus: b, id: b.next}, nil
+}
+
+func (b *syncBus) Unsubscribe(ctx context.Context, topic string) error { return nil }
+
+func (b *syncBus) Close() error { return nil }
+
+func (b *syncBus) waitSubscribed(t *testing.T) {
+	t.Helper()
+	select {
+	case <-b.subscribed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the subscription")
+	}
+}
+
+type syncSubscription struct {
+	bus *syncBus
+	id  int
+}
+
+func (s *syncSubscription) ID() string    { return "sub" }
+func (s *syncSubscription) Topic() string { return "executions" }
+func (s *syncSubscription) Group() string { return "" }
+
+func (s *syncSubscription) Unsubscribe(ctx context.Context) error {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	delete(s.bus.handlers, s.id)
+	return nil
+}
+
+// memoryEvents is a minimal ports.EventStore.
+type memoryEvents []ports.Event
+
+func (s memoryEvents) Store(ctx context.Context, event ports.Event) error {
+	return errors.New("read-only")
+}
+
+func (s memoryEvents) Query(ctx context.Context, filter ports.EventFilter) ([]ports.Event, error) {
+	var out []ports.Event
+	for _, e := range s {
+		if events.Matches(&filter, e) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (s memoryEvents) GetByID(ctx context.Context, id string) (*ports.Event, error) {
+	for _, e := range s {
+		if e.ID == id {
+			return &e, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (s memoryEvents) GetByExecutionID(ctx context.Context, executionID string) ([]ports.Event, error) {
+	return s.Query(ctx, ports.EventFilter{ExecutionID: executionID})
+}
+
+func event(id string, seconds int, eventType ports.EventType, executionID string) ports.Event {
+	return ports.Event{ID: id, Type: eventType, ExecutionID: executionID, Timestamp: epoch.Add(time.Duration(seconds) * time.Second)}
+}
+
+// sseEvent is an event parsed from an SSE stream.
+type sseEvent struct {
+	id, event, data string
+}
+
+// readSSE parses an SSE stream until it ends, skipping comments.
+func readSSE(t *testing.T, body io.Reader) ([]sseEvent, []string) {
+	t.Helper()
+	var out []sseEvent
+	var comments []string
+	var current sseEvent
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if current != (sseEvent{}) {
+				out = append(out, current)
+			}
+			current = sseEvent{}
+		case strings.HasPrefix(line, ":"):
+			comments = append(comments, strings.TrimSpace(line[1:]))
+		case strings.HasPrefix(line, "id: "):
+			current.id = line[4:]
+		case strings.HasPrefix(line, "event: "):
+			current.event = line[7:]
+		case strings.HasPrefix(line, "data: "):
+			current.data = line[6:]
+		}
+	}
+	return out, comments
+}
+
+func TestHandler_SSE(t *testing.T) {
+	bus := newSyncBus()
+	server := httptest.NewServer(NewHandler(bus, "executions").WithHeartbeat(0))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?execution_id=exec-1&types=node.*,graph.completed")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected text/event-stream, got %s", ct)
+	}
+
+	bus.waitSubscribed(t)
+	for _, e := range []ports.Event{
+		event("1", 0, ports.EventTypeGraphStarted, "exec-1"),
+		event("2", 1, ports.EventTypeNodeStarted, "exec-1"),
+		event("3", 1, ports.EventTypeNodeStarted, "exec-2"),
+		event("4", 2, ports.EventTypeGraphCompleted, "exec-1"),
+	} {
+		bus.Publish(context.Background(), "executions", e)
+	}
+
+	received, _ := readSSE(t, resp.Body)
+	if len(received) != 2 || received[0].id != "2" || received[0].event != "node.started" || received[1].id != "4" {
+		t.Fatalf("expected events 2 and 4 then the end of the stream, got %+v", received)
+	}
+	var decoded ports.Event
+	if err := json.Unmarshal([]byte(received[0].data), &decoded); err != nil || decoded.ExecutionID != "exec-1" {
+		t.Errorf("expected the JSON event as data, got %q (%v)", received[0].data, err)
+	}
+}
+
+func TestHandler_SSEResume(t *testing.T) {
+	store := memoryEvents{
+		event("1", 0, ports.EventTypeGraphStarted, "exec-1"),
+		event("2", 1, ports.EventTypeNodeStarted, "exec-1"),
+		event("3", 2, ports.EventTypeNodeCompleted, "exec-1"),
+	}
+	bus := newSyncBus()
+	mux := http.NewServeMux()
+	mux.Handle("GET /executions/{execution_id}/events", NewHandler(bus, "executions").WithEventStore(store).WithHeartbeat(0))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/executions/exec-1/events", nil)
+	req.Header.Set(HeaderLastEventID, "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	bus.waitSubscribed(t)
+	// Event 3 is both stored and published: it is sent once.
+	bus.Publish(context.Background(), "executions", store[2])
+	bus.Publish(context.Background(), "executions", event("4", 3, ports.EventTypeGraphFailed, "exec-1"))
+
+	received, _ := readSSE(t, resp.Body)
+	var ids []string
+	for _, e := range received {
+		ids = append(ids, e.id)
+	}
+	if strings.Join(ids, ",") != "2,3,4" {
+		t.Errorf("expected the events after 1, got %v", ids)
+	}
+}
+
+func TestHandler_SSEAfterCompletion(t *testing.T) {
+	store := memoryEvents{
+		event("1", 0, ports.EventTypeGraphStarted, "exec-1"),
+		event("2", 1, ports.EventTypeNodeCompleted, "exec-1"),
+		event("3", 2, ports.EventTypeGraphCompleted, "exec-1"),
+	}
+	server := httptest.NewServer(NewHandler(newSyncBus(), "executions").WithEventStore(store).WithHeartbeat(0))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?execution_id=exec-1")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	received, _ := readSSE(t, resp.Body)
+	var ids []string
+	for _, e := range received {
+		ids = append(ids, e.id)
+	}
+	if strings.Join(ids, ",") != "1,2,3" {
+		t.Errorf("expected the stored events then the end of the stream, got %v", ids)
+	}
+}
+
+func TestHandler_ReplayOrdersTiesByID(t *testing.T) {
+	// Events of the same timestamp, stored out of ID order.
+	store := memoryEvents{
+		event("c", 1, ports.EventTypeNodeStarted, "exec-1"),
+		event("a", 1, ports.EventTypeNodeStarted, "exec-1"),
+		event("d", 2, ports.EventTypeNodeCompleted, "exec-1"),
+		event("b", 1, ports.EventTypeNodeStarted, "exec-1"),
+	}
+	h := NewHandler(newSyncBus(), "executions").WithEventStore(store)
+
+	tests := []struct {
+		lastEventID string
+		want        string
+	}{
+		{"a", "b,c,d"},
+		{"c", "d"},
+		{"d", ""},
+		{"unknown", "a,b,c,d"},
+		{"", "a,b,c,d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lastEventID, func(t *testing.T) {
+			replay, err := h.replay(context.Background(), ports.EventFilter{ExecutionID: "exec-1"}, tt.lastEventID)
+			if err != nil {
+				t.Fatalf("replay failed: %v", err)
+			}
+			var ids []string
+			for _, e := range replay {
+				ids = append(ids, e.ID)
+			}
+			if got := strings.Join(ids, ","); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHandler_Heartbeat(t *testing.T) {
+	bus := newSyncBus()
+	server := httptest.NewServer(NewHandler(bus, "executions").WithHeartbeat(5 * time.Millisecond))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?execution_id=exec-1")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+	bus.waitSubscribed(t)
+	time.Sleep(30 * time.Millisecond)
+	bus.Publish(context.Background(), "executions", event("1", 0, ports.EventTypeGraphCompleted, "exec-1"))
+
+	_, comments := readSSE(t, resp.Body)
+	if len(comments) == 0 || comments[0] != "heartbeat" {
+		t.Errorf("expected heartbeats, got %v", comments)
+	}
+}
+
+func TestHandler_BadRequests(t *testing.T) {
+	handler := NewHandler(newSyncBus(), "executions")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without an execution ID, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/?execution_id=exec-1", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+}
+
+// gatedWriter is an http.ResponseWriter whose writes block until released.
+type gatedWriter struct {
+	header http.Header
+	gate   chan struct{}
+	mu     sync.Mutex
+	body   strings.Builder
+}
+
+func (w *gatedWriter) Header() http.Header { return w.header }
+func (w *gatedWriter) WriteHeader(int)     {}
+func (w *gatedWriter) Flush()              {}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	<-w.gate
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.body.Write(p)
+}
+
+func TestHandler_SlowConsumer(t *testing.T) {
+	bus := newSyncBus()
+	handler := NewHandler(bus, "executions").WithBufferSize(1).WithHeartbeat(0)
+	w := &gatedWriter{header: make(http.Header), gate: make(chan struct{})}
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?execution_id=exec-1", nil))
+		close(done)
+	}()
+	bus.waitSubscribed(t)
+	// The stream holds at most one event being written and one buffered.
+	for _, id := range []string{"1", "2", "3"} {
+		bus.Publish(context.Background(), "executions", event(id, 0, ports.EventTypeNodeStarted, "exec-1"))
+	}
+	close(w.gate)
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the slow client to be disconnected")
+	}
+	if !strings.Contains(w.body.String(), "event: error\ndata: \""+ErrSlowConsumer.Error()) {
+		t.Errorf("expected an error event, got %q", w.body.String())
+	}
+}
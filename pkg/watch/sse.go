@@ -0,0 +1,76 @@
+package watch
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// sseConn streams events as Server-Sent Events. Each event has the event ID
+// as "id", so that reconnecting clients send it as Last-Event-ID, the event
+// type as "event" and the JSON event as "data".
+type sseConn struct {
+	w    http.ResponseWriter
+	rc   *http.ResponseController
+	done chan struct{}
+}
+
+func newSSE(w http.ResponseWriter) (*sseConn, error) {
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	c := &sseConn{w: w, rc: http.NewResponseController(w), done: make(chan struct{})}
+	if err := c.rc.Flush(); err != nil {
+		return nil, fmt.Errorf("streaming not supported: %w", err)
+	}
+	return c, nil
+}
+
+// Send implements conn.
+func (c *sseConn) Send(event ports.Event) error {
+	data, err := marshal(event)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if event.ID != "" {
+		fmt.Fprintf(&buf, "id: %s\n", event.ID)
+	}
+	fmt.Fprintf(&buf, "event: %s\ndata: %s\n\n", event.Type, data)
+	return c.write(buf.Bytes())
+}
+
+// Heartbeat implements conn with a comment line, ignored by clients.
+func (c *sseConn) Heartbeat() error {
+	return c.write([]byte(": heartbeat\n\n"))
+}
+
+// Fail implements conn with an "error" event.
+func (c *sseConn) Fail(err error) {
+	_ = c.write([]byte(fmt.Sprintf("event: error\ndata: %q\n\n", err.Error())))
+}
+
+// Done implements conn. The end of the request context signals SSE clients
+// leaving, so Done is only closed by Close.
+func (c *sseConn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close implements conn.
+func (c *sseConn) Close() error {
+	close(c.done)
+	return nil
+}
+
+func (c *sseConn) write(p []byte) error {
+	if _, err := c.w.Write(p); err != nil {
+		return err
+	}
+	return c.rc.Flush()
+}
@@ -0,0 +1,276 @@
+package watch
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// websocketGUID is the GUID of the RFC 6455 opening handshake.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes.
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+)
+
+// WebSocket close codes.
+const (
+	closeNormal        = 1000
+	closeProtocolError = 1002
+	closeTooBig        = 1009
+	closeTryAgainLater = 1013
+)
+
+// Errors of invalid client frames, which close the connection.
+var (
+	errUnmaskedFrame = errors.New("websocket client frame is not masked")
+	errFrameTooBig   = errors.New("websocket frame too big")
+)
+
+const (
+	// writeTimeout bounds each frame write, so that a dead client does not
+	// block its stream.
+	writeTimeout = 10 * time.Second
+
+	// maxFramePayload bounds the frames read from clients, which only send
+	// control frames.
+	maxFramePayload = 1 << 16
+)
+
+// wsConn streams events as WebSocket text messages. It implements the
+// server side of RFC 6455 needed by the handler: the opening handshake,
+// unfragmented text frames, pings as heartbeats and the closing handshake.
+type wsConn struct {
+	conn      net.Conn
+	rw        *bufio.ReadWriter
+	mu        sync.Mutex
+	done      chan struct{}
+	closeOnce sync.Once
+	doneOnce  sync.Once
+}
+
+// isWebSocket reports whether r asks for a WebSocket upgrade.
+func isWebSocket(r *http.Request) bool {
+	return headerContains(r.Header, "Connection", "upgrade") && strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// SameOrigin reports whether a request comes from a page of the same origin
+// as the handler, or from a client that does not send an Origin header. It
+// is the default origin check of WebSocket upgrades (see
+// Handler.WithCheckOrigin).
+func SameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// upgrade completes the opening handshake and takes over the connection.
+func upgrade(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	if v := r.Header.Get("Sec-WebSocket-Version"); v != "13" {
+		return nil, fmt.Errorf("unsupported websocket version %q", v)
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, errors.New("missing Sec-WebSocket-Key")
+	}
+
+	netConn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("websocket not supported: %w", err)
+	}
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+		base64.StdEncoding.EncodeToString(sum[:]))
+	if err := rw.Flush(); err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("failed to complete websocket handshake: %w", err)
+	}
+
+	c := &wsConn{conn: netConn, rw: rw, done: make(chan struct{})}
+	go c.readLoop()
+	return c, nil
+}
+
+// Send implements conn.
+func (c *wsConn) Send(event ports.Event) error {
+	data, err := marshal(event)
+	if err != nil {
+		return err
+	}
+	return c.writeFrame(opText, data)
+}
+
+// Heartbeat implements conn with a ping frame.
+func (c *wsConn) Heartbeat() error {
+	return c.writeFrame(opPing, nil)
+}
+
+// Fail implements conn with a close frame giving the reason.
+func (c *wsConn) Fail(err error) {
+	c.close(closeTryAgainLater, err.Error())
+}
+
+// Done implements conn.
+func (c *wsConn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close implements conn.
+func (c *wsConn) Close() error {
+	c.close(closeNormal, "")
+	return nil
+}
+
+// close sends a close frame, once, and closes the connection.
+func (c *wsConn) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		if len(reason) > 123 {
+			reason = reason[:123]
+		}
+		payload := make([]byte, 2+len(reason))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		copy(payload[2:], reason)
+		_ = c.writeFrame(opClose, payload)
+		c.conn.Close()
+		c.finish()
+	})
+}
+
+func (c *wsConn) finish() {
+	c.doneOnce.Do(func() { close(c.done) })
+}
+
+// readLoop answers the control frames of the client until it closes the
+// connection.
+func (c *wsConn) readLoop() {
+	defer c.finish()
+	for {
+		opcode, payload, err := c.readFrame()
+		switch {
+		case errors.Is(err, errUnmaskedFrame):
+			// RFC 6455 section 5.1: servers close the connection.
+			c.close(closeProtocolError, err.Error())
+			return
+		case errors.Is(err, errFrameTooBig):
+			c.close(closeTooBig, err.Error())
+			return
+		case err != nil:
+			return
+		}
+		switch opcode {
+		case opClose:
+			code := closeNormal
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			c.close(code, "")
+			return
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readFrame reads a frame, unmasking its payload. Clients must mask their
+// frames: unmasked frames are rejected with errUnmaskedFrame.
+func (c *wsConn) readFrame() (byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.rw, header[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode := header[0] & 0x0F
+	if header[1]&0x80 == 0 {
+		return 0, nil, errUnmaskedFrame
+	}
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > maxFramePayload {
+		return 0, nil, fmt.Errorf("%w: %d bytes exceeds %d", errFrameTooBig, length, maxFramePayload)
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(c.rw, mask[:]); err != nil {
+		return 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.rw, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
+
+// writeFrame writes an unfragmented, unmasked frame.
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126, byte(n>>8), byte(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.rw.Write(header); err != nil {
+		return err
+	}
+	if _, err := c.rw.Write(payload); err != nil {
+		return err
+	}
+	return c.rw.Flush()
+}
+
+// headerContains reports whether a comma-separated header lists token.
+func headerContains(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, v := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
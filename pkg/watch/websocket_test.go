@@ -0,0 +1,208 @@
+package watch
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// wsClient is a minimal WebSocket client for tests.
+type wsClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dialWebSocket(t *testing.T, url string) *wsClient {
+	t.Helper()
+	client, resp := handshake(t, url, "")
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected 101, got %d", resp.StatusCode)
+	}
+	// The accept value of the RFC 6455 example key.
+	if accept := resp.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected Sec-WebSocket-Accept %q", accept)
+	}
+	return client
+}
+
+// handshake sends an opening handshake, with an Origin header unless origin
+// is empty, and returns the client and the server's response.
+func handshake(t *testing.T, url, origin string) (*wsClient, *http.Response) {
+	t.Helper()
+	addr := strings.TrimPrefix(url, "http://")
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	req := "GET /?execution_id=exec-1 HTTP/1.1\r\nHost: " + addr + "\r\n" +
+		"Upgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n"
+	if origin != "" {
+		req += "Origin: " + origin + "\r\n"
+	}
+	if _, err := conn.Write([]byte(req + "\r\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatalf("ReadResponse failed: %v", err)
+	}
+	return &wsClient{conn: conn, r: r}, resp
+}
+
+// write sends a masked frame.
+func (c *wsClient) write(t *testing.T, opcode byte, payload []byte) {
+	t.Helper()
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opcode, 0x80 | byte(len(payload))}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+}
+
+// read reads an unmasked frame.
+func (c *wsClient) read(t *testing.T) (byte, []byte) {
+	t.Helper()
+	var header [2]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	length := int(header[1] & 0x7F)
+	if length == 126 {
+		var ext [2]byte
+		io.ReadFull(c.r, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.r, payload); err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	return header[0] & 0x0F, payload
+}
+
+func TestHandler_WebSocket(t *testing.T) {
+	bus := newSyncBus()
+	server := httptest.NewServer(NewHandler(bus, "executions").WithHeartbeat(0))
+	defer server.Close()
+
+	client := dialWebSocket(t, server.URL)
+	defer client.conn.Close()
+	bus.waitSubscribed(t)
+
+	client.write(t, opPing, []byte("hi"))
+	if opcode, payload := client.read(t); opcode != opPong || string(payload) != "hi" {
+		t.Fatalf("expected a pong, got %x %q", opcode, payload)
+	}
+
+	bus.Publish(context.Background(), "executions", event("1", 0, ports.EventTypeNodeStarted, "exec-1"))
+	bus.Publish(context.Background(), "executions", event("2", 1, ports.EventTypeGraphCompleted, "exec-1"))
+	for _, want := range []string{"1", "2"} {
+		opcode, payload := client.read(t)
+		var received ports.Event
+		if opcode != opText || json.Unmarshal(payload, &received) != nil || received.ID != want {
+			t.Fatalf("expected event %s as a text message, got %x %q", want, opcode, payload)
+		}
+	}
+	opcode, payload := client.read(t)
+	if opcode != opClose || binary.BigEndian.Uint16(payload) != closeNormal {
+		t.Errorf("expected a normal close after the execution completed, got %x %v", opcode, payload)
+	}
+}
+
+func TestHandler_WebSocketClientClose(t *testing.T) {
+	bus := newSyncBus()
+	server := httptest.NewServer(NewHandler(bus, "executions").WithHeartbeat(0))
+	defer server.Close()
+
+	client := dialWebSocket(t, server.URL)
+	defer client.conn.Close()
+	bus.waitSubscribed(t)
+
+	client.write(t, opClose, []byte{0x03, 0xE8})
+	if opcode, _ := client.read(t); opcode != opClose {
+		t.Fatalf("expected the close to be echoed, got %x", opcode)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		bus.mu.Lock()
+		n := len(bus.handlers)
+		bus.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the subscription to end with the connection")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHandler_WebSocketOrigin(t *testing.T) {
+	bus := newSyncBus()
+	handler := NewHandler(bus, "executions").WithHeartbeat(0)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		origin string
+		check  func(r *http.Request) bool
+		want   int
+	}{
+		{"no origin", "", nil, http.StatusSwitchingProtocols},
+		{"same origin", server.URL, nil, http.StatusSwitchingProtocols},
+		{"cross origin", "https://evil.example", nil, http.StatusForbidden},
+		{"malformed origin", "://", nil, http.StatusForbidden},
+		{"allowed by check", "https://app.example", func(r *http.Request) bool {
+			return r.Header.Get("Origin") == "https://app.example"
+		}, http.StatusSwitchingProtocols},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler.WithCheckOrigin(tt.check)
+			client, resp := handshake(t, server.URL, tt.origin)
+			defer client.conn.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestHandler_WebSocketUnmaskedFrame(t *testing.T) {
+	bus := newSyncBus()
+	server := httptest.NewServer(NewHandler(bus, "executions").WithHeartbeat(0))
+	defer server.Close()
+
+	client := dialWebSocket(t, server.URL)
+	defer client.conn.Close()
+	bus.waitSubscribed(t)
+
+	if _, err := client.conn.Write([]byte{0x80 | opPing, 2, 'h', 'i'}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	opcode, payload := client.read(t)
+	if opcode != opClose || len(payload) < 2 || binary.BigEndian.Uint16(payload) != closeProtocolError {
+		t.Fatalf("expected a protocol error close, got %x %q", opcode, payload)
+	}
+	if _, err := client.r.ReadByte(); err != io.EOF {
+		t.Errorf("expected the server to close the connection, got %v", err)
+	}
+}
@@ -0,0 +1,449 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+	"github.com/aescanero/dago-libs/pkg/events"
+	"github.com/aescanero/dago-libs/pkg/ports"
+	"github.com/aescanero/dago-libs/pkg/utils/logging"
+)
+
+// Defaults of Dispatcher settings.
+const (
+	// DefaultGroup is the consumer group of the dispatcher subscription,
+	// so that dispatchers of several processes share the events.
+	DefaultGroup = "webhooks"
+
+	// DefaultSource is the CloudEvents source of delivered events.
+	DefaultSource = "/dago"
+
+	// DefaultDeliveryTopic is the topic of the delivery events, one per
+	// event and endpoint.
+	DefaultDeliveryTopic = "webhook.deliveries"
+
+	// DefaultTimeout bounds each delivery attempt.
+	DefaultTimeout = 10 * time.Second
+
+	// ContentTypeCloudEvents is the content type of webhook requests.
+	ContentTypeCloudEvents = "application/cloudevents+json"
+)
+
+// DefaultEventTypes are the event types delivered to endpoints.
+var DefaultEventTypes = []ports.EventType{ports.EventTypeGraphCompleted, ports.EventTypeGraphFailed}
+
+// DefaultRetry is the retry policy of deliveries: five attempts, one second
+// apart and then exponentially longer, up to a minute.
+var DefaultRetry = &graph.RetryPolicy{
+	MaxAttempts:     5,
+	InitialInterval: graph.Duration(time.Second),
+	MaxInterval:     graph.Duration(time.Minute),
+}
+
+// ErrDeliveryFailed is returned when an endpoint does not accept an event.
+var ErrDeliveryFailed = errors.New("webhook delivery failed")
+
+// statusError is the error of a non-2xx response.
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%v: endpoint responded %d %s", ErrDeliveryFailed, e.code, http.StatusText(e.code))
+}
+
+func (e *statusError) Unwrap() error { return ErrDeliveryFailed }
+
+// urlError is the error of an endpoint URL that is invalid or rejected by
+// the URL validator.
+type urlError struct {
+	err error
+}
+
+func (e *urlError) Error() string {
+	return fmt.Sprintf("%v: invalid endpoint URL: %v", ErrDeliveryFailed, e.err)
+}
+
+func (e *urlError) Unwrap() []error { return []error{ErrDeliveryFailed, e.err} }
+
+// retryable reports whether a failed attempt is worth retrying: network
+// errors, timeouts, rate limiting and server errors are; rejected URLs and
+// other client errors are not.
+func retryable(err error) bool {
+	var invalid *urlError
+	if errors.As(err, &invalid) {
+		return false
+	}
+	var status *statusError
+	if !errors.As(err, &status) {
+		return true
+	}
+	return status.code == http.StatusRequestTimeout || status.code == http.StatusTooManyRequests || status.code >= 500
+}
+
+// Metadata keys of the delivery events the dispatcher publishes to its
+// delivery topic.
+const (
+	// MetadataEndpointID is the ID of the endpoint the event is delivered to.
+	MetadataEndpointID = "webhook_endpoint_id"
+
+	// MetadataEventID is the ID of the delivered event.
+	MetadataEventID = "webhook_event_id"
+)
+
+// deliveryNamespace derives delivery IDs from event and endpoint IDs.
+var deliveryNamespace = uuid.MustParse("6f1c7e52-5b0a-4d8e-9f43-0c1d2b7a9e61")
+
+// Dispatcher delivers execution events from a ports.EventBus to the webhook
+// endpoints of a ports.WebhookRegistry.
+//
+// Each event is POSTed to every enabled endpoint whose filter it passes, as
+// a CloudEvent (see events.ToCloudEvent) signed with the endpoint secret
+// (see Sign). Handle does not make the requests: it publishes a delivery
+// event per endpoint to the delivery topic, whose subscription makes one
+// attempt per delivery of the bus. Failed attempts are negatively
+// acknowledged, so that the bus retries them with the backoff of the retry
+// policy and moves them to the dead-letter topic of the delivery topic once
+// the attempts are exhausted. Every attempt is recorded in the delivery log.
+type Dispatcher struct {
+	bus           ports.EventBus
+	topic         string
+	deliveryTopic string
+	registry      ports.WebhookRegistry
+	log           ports.WebhookDeliveryLog
+	client        *http.Client
+	validate      URLValidator
+	types         []ports.EventType
+	group         string
+	source        string
+	retry         *graph.RetryPolicy
+	logger        *logging.Logger
+	now           func() time.Time
+
+	mu   sync.Mutex
+	subs []ports.Subscription
+}
+
+// NewDispatcher creates a dispatcher of the events published to topic, which
+// may be a pattern, to the endpoints of registry.
+func NewDispatcher(bus ports.EventBus, topic string, registry ports.WebhookRegistry) *Dispatcher {
+	return &Dispatcher{
+		bus:           bus,
+		topic:         topic,
+		deliveryTopic: DefaultDeliveryTopic,
+		registry:      registry,
+		client:        &http.Client{Timeout: DefaultTimeout},
+		types:         DefaultEventTypes,
+		group:         DefaultGroup,
+		source:        DefaultSource,
+		retry:         DefaultRetry,
+		now:           time.Now,
+	}
+}
+
+// WithDeliveryLog records every delivery attempt in log.
+func (d *Dispatcher) WithDeliveryLog(log ports.WebhookDeliveryLog) *Dispatcher {
+	d.log = log
+	return d
+}
+
+// WithClient sets the HTTP client of deliveries. Its CheckRedirect is
+// ignored: redirects are never followed, and a 3xx response fails the
+// delivery.
+func (d *Dispatcher) WithClient(client *http.Client) *Dispatcher {
+	d.client = client
+	return d
+}
+
+// WithURLValidator checks the URL of an endpoint before each request; a URL
+// it rejects fails the delivery without retries. Use DenyPrivateNetworks to
+// keep endpoints from reaching internal services.
+func (d *Dispatcher) WithURLValidator(validate URLValidator) *Dispatcher {
+	d.validate = validate
+	return d
+}
+
+// WithDeliveryTopic sets the topic of the delivery events.
+func (d *Dispatcher) WithDeliveryTopic(topic string) *Dispatcher {
+	d.deliveryTopic = topic
+	return d
+}
+
+// WithEventTypes sets the event types, or patterns, delivered to
+// endpoints.
+func (d *Dispatcher) WithEventTypes(types ...ports.EventType) *Dispatcher {
+	d.types = types
+	return d
+}
+
+// WithGroup sets the consumer group of the subscriptions.
+func (d *Dispatcher) WithGroup(group string) *Dispatcher {
+	d.group = group
+	return d
+}
+
+// WithSource sets the CloudEvents source of delivered events.
+func (d *Dispatcher) WithSource(source string) *Dispatcher {
+	d.source = source
+	return d
+}
+
+// WithRetry sets the retry policy of deliveries, applied by the bus to the
+// subscription of the delivery topic. Nil makes a single attempt.
+func (d *Dispatcher) WithRetry(retry *graph.RetryPolicy) *Dispatcher {
+	d.retry = retry
+	return d
+}
+
+// WithLogger logs failed deliveries.
+func (d *Dispatcher) WithLogger(logger *logging.Logger) *Dispatcher {
+	d.logger = logger
+	return d
+}
+
+// WithClock sets the clock used to sign requests and time attempts.
+func (d *Dispatcher) WithClock(now func() time.Time) *Dispatcher {
+	d.now = now
+	return d
+}
+
+// Start subscribes the dispatcher to the events of its event types and to
+// its delivery topic.
+func (d *Dispatcher) Start(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.subs != nil {
+		return errors.New("webhook dispatcher already started")
+	}
+	dispatched, err := d.bus.Subscribe(ctx, d.topic, d.Handle, ports.SubscribeOptions{
+		Group:  d.group,
+		Filter: &ports.EventFilter{Types: d.types},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to '%s': %w", d.topic, err)
+	}
+	retry := d.retry
+	if retry == nil {
+		retry = &graph.RetryPolicy{MaxAttempts: 1}
+	}
+	deliveries, err := d.bus.Subscribe(ctx, d.deliveryTopic, d.HandleDelivery, ports.SubscribeOptions{
+		Group: d.group,
+		Retry: retry,
+	})
+	if err != nil {
+		return errors.Join(fmt.Errorf("failed to subscribe to '%s': %w", d.deliveryTopic, err), dispatched.Unsubscribe(ctx))
+	}
+	d.subs = []ports.Subscription{dispatched, deliveries}
+	return nil
+}
+
+// Stop ends the subscriptions of the dispatcher.
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	d.mu.Lock()
+	subs := d.subs
+	d.subs = nil
+	d.mu.Unlock()
+	var errs []error
+	for _, sub := range subs {
+		if err := sub.Unsubscribe(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Handle is the ports.EventHandler of the dispatched events: it publishes a
+// delivery event to the delivery topic for every matching endpoint, and
+// returns without waiting for the endpoints. An error is returned if the
+// endpoints cannot be listed or a delivery cannot be published, so that the
+// bus delivers the event again; deliveries keep their IDs (see DeliveryID),
+// so that endpoints can discard the duplicates.
+func (d *Dispatcher) Handle(ctx context.Context, event ports.Event) error {
+	endpoints, err := d.registry.ListEndpoints(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list webhook endpoints: %w", err)
+	}
+	var errs []error
+	for _, endpoint := range endpoints {
+		if !d.Matches(endpoint, event) {
+			continue
+		}
+		if err := d.bus.Publish(ctx, d.deliveryTopic, deliveryEvent(endpoint, event)); err != nil {
+			errs = append(errs, fmt.Errorf("failed to queue delivery of event '%s' to endpoint '%s': %w", event.ID, endpoint.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// HandleDelivery is the ports.EventHandler of the delivery topic: it makes
+// one attempt to deliver the event to its endpoint, and returns the error of
+// a failed attempt so that the bus retries it. Failures that are not worth
+// retrying wrap ports.ErrPoisonEvent. Deliveries to endpoints that have been
+// removed or disabled since are dropped.
+func (d *Dispatcher) HandleDelivery(ctx context.Context, delivery ports.Event) error {
+	endpointID, _ := delivery.Metadata[MetadataEndpointID].(string)
+	eventID, _ := delivery.Metadata[MetadataEventID].(string)
+	if endpointID == "" || eventID == "" {
+		return fmt.Errorf("%w: delivery '%s' has no endpoint or event", ports.ErrPoisonEvent, delivery.ID)
+	}
+	endpoint, err := d.registry.GetEndpoint(ctx, endpointID)
+	if errors.Is(err, ErrEndpointNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get webhook endpoint '%s': %w", endpointID, err)
+	}
+	if endpoint.Disabled {
+		return nil
+	}
+
+	event := delivery
+	event.ID = eventID
+	event.Metadata = make(map[string]interface{}, len(delivery.Metadata))
+	for k, v := range delivery.Metadata {
+		if k != MetadataEndpointID && k != MetadataEventID {
+			event.Metadata[k] = v
+		}
+	}
+	if len(event.Metadata) == 0 {
+		event.Metadata = nil
+	}
+
+	err = d.Deliver(ctx, *endpoint, event)
+	if err == nil {
+		return nil
+	}
+	if d.logger != nil {
+		d.logger.WithExecutionID(event.ExecutionID).Error("failed to deliver webhook",
+			"endpoint", endpoint.ID, "event", event.ID, "attempt", events.DeliveryAttempt(ctx), "error", err)
+	}
+	if !retryable(err) {
+		return fmt.Errorf("%w: %w", ports.ErrPoisonEvent, err)
+	}
+	return err
+}
+
+// DeliveryID returns the ID of the delivery of an event to an endpoint,
+// shared by its attempts and sent in the HeaderDelivery header.
+func DeliveryID(endpointID, eventID string) string {
+	return uuid.NewSHA1(deliveryNamespace, []byte(endpointID+"\x00"+eventID)).String()
+}
+
+// deliveryEvent returns the delivery event of an event to an endpoint.
+func deliveryEvent(endpoint ports.WebhookEndpoint, event ports.Event) ports.Event {
+	delivery := event
+	delivery.ID = DeliveryID(endpoint.ID, event.ID)
+	delivery.Metadata = make(map[string]interface{}, len(event.Metadata)+2)
+	for k, v := range event.Metadata {
+		delivery.Metadata[k] = v
+	}
+	delivery.Metadata[MetadataEndpointID] = endpoint.ID
+	delivery.Metadata[MetadataEventID] = event.ID
+	return delivery
+}
+
+// Matches reports whether an event is delivered to an endpoint: the event
+// is of one of the dispatcher's event types, the endpoint is enabled and
+// the event passes its filter.
+func (d *Dispatcher) Matches(endpoint ports.WebhookEndpoint, event ports.Event) bool {
+	if endpoint.Disabled || !events.Matches(&ports.EventFilter{Types: d.types}, event) {
+		return false
+	}
+	return events.Matches(&endpoint.Filter, event)
+}
+
+// Deliver makes one attempt to POST an event to an endpoint and records it
+// in the delivery log, numbered with events.DeliveryAttempt (1 if ctx does
+// not carry it). Responses with a 2xx status are accepted. Retries are left
+// to the caller; the error of a rejected URL or of a client error other than
+// 408 and 429 is not worth retrying.
+func (d *Dispatcher) Deliver(ctx context.Context, endpoint ports.WebhookEndpoint, event ports.Event) error {
+	ce, err := events.ToCloudEvent(event, d.source)
+	if err != nil {
+		return fmt.Errorf("failed to map event '%s': %w", event.ID, err)
+	}
+	body, err := json.Marshal(ce)
+	if err != nil {
+		return fmt.Errorf("failed to encode event '%s': %w", event.ID, err)
+	}
+	attempt := events.DeliveryAttempt(ctx)
+	if attempt == 0 {
+		attempt = 1
+	}
+	return d.attempt(ctx, endpoint, event, DeliveryID(endpoint.ID, event.ID), attempt, body)
+}
+
+// attempt makes one delivery attempt and records it in the delivery log.
+func (d *Dispatcher) attempt(ctx context.Context, endpoint ports.WebhookEndpoint, event ports.Event, deliveryID string, attempt int, body []byte) error {
+	started := d.now()
+	record := ports.WebhookDelivery{
+		ID:         deliveryID,
+		EndpointID: endpoint.ID,
+		EventID:    event.ID,
+		EventType:  event.Type,
+		Attempt:    attempt,
+		Timestamp:  started,
+	}
+	err := d.post(ctx, endpoint, event, deliveryID, body, started, &record)
+	record.Duration = d.now().Sub(started)
+	record.Succeeded = err == nil
+	if err != nil {
+		record.Error = err.Error()
+	}
+	if d.log != nil {
+		if logErr := d.log.RecordDelivery(ctx, record); logErr != nil && d.logger != nil {
+			d.logger.Error("failed to record webhook delivery", "endpoint", endpoint.ID, "event", event.ID, "error", logErr)
+		}
+	}
+	return err
+}
+
+// post sends the signed request, setting the status code of record.
+func (d *Dispatcher) post(ctx context.Context, endpoint ports.WebhookEndpoint, event ports.Event, deliveryID string, body []byte, now time.Time, record *ports.WebhookDelivery) error {
+	target, err := url.Parse(endpoint.URL)
+	if err != nil {
+		return &urlError{err: err}
+	}
+	if d.validate != nil {
+		if err := d.validate(ctx, target); err != nil {
+			return &urlError{err: err}
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return &urlError{err: err}
+	}
+	req.Header.Set("Content-Type", ContentTypeCloudEvents)
+	req.Header.Set(HeaderEvent, string(event.Type))
+	req.Header.Set(HeaderDelivery, deliveryID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(endpoint.Secret, now, body))
+
+	// Redirects are not followed: the target of a redirect has not been
+	// through the URL validator and would receive the signed body.
+	client := *d.client
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDeliveryFailed, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	record.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &statusError{code: resp.StatusCode}
+	}
+	return nil
+}
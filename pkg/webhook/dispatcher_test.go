@@ -0,0 +1,385 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aescanero/dago-libs/pkg/domain/graph"
+	"github.com/aescanero/dago-libs/pkg/events"
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// request is a webhook request received by a test endpoint.
+type request struct {
+	header http.Header
+	event  events.CloudEvent
+	err    error
+}
+
+// endpointServer starts an endpoint answering with the next status of
+// statuses, then 200, and verifying signatures with secret.
+func endpointServer(t *testing.T, secret string, statuses ...int) (*httptest.Server, <-chan request) {
+	t.Helper()
+	received := make(chan request, 16)
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		req := request{header: r.Header, err: Verify(secret, r.Header, body, 0, time.Now())}
+		if err := json.Unmarshal(body, &req.event); err != nil && req.err == nil {
+			req.err = err
+		}
+		received <- req
+
+		mu.Lock()
+		status := http.StatusOK
+		if len(statuses) > 0 {
+			status, statuses = statuses[0], statuses[1:]
+		}
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+func receive(t *testing.T, ch <-chan request) request {
+	t.Helper()
+	select {
+	case req := <-ch:
+		if req.err != nil {
+			t.Fatalf("invalid webhook request: %v", req.err)
+		}
+		return req
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a webhook request")
+		return request{}
+	}
+}
+
+func expectNone(t *testing.T, ch <-chan request) {
+	t.Helper()
+	select {
+	case req := <-ch:
+		t.Fatalf("unexpected webhook request %+v", req.event)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func newEvent(t *testing.T, executionID string, payload events.Payload) ports.Event {
+	t.Helper()
+	event, err := events.NewEvent(executionID, "", payload)
+	if err != nil {
+		t.Fatalf("NewEvent failed: %v", err)
+	}
+	return event
+}
+
+var fastRetry = &graph.RetryPolicy{MaxAttempts: 3, Backoff: graph.BackoffFixed, InitialInterval: graph.Duration(time.Millisecond)}
+
+func TestDispatcher_DeliversLifecycleEvents(t *testing.T) {
+	ctx := context.Background()
+	all, allReceived := endpointServer(t, "secret-a")
+	failures, failuresReceived := endpointServer(t, "secret-b")
+
+	registry := NewMemoryRegistry()
+	registry.RegisterEndpoint(ctx, ports.WebhookEndpoint{ID: "all", URL: all.URL, Secret: "secret-a"})
+	registry.RegisterEndpoint(ctx, ports.WebhookEndpoint{ID: "failures", URL: failures.URL, Secret: "secret-b",
+		Filter: ports.EventFilter{Types: []ports.EventType{ports.EventTypeGraphFailed}}})
+	registry.RegisterEndpoint(ctx, ports.WebhookEndpoint{ID: "disabled", URL: all.URL, Secret: "secret-a", Disabled: true})
+
+	bus := events.NewMemoryBus()
+	defer bus.Close()
+	dispatcher := NewDispatcher(bus, "executions.>", registry).WithSource("/tests")
+	if err := dispatcher.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer dispatcher.Stop(ctx)
+	if err := dispatcher.Start(ctx); err == nil {
+		t.Error("expected a second Start to fail")
+	}
+
+	completed := newEvent(t, "exec-1", events.GraphCompletedPayload{})
+	bus.Publish(ctx, "executions.exec-1", newEvent(t, "exec-1", events.NodeStartedPayload{}))
+	bus.Publish(ctx, "executions.exec-1", completed)
+	bus.Publish(ctx, "executions.exec-2", newEvent(t, "exec-2", events.GraphFailedPayload{Error: "boom"}))
+
+	req := receive(t, allReceived)
+	if req.event.ID != completed.ID || req.event.Type != events.CloudEventTypePrefix+"graph.completed" || req.event.Source != "/tests" {
+		t.Errorf("expected the completed event as a CloudEvent, got %+v", req.event)
+	}
+	if req.header.Get("Content-Type") != ContentTypeCloudEvents || req.header.Get(HeaderEvent) != "graph.completed" || req.header.Get(HeaderDelivery) == "" {
+		t.Errorf("unexpected headers %v", req.header)
+	}
+	if req := receive(t, allReceived); req.header.Get(HeaderEvent) != "graph.failed" {
+		t.Errorf("expected the failed event, got %s", req.header.Get(HeaderEvent))
+	}
+	if req := receive(t, failuresReceived); req.header.Get(HeaderEvent) != "graph.failed" {
+		t.Errorf("expected only the failed event on the filtered endpoint, got %s", req.header.Get(HeaderEvent))
+	}
+	expectNone(t, allReceived)
+	expectNone(t, failuresReceived)
+}
+
+// startDispatcher starts a dispatcher of the "executions" topic to an
+// endpoint, and subscribes to the dead letters of its deliveries.
+func startDispatcher(t *testing.T, endpoint ports.WebhookEndpoint, log ports.WebhookDeliveryLog) (*events.MemoryBus, <-chan ports.Event) {
+	t.Helper()
+	ctx := context.Background()
+	registry := NewMemoryRegistry()
+	registry.RegisterEndpoint(ctx, endpoint)
+	bus := events.NewMemoryBus()
+	t.Cleanup(func() { bus.Close() })
+
+	dispatcher := NewDispatcher(bus, "executions", registry).WithDeliveryLog(log).WithRetry(fastRetry)
+	if err := dispatcher.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { dispatcher.Stop(ctx) })
+
+	dead := make(chan ports.Event, 4)
+	_, err := bus.Subscribe(ctx, DefaultDeliveryTopic+ports.DeadLetterSuffix, func(ctx context.Context, event ports.Event) error {
+		dead <- event
+		return nil
+	}, ports.SubscribeOptions{})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	return bus, dead
+}
+
+func TestDispatcher_Retries(t *testing.T) {
+	ctx := context.Background()
+	server, received := endpointServer(t, "secret", http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	log := NewMemoryDeliveryLog(0)
+	bus, dead := startDispatcher(t, ports.WebhookEndpoint{ID: "hook", URL: server.URL, Secret: "secret"}, log)
+
+	bus.Publish(ctx, "executions", newEvent(t, "exec-1", events.GraphCompletedPayload{}))
+	first, second, third := receive(t, received), receive(t, received), receive(t, received)
+	if id := first.header.Get(HeaderDelivery); id != second.header.Get(HeaderDelivery) || id != third.header.Get(HeaderDelivery) {
+		t.Error("expected the attempts to share the delivery ID")
+	}
+	expectNone(t, received)
+
+	var deliveries []ports.WebhookDelivery
+	deadline := time.Now().Add(2 * time.Second)
+	for len(deliveries) < 3 && time.Now().Before(deadline) {
+		deliveries, _ = log.ListDeliveries(ctx, "hook", 0)
+		time.Sleep(time.Millisecond)
+	}
+	if len(deliveries) != 3 {
+		t.Fatalf("expected 3 logged attempts, got %+v", deliveries)
+	}
+	if d := deliveries[0]; !d.Succeeded || d.Attempt != 3 || d.StatusCode != http.StatusOK {
+		t.Errorf("expected the last attempt to succeed, got %+v", d)
+	}
+	if d := deliveries[2]; d.Succeeded || d.Attempt != 1 || d.StatusCode != http.StatusServiceUnavailable || d.Error == "" {
+		t.Errorf("expected the first attempt to fail with 503, got %+v", d)
+	}
+	select {
+	case event := <-dead:
+		t.Errorf("unexpected dead letter %+v", event)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestDispatcher_PermanentFailure(t *testing.T) {
+	ctx := context.Background()
+	server, received := endpointServer(t, "secret", http.StatusGone)
+	bus, dead := startDispatcher(t, ports.WebhookEndpoint{ID: "hook", URL: server.URL, Secret: "secret"}, nil)
+
+	event := newEvent(t, "exec-1", events.GraphFailedPayload{})
+	bus.Publish(ctx, "executions", event)
+	receive(t, received)
+	select {
+	case letter := <-dead:
+		if letter.Metadata[MetadataEventID] != event.ID || letter.Metadata[MetadataEndpointID] != "hook" {
+			t.Errorf("expected the delivery of the event to hook, got %+v", letter)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the dead letter")
+	}
+	expectNone(t, received)
+}
+
+func TestDispatcher_GivesUp(t *testing.T) {
+	ctx := context.Background()
+	server, received := endpointServer(t, "secret", 500, 500, 500, 500)
+	bus, dead := startDispatcher(t, ports.WebhookEndpoint{ID: "hook", URL: server.URL, Secret: "secret"}, nil)
+
+	bus.Publish(ctx, "executions", newEvent(t, "exec-1", events.GraphFailedPayload{}))
+	for range fastRetry.MaxAttempts {
+		receive(t, received)
+	}
+	select {
+	case letter := <-dead:
+		if letter.Metadata[ports.MetadataDeliveries] != fastRetry.MaxAttempts {
+			t.Errorf("expected %d deliveries, got %v", fastRetry.MaxAttempts, letter.Metadata[ports.MetadataDeliveries])
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the dead letter")
+	}
+	expectNone(t, received)
+}
+
+func TestDispatcher_HandleDoesNotWaitForEndpoints(t *testing.T) {
+	ctx := context.Background()
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { <-release }))
+	defer server.Close()
+	defer close(release)
+
+	registry := NewMemoryRegistry()
+	registry.RegisterEndpoint(ctx, ports.WebhookEndpoint{ID: "slow", URL: server.URL})
+	bus := events.NewMemoryBus()
+	defer bus.Close()
+	dispatcher := NewDispatcher(bus, "executions", registry)
+	if err := dispatcher.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer dispatcher.Stop(ctx)
+
+	done := make(chan error, 1)
+	go func() { done <- dispatcher.Handle(ctx, newEvent(t, "exec-1", events.GraphCompletedPayload{})) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Handle failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Handle waited for the endpoint")
+	}
+}
+
+func TestDispatcher_Deliver(t *testing.T) {
+	ctx := context.Background()
+	server, received := endpointServer(t, "secret", http.StatusServiceUnavailable)
+	log := NewMemoryDeliveryLog(0)
+	dispatcher := NewDispatcher(events.NewMemoryBus(), "executions", NewMemoryRegistry()).WithDeliveryLog(log)
+	endpoint := ports.WebhookEndpoint{ID: "hook", URL: server.URL, Secret: "secret"}
+	event := newEvent(t, "exec-1", events.GraphCompletedPayload{})
+
+	if err := dispatcher.Deliver(ctx, endpoint, event); !errors.Is(err, ErrDeliveryFailed) {
+		t.Fatalf("expected ErrDeliveryFailed, got %v", err)
+	}
+	if req := receive(t, received); req.header.Get(HeaderDelivery) != DeliveryID("hook", event.ID) {
+		t.Errorf("unexpected delivery ID %s", req.header.Get(HeaderDelivery))
+	}
+	expectNone(t, received)
+
+	if err := dispatcher.Deliver(events.WithDeliveryAttempt(ctx, 2), endpoint, event); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+	if deliveries, _ := log.ListDeliveries(ctx, "hook", 0); len(deliveries) != 2 || deliveries[0].Attempt != 2 || deliveries[1].Attempt != 1 {
+		t.Errorf("expected two numbered attempts, got %+v", deliveries)
+	}
+}
+
+func TestDispatcher_URLValidator(t *testing.T) {
+	ctx := context.Background()
+	server, received := endpointServer(t, "secret")
+	dispatcher := NewDispatcher(events.NewMemoryBus(), "executions", NewMemoryRegistry()).WithURLValidator(DenyPrivateNetworks)
+
+	err := dispatcher.Deliver(ctx, ports.WebhookEndpoint{ID: "local", URL: server.URL}, newEvent(t, "exec-1", events.GraphCompletedPayload{}))
+	if !errors.Is(err, ErrURLNotAllowed) || retryable(err) {
+		t.Fatalf("expected a final ErrURLNotAllowed, got %v", err)
+	}
+	expectNone(t, received)
+}
+
+func TestDispatcher_DoesNotFollowRedirects(t *testing.T) {
+	ctx := context.Background()
+	internal, reached := endpointServer(t, "secret")
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL, http.StatusTemporaryRedirect)
+	}))
+	t.Cleanup(redirect.Close)
+	log := NewMemoryDeliveryLog(0)
+	dispatcher := NewDispatcher(events.NewMemoryBus(), "executions", NewMemoryRegistry()).WithDeliveryLog(log)
+
+	err := dispatcher.Deliver(ctx, ports.WebhookEndpoint{ID: "hook", URL: redirect.URL, Secret: "secret"}, newEvent(t, "exec-1", events.GraphCompletedPayload{}))
+	if !errors.Is(err, ErrDeliveryFailed) || retryable(err) {
+		t.Fatalf("expected a final ErrDeliveryFailed, got %v", err)
+	}
+	expectNone(t, reached)
+	if deliveries, _ := log.ListDeliveries(ctx, "hook", 0); len(deliveries) != 1 || deliveries[0].StatusCode != http.StatusTemporaryRedirect {
+		t.Errorf("expected the redirect to be recorded, got %+v", deliveries)
+	}
+}
+
+func TestDenyPrivateNetworks(t *testing.T) {
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://93.184.216.34/hook", true},
+		{"http://[2606:2800:220:1::1]/hook", true},
+		{"http://127.0.0.1:8080/hook", false},
+		{"http://10.1.2.3/hook", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://[::1]/hook", false},
+		{"http://[::ffff:192.168.0.1]/hook", false},
+		{"http://0.0.0.0/hook", false},
+		{"file:///etc/passwd", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			err = DenyPrivateNetworks(context.Background(), u)
+			if tt.allowed != (err == nil) {
+				t.Errorf("expected allowed=%v, got %v", tt.allowed, err)
+			}
+			if err != nil && !errors.Is(err, ErrURLNotAllowed) {
+				t.Errorf("expected ErrURLNotAllowed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestWebhookEndpoint_SecretNotEncoded(t *testing.T) {
+	data, err := json.Marshal(ports.WebhookEndpoint{ID: "hook", URL: "https://example.com", Secret: "s3cr3t"})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if strings.Contains(string(data), "s3cr3t") {
+		t.Errorf("expected the secret to be left out, got %s", data)
+	}
+}
+
+func TestDispatcher_Matches(t *testing.T) {
+	dispatcher := NewDispatcher(events.NewMemoryBus(), "executions", NewMemoryRegistry()).
+		WithEventTypes(ports.EventTypeGraphCompleted, "node.*")
+	event := ports.Event{Type: ports.EventTypeNodeFailed, ExecutionID: "exec-1", Metadata: map[string]interface{}{"tenant": "acme"}}
+
+	tests := []struct {
+		name     string
+		endpoint ports.WebhookEndpoint
+		event    ports.Event
+		want     bool
+	}{
+		{"dispatcher types", ports.WebhookEndpoint{}, event, true},
+		{"other dispatcher types", ports.WebhookEndpoint{}, ports.Event{Type: ports.EventTypeGraphFailed}, false},
+		{"endpoint types", ports.WebhookEndpoint{Filter: ports.EventFilter{Types: []ports.EventType{ports.EventTypeGraphCompleted}}}, event, false},
+		{"labels", ports.WebhookEndpoint{Filter: ports.EventFilter{Labels: map[string]string{"tenant": "acme"}}}, event, true},
+		{"other labels", ports.WebhookEndpoint{Filter: ports.EventFilter{Labels: map[string]string{"tenant": "globex"}}}, event, false},
+		{"disabled", ports.WebhookEndpoint{Disabled: true}, event, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dispatcher.Matches(tt.endpoint, tt.event); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+// Package webhook notifies external URLs of execution events.
+//
+// A Dispatcher subscribes to the ports.EventBus and delivers the events of
+// its event types, graph.completed and graph.failed by default, to the
+// endpoints of a ports.WebhookRegistry whose ports.EventFilter they pass.
+// Each event is POSTed as a CloudEvent in structured JSON mode, with
+// headers naming the event type and the delivery.
+//
+// Requests are signed with the HMAC-SHA256 of their timestamp and body,
+// keyed with the endpoint secret (see Sign). Receivers check the signature
+// and its age with Verify.
+//
+// The handler of the dispatched events only queues one delivery event per
+// endpoint on a delivery topic; the requests are made by the handler of that
+// topic, one attempt per delivery. Failed attempts are negatively
+// acknowledged and retried by the bus with the backoff of a
+// graph.RetryPolicy, then dead-lettered; client errors other than 408 and 429
+// are final. Every attempt is recorded in a ports.WebhookDeliveryLog.
+//
+// Endpoint URLs come from users, so a URLValidator such as
+// DenyPrivateNetworks should keep requests away from internal hosts.
+// Redirects are not followed, so endpoints cannot bounce requests past it.
+// MemoryRegistry and MemoryDeliveryLog are in-memory implementations of the
+// ports.
+package webhook
@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+// ErrEndpointNotFound is returned for unknown endpoint IDs.
+var ErrEndpointNotFound = errors.New("webhook endpoint not found")
+
+// MemoryRegistry is an in-memory ports.WebhookRegistry.
+type MemoryRegistry struct {
+	mu        sync.RWMutex
+	endpoints map[string]ports.WebhookEndpoint
+}
+
+// NewMemoryRegistry creates an empty in-memory registry.
+func NewMemoryRegistry() *MemoryRegistry {
+	return &MemoryRegistry{endpoints: make(map[string]ports.WebhookEndpoint)}
+}
+
+// RegisterEndpoint saves an endpoint, replacing any endpoint with its ID.
+func (r *MemoryRegistry) RegisterEndpoint(ctx context.Context, endpoint ports.WebhookEndpoint) error {
+	if endpoint.ID == "" || endpoint.URL == "" {
+		return errors.New("failed to register endpoint: ID and URL are required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.endpoints[endpoint.ID] = endpoint
+	return nil
+}
+
+// UnregisterEndpoint removes an endpoint.
+func (r *MemoryRegistry) UnregisterEndpoint(ctx context.Context, endpointID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.endpoints[endpointID]; !ok {
+		return fmt.Errorf("%w: '%s'", ErrEndpointNotFound, endpointID)
+	}
+	delete(r.endpoints, endpointID)
+	return nil
+}
+
+// GetEndpoint retrieves an endpoint.
+func (r *MemoryRegistry) GetEndpoint(ctx context.Context, endpointID string) (*ports.WebhookEndpoint, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	endpoint, ok := r.endpoints[endpointID]
+	if !ok {
+		return nil, fmt.Errorf("%w: '%s'", ErrEndpointNotFound, endpointID)
+	}
+	return &endpoint, nil
+}
+
+// ListEndpoints returns every endpoint, sorted by ID.
+func (r *MemoryRegistry) ListEndpoints(ctx context.Context) ([]ports.WebhookEndpoint, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := make([]ports.WebhookEndpoint, 0, len(r.endpoints))
+	for _, endpoint := range r.endpoints {
+		list = append(list, endpoint)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+// MemoryDeliveryLog is an in-memory ports.WebhookDeliveryLog keeping the
+// most recent attempts of each endpoint.
+type MemoryDeliveryLog struct {
+	mu         sync.Mutex
+	capacity   int
+	deliveries map[string][]ports.WebhookDelivery // by endpoint, oldest first
+}
+
+// NewMemoryDeliveryLog creates a log keeping up to capacity attempts per
+// endpoint. Zero or less keeps every attempt.
+func NewMemoryDeliveryLog(capacity int) *MemoryDeliveryLog {
+	return &MemoryDeliveryLog{capacity: capacity, deliveries: make(map[string][]ports.WebhookDelivery)}
+}
+
+// RecordDelivery appends an attempt, dropping the oldest attempt of its
+// endpoint beyond the capacity.
+func (l *MemoryDeliveryLog) RecordDelivery(ctx context.Context, delivery ports.WebhookDelivery) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	list := append(l.deliveries[delivery.EndpointID], delivery)
+	if l.capacity > 0 && len(list) > l.capacity {
+		list = list[len(list)-l.capacity:]
+	}
+	l.deliveries[delivery.EndpointID] = list
+	return nil
+}
+
+// ListDeliveries returns up to limit of the most recent attempts of an
+// endpoint, newest first.
+func (l *MemoryDeliveryLog) ListDeliveries(ctx context.Context, endpointID string, limit int) ([]ports.WebhookDelivery, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	list := l.deliveries[endpointID]
+	out := make([]ports.WebhookDelivery, 0, len(list))
+	for i := len(list) - 1; i >= 0 && (limit <= 0 || len(out) < limit); i-- {
+		out = append(out, list[i])
+	}
+	return out, nil
+}
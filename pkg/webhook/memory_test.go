@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aescanero/dago-libs/pkg/ports"
+)
+
+func TestMemoryRegistry(t *testing.T) {
+	ctx := context.Background()
+	registry := NewMemoryRegistry()
+	for _, id := range []string{"b", "a"} {
+		if err := registry.RegisterEndpoint(ctx, ports.WebhookEndpoint{ID: id, URL: "https://example.com/" + id}); err != nil {
+			t.Fatalf("RegisterEndpoint failed: %v", err)
+		}
+	}
+	if err := registry.RegisterEndpoint(ctx, ports.WebhookEndpoint{ID: "c"}); err == nil {
+		t.Error("expected an endpoint without URL to be rejected")
+	}
+
+	list, _ := registry.ListEndpoints(ctx)
+	if len(list) != 2 || list[0].ID != "a" || list[1].ID != "b" {
+		t.Errorf("expected endpoints a and b, got %+v", list)
+	}
+	if err := registry.UnregisterEndpoint(ctx, "a"); err != nil {
+		t.Fatalf("UnregisterEndpoint failed: %v", err)
+	}
+	if _, err := registry.GetEndpoint(ctx, "a"); !errors.Is(err, ErrEndpointNotFound) {
+		t.Errorf("expected ErrEndpointNotFound, got %v", err)
+	}
+}
+
+func TestMemoryDeliveryLog(t *testing.T) {
+	ctx := context.Background()
+	log := NewMemoryDeliveryLog(2)
+	for attempt := 1; attempt <= 3; attempt++ {
+		log.RecordDelivery(ctx, ports.WebhookDelivery{EndpointID: "a", Attempt: attempt})
+	}
+
+	list, _ := log.ListDeliveries(ctx, "a", 0)
+	if len(list) != 2 || list[0].Attempt != 3 || list[1].Attempt != 2 {
+		t.Errorf("expected the 2 latest attempts, newest first, got %+v", list)
+	}
+	if list, _ := log.ListDeliveries(ctx, "a", 1); len(list) != 1 || list[0].Attempt != 3 {
+		t.Errorf("expected the latest attempt, got %+v", list)
+	}
+}
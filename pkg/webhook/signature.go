@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers of webhook requests.
+const (
+	// HeaderSignature holds the HMAC-SHA256 signature of the request, as
+	// "sha256=" followed by the hex digest (see Sign).
+	HeaderSignature = "X-Dago-Signature"
+
+	// HeaderTimestamp holds the Unix time the request was signed at.
+	HeaderTimestamp = "X-Dago-Timestamp"
+
+	// HeaderEvent holds the event type.
+	HeaderEvent = "X-Dago-Event"
+
+	// HeaderDelivery holds the delivery ID, the same for every attempt.
+	HeaderDelivery = "X-Dago-Delivery"
+)
+
+// signaturePrefix names the signature algorithm in HeaderSignature.
+const signaturePrefix = "sha256="
+
+// DefaultTolerance is the maximum age of a signature accepted by Verify.
+const DefaultTolerance = 5 * time.Minute
+
+// ErrInvalidSignature is returned by Verify for requests that were not
+// signed with the endpoint secret, or were signed too long ago.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Sign returns the HeaderSignature value of a request body sent at
+// timestamp: the HMAC-SHA256, keyed with secret, of the Unix timestamp, a
+// dot and the body. Signing the timestamp prevents replaying old requests.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a webhook request received at now, for
+// receivers of webhooks. Signatures older than tolerance are rejected; zero
+// means DefaultTolerance.
+func Verify(secret string, header http.Header, body []byte, tolerance time.Duration, now time.Time) error {
+	if tolerance == 0 {
+		tolerance = DefaultTolerance
+	}
+	unix, err := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: missing or malformed %s", ErrInvalidSignature, HeaderTimestamp)
+	}
+	timestamp := time.Unix(unix, 0)
+	if age := now.Sub(timestamp); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: signed %s ago", ErrInvalidSignature, age.Round(time.Second))
+	}
+	signature := header.Get(HeaderSignature)
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return fmt.Errorf("%w: missing or malformed %s", ErrInvalidSignature, HeaderSignature)
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body))) {
+		return fmt.Errorf("%w: signature mismatch", ErrInvalidSignature)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func signedHeader(secret string, at time.Time, body []byte) http.Header {
+	header := make(http.Header)
+	header.Set(HeaderTimestamp, strconv.FormatInt(at.Unix(), 10))
+	header.Set(HeaderSignature, Sign(secret, at, body))
+	return header
+}
+
+func TestVerify(t *testing.T) {
+	at := time.Unix(1735689600, 0)
+	body := []byte(`{"id":"1"}`)
+
+	if err := Verify("s3cret", signedHeader("s3cret", at, body), body, 0, at.Add(time.Minute)); err != nil {
+		t.Errorf("expected a valid signature, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		now    time.Time
+	}{
+		{"other secret", signedHeader("other", at, body), body, at},
+		{"tampered body", signedHeader("s3cret", at, body), []byte(`{"id":"2"}`), at},
+		{"too old", signedHeader("s3cret", at, body), body, at.Add(DefaultTolerance + time.Second)},
+		{"unsigned", make(http.Header), body, at},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify("s3cret", tt.header, tt.body, 0, tt.now); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("expected ErrInvalidSignature, got %v", err)
+			}
+		})
+	}
+}
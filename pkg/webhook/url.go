@@ -0,0 +1,55 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+)
+
+// ErrURLNotAllowed is returned by URL validators for endpoint URLs that must
+// not be requested.
+var ErrURLNotAllowed = errors.New("webhook URL not allowed")
+
+// URLValidator checks the URL of an endpoint before it is requested. It
+// returns an error, typically wrapping ErrURLNotAllowed, for URLs that must
+// not be requested.
+type URLValidator func(ctx context.Context, u *url.URL) error
+
+// DenyPrivateNetworks is a URLValidator that accepts http and https URLs
+// whose host resolves to public addresses only: loopback, private,
+// link-local, multicast and unspecified addresses are rejected, so that
+// endpoints cannot reach internal services.
+//
+// The host is resolved again when the request connects; where a DNS server
+// may answer differently the second time, also restrict the addresses the
+// HTTP client dials.
+func DenyPrivateNetworks(ctx context.Context, u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme '%s'", ErrURLNotAllowed, u.Scheme)
+	}
+	host := u.Hostname()
+	if host == "" {
+		return fmt.Errorf("%w: no host", ErrURLNotAllowed)
+	}
+	var addrs []netip.Addr
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addrs = []netip.Addr{addr}
+	} else {
+		ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+		if err != nil {
+			return fmt.Errorf("failed to resolve '%s': %w", host, err)
+		}
+		addrs = ips
+	}
+	for _, addr := range addrs {
+		addr = addr.Unmap()
+		if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+			addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+			return fmt.Errorf("%w: '%s' resolves to %s", ErrURLNotAllowed, host, addr)
+		}
+	}
+	return nil
+}